@@ -0,0 +1,73 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"encoding"
+	"encoding/xml"
+	"time"
+)
+
+var (
+	_ = encoding.TextMarshaler((*Date)(nil))
+	_ = encoding.TextUnmarshaler((*Date)(nil))
+	_ = xml.Marshaler((*Date)(nil))
+	_ = xml.Unmarshaler((*Date)(nil))
+)
+
+// DateLayout is the format of a Date.
+const DateLayout = "2006-01-02"
+
+// Date is a DateTime without the time part.
+type Date struct {
+	time.Time
+}
+
+func (d *Date) IsZero() bool { return d == nil || (&DateTime{Time: d.Time}).IsZero() }
+
+func (d *Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Time.Format(DateLayout)
+}
+
+func (d *Date) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if d.IsZero() {
+		return encodeXMLNil(enc, start)
+	}
+	return enc.EncodeElement(d.Time.Format(DateLayout), start)
+}
+func (d *Date) UnmarshalXML(dec *xml.Decoder, st xml.StartElement) error {
+	var s string
+	if err := dec.DecodeElement(&s, &st); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d *Date) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.Time.AppendFormat(make([]byte, 0, len(DateLayout)), DateLayout), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It accepts anything DateTime accepts, but drops the time part.
+func (d *Date) UnmarshalText(data []byte) error {
+	var dt DateTime
+	if err := dt.UnmarshalText(data); err != nil {
+		return err
+	}
+	if dt.IsZero() {
+		d.Time = time.Time{}
+		return nil
+	}
+	y, m, day := dt.Time.Date()
+	d.Time = time.Date(y, m, day, 0, 0, 0, 0, time.Local)
+	return nil
+}
@@ -81,6 +81,11 @@ func (dt *DateTime) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 	if dt != nil && !dt.IsZero() {
 		return enc.EncodeElement(dt.Time.In(time.Local).Format(time.RFC3339), start)
 	}
+	return encodeXMLNil(enc, start)
+}
+
+// encodeXMLNil encodes an empty element with the xsi:nil="true" attribute.
+func encodeXMLNil(enc *xml.Encoder, start xml.StartElement) error {
 	start.Attr = append(start.Attr,
 		xml.Attr{Name: xml.Name{Space: "http://www.w3.org/2001/XMLSchema-instance", Local: "nil"}, Value: "true"})
 
@@ -5,6 +5,7 @@
 package custom_test
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"strings"
 	"testing"
@@ -64,3 +65,142 @@ func TestDateTimeXML(t *testing.T) {
 		}
 	}
 }
+
+func TestDateXML(t *testing.T) {
+	var buf strings.Builder
+	enc := xml.NewEncoder(&buf)
+	st := xml.StartElement{Name: xml.Name{Local: "element"}}
+	for _, tC := range []struct {
+		D custom.Date
+		S string
+	}{
+		{
+			D: custom.Date{},
+			S: `<element xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></element>`,
+		},
+		{
+			D: custom.Date{Time: time.Date(2023, 6, 30, 0, 0, 0, 0, time.Local)},
+			S: `<element>2023-06-30</element>`,
+		},
+	} {
+		buf.Reset()
+		if err := tC.D.MarshalXML(enc, st); err != nil {
+			t.Fatalf("%v: %+v", tC.D, err)
+		}
+		if got := buf.String(); got != tC.S {
+			t.Errorf("%v: got %q wanted %q", tC.D, got, tC.S)
+		}
+
+		var d custom.Date
+		if err := xml.Unmarshal([]byte(tC.S), &d); err != nil {
+			t.Fatalf("%v: %+v", tC.S, err)
+		}
+		if !d.Time.Equal(tC.D.Time) {
+			t.Errorf("%v: got %q wanted %q", tC.S, d, tC.D)
+		}
+	}
+
+	var d custom.Date
+	if err := xml.Unmarshal([]byte(`<element>2019-10-22T16:56:32</element>`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.String(); got != "2019-10-22" {
+		t.Errorf("got %q wanted 2019-10-22", got)
+	}
+}
+
+func TestNumberXML(t *testing.T) {
+	var buf strings.Builder
+	enc := xml.NewEncoder(&buf)
+	st := xml.StartElement{Name: xml.Name{Local: "element"}}
+	for _, tC := range []struct {
+		N custom.Number
+		S string
+	}{
+		{
+			N: "",
+			S: `<element xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></element>`,
+		},
+		{N: "0", S: `<element>0</element>`},
+		{N: "-3.14", S: `<element>-3.14</element>`},
+		{N: "12345678901234567890", S: `<element>12345678901234567890</element>`},
+	} {
+		buf.Reset()
+		if err := tC.N.MarshalXML(enc, st); err != nil {
+			t.Fatalf("%q: %+v", tC.N, err)
+		}
+		if got := buf.String(); got != tC.S {
+			t.Errorf("%q: got %q wanted %q", tC.N, got, tC.S)
+		}
+
+		var n custom.Number
+		if err := xml.Unmarshal([]byte(tC.S), &n); err != nil {
+			t.Fatalf("%v: %+v", tC.S, err)
+		}
+		if n != tC.N {
+			t.Errorf("%v: got %q wanted %q", tC.S, n, tC.N)
+		}
+	}
+
+	var n custom.Number
+	if err := xml.Unmarshal([]byte(`<element>abc</element>`), &n); err == nil {
+		t.Errorf("wanted error for non-number, got %q", n)
+	}
+}
+
+func TestTimestampMicros(t *testing.T) {
+	want := custom.Timestamp{Time: time.Date(2023, 7, 14, 10, 11, 12, 123456000, time.Local)}
+
+	b, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"element"`
+		Ts      *custom.Timestamp `xml:"ts"`
+	}{Ts: &want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), ".123456") {
+		t.Errorf("XML lost the microseconds: %s", b)
+	}
+	var x struct {
+		Ts custom.Timestamp `xml:"ts"`
+	}
+	if err = xml.Unmarshal(b, &x); err != nil {
+		t.Fatalf("%s: %+v", b, err)
+	}
+	if !x.Ts.Time.Equal(want.Time) {
+		t.Errorf("XML: got %v, wanted %v", x.Ts, want)
+	}
+
+	if b, err = json.Marshal(&want); err != nil {
+		t.Fatal(err)
+	}
+	var j custom.Timestamp
+	if err = json.Unmarshal(b, &j); err != nil {
+		t.Fatalf("%s: %+v", b, err)
+	}
+	if !j.Time.Equal(want.Time) {
+		t.Errorf("JSON %s: got %v, wanted %v", b, j, want)
+	}
+
+	if b, err = want.MarshalText(); err != nil {
+		t.Fatal(err)
+	}
+	var txt custom.Timestamp
+	if err = txt.UnmarshalText(b); err != nil {
+		t.Fatalf("%s: %+v", b, err)
+	}
+	if !txt.Time.Equal(want.Time) || txt.String() != want.String() {
+		t.Errorf("text %s: got %v, wanted %v", b, txt, want)
+	}
+
+	if b, err = want.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	var pb custom.Timestamp
+	if err = pb.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if !pb.Time.Equal(want.Time) {
+		t.Errorf("proto: got %v, wanted %v", pb, want)
+	}
+}
@@ -5,13 +5,17 @@
 package custom
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
 	"math"
+	"mime"
 	"strconv"
 	"strings"
 	"time"
@@ -59,6 +63,30 @@ func (n *Number) Scan(src interface{}) error {
 	return nil
 }
 
+// MarshalXML encodes the number as the element's text,
+// or as an xsi:nil="true" element if it is NULL (empty).
+func (n Number) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if n == "" {
+		return encodeXMLNil(enc, start)
+	}
+	return enc.EncodeElement(string(n), start)
+}
+
+// UnmarshalXML decodes the element's text as a number; an empty element is NULL.
+func (n *Number) UnmarshalXML(dec *xml.Decoder, st xml.StartElement) error {
+	var s string
+	if err := dec.DecodeElement(&s, &st); err != nil {
+		return err
+	}
+	if s = strings.TrimSpace(s); s != "" {
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return fmt.Errorf("%q is not a number: %w", s, err)
+		}
+	}
+	*n = Number(s)
+	return nil
+}
+
 func NumbersFromStrings(s *[]string) *[]godror.Number {
 	if s == nil {
 		return nil
@@ -97,14 +125,27 @@ func ParseTime(t *time.Time, s string) error {
 type Lob struct {
 	err error
 	*godror.Lob
-	data []byte
+	// ContentType is the optional media type (with charset) of the data,
+	// such as "text/plain; charset=utf-8".
+	// When set, the Lob is marshaled to JSON as a {"contentType", "data"} envelope.
+	ContentType string
+	data        []byte
+}
+
+// SetContentType sets the media type hint of the data, such as "application/pdf",
+// optionally with its charset: SetContentType("text/plain", "utf-8").
+func (L *Lob) SetContentType(mediaType, charset string) {
+	if charset != "" {
+		mediaType = mime.FormatMediaType(mediaType, map[string]string{"charset": charset})
+	}
+	L.ContentType = mediaType
 }
 
 func (L *Lob) read() error {
 	if L.err != nil {
 		return L.err
 	}
-	if L.data == nil {
+	if L.data == nil && L.Lob != nil {
 		L.data, L.err = io.ReadAll(L.Lob)
 	}
 	return L.err
@@ -129,10 +170,42 @@ func (L *Lob) Unmarshal(p []byte) error {
 	return nil
 }
 
+// lobEnvelope is the JSON form of a Lob with ContentType.
+type lobEnvelope struct {
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
+}
+
+// MarshalJSON encodes the data as base64, or, if ContentType is set,
+// as a {"contentType": ..., "data": base64} envelope.
+func (L *Lob) MarshalJSON() ([]byte, error) {
+	if err := L.read(); err != nil {
+		return nil, err
+	}
+	if L.ContentType == "" {
+		return json.Marshal(L.data)
+	}
+	return json.Marshal(lobEnvelope{ContentType: L.ContentType, Data: L.data})
+}
+
+// UnmarshalJSON decodes both the plain and the enveloped forms of MarshalJSON.
+func (L *Lob) UnmarshalJSON(p []byte) error {
+	if b := bytes.TrimSpace(p); len(b) != 0 && b[0] == '{' {
+		var env lobEnvelope
+		if err := json.Unmarshal(b, &env); err != nil {
+			return err
+		}
+		L.ContentType, L.data = env.ContentType, env.Data
+		return nil
+	}
+	L.ContentType = ""
+	return json.Unmarshal(p, &L.data)
+}
+
 // Value returns a driver Value.
 func (L *Lob) Value() (driver.Value, error) {
 	err := L.read()
-	if L.Lob.IsClob {
+	if L.Lob != nil && L.Lob.IsClob {
 		return string(L.data), err
 	}
 	return L.data, err
@@ -356,6 +429,12 @@ func AsTimestamp(v interface{}) *timestamppb.Timestamp {
 		if !d.IsZero() {
 			return timestamppb.New(d.Time)
 		}
+	case Timestamp:
+		return timestamppb.New(d.Time)
+	case *Timestamp:
+		if !d.IsZero() {
+			return timestamppb.New(d.Time)
+		}
 	case string:
 		var t time.Time
 		_ = ParseTime(&t, d)
@@ -382,6 +461,11 @@ func AsDate(v interface{}) *DateTime {
 			return new(DateTime)
 		}
 		return d
+	case *Timestamp:
+		if d == nil {
+			return new(DateTime)
+		}
+		return (*DateTime)(d)
 	case *timestamppb.Timestamp:
 		if d == nil {
 			return new(DateTime)
@@ -392,6 +476,8 @@ func AsDate(v interface{}) *DateTime {
 	switch x := v.(type) {
 	case DateTime:
 		*d = x
+	case Timestamp:
+		d.Time = x.Time
 	case time.Time:
 		d.Time = x
 	case string:
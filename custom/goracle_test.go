@@ -0,0 +1,66 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package custom_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/godror/knownpb/timestamppb"
+	"github.com/tgulacsi/oracall/custom"
+)
+
+func TestLobJSON(t *testing.T) {
+	for nm, tC := range map[string]struct {
+		MediaType, Charset string
+		Want               string
+	}{
+		"plain":     {Want: `"aGVsbG8="`},
+		"enveloped": {MediaType: "text/plain", Charset: "utf-8", Want: `{"contentType":"text/plain; charset=utf-8","data":"aGVsbG8="}`},
+		"binary":    {MediaType: "application/pdf", Want: `{"contentType":"application/pdf","data":"aGVsbG8="}`},
+	} {
+		t.Run(nm, func(t *testing.T) {
+			var L custom.Lob
+			if err := L.Scan("hello"); err != nil {
+				t.Fatal(err)
+			}
+			L.SetContentType(tC.MediaType, tC.Charset)
+			b, err := json.Marshal(&L)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tC.Want {
+				t.Errorf("got %s, wanted %s", got, tC.Want)
+			}
+
+			var back custom.Lob
+			if err := json.Unmarshal(b, &back); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := back.Marshal(); string(data) != "hello" {
+				t.Errorf("got data %q, wanted %q", data, "hello")
+			}
+			if back.ContentType != L.ContentType {
+				t.Errorf("got content type %q, wanted %q", back.ContentType, L.ContentType)
+			}
+		})
+	}
+}
+
+func TestAsTimeRoundTrip(t *testing.T) {
+	want := time.Date(2013, 12, 25, 21, 15, 0, 0, time.FixedZone("", 3600))
+	if got := custom.AsTime(custom.AsTimestamp(want)); !got.Equal(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+	if got := custom.AsTimestamp(custom.AsTime(timestamppb.New(want))); !got.AsTime().Equal(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+	// NULL is bound as the zero time
+	var ts *timestamppb.Timestamp
+	if got := custom.AsTime(ts); !got.IsZero() {
+		t.Errorf("got %v for nil, wanted the zero time", got)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	_ = json.Marshaler((*Timestamp)(nil))
+	_ = json.Unmarshaler((*Timestamp)(nil))
+	_ = encoding.TextMarshaler((*Timestamp)(nil))
+	_ = encoding.TextUnmarshaler((*Timestamp)(nil))
+	_ = xml.Marshaler((*Timestamp)(nil))
+	_ = xml.Unmarshaler((*Timestamp)(nil))
+	_ = proto.Message((*Timestamp)(nil))
+)
+
+// TimestampLayout is the format of a Timestamp: RFC3339 with the fractional seconds, if present.
+const TimestampLayout = time.RFC3339Nano
+
+// Timestamp is a DateTime for the TIMESTAMP (without zone) columns,
+// which keeps the fractional seconds everywhere (XML and String, too), not just in JSON.
+//
+// It has the same layout as DateTime, so it converts to *DateTime for the rest of the methods.
+type Timestamp struct {
+	time.Time
+}
+
+func (ts *Timestamp) dt() *DateTime { return (*DateTime)(ts) }
+
+func (ts *Timestamp) IsZero() bool { return ts.dt().IsZero() }
+
+func (ts *Timestamp) String() string {
+	if ts.IsZero() {
+		return ""
+	}
+	return ts.Time.In(time.Local).Format(TimestampLayout)
+}
+
+func (ts *Timestamp) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if ts.IsZero() {
+		return encodeXMLNil(enc, start)
+	}
+	return enc.EncodeElement(ts.String(), start)
+}
+func (ts *Timestamp) UnmarshalXML(dec *xml.Decoder, st xml.StartElement) error {
+	return ts.dt().UnmarshalXML(dec, st)
+}
+
+// nosemgrep: dgryski.semgrep-go.marshaljson.marshal-json-pointer-receiver
+func (ts *Timestamp) MarshalJSON() ([]byte, error) { return ts.dt().MarshalJSON() }
+func (ts *Timestamp) UnmarshalJSON(data []byte) error {
+	return ts.dt().UnmarshalJSON(data)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (ts *Timestamp) MarshalText() ([]byte, error) {
+	if ts.IsZero() {
+		return nil, nil
+	}
+	return ts.Time.In(time.Local).AppendFormat(make([]byte, 0, len(TimestampLayout)), TimestampLayout), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It accepts anything DateTime accepts, keeping the fractional seconds.
+func (ts *Timestamp) UnmarshalText(data []byte) error { return ts.dt().UnmarshalText(data) }
+
+func (ts *Timestamp) Scan(src interface{}) error   { return ts.dt().Scan(src) }
+func (ts *Timestamp) Value() (driver.Value, error) { return ts.dt().Value() }
+func (ts *Timestamp) Timestamp() *timestamppb.Timestamp {
+	return ts.dt().Timestamp()
+}
+
+func (ts *Timestamp) MarshalTo(dAtA []byte) (int, error) { return ts.dt().MarshalTo(dAtA) }
+func (ts *Timestamp) Marshal() ([]byte, error)           { return ts.dt().Marshal() }
+func (ts *Timestamp) Unmarshal(dAtA []byte) error        { return ts.dt().Unmarshal(dAtA) }
+func (ts *Timestamp) ProtoMessage()                      {}
+func (ts *Timestamp) ProtoSize() int                     { return ts.dt().ProtoSize() }
+func (ts *Timestamp) Size() int                          { return ts.dt().Size() }
+func (ts *Timestamp) Reset()                             { ts.dt().Reset() }
+func (ts *Timestamp) ProtoReflect() protoreflect.Message {
+	return ts.dt().ProtoReflect()
+}
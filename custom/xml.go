@@ -0,0 +1,65 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"encoding"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	_ = encoding.TextMarshaler(XML(""))
+	_ = encoding.TextUnmarshaler((*XML)(nil))
+)
+
+// XML is a serialized XML document, as an XMLTYPE is passed to and from the database.
+//
+// The empty XML is NULL.
+type XML string
+
+// Validate returns an error if the document is not well-formed.
+func (x XML) Validate() error {
+	if x == "" {
+		return nil
+	}
+	dec := xml.NewDecoder(strings.NewReader(string(x)))
+	var hasRoot bool
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				if !hasRoot {
+					return errors.New("XML: no root element")
+				}
+				return nil
+			}
+			return fmt.Errorf("XML: %w", err)
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			hasRoot = true
+		}
+	}
+}
+
+// MarshalText returns the document, if it is well-formed.
+func (x XML) MarshalText() ([]byte, error) {
+	if err := x.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(x), nil
+}
+
+// UnmarshalText sets the document, if it is well-formed.
+func (x *XML) UnmarshalText(data []byte) error {
+	if err := XML(data).Validate(); err != nil {
+		return err
+	}
+	*x = XML(data)
+	return nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import "testing"
+
+func TestXMLValidate(t *testing.T) {
+	for s, ok := range map[string]bool{
+		"":     true,
+		"<a/>": true,
+		`<?xml version="1.0"?><a><b x="1">c</b></a>`: true,
+		"<a>":       false,
+		"<a></b>":   false,
+		"just text": false,
+	} {
+		x := XML(s)
+		if err := x.Validate(); (err == nil) != ok {
+			t.Errorf("%q: got %v, wanted ok=%t", s, err, ok)
+		}
+		if _, err := x.MarshalText(); (err == nil) != ok {
+			t.Errorf("%q: marshal got %v, wanted ok=%t", s, err, ok)
+		}
+		var y XML
+		if err := y.UnmarshalText([]byte(s)); (err == nil) != ok {
+			t.Errorf("%q: unmarshal got %v, wanted ok=%t", s, err, ok)
+		} else if ok && y != x {
+			t.Errorf("%q: unmarshaled %q", s, y)
+		}
+	}
+}
@@ -14,7 +14,6 @@ require (
 	github.com/grpc-ecosystem/go-grpc-middleware/v2 v2.0.0-rc.5
 	github.com/kylelemons/godebug v1.1.0
 	github.com/oklog/ulid v1.3.1
-	github.com/tgulacsi/go v0.25.1
 	golang.org/x/net v0.9.0
 	golang.org/x/sync v0.3.0
 	google.golang.org/grpc v1.57.0
@@ -22,14 +21,13 @@ require (
 )
 
 require (
-	github.com/davecgh/go-spew v1.1.1
 	github.com/godror/knownpb v0.1.1
 	github.com/google/renameio/v2 v2.0.0
 	github.com/peterbourgon/ff/v3 v3.4.0
-	github.com/pkg/errors v0.9.1
 )
 
 require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/go-logfmt/logfmt v0.6.0 // indirect
 	github.com/go-logr/logr v1.2.4 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
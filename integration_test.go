@@ -8,7 +8,6 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
-	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -30,8 +29,6 @@ var (
 )
 
 func init() {
-	flag.Parse()
-
 	os.Setenv("NLS_LANG", "american_america.AL32UTF8")
 
 	now := time.Now()
@@ -122,6 +119,37 @@ func TestGenSimple(t *testing.T) {
 	}
 }
 
+// TestGenDateNLS checks that the dates are bound as typed values,
+// so they round-trip regardless of the NLS_DATE_FORMAT of the session.
+func TestGenDateNLS(t *testing.T) {
+	if finish {
+		t.FailNow()
+	}
+	build(t)
+	outFn := generateAndBuild(t, "SIMPLE_DATE")
+	defer func() { nlsEnv = nil }()
+	in := time.Date(2013, 12, 25, 21, 15, 0, 0, time.Local)
+	await := `{"dat":"` + in.AddDate(0, 0, 1).Format(time.RFC3339) + `"}`
+	for _, format := range []string{"YYYY-MM-DD", "DD/MON/RR", "MM-DD-YYYY HH24:MI:SS"} {
+		t.Run(format, func(t *testing.T) {
+			nlsEnv = append(make([]string, 0, len(os.Environ())+2), "NLS_DATE_FORMAT="+format, "ORA_SDTZ="+time.Local.String())
+			for _, line := range os.Environ() {
+				if !(strings.HasPrefix(line, "NLS_DATE_FORMAT=") || strings.HasPrefix(line, "ORA_SDTZ=")) {
+					nlsEnv = append(nlsEnv, line)
+				}
+			}
+			got := strings.TrimSpace(runTest(t, outFn, "-connect="+dsn, "SimpleDateInout",
+				`{"dat": "`+in.Format(time.RFC3339)+`"}`))
+			if got == await {
+				return
+			}
+			if dist, err := matchr.Hamming(got, await); err != nil || dist > 1 {
+				t.Errorf("awaited\n\t%s\ngot (distance=%d)\n\t%s", await, dist, got)
+			}
+		})
+	}
+}
+
 func TestGenRec(t *testing.T) {
 	if finish {
 		t.FailNow()
@@ -275,6 +303,7 @@ PROCEDURE simple_num_in(num IN NUMBER);
 FUNCTION simple_num_out RETURN NUMBER;
 PROCEDURE simple_date_in(dat IN DATE);
 FUNCTION simple_date_out RETURN DATE;
+PROCEDURE simple_date_inout(dat IN OUT DATE);
 FUNCTION simple_char_in_char_ret(txt IN VARCHAR2) RETURN VARCHAR2;
 PROCEDURE simple_all_inout(
     txt1 IN VARCHAR2, int1 IN PLS_INTEGER, num1 IN NUMBER, dt1 IN DATE,
@@ -341,6 +370,7 @@ BEGIN
 END simple_char_in_char_ret;
 
 FUNCTION simple_date_out RETURN DATE IS BEGIN RETURN TRUNC(SYSDATE); END simple_date_out;
+PROCEDURE simple_date_inout(dat IN OUT DATE) IS BEGIN dat := dat + 1; END simple_date_inout;
 FUNCTION simple_num_out RETURN NUMBER IS BEGIN RETURN 2/3; END simple_num_out;
 
 PROCEDURE simple_all_inout(
@@ -574,10 +604,6 @@ func runTest(t *testing.T, prog string, args ...string) string {
 // var dbg = flag.Bool("debug", false, "print debug messages?")
 var buildOnce sync.Once
 
-func init() {
-	flag.Parse()
-}
-
 var db *sql.DB
 
 func getConnection(t *testing.T) *sql.DB {
@@ -0,0 +1,148 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+)
+
+// GenAccessors makes SaveFunctions generate Each<Message>_<Field> and Nth<Message>_<Field> funcs
+// for the repeated record fields of the input and output messages, at any depth (see SaveAccessors).
+var GenAccessors bool
+
+// accessorStep is a field on the path from the message to a repeated record field.
+type accessorStep struct {
+	Name    string
+	IsTable bool
+}
+
+// SaveAccessors writes the accessors of the repeated record fields of the input (or output) message,
+// derived from the flavor tree of the arguments:
+//
+//   - Each<Message>_<Field>[_<Sub>...](s, fn) calls fn with each (non-nil) element,
+//     traversing all the elements of the enclosing repeated fields,
+//   - Nth<Message>_<Field>[_<Sub>...](s, i1[, i2...]) returns the element at the indexes
+//     (one for each repeated field on the path), or nil if any of them is out of range.
+//
+// Both accept nil messages and skip nil records on the way, so there is no need to check them.
+func (f Function) SaveAccessors(dst io.Writer, out bool) error {
+	args := make([]Argument, 0, len(f.Args)+1)
+	for _, arg := range f.Args {
+		if out && arg.IsOutput() || !out && arg.IsInput() {
+			args = append(args, arg)
+		}
+	}
+	if out && f.Returns != nil {
+		args = append(args, *f.Returns)
+	}
+	structName := strings.TrimPrefix(f.pbTypeName(out), "pb.")
+
+	buf := Buffers.Get()
+	defer Buffers.Put(buf)
+	var walk func(arg Argument, path []accessorStep) error
+	walk = func(arg Argument, path []accessorStep) error {
+		path = append(path[:len(path):len(path)], accessorStep{Name: CamelCase(arg.Name)})
+		switch arg.Flavor {
+		case FLAVOR_RECORD:
+			for _, sub := range arg.RecordOf {
+				if err := walk(*sub.Argument, path); err != nil {
+					return err
+				}
+			}
+		case FLAVOR_TABLE:
+			// the cursors are streamed, the string-indexed tables are maps
+			if arg.Type == "REF CURSOR" || arg.IsStringIndexed() ||
+				arg.TableOf == nil || arg.TableOf.Flavor != FLAVOR_RECORD {
+				return nil
+			}
+			path[len(path)-1].IsTable = true
+			got, err := arg.TableOf.goType(true)
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg.Name, err)
+			}
+			writeAccessors(buf, structName, path, withPb(CamelCase(got)))
+			for _, sub := range arg.TableOf.RecordOf {
+				if err := walk(*sub.Argument, path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, arg := range args {
+		if err := walk(arg, nil); err != nil {
+			return err
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("save accessors of %q (%s): %w", structName, buf.String(), err)
+	}
+	_, err = dst.Write(b)
+	return err
+}
+
+// writeAccessors writes the Each and Nth funcs of the repeated field at the end of the path,
+// with elements of type elem.
+func writeAccessors(w io.Writer, structName string, path []accessorStep, elem string) {
+	names := make([]string, len(path))
+	for i, step := range path {
+		names[i] = step.Name
+	}
+	nm := structName + "_" + strings.Join(names, "_")
+
+	var each, nth strings.Builder
+	var indexes []string
+	cur, closing := "s", 0
+	for i, step := range path {
+		v := fmt.Sprintf("v%d", i+1)
+		field := cur + "." + step.Name
+		if !step.IsTable {
+			fmt.Fprintf(&each, "if %s := %s; %s != nil {\n", v, field, v)
+			closing++
+			fmt.Fprintf(&nth, "%s := %s\nif %s == nil {\nreturn nil\n}\n", v, field, v)
+			cur = v
+			continue
+		}
+		idx := fmt.Sprintf("i%d", len(indexes)+1)
+		indexes = append(indexes, idx)
+		fmt.Fprintf(&each, "for _, %s := range %s {\nif %s == nil {\ncontinue\n}\n", v, field, v)
+		closing++
+		if i == len(path)-1 {
+			each.WriteString("fn(" + v + ")\n")
+			fmt.Fprintf(&nth, "if %s < 0 || %s >= len(%s) {\nreturn nil\n}\nreturn %s[%s]\n", idx, idx, field, field, idx)
+		} else {
+			fmt.Fprintf(&nth, "if %s < 0 || %s >= len(%s) {\nreturn nil\n}\n%s := %s[%s]\nif %s == nil {\nreturn nil\n}\n",
+				idx, idx, field, v, field, idx, v)
+		}
+		cur = v
+	}
+	each.WriteString(strings.Repeat("}\n", closing))
+
+	fmt.Fprintf(w, `
+// Each%[1]s calls fn with each %[2]s of s.%[3]s.
+func Each%[1]s(s *pb.%[4]s, fn func(%[2]s)) {
+	if s == nil {
+		return
+	}
+	%[5]s}
+
+// Nth%[1]s returns the %[2]s of s.%[3]s at the indexes, or nil if any of them is out of range.
+func Nth%[1]s(s *pb.%[4]s, %[6]s int) %[2]s {
+	if s == nil {
+		return nil
+	}
+	%[7]s}
+`,
+		nm, elem, strings.Join(names, "[]."), structName,
+		each.String(), strings.Join(indexes, ", "), nth.String())
+}
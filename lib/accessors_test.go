@@ -0,0 +1,176 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenAccessors(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_ORDERS,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_ORDERS,0,2,P_ORDERS,OUT,PL/SQL TABLE,,,,PLS_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,ORDER_TAB_TYP,",
+		"1,1,3,DB_WEB,GET_ORDERS,1,1,,OUT,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,ORDER_REC_TYP,",
+		"1,1,4,DB_WEB,GET_ORDERS,2,1,ID,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,5,DB_WEB,GET_ORDERS,2,2,LINES,OUT,PL/SQL TABLE,,,,PLS_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,LINE_TAB_TYP,",
+		"1,1,6,DB_WEB,GET_ORDERS,3,1,,OUT,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,LINE_REC_TYP,",
+		"1,1,7,DB_WEB,GET_ORDERS,4,1,QTY,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,8,DB_WEB,GET_ORDERS,4,2,NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,",
+		"1,2,1,DB_WEB,SET_ITEMS,0,1,P_ITEMS,IN,PL/SQL TABLE,,,,PLS_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,ITEM_TAB_TYP,",
+		"1,2,2,DB_WEB,SET_ITEMS,1,1,,IN,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,ITEM_REC_TYP,",
+		"1,2,3,DB_WEB,SET_ITEMS,2,1,ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,2,4,DB_WEB,SET_ITEMS,0,2,P_COUNT,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	var getOrders, setItems Function
+	for _, f := range functions {
+		switch f.name {
+		case "GET_ORDERS":
+			getOrders = f
+		case "SET_ITEMS":
+			setItems = f
+		}
+	}
+
+	// only with GenAccessors
+	defer func(old bool) { GenAccessors = old }(GenAccessors)
+	for _, gen := range []bool{false, true} {
+		GenAccessors = gen
+		var buf strings.Builder
+		if err := SaveFunctions(&buf, []Function{setItems}, "main", "example.com/db_web/pb", false); err != nil {
+			t.Fatal(err)
+		}
+		s := buf.String()
+		if got := strings.Contains(s, "func EachSetItems_Input_PItems(s *pb.SetItems_Input, fn func(*pb.DbWeb_ItemRecTyp_Scott)) {"); got != gen {
+			t.Errorf("GenAccessors=%t, but accessors generated=%t", gen, got)
+		}
+		if strings.Contains(s, "SetItems_Output_") {
+			t.Errorf("accessors generated for the output without repeated records:\n%s", s)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := getOrders.SaveAccessors(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+	wantContains(t, s,
+		"func EachGetOrders_Output_POrders(s *pb.GetOrders_Output, fn func(*pb.DbWeb_OrderRecTyp_Scott)) {",
+		"func NthGetOrders_Output_POrders(s *pb.GetOrders_Output, i1 int) *pb.DbWeb_OrderRecTyp_Scott {",
+		"func EachGetOrders_Output_POrders_Lines(s *pb.GetOrders_Output, fn func(*pb.DbWeb_LineRecTyp_Scott)) {",
+		"func NthGetOrders_Output_POrders_Lines(s *pb.GetOrders_Output, i1, i2 int) *pb.DbWeb_LineRecTyp_Scott {",
+	)
+	var in bytes.Buffer
+	if err := getOrders.SaveAccessors(&in, false); err != nil {
+		t.Fatal(err)
+	}
+	if in.Len() != 0 {
+		t.Errorf("accessors generated for the input without repeated records:\n%s", in.String())
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "accessors-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for GET_ORDERS
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type GetOrders_Output struct {
+	POrders []*DbWeb_OrderRecTyp_Scott
+}
+type DbWeb_OrderRecTyp_Scott struct {
+	Id    int32
+	Lines []*DbWeb_LineRecTyp_Scott
+}
+type DbWeb_LineRecTyp_Scott struct {
+	Qty  int32
+	Name string
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "accessors.go"),
+		append([]byte("package main\n\nimport pb \""+pbImport+"\"\n"), buf.Bytes()...),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "main.go"), []byte(`package main
+
+import (
+	"fmt"
+	"os"
+
+	pb "`+pbImport+`"
+)
+
+func main() {
+	output := &pb.GetOrders_Output{POrders: []*pb.DbWeb_OrderRecTyp_Scott{
+		{Id: 1, Lines: []*pb.DbWeb_LineRecTyp_Scott{{Qty: 1, Name: "a"}, nil, {Qty: 2, Name: "b"}}},
+		nil,
+		{Id: 2},
+		{Id: 3, Lines: []*pb.DbWeb_LineRecTyp_Scott{{Qty: 3, Name: "c"}}},
+	}}
+	var fails int
+	check := func(name, got, want string) {
+		if got != want {
+			fmt.Printf("%s: got %q, wanted %q\n", name, got, want)
+			fails++
+		}
+	}
+
+	var ids, names []string
+	EachGetOrders_Output_POrders(output, func(o *pb.DbWeb_OrderRecTyp_Scott) { ids = append(ids, fmt.Sprint(o.Id)) })
+	check("orders", fmt.Sprint(ids), "[1 2 3]")
+	EachGetOrders_Output_POrders_Lines(output, func(l *pb.DbWeb_LineRecTyp_Scott) { names = append(names, l.Name) })
+	check("lines", fmt.Sprint(names), "[a b c]")
+	EachGetOrders_Output_POrders_Lines(nil, func(*pb.DbWeb_LineRecTyp_Scott) { fails++; fmt.Println("called for nil") })
+
+	if o := NthGetOrders_Output_POrders(output, 2); o == nil || o.Id != 2 {
+		check("order 2", fmt.Sprint(o), "Id:2")
+	}
+	if l := NthGetOrders_Output_POrders_Lines(output, 3, 0); l == nil || l.Name != "c" {
+		check("line 3,0", fmt.Sprint(l), "c")
+	}
+	for _, ij := range [][2]int{{-1, 0}, {4, 0}, {0, 3}, {1, 0}, {2, 0}, {0, 1}} {
+		if l := NthGetOrders_Output_POrders_Lines(output, ij[0], ij[1]); l != nil {
+			check(fmt.Sprintf("line %v", ij), fmt.Sprint(l), "<nil>")
+		}
+	}
+	if o := NthGetOrders_Output_POrders(nil, 0); o != nil {
+		check("nil", fmt.Sprint(o), "<nil>")
+	}
+	if fails != 0 {
+		os.Exit(1)
+	}
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "run", "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go run: %+v\n%s", err, out.String())
+	}
+}
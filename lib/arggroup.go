@@ -0,0 +1,85 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"strings"
+)
+
+// argGroup is a cross-field constraint of the input arguments, set by a requires or excludes annotation:
+// the arguments of a "requires" group must be set together (all or none of them),
+// at most one of the arguments of an "excludes" group can be set.
+type argGroup struct {
+	Kind string   // "requires" or "excludes"
+	Args []string // the names of the arguments
+}
+
+func (g argGroup) String() string { return g.Kind + " " + strings.Join(g.Args, ", ") }
+
+// addArgGroup adds the kind ("requires" or "excludes") group of the comma-separated input arguments.
+func (f *Function) addArgGroup(kind, names string) error {
+	if kind != "requires" && kind != "excludes" {
+		return fmt.Errorf("unknown group kind %q: %w", kind, ErrInvalidArgument)
+	}
+	g := argGroup{Kind: kind}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		var found bool
+		for _, arg := range f.Args {
+			if strings.EqualFold(arg.Name, name) {
+				if !arg.IsInput() {
+					return fmt.Errorf("%s is not an input argument: %w", name, ErrInvalidArgument)
+				}
+				g.Args, found = append(g.Args, arg.Name), true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: no such argument: %w", name, ErrInvalidArgument)
+		}
+	}
+	if len(g.Args) < 2 {
+		return fmt.Errorf("%s: a group needs at least two arguments: %w", g, ErrInvalidArgument)
+	}
+	f.argGroups = append(f.argGroups, g)
+	return nil
+}
+
+// genGroupChecks appends the checks of the argument groups of the function
+// on the input struct named base to checks.
+func (f Function) genGroupChecks(checks []string, base string) ([]string, error) {
+	for _, g := range f.argGroups {
+		conds := make([]string, len(g.Args))
+		for i, name := range g.Args {
+			for _, arg := range f.Args {
+				if arg.Name == name {
+					var err error
+					if conds[i], err = arg.isSetExpr(base + "." + CamelCase(arg.Name)); err != nil {
+						return checks, fmt.Errorf("%s: %w", g, err)
+					}
+					break
+				}
+			}
+		}
+		switch g.Kind {
+		case "requires":
+			checks = append(checks, fmt.Sprintf(`if (%s) && !(%s) {
+		return fmt.Errorf("%s: all or none of them must be set: %%w", oracall.ErrInvalidArgument)
+	}`,
+				strings.Join(conds, " || "), strings.Join(conds, " && "), g))
+		case "excludes":
+			checks = append(checks, "{\n\tvar n int")
+			for _, cond := range conds {
+				checks = append(checks, fmt.Sprintf("\tif %s {\n\t\tn++\n\t}", cond))
+			}
+			checks = append(checks, fmt.Sprintf(`	if n > 1 {
+		return fmt.Errorf("%s: at most one of them can be set: %%w", oracall.ErrInvalidArgument)
+	}
+}`, g))
+		}
+	}
+	return checks, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsStringIndexed reports whether the argument is an associative array of simple values
+// indexed by VARCHAR2 - a map<string, T> in the proto, a map[string]T in Go.
+//
+// As such arrays cannot be bound, they are passed as two parallel arrays of keys and values.
+func (a Argument) IsStringIndexed() bool {
+	return a.Flavor == FLAVOR_TABLE && a.TableOf != nil && a.TableOf.Flavor == FLAVOR_SIMPLE &&
+		strings.HasPrefix(a.IndexBy, "VARCHAR")
+}
+
+// mapKeyType is the PL/SQL type of the keys of the string-indexed associative arrays.
+const mapKeyType = "VARCHAR2(32767)"
+
+// getConvStringMap is getConvSimpleTable for the string-indexed associative arrays:
+// the map is bound as the keys and the values arrays.
+func (arg Argument) getConvStringMap(
+	convIn, convOut []string,
+	name, keysParam, valsParam string,
+	tableSize int,
+) ([]string, []string) {
+	elem := *arg.TableOf
+	got, err := elem.goType(true)
+	if err != nil {
+		panic(err)
+	}
+	// the Go type of the bound values, and the conversions from/to the message's values
+	toVar, fromVar := "v", "v"
+	switch got {
+	case "godror.Number":
+		toVar, fromVar = "godror.Number(v)", "string(v)"
+	case "time.Time":
+		toVar, fromVar = "v.AsTime()", "timestamppb.New(v)"
+	}
+	keys, vals := mkVarName(keysParam), mkVarName(valsParam)
+	convIn = append(convIn,
+		fmt.Sprintf("%s, %s := make([]string, 0, %d), make([]%s, 0, %d)  // gcsm1",
+			keys, vals, tableSize, got, tableSize))
+	if arg.IsInput() {
+		convIn = append(convIn,
+			fmt.Sprintf(`for k, v := range input.%s {  // gcsm2
+			%s, %s = append(%s, k), append(%s, %s)
+		}`,
+				name,
+				keys, vals, keys, vals, toVar))
+	}
+	if !arg.IsOutput() {
+		convIn = append(convIn,
+			fmt.Sprintf("%s, %s = %s, %s  // gcsm3", keysParam, valsParam, keys, vals))
+		return convIn, convOut
+	}
+	convIn = append(convIn,
+		fmt.Sprintf("%s = sql.Out{Dest: &%s, In: %t}  // gcsm4", keysParam, keys, arg.IsInput()),
+		fmt.Sprintf("%s = sql.Out{Dest: &%s, In: %t}  // gcsm4", valsParam, vals, arg.IsInput()))
+	convOut = append(convOut,
+		fmt.Sprintf(`output.%s = make(map[string]%s, len(%s))  // gcsm5
+		for i, k := range %s {
+			v := %s[i]
+			output.%s[k] = %s
+		}`,
+			name, arg.protoMapValueType(got), keys,
+			keys,
+			vals,
+			name, fromVar))
+	return convIn, convOut
+}
+
+// protoMapValueType returns the Go type of the values of the map in the protobuf message.
+func (arg Argument) protoMapValueType(got string) string {
+	switch got {
+	case "godror.Number":
+		return "string"
+	case "time.Time":
+		return "*timestamppb.Timestamp"
+	}
+	return got
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringIndexedTable(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ATTRS,IN,PL/SQL TABLE,,,,VARCHAR2,PL/SQL TABLE,0,SCOTT,DB_WEB,ATTR_TAB_TYP,",
+		"1,1,2,DB_WEB,GET_X,1,1,,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,",
+		"1,1,3,DB_WEB,GET_X,0,2,P_IDS,IN,PL/SQL TABLE,,,,BINARY_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,NUM_TAB_TYP,",
+		"1,1,4,DB_WEB,GET_X,1,1,,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,5,DB_WEB,GET_X,0,3,P_COUNTS,OUT,PL/SQL TABLE,,,,VARCHAR2,PL/SQL TABLE,0,SCOTT,DB_WEB,CNT_TAB_TYP,",
+		"1,1,6,DB_WEB,GET_X,1,1,,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	if len(functions) != 1 {
+		t.Fatalf("got %d functions, wanted 1", len(functions))
+	}
+	fun := functions[0]
+	if !fun.Args[0].IsStringIndexed() || fun.Args[1].IsStringIndexed() {
+		t.Errorf("IsStringIndexed: got %t, %t", fun.Args[0].IsStringIndexed(), fun.Args[1].IsStringIndexed())
+	}
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantContains(t, s,
+		"\tmap<string, string> p_attrs = 1;",
+		"\trepeated sint32 p_ids = 2;",
+		"\tmap<string, sint32> p_counts = 1;",
+	)
+
+	plsql, callFun := fun.PlsqlBlock("")
+	wantContains(t, plsql,
+		"k1 VARCHAR2(32767);",
+		"WHILE k1 IS NOT NULL LOOP",
+	)
+	wantContains(t, callFun,
+		"for k, v := range input.PAttrs {",
+		"output.PCounts = make(map[string]int32, len(",
+	)
+
+	buf.Reset()
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", true); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "map[string]string") {
+		t.Errorf("no Go map in\n%s", s)
+	}
+}
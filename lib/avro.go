@@ -0,0 +1,181 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SaveAvro writes the Avro schemas of the response messages of the functions, as a JSON array of records
+// (named as the proto messages), for publishing the results to Kafka.
+//
+// The NUMBERs with precision and scale become decimals (bytes), the integers int or long, and the NUMBERs
+// without precision strings, as those have no fixed scale. DATE is a date, TIMESTAMP a timestamp-micros
+// (keeping the fractional seconds of the default TIMESTAMP(6)).
+// Records become nested records, tables arrays (maps if indexed by strings).
+// The simple fields are nullable, as in the database.
+func SaveAvro(dst io.Writer, functions []Function) error {
+	naming := DefaultNaming{}
+	seen := make(map[string]struct{})
+	records := make([]avroRecord, 0, len(functions))
+	for _, fun := range functions {
+		rec := avroRecord{Type: "record", Name: naming.MessageName(fun, true), Doc: fun.Documentation}
+		if fun.Package != "" {
+			rec.Namespace = strings.ToLower(fun.Package)
+		}
+		args := make([]Argument, 0, len(fun.Args)+1)
+		for _, arg := range fun.Args {
+			if arg.IsOutput() {
+				args = append(args, arg)
+			}
+		}
+		if fun.Returns != nil {
+			args = append(args, *fun.Returns)
+		}
+		var err error
+		if rec.Fields, err = avroFields(seen, args); err != nil {
+			if SkipMissingTableOf && (errors.Is(err, ErrMissingTableOf) || errors.Is(err, ErrUnknownSimpleType)) {
+				logger.Info("SKIP function, missing TableOf info", "function", fun.Name())
+				continue
+			}
+			return fmt.Errorf("%s: %w", fun.Name(), err)
+		}
+		records = append(records, rec)
+	}
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Doc       string      `json:"doc,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name    string          `json:"name"`
+	Doc     string          `json:"doc,omitempty"`
+	Type    interface{}     `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+// avroLogical is a primitive type annotated with a logical type.
+type avroLogical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+	Precision   int    `json:"precision,omitempty"`
+	Scale       int    `json:"scale,omitempty"`
+}
+
+type avroArray struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+type avroMap struct {
+	Type   string      `json:"type"`
+	Values interface{} `json:"values"`
+}
+
+func avroFields(seen map[string]struct{}, args []Argument) ([]avroField, error) {
+	fields := make([]avroField, 0, len(args))
+	for _, arg := range args {
+		typ, err := avroType(seen, arg)
+		if err != nil {
+			return fields, fmt.Errorf("%s: %w", arg.Name, err)
+		}
+		f := avroField{Name: replHidden(arg.Name), Doc: arg.Description, Type: typ}
+		if arg.Flavor == FLAVOR_SIMPLE {
+			f.Type, f.Default = []interface{}{"null", typ}, json.RawMessage("null")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// avroType returns the Avro type of the argument - the name of the record, if it has already been defined.
+func avroType(seen map[string]struct{}, arg Argument) (interface{}, error) {
+	switch arg.Flavor {
+	case FLAVOR_SIMPLE:
+		return avroSimpleType(arg)
+
+	case FLAVOR_TABLE:
+		if arg.TableOf == nil {
+			return nil, ErrMissingTableOf
+		}
+		items, err := avroType(seen, *arg.TableOf)
+		if err != nil {
+			return nil, err
+		}
+		if arg.IsStringIndexed() {
+			return avroMap{Type: "map", Values: items}, nil
+		}
+		return avroArray{Type: "array", Items: items}, nil
+
+	default: // FLAVOR_RECORD
+		name := CamelCase(strings.Replace(strings.ToUpper(arg.TypeName), "%ROWTYPE", "_rt", 1))
+		if name == "" {
+			name = mkRecTypName(arg.Name)
+		}
+		name = strings.ReplaceAll(name, ".", "__")
+		if _, ok := seen[name]; ok {
+			return name, nil
+		}
+		seen[name] = struct{}{}
+		args := make([]Argument, len(arg.RecordOf))
+		for i, sub := range arg.RecordOf {
+			args[i] = *sub.Argument
+			args[i].Name = sub.Name
+		}
+		fields, err := avroFields(seen, args)
+		if err != nil {
+			return nil, err
+		}
+		return avroRecord{Type: "record", Name: name, Fields: fields}, nil
+	}
+}
+
+func avroSimpleType(arg Argument) (interface{}, error) {
+	switch arg.Type {
+	case "CHAR", "NCHAR", "VARCHAR", "NVARCHAR", "VARCHAR2", "NVARCHAR2", "ROWID", "LONG", "CLOB", "NCLOB", "XMLTYPE":
+		return "string", nil
+	case "RAW", "LONG RAW", "BLOB":
+		return "bytes", nil
+	case "BOOLEAN", "PL/SQL BOOLEAN":
+		return "boolean", nil
+	case "PLS_INTEGER", "BINARY_INTEGER":
+		return "int", nil
+	case "INTEGER":
+		if arg.Scale < 10 {
+			return "int", nil
+		}
+		return "long", nil
+	case "NUMBER":
+		if arg.Precision == 0 {
+			return "string", nil
+		}
+		if arg.Scale == 0 && arg.Precision < 19 {
+			if arg.Precision < 10 {
+				return "int", nil
+			}
+			return "long", nil
+		}
+		return avroLogical{Type: "bytes", LogicalType: "decimal", Precision: int(arg.Precision), Scale: int(arg.Scale)}, nil
+	case "DATE":
+		return avroLogical{Type: "int", LogicalType: "date"}, nil
+	case "TIMESTAMP":
+		return avroLogical{Type: "long", LogicalType: "timestamp-micros"}, nil
+	case "DATETIME", "TIME":
+		return avroLogical{Type: "long", LogicalType: "timestamp-millis"}, nil
+	}
+	return nil, fmt.Errorf("%v: %w", arg, ErrUnknownSimpleType)
+}
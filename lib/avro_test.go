@@ -0,0 +1,109 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSaveAvro(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_AMOUNT,OUT,NUMBER,12,2,,,NUMBER,0,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,P_COUNT,OUT,NUMBER,5,,,,NUMBER,0,,,,",
+		"1,1,4,DB_WEB,GET_X,0,4,P_ANY,OUT,NUMBER,,,,,NUMBER,0,,,,",
+		"1,1,5,DB_WEB,GET_X,0,5,P_DAY,OUT,DATE,,,,,DATE,0,,,,",
+		"1,1,6,DB_WEB,GET_X,0,6,P_IDS,OUT,PL/SQL TABLE,,,,BINARY_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,NUM_TAB_TYP,",
+		"1,1,7,DB_WEB,GET_X,1,1,,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,8,DB_WEB,GET_X,0,7,P_REC,OUT,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,X_REC_TYP,",
+		"1,1,9,DB_WEB,GET_X,1,1,NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,10,DB_WEB,GET_X,1,2,PRICE,OUT,NUMBER,10,4,,,NUMBER,0,,,,",
+	)
+	var buf strings.Builder
+	if err := SaveAvro(&buf, functions); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+
+	type field struct {
+		Name string
+		Type json.RawMessage
+	}
+	type typ struct {
+		Type        interface{}
+		LogicalType string
+		Precision   int
+		Scale       int
+		Fields      []field
+	}
+	var records []struct {
+		Name, Namespace string
+		Fields          []field
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Name != "GetX_Output" || records[0].Namespace != "db_web" {
+		t.Fatalf("got %+v", records)
+	}
+	fields := make(map[string]json.RawMessage)
+	for _, f := range records[0].Fields {
+		fields[f.Name] = f.Type
+	}
+	if _, ok := fields["p_id"]; ok {
+		t.Error("input field in the response")
+	}
+	compact := func(raw json.RawMessage) string {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	// nullable returns the non-null type of the ["null", T] union
+	nullable := func(name string, raw json.RawMessage) json.RawMessage {
+		var union []json.RawMessage
+		if err := json.Unmarshal(raw, &union); err != nil || len(union) != 2 || string(union[0]) != `"null"` {
+			t.Fatalf("%s: not nullable: %s", name, raw)
+		}
+		return union[1]
+	}
+	var amount typ
+	if err := json.Unmarshal(nullable("p_amount", fields["p_amount"]), &amount); err != nil {
+		t.Fatal(err)
+	}
+	if amount.Type != "bytes" || amount.LogicalType != "decimal" || amount.Precision != 12 || amount.Scale != 2 {
+		t.Errorf("NUMBER(12,2): got %+v", amount)
+	}
+	for name, want := range map[string]string{
+		"p_count": `"int"`,
+		"p_any":   `"string"`,
+		"p_day":   `{"type":"int","logicalType":"date"}`,
+	} {
+		if got := compact(nullable(name, fields[name])); got != want {
+			t.Errorf("%s: got %s, wanted %s", name, got, want)
+		}
+	}
+	if got := compact(fields["p_ids"]); got != `{"type":"array","items":"int"}` {
+		t.Errorf("p_ids: got %s", got)
+	}
+	var rec typ
+	if err := json.Unmarshal(fields["p_rec"], &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Type != "record" || len(rec.Fields) != 2 {
+		t.Fatalf("p_rec: got %+v", rec)
+	}
+	var price typ
+	if err := json.Unmarshal(nullable("price", rec.Fields[1].Type), &price); err != nil {
+		t.Fatal(err)
+	}
+	if price.LogicalType != "decimal" || price.Precision != 10 || price.Scale != 4 {
+		t.Errorf("NUMBER(10,4): got %+v", price)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import "fmt"
+
+// isBoolean reports whether the argument is a PL/SQL BOOLEAN - which is not a SQL type,
+// so it cannot be bound: the generated PL/SQL block converts it from/to a 0/1 number
+// in an inner variable (see getConvBool).
+func (arg Argument) isBoolean() bool {
+	return arg.Flavor == FLAVOR_SIMPLE && (arg.Type == "BOOLEAN" || arg.Type == "PL/SQL BOOLEAN")
+}
+
+// getConvBool is getConvSimple for the BOOLEAN arguments, bound as 0/1 numbers:
+// a NULL output is false.
+func (arg Argument) getConvBool(
+	convIn, convOut []string,
+	name, paramName string,
+) ([]string, []string) {
+	vn := mkVarName(paramName)
+	if !arg.IsOutput() {
+		convIn = append(convIn,
+			fmt.Sprintf("var %s int32; if input.%s { %s = 1 }; %s = %s  // gcb1", vn, name, vn, paramName, vn))
+		return convIn, convOut
+	}
+	convIn = append(convIn, fmt.Sprintf("var %s sql.NullInt32", vn))
+	if arg.IsInput() {
+		convIn = append(convIn,
+			fmt.Sprintf("%s.Valid = true; if input.%s { %s.Int32 = 1 }  // gcb2", vn, name, vn))
+	}
+	convIn = append(convIn, fmt.Sprintf("%s = sql.Out{Dest:&%s, In:%t}  // gcb3", paramName, vn, arg.IsInput()))
+	convOut = append(convOut, fmt.Sprintf("output.%s = %s.Valid && %s.Int32 != 0  // gcb4", name, vn, vn))
+	return convIn, convOut
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestBooleanReturn(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	fh, err := os.Open("testdata/boolean.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fun := functions[0]
+	if fun.Returns == nil || !fun.Returns.isBoolean() {
+		t.Fatalf("returns %+v, wanted BOOLEAN", fun.Returns)
+	}
+
+	plsql, callFun := fun.PlsqlBlock("")
+	t.Log(plsql)
+	wantContains(t, plsql,
+		" BOOLEAN; --L=ret", " BOOLEAN; --L=p_strict", " BOOLEAN; --L=p_changed",
+		" := DB_web.is_valid(", // into the BOOLEAN variable, not the bind
+		" WHEN TRUE THEN 1 WHEN FALSE THEN 0 END;",
+		" = 1;",
+	)
+	if strings.Contains(plsql, ":1 := DB_web.is_valid(") {
+		t.Errorf("the BOOLEAN is bound:\n%s", plsql)
+	}
+	wantContains(t, callFun,
+		"if input.PStrict {", "if input.PChanged {",
+		".Valid && ", "output.Ret = ", "output.PChanged = ",
+	)
+
+	var buf strings.Builder
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"bool ret = ", "bool p_strict = ", "bool p_changed = "} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("no %q in\n%s", want, buf.String())
+		}
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "boolean-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for IS_VALID
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type IsValid_Input struct {
+	PId      int32
+	PStrict  bool
+	PChanged bool
+}
+type IsValid_Output struct {
+	PChanged bool
+	Ret      bool
+}
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var goBuf bytes.Buffer
+	if err = SaveFunctions(&goBuf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	goBuf.WriteString("\nfunc main() {}\n")
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), goBuf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go build: %+v\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"go/format"
+	"io"
+	"strings"
+)
+
+// SaveCLI writes the main package of a command calling the rpcs of the generated service by name,
+// with JSON requests (see orasrv.CallJSON) - such as
+//
+//	db_webcli -addr=localhost:8080 GetX '{"pId":1}'
+//
+// The pbImport package is linked for its service descriptors only, so the command needs no per-rpc code.
+func SaveCLI(dst io.Writer, pbImport string) error {
+	b, err := format.Source([]byte(strings.Replace(cliMain, "{{pbImport}}", pbImport, 1)))
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(b)
+	return err
+}
+
+const cliMain = `// Code generated by oracall, DO NOT EDIT.
+
+// Command calls the rpcs of the service with JSON requests, printing the JSON responses.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/tgulacsi/oracall/orasrv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	_ "{{pbImport}}" // registers the service
+)
+
+func main() {
+	if err := Main(); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		os.Exit(1)
+	}
+}
+
+func Main() error {
+	flagAddr := flag.String("addr", "localhost:8080", "address of the server")
+	flagList := flag.Bool("list", false, "list the methods")
+	flagTimeout := flag.Duration("timeout", time.Minute, "timeout of the call")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n\t%s [flags] <method> [request JSON, read from stdin if missing]\n\t%s -list\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *flagList {
+		for _, m := range orasrv.Methods() {
+			fmt.Println(m)
+		}
+		return nil
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return errors.New("method is required")
+	}
+	var req []byte
+	if flag.NArg() > 1 {
+		req = []byte(flag.Arg(1))
+	} else {
+		var err error
+		if req, err = io.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if *flagTimeout > 0 {
+		var timeoutCancel context.CancelFunc
+		ctx, timeoutCancel = context.WithTimeout(ctx, *flagTimeout)
+		defer timeoutCancel()
+	}
+	cc, err := grpc.DialContext(ctx, *flagAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", *flagAddr, err)
+	}
+	defer cc.Close()
+	return orasrv.CallJSON(ctx, cc, flag.Arg(0), req, os.Stdout)
+}
+`
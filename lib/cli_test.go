@@ -0,0 +1,37 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSaveCLI(t *testing.T) {
+	var buf strings.Builder
+	if err := SaveCLI(&buf, "example.com/db_web/pb"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", buf.String(), parser.ImportsOnly)
+	if err != nil {
+		t.Fatalf("%+v\n%s", err, buf.String())
+	}
+	if f.Name.Name != "main" {
+		t.Errorf("got package %q, wanted main", f.Name.Name)
+	}
+	imports := make(map[string]bool)
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		imports[path] = true
+	}
+	for _, want := range []string{"example.com/db_web/pb", "github.com/tgulacsi/oracall/orasrv"} {
+		if !imports[want] {
+			t.Errorf("%q is not imported:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadArgumentComments reads the descriptions of the arguments from the given csv file.
+//
+// See ParseArgumentComments for the format.
+func LoadArgumentComments(fn string) (map[string]string, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	comments, err := ParseArgumentComments(fh)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return comments, nil
+}
+
+// ParseArgumentComments reads the descriptions of the arguments from a csv
+// with PACKAGE_NAME, OBJECT_NAME, ARGUMENT_NAME and COMMENTS columns, in any order.
+// The delimiter is detected as in ReadCsv.
+//
+// The returned map is keyed by the lowercase "package.object.argument".
+func ParseArgumentComments(r io.Reader) (map[string]string, error) {
+	br := bufio.NewReader(r)
+	csvr := csv.NewReader(br)
+	if csvr.Comma = CsvDelimiter; csvr.Comma == 0 {
+		b, err := br.Peek(br.Size())
+		if len(b) == 0 {
+			return nil, fmt.Errorf("error peeking into file: %w", err)
+		}
+		csvr.Comma = detectDelimiter(b)
+	}
+	csvr.LazyQuotes = true
+	head, err := csvr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read head: %w", err)
+	}
+	idx := map[string]int{"PACKAGE_NAME": -1, "OBJECT_NAME": -1, "ARGUMENT_NAME": -1, "COMMENTS": -1}
+	for i, h := range head {
+		if j, ok := idx[strings.ToUpper(strings.TrimSpace(h))]; ok && j < 0 {
+			idx[strings.ToUpper(strings.TrimSpace(h))] = i
+		}
+	}
+	for k, i := range idx {
+		if i < 0 {
+			return nil, fmt.Errorf("missing column %s from head %q", k, head)
+		}
+	}
+	comments := make(map[string]string)
+	for {
+		rec, err := csvr.Read()
+		if err != nil {
+			if err == io.EOF {
+				return comments, nil
+			}
+			return comments, err
+		}
+		comment := strings.TrimSpace(rec[idx["COMMENTS"]])
+		if comment == "" {
+			continue
+		}
+		comments[strings.ToLower(rec[idx["PACKAGE_NAME"]]+"."+rec[idx["OBJECT_NAME"]]+"."+rec[idx["ARGUMENT_NAME"]])] = comment
+	}
+}
+
+// ApplyArgumentComments sets the Description of the arguments (and return values) found in comments,
+// as returned by ParseArgumentComments.
+// The functions are returned, the Args of the commented ones copied.
+func ApplyArgumentComments(functions []Function, comments map[string]string) []Function {
+	if len(comments) == 0 {
+		return functions
+	}
+	functions = append([]Function(nil), functions...)
+	for i, f := range functions {
+		prefix := strings.ToLower(f.Package + "." + f.name + ".")
+		var copied bool
+		for j, arg := range f.Args {
+			if s := comments[prefix+strings.ToLower(arg.RealName())]; s != "" {
+				if !copied {
+					// do not modify the caller's Args
+					f.Args, copied = append([]Argument(nil), f.Args...), true
+				}
+				f.Args[j].Description = s
+			}
+		}
+		if f.Returns != nil {
+			if s := comments[prefix]; s != "" {
+				ret := *f.Returns
+				ret.Description = s
+				f.Returns = &ret
+			}
+		}
+		functions[i] = f
+	}
+	return functions
+}
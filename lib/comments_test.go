@@ -0,0 +1,49 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestApplyArgumentComments(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	comments, err := LoadArgumentComments("testdata/arg_comments.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 {
+		t.Errorf("got %v, wanted only the non-empty comment", comments)
+	}
+	orig := functions
+	functions = ApplyArgumentComments(functions, comments)
+	if got := orig[0].Args[0].Description; got != "" {
+		t.Errorf("the original p_id got description %q", got)
+	}
+	if got := functions[0].Args[1].Description; got != "" {
+		t.Errorf("p_x1 got description %q", got)
+	}
+
+	var buf strings.Builder
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\t// The identifier of the X\n\t// NUMBER(9)\n\tsint32 p_id = 1;"; !strings.Contains(buf.String(), want) {
+		t.Errorf("%q not found in\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err = SaveFunctions(&buf, functions, "main", "example.com/db_web", true); err != nil {
+		t.Fatal(err)
+	}
+	if rWant := regexp.MustCompile(`// The identifier of the X\n\s*P_id `); !rWant.MatchString(buf.String()) {
+		t.Errorf("%q not found in\n%s", rWant, buf.String())
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+)
+
+// GenConverters makes SaveFunctions generate ToProto and FromProto methods for the
+// plain Go structs (see SaveStruct), converting them to and from the protobuf messages
+// without reflection (as CopyStruct does).
+//
+// It has no effect with Gogo.
+var GenConverters bool
+
+// SaveConverters writes the ToProto and FromProto methods of the input (or output) struct
+// written by SaveStruct.
+func (f Function) SaveConverters(dst io.Writer, out bool) error {
+	dirmap := DIR_IN
+	if out {
+		dirmap = DIR_OUT
+	}
+	args := make([]Argument, 0, len(f.Args)+1)
+	for _, arg := range f.Args {
+		if arg.Direction&dirmap > 0 {
+			args = append(args, arg)
+		}
+	}
+	if out && f.Returns != nil {
+		args = append(args, *f.Returns)
+	}
+	structName, pbName := CamelCase(f.getStructName(out, true)), f.pbTypeName(out)
+
+	to := &converter{toProto: true, fail: "return nil, err"}
+	from := &converter{fail: "return err"}
+	for _, arg := range args {
+		if arg.Type == "REF CURSOR" {
+			// streamed, not part of the message
+			continue
+		}
+		plain, pb := "s."+capitalize(replHidden(arg.Name)), "p."+CamelCase(arg.Name)
+		if err := to.convert(arg, pb, plain, false, true); err != nil {
+			return fmt.Errorf("%s: %w", arg.Name, err)
+		}
+		if err := from.convert(arg, plain, pb, false, true); err != nil {
+			return fmt.Errorf("%s: %w", arg.Name, err)
+		}
+	}
+
+	buf := Buffers.Get()
+	defer Buffers.Put(buf)
+	fmt.Fprintf(buf, `
+// ToProto converts s to a %[2]s.
+func (s *%[1]s) ToProto() (*%[2]s, error) {
+	p := new(%[2]s)
+	var err error
+	_ = err
+	%[3]s
+	return p, nil
+}
+
+// FromProto sets s from the %[2]s.
+func (s *%[1]s) FromProto(p *%[2]s) error {
+	var err error
+	_ = err
+	%[4]s
+	return nil
+}
+`, structName, pbName, to.String(), from.String())
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("save converters of %q (%s): %w", structName, buf.String(), err)
+	}
+	_, err = dst.Write(b)
+	return err
+}
+
+// converter collects the statements converting the plain struct to the protobuf message (or back).
+type converter struct {
+	strings.Builder
+	// fail is the statement returning err.
+	fail    string
+	toProto bool
+	// n is for the unique variable names.
+	n int
+}
+
+func (c *converter) newVar(prefix string) string {
+	c.n++
+	return fmt.Sprintf("%s%d", prefix, c.n)
+}
+
+// convert writes the statements setting dst from src.
+//
+// The protobuf side of a top-level simple argument may be a wrapper, see NullableWrappers.
+func (c *converter) convert(arg Argument, dst, src string, parentIsTable, top bool) error {
+	got, err := arg.goType(parentIsTable || arg.Flavor == FLAVOR_TABLE)
+	if err != nil {
+		return err
+	}
+	switch arg.Flavor {
+	case FLAVOR_SIMPLE:
+		if _, ok := arg.protoWrapper(); ok && top {
+			fmt.Fprintf(c, "%s = %s\n", dst, src)
+			return nil
+		}
+		base, ptr := strings.TrimPrefix(got, "*"), strings.HasPrefix(got, "*")
+		typ, _ := arg.mappedProtoType(got)
+		if !ptr {
+			c.scalar(base, typ, dst, src)
+			return nil
+		}
+		if c.toProto {
+			fmt.Fprintf(c, "if %s != nil {\n", src)
+			c.scalar(base, typ, dst, "(*"+src+")")
+			c.WriteString("}\n")
+			return nil
+		}
+		x := c.newVar("x")
+		fmt.Fprintf(c, "{\nvar %s %s\n", x, base)
+		c.scalar(base, typ, x, src)
+		fmt.Fprintf(c, "%s = &%s\n}\n", dst, x)
+		return nil
+
+	case FLAVOR_TABLE:
+		if arg.TableOf == nil {
+			return fmt.Errorf("no table of data for %s: %w", arg.Name, ErrMissingTableOf)
+		}
+		elem := *arg.TableOf
+		elemGot, err := elem.goType(true)
+		if err != nil {
+			return err
+		}
+		v, x := c.newVar("v"), c.newVar("x")
+		if arg.IsStringIndexed() {
+			typ := elemGot
+			if c.toProto {
+				typ = c.pbGoType(elem, elemGot)
+			}
+			fmt.Fprintf(c, "%s = make(map[string]%s, len(%s))\nfor k, %s := range %s {\nvar %s %s\n",
+				dst, typ, src, v, src, x, typ)
+			if err := c.convert(elem, x, v, true, false); err != nil {
+				return err
+			}
+			fmt.Fprintf(c, "%s[k] = %s\n}\n", dst, x)
+			return nil
+		}
+		ptr := strings.HasPrefix(got, "*")
+		if c.toProto {
+			if ptr {
+				fmt.Fprintf(c, "if %s != nil {\n", src)
+				src = "(*" + src + ")"
+			}
+			fmt.Fprintf(c, "%s = make([]%s, 0, len(%s))\nfor _, %s := range %s {\nvar %s %s\n",
+				dst, c.pbGoType(elem, elemGot), src, v, src, x, c.pbGoType(elem, elemGot))
+		} else {
+			a := dst
+			if ptr {
+				a = c.newVar("a")
+				fmt.Fprintf(c, "{\nvar %s %s\n", a, got[1:])
+			}
+			fmt.Fprintf(c, "%s = make([]%s, 0, len(%s))\nfor _, %s := range %s {\nvar %s %s\n",
+				a, elemGot, src, v, src, x, elemGot)
+			defer func() {
+				if ptr {
+					fmt.Fprintf(c, "%s = &%s\n}\n", dst, a)
+				}
+			}()
+			dst = a
+		}
+		if err := c.convert(elem, x, v, true, false); err != nil {
+			return err
+		}
+		fmt.Fprintf(c, "%s = append(%s, %s)\n}\n", dst, dst, x)
+		if c.toProto && ptr {
+			c.WriteString("}\n")
+		}
+		return nil
+
+	default: // FLAVOR_RECORD
+		ptr := strings.HasPrefix(got, "*")
+		plainType, pbType := strings.TrimPrefix(got, "*"), withPb(CamelCase(strings.TrimPrefix(got, "*")))
+		r := c.newVar("r")
+		if ptr || !c.toProto {
+			fmt.Fprintf(c, "if %s != nil {\n", src)
+		} else {
+			c.WriteString("{\n")
+		}
+		if c.toProto {
+			fmt.Fprintf(c, "%s := new(%s)\n", r, pbType)
+		} else {
+			fmt.Fprintf(c, "%s := new(%s)\n", r, plainType)
+		}
+		for _, sub := range arg.RecordOf {
+			plain, pb := capitalize(replHidden(sub.Name)), CamelCase(sub.Name)
+			d, s := r+"."+pb, src+"."+plain
+			if !c.toProto {
+				d, s = r+"."+plain, src+"."+pb
+			}
+			if err := c.convert(*sub.Argument, d, s, parentIsTable, false); err != nil {
+				return fmt.Errorf("%s: %w", sub.Name, err)
+			}
+		}
+		if c.toProto || ptr {
+			fmt.Fprintf(c, "%s = %s\n}\n", dst, r)
+		} else {
+			fmt.Fprintf(c, "%s = *%s\n}\n", dst, r)
+		}
+		return nil
+	}
+}
+
+// scalar writes the statements setting dst from src, where the Go type of the plain side is base,
+// and typ is the protobuf type.
+func (c *converter) scalar(base, typ, dst, src string) {
+	switch base {
+	case "time.Time":
+		if c.toProto {
+			fmt.Fprintf(c, "if !%s.IsZero() {\n%s = timestamppb.New(%s)\n}\n", src, dst, src)
+		} else {
+			fmt.Fprintf(c, "if %s != nil {\n%s = %s.AsTime()\n}\n", src, dst, src)
+		}
+		return
+
+	case "godror.Number":
+		bits := map[string]string{"sint32": "32", "sint64": "64"}[typ]
+		switch {
+		case bits == "":
+			if c.toProto {
+				fmt.Fprintf(c, "%s = string(%s)\n", dst, src)
+			} else {
+				fmt.Fprintf(c, "%s = godror.Number(%s)\n", dst, src)
+			}
+		case c.toProto:
+			i := c.newVar("i")
+			fmt.Fprintf(c, "if %s != \"\" {\nvar %s int64\nif %s, err = strconv.ParseInt(string(%s), 10, %s); err != nil {\n%s\n}\n%s = int%s(%s)\n}\n",
+				src, i, i, src, bits, c.fail, dst, bits, i)
+		default:
+			fmt.Fprintf(c, "%s = godror.Number(strconv.FormatInt(int64(%s), 10))\n", dst, src)
+		}
+		return
+	}
+	fmt.Fprintf(c, "%s = %s\n", dst, src)
+}
+
+// pbGoType returns the Go type of the protobuf field of arg.
+func (c *converter) pbGoType(arg Argument, got string) string {
+	if arg.Flavor != FLAVOR_SIMPLE {
+		return "*" + withPb(CamelCase(strings.TrimPrefix(got, "*")))
+	}
+	typ, _ := arg.mappedProtoType(got)
+	switch typ {
+	case "sint32":
+		return "int32"
+	case "sint64":
+		return "int64"
+	case "float":
+		return "float32"
+	case "double":
+		return "float64"
+	case "bytes":
+		return "[]byte"
+	case "google.protobuf.Timestamp":
+		return "*timestamppb.Timestamp"
+	}
+	return typ
+}
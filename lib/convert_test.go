@@ -0,0 +1,48 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenConverters(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_AMOUNT,IN,NUMBER,,,,,NUMBER,0,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,P_IDS,IN,PL/SQL TABLE,,,,BINARY_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,NUM_TAB_TYP,",
+		"1,1,4,DB_WEB,GET_X,1,1,,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,5,DB_WEB,GET_X,0,4,P_REC,OUT,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,X_REC_TYP,",
+		"1,1,6,DB_WEB,GET_X,1,1,NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,7,DB_WEB,GET_X,1,2,BIRTH,OUT,DATE,,,,,DATE,0,,,,",
+	)
+	defer func(old bool) { GenConverters = old }(GenConverters)
+	for _, gen := range []bool{false, true} {
+		GenConverters = gen
+		var buf strings.Builder
+		if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", true); err != nil {
+			t.Fatal(err)
+		}
+		s := buf.String()
+		if got := strings.Contains(s, ") ToProto() ("); got != gen {
+			t.Errorf("GenConverters=%t, but converters generated=%t", gen, got)
+			continue
+		}
+		if !gen {
+			continue
+		}
+		wantContains(t, s,
+			"func (s *DbWeb_GetX_Input) ToProto() (*pb.GetX_Input, error) {",
+			"func (s *DbWeb_GetX_Output) FromProto(p *pb.GetX_Output) error {",
+			"p.PId = s.P_id\n",
+			"p.PAmount = string(s.P_amount)\n",
+			"s.P_amount = godror.Number(p.PAmount)\n",
+			"p.PIds = make([]int32, 0, len(s.P_ids))",
+			"= timestamppb.New(s.P_rec.Birth)",
+			"= p.PRec.Birth.AsTime()",
+		)
+	}
+}
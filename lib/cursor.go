@@ -0,0 +1,243 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setCursorShape sets the row type of the weakly typed (SYS_REFCURSOR) argument (or "ret"),
+// from the comma-separated "name TYPE" column list, such as "id NUMBER(9), name VARCHAR2(100)".
+func (f *Function) setCursorShape(argName, shape string) error {
+	columns, err := parseCursorShape(shape)
+	if err != nil {
+		return err
+	}
+	set := func(arg *Argument) {
+		typeName := strings.ToUpper(f.name + "_" + argName + "_row")
+		if f.Package != "" {
+			typeName = strings.ToUpper(f.Package) + "." + typeName
+		}
+		row := NewArgument("", "PL/SQL RECORD", typeName, typeName, "", arg.Direction, "", "", 0, 0, 0)
+		row.RecordOf = columns
+		arg.TableOf = &row
+	}
+	for i := range f.Args {
+		if strings.EqualFold(f.Args[i].Name, argName) {
+			if f.Args[i].Type != "REF CURSOR" {
+				return fmt.Errorf("%s is %s, not REF CURSOR: %w", argName, f.Args[i].Type, ErrInvalidArgument)
+			}
+			// do not modify the caller's Args
+			f.Args = append([]Argument(nil), f.Args...)
+			set(&f.Args[i])
+			return nil
+		}
+	}
+	if f.Returns != nil && strings.EqualFold(f.Returns.Name, argName) && f.Returns.Type == "REF CURSOR" {
+		ret := *f.Returns
+		set(&ret)
+		f.Returns = &ret
+		return nil
+	}
+	return fmt.Errorf("%s: no such REF CURSOR argument: %w", argName, ErrInvalidArgument)
+}
+
+// setCursorRowName names the row message of the REF CURSOR argument (or "ret") name,
+// or of the only REF CURSOR of the function if argName is empty.
+// The cursors with the same row name share one message.
+func (f *Function) setCursorRowName(argName, name string) error {
+	var idx []int
+	for i, arg := range f.Args {
+		if arg.Type == "REF CURSOR" && (argName == "" || strings.EqualFold(arg.Name, argName)) {
+			idx = append(idx, i)
+		}
+	}
+	isRet := f.Returns != nil && f.Returns.Type == "REF CURSOR" &&
+		(argName == "" || strings.EqualFold(f.Returns.Name, argName))
+	n := len(idx)
+	if isRet {
+		n++
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: no such REF CURSOR argument: %w", argName, ErrInvalidArgument)
+	}
+	if n > 1 {
+		return fmt.Errorf("%d REF CURSOR arguments, name one of them: %w", n, ErrInvalidArgument)
+	}
+	// the message name is CamelCase(typeName), as for the record types
+	typeName := strings.ToUpper(SnakeCase(name))
+	set := func(arg *Argument) error {
+		if arg.TableOf == nil || arg.TableOf.dynamicRow {
+			return fmt.Errorf("%s has no row type (set it with a cursor annotation): %w", arg.Name, ErrMissingTableOf)
+		}
+		row := *arg.TableOf
+		row.TypeName, row.goTypeName, row.mu = typeName, "", nil
+		arg.TableOf, arg.goTypeName, arg.mu = &row, "", nil
+		arg.namedRow = true
+		return nil
+	}
+	if isRet {
+		ret := *f.Returns
+		if err := set(&ret); err != nil {
+			return err
+		}
+		f.Returns = &ret
+		return nil
+	}
+	// do not modify the caller's Args
+	f.Args = append([]Argument(nil), f.Args...)
+	return set(&f.Args[idx[0]])
+}
+
+// cursorRowShape returns the columns of the row of the REF CURSOR argument named by setCursorRowName.
+func (f Function) cursorRowShape(name string) string {
+	typeName := strings.ToUpper(SnakeCase(name))
+	args := f.Args
+	if f.Returns != nil {
+		args = append(args[:len(args):len(args)], *f.Returns)
+	}
+	for _, arg := range args {
+		if arg.Type != "REF CURSOR" || arg.TableOf == nil || arg.TableOf.dynamicRow || arg.TableOf.TypeName != typeName {
+			continue
+		}
+		cols := make([]string, len(arg.TableOf.RecordOf))
+		for i, col := range arg.TableOf.RecordOf {
+			cols[i] = col.Name + " " + col.Argument.AbsType
+		}
+		return strings.Join(cols, ", ")
+	}
+	return ""
+}
+
+// dynamicRowType is the type name of the row of the weakly typed REF CURSORs without a shape:
+// the DynamicRow message, with the columns of the row in its map<string, string> columns field.
+const dynamicRowType = "DYNAMIC_ROW"
+
+// setDynamicRows sets the row type of the weakly typed (SYS_REFCURSOR) arguments (and return value) of the function
+// to the dynamic row (see dynamicRowType), to be replaced by a cursor annotation (see setCursorShape).
+//
+// The dynamic row has the columns by their names, the values as strings (see RowColumns).
+func (f *Function) setDynamicRows() {
+	set := func(arg *Argument) {
+		if arg.Type != "REF CURSOR" || arg.TableOf != nil {
+			return
+		}
+		value := NewArgument("", "VARCHAR2", "VARCHAR2", "", "", DIR_OUT, "CHAR_CS", "", 0, 0, 32767)
+		columns := NewArgument("columns", "PL/SQL TABLE", "PL/SQL TABLE", "", "", DIR_OUT, "", "VARCHAR2", 0, 0, 0)
+		columns.TableOf = &value
+		row := NewArgument("", "PL/SQL RECORD", dynamicRowType, dynamicRowType, "", arg.Direction, "", "", 0, 0, 0)
+		row.RecordOf = []NamedArgument{{Name: columns.Name, Argument: &columns}}
+		row.dynamicRow = true
+		arg.TableOf = &row
+	}
+	for i := range f.Args {
+		set(&f.Args[i])
+	}
+	if f.Returns != nil {
+		set(f.Returns)
+	}
+}
+
+// RowColumns returns the columns of the row fetched from a weakly typed REF CURSOR without a shape
+// (the DynamicRow message, see the cursor annotation) by their names, the values as strings:
+// the dates in RFC3339, the RAWs in hex, the NULLs left out.
+func RowColumns(columns []string, values []driver.Value) map[string]string {
+	m := make(map[string]string, len(columns))
+	for i, c := range columns {
+		switch v := values[i].(type) {
+		case nil:
+		case string:
+			m[c] = v
+		case []byte:
+			m[c] = hex.EncodeToString(v)
+		case time.Time:
+			m[c] = v.Format(time.RFC3339)
+		case fmt.Stringer:
+			m[c] = v.String()
+		default:
+			m[c] = fmt.Sprint(v)
+		}
+	}
+	return m
+}
+
+// parseCursorShape parses the comma-separated "name TYPE[(precision[,scale])]" column list.
+func parseCursorShape(shape string) ([]NamedArgument, error) {
+	var columns []NamedArgument
+	var depth, start int
+	add := func(s string) error {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil
+		}
+		name, typ, ok := strings.Cut(s, " ")
+		if !ok {
+			return fmt.Errorf("%q: no type: %w", s, ErrInvalidArgument)
+		}
+		typ = strings.ToUpper(strings.TrimSpace(typ))
+		var nums []uint64
+		if i := strings.IndexByte(typ, '('); i >= 0 {
+			if !strings.HasSuffix(typ, ")") {
+				return fmt.Errorf("%q: unbalanced parenthesis: %w", s, ErrInvalidArgument)
+			}
+			for _, t := range strings.Split(typ[i+1:len(typ)-1], ",") {
+				t, _, _ = strings.Cut(strings.TrimSpace(t), " ") // VARCHAR2(10 CHAR)
+				n, err := strconv.ParseUint(t, 10, 16)
+				if err != nil {
+					return fmt.Errorf("%q: %w", s, err)
+				}
+				nums = append(nums, n)
+			}
+			typ = strings.TrimSpace(typ[:i])
+		}
+		var prec, scale uint8
+		var length uint
+		var charset string
+		switch typ {
+		case "CHAR", "NCHAR", "VARCHAR", "NVARCHAR", "VARCHAR2", "NVARCHAR2":
+			charset = "CHAR_CS"
+			if len(nums) != 0 {
+				length = uint(nums[0])
+			}
+		default:
+			if len(nums) != 0 {
+				prec = uint8(nums[0])
+			}
+			if len(nums) > 1 {
+				scale = uint8(nums[1])
+			}
+		}
+		arg := NewArgument(name, typ, typ, "", "", DIR_OUT, charset, "", prec, scale, length)
+		columns = append(columns, NamedArgument{Name: arg.Name, Argument: &arg})
+		return nil
+	}
+	for i, r := range shape {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				if err := add(shape[start:i]); err != nil {
+					return nil, err
+				}
+				start = i + 1
+			}
+		}
+	}
+	if err := add(shape[start:]); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("empty cursor shape: %w", ErrInvalidArgument)
+	}
+	return columns, nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+const weakCursorCsv = testCsvHeader + `
+1,1,1,DB_WEB,LIST_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+1,1,2,DB_WEB,LIST_X,0,2,P_CUR,OUT,REF CURSOR,,,,,REF CURSOR,0,,,,
+`
+
+func TestCursorShape(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, err := ParseCsv(strings.NewReader(weakCursorCsv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// without shape, the rows are DynamicRows
+	wantContains(t, buf.String(),
+		"rpc ListX (ListX_Input) returns (stream ListX_Output)",
+		"repeated DynamicRow p_cur = 1;",
+		"message DynamicRow {",
+		"map<string, string> columns = 1;",
+	)
+	_, callFun := functions[0].PlsqlBlock("")
+	wantContains(t, callFun,
+		"output.PCur = make([]*pb.DynamicRow, 0, ",
+		"cols := rset.Columns()",
+		"a = append(a, &pb.DynamicRow{Columns: oracall.RowColumns(cols, I)})",
+	)
+	if f := functions[0]; !errors.Is(f.setCursorRowName("", "customer_row"), ErrMissingTableOf) {
+		t.Error("cursor-row named a DynamicRow")
+	}
+
+	functions = ApplyAnnotations(functions, []Annotation{{
+		Package: "DB_WEB", Type: "cursor", Name: "list_x.p_cur",
+		Other: "id NUMBER(9), amount NUMBER(12, 2), name VARCHAR2(100 CHAR), created DATE",
+	}})
+	cur := functions[0].Args[1]
+	if cur.TableOf == nil || len(cur.TableOf.RecordOf) != 4 {
+		t.Fatalf("got %#v", cur.TableOf)
+	}
+	if got := cur.TableOf.RecordOf[1].AbsType; got != "NUMBER(12, 2)" {
+		t.Errorf("amount: got %q", got)
+	}
+	if got := cur.TableOf.RecordOf[2].Charlength; got != 100 {
+		t.Errorf("name: got length %d", got)
+	}
+
+	buf.Reset()
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	wantContains(t, proto,
+		"rpc ListX (ListX_Input) returns (stream ListX_Output)",
+		"repeated ListXPCurRow_DbWeb p_cur = 1;",
+		"string name = 3;",
+	)
+	if _, callFun := functions[0].PlsqlBlock(""); !strings.Contains(callFun, "Created: custom.AsTimestamp(I[3])") {
+		t.Errorf("no row conversion in\n%s", callFun)
+	}
+}
+
+func TestRowColumns(t *testing.T) {
+	got := RowColumns(
+		[]string{"ID", "NAME", "CREATED", "HASH", "NOTE"},
+		[]driver.Value{int64(1), "x", time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC), []byte{0xca, 0xfe}, nil})
+	want := map[string]string{"ID": "1", "NAME": "x", "CREATED": "2023-04-05T06:07:08Z", "HASH": "cafe"}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestParseCursorShape(t *testing.T) {
+	for _, s := range []string{"", "id", "id NUMBER(9", "id NUMBER(x)"} {
+		if _, err := parseCursorShape(s); err == nil {
+			t.Errorf("%q: wanted error", s)
+		} else if !errors.Is(err, ErrInvalidArgument) && !strings.Contains(err.Error(), "invalid syntax") {
+			t.Errorf("%q: got %+v", s, err)
+		}
+	}
+}
+
+func TestFetchSize(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, err := ParseCsv(strings.NewReader(weakCursorCsv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape := Annotation{
+		Package: "DB_WEB", Type: "cursor", Name: "list_x.p_cur",
+		Other: "id NUMBER(9), name VARCHAR2(100 CHAR)",
+	}
+	for _, tc := range []struct {
+		Size int
+		Want string
+	}{
+		{0, "const fetchSize = 1024"},
+		{-1, "const fetchSize = 1024"},
+		{100, "const fetchSize = 100"},
+	} {
+		annotations := []Annotation{shape}
+		if tc.Size != 0 {
+			annotations = append(annotations, Annotation{Package: "DB_WEB", Type: "fetch-size", Name: "list_x", Size: tc.Size})
+		}
+		funcs := ApplyAnnotations(append([]Function(nil), functions...), annotations)
+		_, callFun := funcs[0].PlsqlBlock("")
+		for _, want := range []string{
+			tc.Want,
+			"godror.FetchArraySize(fetchSize), godror.PrefetchCount(fetchSize+1)",
+			"for i := 0; i < fetchSize; i++ {",
+		} {
+			if !strings.Contains(callFun, want) {
+				t.Errorf("%d: no %q in\n%s", tc.Size, want, callFun)
+			}
+		}
+	}
+
+	a, err := ParseAnnotation("fetch-size DB_WEB.list_x=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Annotation{Package: "DB_WEB", Type: "fetch-size", Name: "list_x", Size: 100}); a != want {
+		t.Errorf("got %#v, wanted %#v", a, want)
+	}
+	if s := a.String(); s != "fetch-size DB_WEB.list_x=100" {
+		t.Errorf("round-trip: got %q", s)
+	}
+}
+
+func TestCursorClobLimit(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	defer func(limit int) { CursorClobLimit = limit }(CursorClobLimit)
+	functions, err := ParseCsv(strings.NewReader(weakCursorCsv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions = ApplyAnnotations(functions, []Annotation{{
+		Package: "DB_WEB", Type: "cursor", Name: "list_x.p_cur",
+		Other: "id NUMBER(9), doc CLOB, title VARCHAR2(100), note CLOB",
+	}})
+
+	CursorClobLimit = 1000
+	_, callFun := functions[0].PlsqlBlock("")
+	wantContains(t, callFun,
+		"var clobSize int",
+		"custom.AsString(I[1]), // string",
+		"a = append(a, row)",
+		"if clobSize += len(row.Doc) + len(row.Note); clobSize >= 1000 {",
+	)
+
+	CursorClobLimit = 0
+	if _, callFun = functions[0].PlsqlBlock(""); strings.Contains(callFun, "clobSize") {
+		t.Errorf("limited without CursorClobLimit:\n%s", callFun)
+	}
+}
+
+func TestCursorRowName(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, err := ParseCsv(strings.NewReader(weakCursorCsv+
+		"1,2,1,DB_WEB,SEARCH_X,0,1,P_NAME,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,\n"+
+		"1,2,2,DB_WEB,SEARCH_X,0,2,P_CUR,OUT,REF CURSOR,,,,,REF CURSOR,0,,,,\n",
+	), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var annotations []Annotation
+	for _, s := range []string{
+		// before the cursor annotations, to check that those are applied first
+		"cursor-row DB_WEB.list_x=>customer_row",
+		"cursor-row DB_WEB.search_x.p_cur=>customer_row",
+		"cursor DB_WEB.list_x.p_cur=>id NUMBER(9), name VARCHAR2(100)",
+		"cursor DB_WEB.search_x.p_cur=>id NUMBER(9), name VARCHAR2(100)",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	functions = ApplyAnnotations(functions, annotations)
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name() < functions[j].Name() })
+
+	var buf strings.Builder
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	t.Log(proto)
+	if n := strings.Count(proto, "message CustomerRow {"); n != 1 {
+		t.Errorf("got %d CustomerRow messages, wanted 1", n)
+	}
+	if n := strings.Count(proto, "repeated CustomerRow p_cur = "); n != 2 {
+		t.Errorf("got %d CustomerRow fields, wanted 2", n)
+	}
+	if strings.Contains(proto, "PCurRow") {
+		t.Error("the derived row name is still used")
+	}
+	for _, f := range functions {
+		if _, callFun := f.PlsqlBlock(""); !strings.Contains(callFun, "&pb.CustomerRow{") {
+			t.Errorf("%s: the rows are not pb.CustomerRow:\n%s", f.Name(), callFun)
+		}
+	}
+}
+
+func TestCursorInput(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, stats, err := ParseCsvStats(strings.NewReader(weakCursorCsv+
+		`2,1,1,DB_WEB,LOAD_X,0,1,P_CUR,IN,REF CURSOR,,,,,REF CURSOR,0,SCOTT,DB_WEB,X_CUR,
+2,1,2,DB_WEB,LOAD_X,1,1,,IN,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,X_REC,
+2,1,3,DB_WEB,LOAD_X,2,1,ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+3,1,1,DB_WEB,SWAP_X,0,1,P_CUR,IN/OUT,REF CURSOR,,,,,REF CURSOR,0,,,,
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the REF CURSOR OUT is kept, the IN and IN OUT are skipped
+	if len(functions) != 1 || functions[0].Name() != "DB_web.list_x" {
+		t.Errorf("got %v, wanted only DB_web.list_x", functions)
+	}
+	if stats.CursorInput != 2 || stats.Functions != 1 {
+		t.Errorf("got %+v, wanted 2 CursorInput, 1 Functions", stats)
+	}
+
+	fun := Function{Package: "DB_WEB", name: "load_x", Args: []Argument{
+		{Name: "p_cur", Type: "REF CURSOR", Flavor: FLAVOR_TABLE, Direction: DIR_IN},
+	}}
+	if err = fun.checkCursorInput(); !errors.Is(err, ErrCursorInput) || !strings.Contains(err.Error(), "p_cur") {
+		t.Errorf("got %+v, wanted ErrCursorInput naming p_cur", err)
+	}
+}
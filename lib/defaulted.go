@@ -0,0 +1,138 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"strings"
+)
+
+// omittable reports whether the argument can be left out of the call when it is unset,
+// for the called function to use its DEFAULT value: a simple, IN-only argument with a DEFAULT,
+// bound directly (not through a variable of the block, as the XMLTYPEs and the BOOLEANs)
+// and without an empty-string annotation (that binds a sentinel in place of the empty string).
+func (arg Argument) omittable() bool {
+	return arg.Defaulted && arg.Direction == DIR_IN && arg.Flavor == FLAVOR_SIMPLE &&
+		arg.Type != "XMLTYPE" && !arg.isBoolean() && arg.emptyAs == ""
+}
+
+// hasOmittable reports whether the function has an omittable argument (see omittable).
+func (f Function) hasOmittable() bool {
+	for _, arg := range f.Args {
+		if arg.omittable() {
+			return true
+		}
+	}
+	return false
+}
+
+// goOmit returns the Go code recording the omittable argument in the omitted map
+// (the name of the argument to the index of its bind, see OmitCallArgs), if it is unset in the input.
+func (arg Argument) goOmit(paramName string) (string, error) {
+	isSet, err := arg.isSetExpr("input." + CamelCase(arg.Name))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`if !(%s) {
+		// unset, left out for the DEFAULT to be used
+		omitted[%q] = {{paramsIdx %q}}
+	}`, isSet, arg.RealName(), paramName), nil
+}
+
+// isSetExpr returns the Go expression telling whether the field (of the argument) of the input is set.
+func (arg Argument) isSetExpr(name string) (string, error) {
+	if _, ok := arg.protoWrapper(); ok {
+		return name + " != nil", nil
+	}
+	// the type of the input field
+	in := arg
+	in.Direction, in.goTypeName = DIR_IN, ""
+	got, err := in.goType(false)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case strings.HasPrefix(got, "[]"), strings.HasPrefix(got, "map["):
+		return "len(" + name + ") != 0", nil
+	case strings.HasPrefix(got, "*"):
+		return name + " != nil", nil
+	}
+	switch got {
+	case "string", "godror.Number":
+		return name + ` != ""`, nil
+	case "int32", "int64", "float32", "float64":
+		return name + " != 0", nil
+	case "bool":
+		return name, nil
+	case "time.Time":
+		if Gogo {
+			return "!" + name + ".IsZero()", nil
+		}
+		return name + " != nil", nil
+	}
+	return "", fmt.Errorf("%s: cannot check whether %q is set: %w", arg.Name, got, ErrInvalidArgument)
+}
+
+// OmitCallArgs leaves the omitted arguments (the name=>:bind parts) out of the named-notation call
+// of the PL/SQL block qry, for the called function to use their DEFAULT values.
+//
+// The omitted map is keyed by the names of the arguments, the values are the indexes of their binds,
+// which are left out of the params by OmitParams.
+func OmitCallArgs(qry string, omitted map[string]int) string {
+	for name := range omitted {
+		i := callArgIndex(qry, name)
+		if i < 0 {
+			continue
+		}
+		j := strings.IndexAny(qry[i:], ",)")
+		if j < 0 {
+			continue
+		}
+		j += i
+		if qry[j] == ',' {
+			// the separator and the indentation of the next argument
+			for j++; j < len(qry) && strings.IndexByte(" \t\n", qry[j]) >= 0; j++ {
+			}
+		} else if prev := strings.TrimRight(qry[:i], " \t\n"); strings.HasSuffix(prev, ",") {
+			// the last argument: the separator before it
+			i = len(prev) - 1
+		}
+		qry = qry[:i] + qry[j:]
+	}
+	return qry
+}
+
+// callArgIndex returns the index of the name=> part of the argument in the call, -1 if not found.
+func callArgIndex(qry, name string) int {
+	for off := 0; off < len(qry); {
+		i := strings.Index(qry[off:], name+"=>")
+		if i < 0 {
+			return -1
+		}
+		if i += off; i > 0 && strings.IndexByte("( \t\n", qry[i-1]) >= 0 {
+			return i
+		}
+		off = i + 1
+	}
+	return -1
+}
+
+// OmitParams returns the params without the binds of the omitted arguments (see OmitCallArgs).
+func OmitParams(omitted map[string]int, params ...interface{}) []interface{} {
+	if len(omitted) == 0 {
+		return params
+	}
+	skip := make(map[int]struct{}, len(omitted))
+	for _, i := range omitted {
+		skip[i] = struct{}{}
+	}
+	kept := make([]interface{}, 0, len(params))
+	for i, p := range params {
+		if _, ok := skip[i]; !ok {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
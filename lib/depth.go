@@ -0,0 +1,64 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxNestingDepth is the maximal depth of the record/table nesting of the arguments
+// (the DATA_LEVEL of user_arguments, 0 for the arguments themselves).
+//
+// The functions with deeper (or cyclic) arguments are skipped by ParseArguments,
+// and rejected by SaveProtobuf and SaveFunctions, with an error wrapping ErrTooDeep,
+// instead of recursing unboundedly on a malformed export.
+var MaxNestingDepth = 32
+
+// ErrTooDeep is wrapped by the errors of the arguments nested deeper than MaxNestingDepth.
+var ErrTooDeep = errors.New("nesting too deep")
+
+// checkDepth returns an error wrapping ErrTooDeep, naming the path of the first argument
+// nested deeper than MaxNestingDepth - it stops there, so it returns for the cyclic types, too.
+func (f Function) checkDepth() error {
+	var check func(arg *Argument, path string, depth int) error
+	check = func(arg *Argument, path string, depth int) error {
+		if depth > MaxNestingDepth {
+			return f.tooDeep(path, arg.TypeName)
+		}
+		switch arg.Flavor {
+		case FLAVOR_TABLE:
+			if arg.TableOf != nil {
+				return check(arg.TableOf, path+"[]", depth+1)
+			}
+		case FLAVOR_RECORD:
+			for _, sub := range arg.RecordOf {
+				if err := check(sub.Argument, path+"."+sub.Name, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for i := range f.Args {
+		if err := check(&f.Args[i], f.Args[i].Name, 0); err != nil {
+			return err
+		}
+	}
+	if f.Returns != nil {
+		return check(f.Returns, f.Returns.Name, 0)
+	}
+	return nil
+}
+
+// tooDeep returns the error of the argument at path (of type typeName) nested deeper than MaxNestingDepth.
+func (f Function) tooDeep(path, typeName string) error {
+	if typeName = strings.Trim(typeName, ".@"); typeName != "" {
+		path += " (" + typeName + ")"
+	}
+	return fmt.Errorf("%s: %s is nested deeper than %d levels (a malformed or cyclic type?): %w",
+		f.Name(), path, MaxNestingDepth, ErrTooDeep)
+}
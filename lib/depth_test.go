@@ -0,0 +1,78 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestMaxNestingDepth(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	// DEEP_X has a record nested 40 levels deep, GET_X is fine
+	var csv strings.Builder
+	csv.WriteString(`OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK
+1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+`)
+	const deep = 40
+	fmt.Fprintf(&csv, "1,2,1,DB_WEB,DEEP_X,0,1,P_REC,IN,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,REC_0,\n")
+	for i := 1; i < deep; i++ {
+		fmt.Fprintf(&csv, "1,2,%d,DB_WEB,DEEP_X,%d,1,R%d,IN,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,REC_%d,\n", i+1, i, i, i)
+	}
+	fmt.Fprintf(&csv, "1,2,%d,DB_WEB,DEEP_X,%d,1,ID,IN,NUMBER,9,,,,NUMBER,0,,,,\n", deep+1, deep)
+
+	functions, stats, err := ParseCsvStats(strings.NewReader(csv.String()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 1 || functions[0].Name() != "DB_web.get_x" || stats.TooDeep != 1 {
+		t.Errorf("got %v (%+v), wanted only DB_web.get_x, and DEEP_X skipped", functions, stats)
+	}
+
+	defer func(old int) { MaxNestingDepth = old }(MaxNestingDepth)
+	MaxNestingDepth = deep
+	if functions, stats, err = ParseCsvStats(strings.NewReader(csv.String()), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 2 || stats.TooDeep != 0 {
+		t.Fatalf("MaxNestingDepth=%d: got %v (%+v), wanted both functions", deep, functions, stats)
+	}
+	deepX := functions[1]
+	if err = deepX.checkDepth(); err != nil {
+		t.Errorf("MaxNestingDepth=%d: %+v", deep, err)
+	}
+
+	MaxNestingDepth = deep - 1
+	err = deepX.checkDepth()
+	t.Log(err)
+	if !errors.Is(err, ErrTooDeep) {
+		t.Fatalf("got %+v, wanted ErrTooDeep", err)
+	}
+	if want := "p_rec.r1.r2.r3."; !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), ".id") {
+		t.Errorf("%q does not name the path %s...id", err, want)
+	}
+
+	// a cyclic record: the generators must return, not recurse unboundedly
+	MaxNestingDepth = 32
+	cyclic := Argument{Name: "p_node", Flavor: FLAVOR_RECORD, Direction: DIR_IN, TypeName: "SCOTT.DB_WEB.NODE_TYP"}
+	cyclic.RecordOf = []NamedArgument{{Name: "next", Argument: &cyclic}}
+	fun := Function{Package: "DB_WEB", name: "CYCLE", Args: []Argument{cyclic}}
+	if err = fun.checkDepth(); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("cyclic: got %+v, wanted ErrTooDeep", err)
+	} else if !strings.Contains(err.Error(), "p_node.next.next.") {
+		t.Errorf("cyclic: %q does not name the path", err)
+	}
+	if err = SaveProtobuf(io.Discard, []Function{fun}, "db_web", "example.com/db_web", ProtoOptions{}); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("SaveProtobuf: got %+v, wanted ErrTooDeep", err)
+	}
+	if err = SaveFunctions(io.Discard, []Function{fun}, "main", "example.com/db_web", false); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("SaveFunctions: got %+v, wanted ErrTooDeep", err)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind uint8
+
+const (
+	FunctionAdded ChangeKind = iota + 1
+	FunctionRemoved
+	ArgumentAdded
+	ArgumentRemoved
+	// ArgumentRetyped is a changed type, such as NUMBER(9) to VARCHAR2(10), or RECORD to TABLE.
+	ArgumentRetyped
+	// ArgumentRedirected is a changed direction, such as IN to INOUT.
+	ArgumentRedirected
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case FunctionAdded:
+		return "function added"
+	case FunctionRemoved:
+		return "function removed"
+	case ArgumentAdded:
+		return "argument added"
+	case ArgumentRemoved:
+		return "argument removed"
+	case ArgumentRetyped:
+		return "argument retyped"
+	case ArgumentRedirected:
+		return "argument redirected"
+	}
+	return fmt.Sprintf("%d", k)
+}
+
+// Change is a difference of the API of two versions of the functions, as reported by DiffFunctions.
+type Change struct {
+	// Function is the Name of the function.
+	Function string
+	// Argument is the path of the argument, such as "p_rec.field" for a field of a record,
+	// or "p_tab[].field" for a field of the element of a table. Empty for the function changes.
+	Argument string
+	// Old and New are the types (for ArgumentRetyped) or the directions (for ArgumentRedirected).
+	Old, New string
+	Kind     ChangeKind
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case FunctionAdded, FunctionRemoved:
+		return fmt.Sprintf("%s: %s", c.Function, c.Kind)
+	case ArgumentRetyped, ArgumentRedirected:
+		return fmt.Sprintf("%s.%s: %s (%s => %s)", c.Function, c.Argument, c.Kind, c.Old, c.New)
+	}
+	return fmt.Sprintf("%s.%s: %s", c.Function, c.Argument, c.Kind)
+}
+
+// DiffFunctions returns the changes of the API from the old to the new functions:
+// the added and removed functions, and the added, removed, retyped and redirected arguments
+// (the returned value is the argument named by Returns, usually "ret").
+//
+// The functions are matched by their Name, the arguments (and record fields) by their RealName.
+// The changes are ordered by the function name, then by the position of the arguments.
+func DiffFunctions(old, new []Function) []Change {
+	oldFuncs, newFuncs := make(map[string]Function, len(old)), make(map[string]Function, len(new))
+	names := make([]string, 0, len(old)+len(new))
+	for _, f := range old {
+		oldFuncs[f.Name()] = f
+		names = append(names, f.Name())
+	}
+	for _, f := range new {
+		if _, ok := oldFuncs[f.Name()]; !ok {
+			names = append(names, f.Name())
+		}
+		newFuncs[f.Name()] = f
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for i, nm := range names {
+		if i != 0 && names[i-1] == nm {
+			continue
+		}
+		o, inOld := oldFuncs[nm]
+		n, inNew := newFuncs[nm]
+		switch {
+		case !inNew:
+			changes = append(changes, Change{Function: nm, Kind: FunctionRemoved})
+		case !inOld:
+			changes = append(changes, Change{Function: nm, Kind: FunctionAdded})
+		default:
+			changes = diffArgs(changes, nm, "", o.diffArgs(), n.diffArgs(), true)
+		}
+	}
+	return changes
+}
+
+// diffArgs returns the arguments of the function, with the returned value as the last one.
+func (f Function) diffArgs() []Argument {
+	if f.Returns == nil {
+		return f.Args
+	}
+	return append(f.Args[:len(f.Args):len(f.Args)], *f.Returns)
+}
+
+// diffArgs appends the changes from the old to the new arguments (or record fields) to changes.
+// The directions are compared for the arguments (top), not the fields.
+func diffArgs(changes []Change, funName, prefix string, old, new []Argument, top bool) []Change {
+	newArgs := make(map[string]Argument, len(new))
+	for _, arg := range new {
+		newArgs[arg.RealName()] = arg
+	}
+	oldNames := make(map[string]struct{}, len(old))
+	for _, o := range old {
+		oldNames[o.RealName()] = struct{}{}
+		path := prefix + o.RealName()
+		n, ok := newArgs[o.RealName()]
+		if !ok {
+			changes = append(changes, Change{Function: funName, Argument: path, Kind: ArgumentRemoved})
+			continue
+		}
+		if top && o.Direction != n.Direction {
+			changes = append(changes, Change{Function: funName, Argument: path, Kind: ArgumentRedirected,
+				Old: o.Direction.String(), New: n.Direction.String()})
+		}
+		changes = diffType(changes, funName, path, o, n)
+	}
+	for _, n := range new {
+		if _, ok := oldNames[n.RealName()]; !ok {
+			changes = append(changes, Change{Function: funName, Argument: prefix + n.RealName(), Kind: ArgumentAdded})
+		}
+	}
+	return changes
+}
+
+// diffType appends the changes of the type of the argument at path to changes:
+// the changes of the fields of a record, of the element of a table, or the changed type itself.
+func diffType(changes []Change, funName, path string, o, n Argument) []Change {
+	if o.Flavor == n.Flavor {
+		switch o.Flavor {
+		case FLAVOR_RECORD:
+			return diffArgs(changes, funName, path+".", o.recordArgs(), n.recordArgs(), false)
+		case FLAVOR_TABLE:
+			if o.TableOf != nil && n.TableOf != nil {
+				if o.IsStringIndexed() == n.IsStringIndexed() {
+					return diffType(changes, funName, path+"[]", *o.TableOf, *n.TableOf)
+				}
+			} else if o.TableOf == nil && n.TableOf == nil && o.diffType() == n.diffType() {
+				return changes
+			}
+		default:
+			if o.diffType() == n.diffType() {
+				return changes
+			}
+		}
+	}
+	return append(changes, Change{Function: funName, Argument: path, Kind: ArgumentRetyped,
+		Old: o.diffType(), New: n.diffType()})
+}
+
+// recordArgs returns the fields of the record, named by their NamedArgument names.
+func (arg Argument) recordArgs() []Argument {
+	args := make([]Argument, len(arg.RecordOf))
+	for i, sub := range arg.RecordOf {
+		args[i] = *sub.Argument
+		args[i].Name, args[i].oraName = sub.Name, ""
+	}
+	return args
+}
+
+// diffType returns the type of the argument as shown in a Change.
+func (arg Argument) diffType() string {
+	typ := arg.AbsType
+	if typ == "" {
+		typ = arg.Type
+	}
+	switch arg.Flavor {
+	case FLAVOR_RECORD:
+		return "RECORD " + typ
+	case FLAVOR_TABLE:
+		if arg.IsStringIndexed() {
+			return "TABLE INDEX BY VARCHAR2 " + typ
+		}
+		return "TABLE " + typ
+	}
+	return typ
+}
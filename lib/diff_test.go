@@ -0,0 +1,51 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffFunctions(t *testing.T) {
+	old := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_OLD,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,P_IO,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,4,DB_WEB,GET_X,0,4,P_SAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,2,1,DB_WEB,GONE,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	new := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,20,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_IO,IN/OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,P_SAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,4,DB_WEB,GET_X,0,4,P_NEW,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,3,1,DB_WEB,FRESH,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+
+	changes := DiffFunctions(old, new)
+	got := make([]string, len(changes))
+	for i, c := range changes {
+		got[i] = c.String()
+	}
+	want := []string{
+		"DB_web.fresh: function added",
+		"DB_web.get_x.p_id: argument retyped (NUMBER(9) => VARCHAR2(20))",
+		"DB_web.get_x.p_old: argument removed",
+		"DB_web.get_x.p_io: argument redirected (IN => INOUT)",
+		"DB_web.get_x.p_new: argument added",
+		"DB_web.gone: function removed",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwanted\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if changes[1].Kind != ArgumentRetyped || changes[1].Argument != "p_id" || changes[1].Old != "NUMBER(9)" {
+		t.Errorf("got %#v", changes[1])
+	}
+
+	if changes = DiffFunctions(new, new); len(changes) != 0 {
+		t.Errorf("no changes wanted, got %v", changes)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"io"
+)
+
+// protoEditions are the protobuf editions SaveProtobuf can target (see ProtoOptions.Edition).
+//
+// 2024 is not among them, as it enforces a naming style the generated names do not follow.
+var protoEditions = map[string]struct{}{"2023": {}}
+
+// checkEdition returns an error wrapping ErrInvalidArgument if the Edition is unknown,
+// or is asked for with gogoproto, as its generator does not support the editions.
+func (opts ProtoOptions) checkEdition() error {
+	if opts.Edition == "" {
+		return nil
+	}
+	if _, ok := protoEditions[opts.Edition]; !ok {
+		return fmt.Errorf("unknown protobuf edition %q: %w", opts.Edition, ErrInvalidArgument)
+	}
+	if Gogo || len(opts.GogoOptions) != 0 {
+		return fmt.Errorf("edition %q cannot be used with gogoproto: %w", opts.Edition, ErrInvalidArgument)
+	}
+	return nil
+}
+
+// writeSyntax writes the syntax (proto3) or the edition declaration.
+func (opts ProtoOptions) writeSyntax(w io.Writer) {
+	if opts.Edition == "" {
+		io.WriteString(w, `syntax = "proto3";`+"\n\n")
+		return
+	}
+	fmt.Fprintf(w, "edition = %q;\n\n", opts.Edition)
+}
+
+// writeFeatures writes the file-level features of the edition, keeping the semantics of proto3:
+// the scalar fields have implicit presence (the zero value is not sent),
+// the nullable ones are told apart by their wrapper messages (see NullableWrappers),
+// as the message fields have explicit presence in every edition.
+func (opts ProtoOptions) writeFeatures(w io.Writer) {
+	io.WriteString(w, "option features.field_presence = IMPLICIT;\n")
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// setArgEmptyString sets how the empty string of the input argument is handled, set by an empty-string annotation,
+// as Oracle binds it as NULL:
+//
+//   - "null" binds it as NULL, as by default,
+//   - "reject" rejects it with ErrInvalidArgument in the generated input checks (see GenChecks),
+//   - any other (single-quoted) value is a sentinel bound instead, such as ' ' for a single space.
+func (f *Function) setArgEmptyString(name, how string) error {
+	for i := range f.Args {
+		if !strings.EqualFold(f.Args[i].Name, name) {
+			continue
+		}
+		arg := f.Args[i]
+		if !arg.IsInput() {
+			return fmt.Errorf("%s is not an input argument: %w", name, ErrInvalidArgument)
+		}
+		if !arg.isPlainString() {
+			return fmt.Errorf("%s is not a (non-nullable) string argument: %w", name, ErrInvalidArgument)
+		}
+		arg.rejectEmpty, arg.emptyAs = false, ""
+		switch how {
+		case "null":
+		case "reject":
+			arg.rejectEmpty = true
+		default:
+			if len(how) >= 2 && how[0] == '\'' && how[len(how)-1] == '\'' {
+				how = how[1 : len(how)-1]
+			}
+			if how == "" {
+				return fmt.Errorf("%s: the empty string is NULL, use null (or reject): %w", name, ErrInvalidArgument)
+			}
+			length, unit := len(how), "bytes"
+			if arg.LengthInChars() {
+				length, unit = utf8.RuneCountInString(how), "characters"
+			}
+			if arg.Charlength != 0 && uint(length) > arg.Charlength {
+				return fmt.Errorf("%s: %q is longer than accepted (%d %s): %w", name, how, arg.Charlength, unit, ErrInvalidArgument)
+			}
+			arg.emptyAs = how
+		}
+		// do not modify the caller's Args
+		f.Args = append([]Argument(nil), f.Args...)
+		f.Args[i] = arg
+		return nil
+	}
+	return fmt.Errorf("%s: no such argument: %w", name, ErrInvalidArgument)
+}
+
+// isPlainString reports whether the input field of the simple argument is a string,
+// which Oracle cannot tell apart from NULL when it is empty.
+func (arg Argument) isPlainString() bool {
+	if arg.Flavor != FLAVOR_SIMPLE || arg.Type == "XMLTYPE" {
+		return false
+	}
+	if _, ok := arg.protoWrapper(); ok {
+		return false
+	}
+	// the type of the input field
+	in := arg
+	in.Direction, in.goTypeName = DIR_IN, ""
+	got, err := in.goType(false)
+	return err == nil && got == "string"
+}
+
+// emptyCheck returns the check of the input field (the name expression) rejecting the empty string,
+// or "" if the argument accepts it.
+func (arg Argument) emptyCheck(name string) string {
+	if !arg.rejectEmpty {
+		return ""
+	}
+	return fmt.Sprintf(`if %s == "" {
+		return fmt.Errorf("%s must not be empty (Oracle would bind it as NULL): %%w", oracall.ErrInvalidArgument)
+	}`,
+		name, name)
+}
+
+// emptyConv returns the conversion binding the sentinel to dst (the param, or the output field)
+// if the src input field is empty, or "" if the argument has no sentinel.
+func (arg Argument) emptyConv(dst, src string) string {
+	if arg.emptyAs == "" {
+		return ""
+	}
+	return fmt.Sprintf("if %s == \"\" { %s = %q }  // empty-string", src, dst, arg.emptyAs)
+}
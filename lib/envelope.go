@@ -0,0 +1,84 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Envelope makes SaveProtobuf wrap the response of each rpc in an envelope message,
+// with the real response in its data field and a ResponseMeta (the request ID and
+// the duration of the call) in its meta field, and the Go code written by SaveFunctions
+// fill the meta - from orasrv.ContextGetReqID for the request ID.
+//
+// The streaming (cursor returning) rpcs send their responses unwrapped.
+var Envelope bool
+
+// envelopeMetaProto is the message of the meta field of the envelopes.
+const envelopeMetaProto = `
+// ResponseMeta is the metadata of a response.
+message ResponseMeta {
+	// request_id identifies the request in the logs of the server.
+	string request_id = 1;
+	// duration of the call.
+	google.protobuf.Duration duration = 2;
+}
+`
+
+// useEnvelope reports whether the response of the function is wrapped in an envelope (see Envelope).
+func (f Function) useEnvelope() bool { return Envelope && !f.HasCursorOut() }
+
+// envelopeName returns the name of the envelope message of the function's response.
+func (f Function) envelopeName(naming NamingStrategy) string {
+	return naming.MessageName(f, true) + "Envelope"
+}
+
+// writeEnvelope writes the envelope message of the function's response.
+func (f Function) writeEnvelope(w io.Writer, naming NamingStrategy) error {
+	outName := naming.MessageName(f, true)
+	if f.usesEmpty() {
+		outName = "google.protobuf.Empty"
+	}
+	_, err := fmt.Fprintf(w, "// %s wraps the response of %s.\nmessage %s {\n\t%s data = 1;\n\tResponseMeta meta = 2;\n}\n",
+		f.envelopeName(naming), naming.RPCName(f), f.envelopeName(naming), outName)
+	return err
+}
+
+// envelopeMethod returns the method returning the response of the method named callName
+// (with goCallSignature) wrapped in the envelope.
+func (f Function) envelopeMethod(callName string) string {
+	var deprecated string
+	if d := f.goDeprecated(); d != "" {
+		deprecated = "//\n" + d
+	}
+	return fmt.Sprintf(`
+// %s calls %s, and wraps its response in the envelope.
+%sfunc (s *oracallServer) %s {
+	start := time.Now()
+	data, err := s.%s(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return &%s{
+		Data: data,
+		Meta: &pb.ResponseMeta{RequestId: orasrv.ContextGetReqID(ctx), Duration: durationpb.New(time.Since(start))},
+	}, nil
+}
+`,
+		f.goName(), callName,
+		deprecated,
+		f.goSignature(),
+		callName,
+		"pb."+CamelCase(f.getStructName(true, false))+"Envelope",
+	)
+}
+
+// goEnvelopeCallName returns the name of the unexported method the envelope method calls.
+func (f Function) goEnvelopeCallName() string {
+	nm := f.goName()
+	return strings.ToLower(nm[:1]) + nm[1:]
+}
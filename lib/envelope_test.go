@@ -0,0 +1,46 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"go/format"
+	"strings"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	defer func(old bool) { Envelope = old }(Envelope)
+	Envelope = true
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_NAME,OUT,VARCHAR2,,,,,VARCHAR2,100,,,,",
+	)
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	t.Log(proto)
+	wantContains(t, proto,
+		`import "google/protobuf/duration.proto";`,
+		"message ResponseMeta {",
+		"message GetX_OutputEnvelope {\n\tGetX_Output data = 1;\n\tResponseMeta meta = 2;\n}",
+		"rpc GetX (GetX_Input) returns (GetX_OutputEnvelope) {}",
+	)
+
+	_, callFun := functions[0].PlsqlBlock("")
+	b, err := format.Source([]byte(callFun))
+	if err != nil {
+		t.Fatalf("%+v\n%s", err, callFun)
+	}
+	callFun = string(b)
+	wantContains(t, callFun,
+		"func (s *oracallServer) getX(ctx context.Context, input *pb.GetX_Input) (output *pb.GetX_Output, err error) {",
+		"func (s *oracallServer) GetX(ctx context.Context, input *pb.GetX_Input) (*pb.GetX_OutputEnvelope, error) {",
+		"data, err := s.getX(ctx, input)",
+		"RequestId: orasrv.ContextGetReqID(ctx)",
+	)
+}
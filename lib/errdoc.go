@@ -0,0 +1,90 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// grpcCodeNames are the names of the gRPC status codes, as in google.golang.org/grpc/codes.
+var grpcCodeNames = []string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded",
+	"NotFound", "AlreadyExists", "PermissionDenied", "ResourceExhausted",
+	"FailedPrecondition", "Aborted", "OutOfRange", "Unimplemented",
+	"Internal", "Unavailable", "DataLoss", "Unauthenticated",
+}
+
+// ParseORACode parses an "oraCode=CodeName" mapping, such as "20404=NotFound",
+// as registered on the server with orasrv.RegisterORACode(20404, codes.NotFound).
+func ParseORACode(s string) (int, string, error) {
+	num, name, ok := strings.Cut(s, "=")
+	if !ok {
+		return 0, "", fmt.Errorf("%q: want oraCode=CodeName", s)
+	}
+	oraCode, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(num), "-"))
+	if err != nil {
+		return 0, "", fmt.Errorf("%q: %w", s, err)
+	}
+	name = strings.TrimSpace(name)
+	for _, nm := range grpcCodeNames {
+		if strings.EqualFold(nm, name) {
+			return oraCode, nm, nil
+		}
+	}
+	return 0, "", fmt.Errorf("%q: unknown gRPC code %q", s, name)
+}
+
+// errorDoc documents the gRPC codes the rpc of the function may return (see orasrv.StatusError):
+// InvalidArgument for an input failing the bounds checks, the codes of the oraCodes mappings,
+// and Unknown for any other error. The exceptions handled by the "handle" annotations are
+// listed, too, as those are not errors - and so are the arguments capturing every database error
+// of a function with a "sqlcode" annotation.
+func (f Function) errorDoc(oraCodes map[int]string) string {
+	var buf strings.Builder
+	buf.WriteString("Errors:")
+	if len(f.Args) != 0 {
+		buf.WriteString("\n  InvalidArgument: the input is out of the bounds of the PL/SQL arguments.")
+	}
+	if f.sqlCode != "" {
+		// the database errors are not raised
+		oraCodes = nil
+	}
+	byName := make(map[string][]int, len(oraCodes))
+	for oraCode, name := range oraCodes {
+		byName[name] = append(byName[name], oraCode)
+	}
+	names := make([]string, 0, len(byName))
+	for name, oraCodes := range byName {
+		sort.Ints(oraCodes)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\n  %s:", name)
+		for i, oraCode := range byName[name] {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, " ORA-%05d", oraCode)
+		}
+	}
+	if f.sqlCode == "" {
+		buf.WriteString("\n  Unknown: any other database error.")
+	}
+	if len(f.handle) != 0 {
+		fmt.Fprintf(&buf, "\nHandled, not errors: %s.", strings.Join(f.handle, ", "))
+	}
+	if f.sqlCode != "" {
+		captured := f.sqlCode
+		if f.sqlErrm != "" {
+			captured += " and " + f.sqlErrm
+		}
+		fmt.Fprintf(&buf, "\nCaptured, not errors: any other database error, into %s.", captured)
+	}
+	return buf.String()
+}
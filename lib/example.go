@@ -0,0 +1,91 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// setArgExample sets the example value of the argument (or the return value, named "ret"),
+// set by an example annotation.
+//
+// The example of a numeric or bool field must be its JSON literal (42, 3.14, true),
+// of the other fields their text, quoted or not.
+func (f *Function) setArgExample(name, example string) error {
+	set := func(arg *Argument) error {
+		if arg.Flavor != FLAVOR_SIMPLE {
+			return fmt.Errorf("%s is not a simple argument: %w", name, ErrInvalidArgument)
+		}
+		typ, err := arg.exampleType(false)
+		if err != nil {
+			return err
+		}
+		if !isJSONStringType(typ) && (!json.Valid([]byte(example)) || strings.HasPrefix(example, `"`)) {
+			return fmt.Errorf("%s: %q is not a %s literal: %w", name, example, typ, ErrInvalidArgument)
+		}
+		arg.example = example
+		return nil
+	}
+	for i := range f.Args {
+		if strings.EqualFold(f.Args[i].Name, name) {
+			arg := f.Args[i]
+			if err := set(&arg); err != nil {
+				return err
+			}
+			// do not modify the caller's Args
+			f.Args = append([]Argument(nil), f.Args...)
+			f.Args[i] = arg
+			return nil
+		}
+	}
+	if f.Returns != nil && strings.EqualFold(f.Returns.Name, name) {
+		ret := *f.Returns
+		if err := set(&ret); err != nil {
+			return err
+		}
+		f.Returns = &ret
+		return nil
+	}
+	return fmt.Errorf("%s: no such argument: %w", name, ErrInvalidArgument)
+}
+
+// exampleType returns the proto type of the simple argument, as its example is written.
+func (arg Argument) exampleType(parentIsTable bool) (string, error) {
+	got, err := arg.goType(parentIsTable)
+	if err != nil {
+		return "", err
+	}
+	typ, _ := arg.mappedProtoType(strings.TrimPrefix(got, "*"))
+	return typ, nil
+}
+
+// isJSONStringType reports whether the fields of the proto type are strings in protojson.
+func isJSONStringType(typ string) bool {
+	switch typ {
+	case "string", "bytes", "google.protobuf.Timestamp":
+		return true
+	}
+	return false
+}
+
+// exampleJSON returns the JSON value of the example of the argument of the proto type:
+// the text of a string field quoted (if it is not already), the literal of the others.
+func (arg Argument) exampleJSON(typ string) string {
+	if !isJSONStringType(typ) || strings.HasPrefix(arg.example, `"`) && json.Valid([]byte(arg.example)) {
+		return arg.example
+	}
+	b, _ := json.Marshal(arg.example)
+	return string(b)
+}
+
+// exampleDoc returns the "example: ..." line of the field's comment, or "" if the argument has no example.
+func (arg Argument) exampleDoc() string {
+	if arg.example == "" {
+		return ""
+	}
+	return "example: " + arg.example
+}
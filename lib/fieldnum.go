@@ -0,0 +1,113 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+const (
+	// maxHashedFieldNumber keeps the hashed field numbers below 2^18, so their tags fit in 3 bytes.
+	maxHashedFieldNumber = 1<<18 - 1
+	// the field numbers reserved for the protobuf implementation
+	reservedFieldNumberFirst, reservedFieldNumberLast = 19000, 19999
+	hashedFieldNumbers                                = maxHashedFieldNumber - (reservedFieldNumberLast - reservedFieldNumberFirst + 1)
+)
+
+// fieldNumbers returns the proto field numbers of the fields named names:
+// their positions (1, 2, ...), or, if hash is true, the numbers derived from the names (see hashFieldNumber).
+//
+// Hashed numbers that collide are resolved in the order of the names: the smallest name gets the number,
+// the others take the next free one. So the numbers of a message's fields do not change when fields are
+// reordered, added or removed - unless a new field collides with an existing one of greater name.
+// Such collisions are logged.
+func fieldNumbers(names []string, hash bool) []int {
+	nums := make([]int, len(names))
+	if !hash {
+		for i := range nums {
+			nums[i] = i + 1
+		}
+		return nums
+	}
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return names[order[i]] < names[order[j]] })
+	used := make(map[int]string, len(names))
+	for _, i := range order {
+		n := hashFieldNumber(names[i])
+		for other, ok := used[n]; ok; other, ok = used[n] {
+			logger.Warn("field number collision", "field", names[i], "other", other, "number", n)
+			if n++; n == reservedFieldNumberFirst {
+				n = reservedFieldNumberLast + 1
+			} else if n > maxHashedFieldNumber {
+				n = 1
+			}
+		}
+		used[n] = names[i]
+		nums[i] = n
+	}
+	return nums
+}
+
+// hashFieldNumber returns the field number of name: its FNV-1a hash, mapped to
+// [1, maxHashedFieldNumber], skipping the reserved 19000-19999.
+func hashFieldNumber(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	n := 1 + int(h.Sum32()%hashedFieldNumbers)
+	if n >= reservedFieldNumberFirst {
+		n += reservedFieldNumberLast - reservedFieldNumberFirst + 1
+	}
+	return n
+}
+
+// pinInOutNumbers gives the IN OUT args found in pinned (by their field names) the number recorded there,
+// swapping it with the field having that number - and records the numbers of the others into pinned.
+// So an IN OUT argument has the same field number in the request and in the response message.
+//
+// The number reserved (for a retyped field) in this message is not pinned, but logged.
+func pinInOutNumbers(args []Argument, names []string, nums []int, reserved []string, pinned map[string]int) {
+	if pinned == nil {
+		return
+	}
+	for i, arg := range args {
+		if arg.Direction != DIR_INOUT {
+			continue
+		}
+		n, ok := pinned[names[i]]
+		if !ok {
+			pinned[names[i]] = nums[i]
+			continue
+		}
+		if n == nums[i] {
+			continue
+		}
+		if isReserved(reserved, n) {
+			logger.Warn("IN OUT field number is reserved", "field", names[i], "number", n, "got", nums[i])
+			continue
+		}
+		for j := range nums {
+			if nums[j] == n {
+				nums[j] = nums[i]
+				break
+			}
+		}
+		nums[i] = n
+	}
+}
+
+func isReserved(reserved []string, n int) bool {
+	s := strconv.Itoa(n)
+	for _, r := range reserved {
+		if r == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadFilterFile reads the include/exclude rules from the given file,
+// and returns a filter usable for ParseCsv.
+//
+// See ParseFilter for the format.
+func LoadFilterFile(fn string) (func(string) bool, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	filter, err := ParseFilter(fh)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return filter, nil
+}
+
+// ParseFilter parses the include/exclude rules.
+//
+// Each non-empty, not #-commented line is a glob (as in path.Match),
+// prefixed with + (include) or - (exclude), matched case-insensitively against PACKAGE.OBJECT.
+// The last matching line decides; if no line matches, the name is included
+// only if there are no include lines at all.
+func ParseFilter(r io.Reader) (func(string) bool, error) {
+	type rule struct {
+		pattern string
+		include bool
+	}
+	var rules []rule
+	var hasInclude bool
+	scanner := bufio.NewScanner(r)
+	var lineNo int
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var rl rule
+		switch line[0] {
+		case '+':
+			rl.include, hasInclude = true, true
+		case '-':
+		default:
+			return nil, fmt.Errorf("line %d: %q should start with + or -: %w", lineNo, line, ErrInvalidArgument)
+		}
+		rl.pattern = strings.ToUpper(strings.TrimSpace(line[1:]))
+		if _, err := path.Match(rl.pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: %q: %w", lineNo, line, err)
+		}
+		rules = append(rules, rl)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return func(name string) bool {
+		name = strings.ToUpper(name)
+		for i := len(rules) - 1; i >= 0; i-- {
+			if ok, _ := path.Match(rules[i].pattern, name); ok {
+				return rules[i].include
+			}
+		}
+		return !hasInclude
+	}, nil
+}
+
+// ExceptFilter returns a filter excluding the given names,
+// matched case-insensitively against both the PACKAGE.OBJECT name and the bare OBJECT name,
+// so "GET_X" excludes DB_WEB.GET_X, too.
+func ExceptFilter(names ...string) func(string) bool {
+	return func(name string) bool {
+		object := name[strings.LastIndexByte(name, '.')+1:]
+		for _, e := range names {
+			e = strings.TrimPrefix(e, ".")
+			if strings.EqualFold(e, name) || strings.EqualFold(e, object) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ChangedSince wraps the filter (nil includes every name) to exclude the functions not changed since the given time:
+// those whose last DDL time, as lastDDL returns it for the PACKAGE.OBJECT (or the standalone OBJECT) name,
+// is known (not zero) and before since. The results of lastDDL are cached.
+//
+// Only the database reader knows the last DDL times (see Function.LastDDL) - the csv has no such column,
+// so with the csv every function is included.
+func ChangedSince(filter func(string) bool, since time.Time, lastDDL func(name string) time.Time) func(string) bool {
+	var mu sync.Mutex
+	cache := make(map[string]time.Time)
+	return func(name string) bool {
+		if filter != nil && !filter(name) {
+			return false
+		}
+		key := strings.ToUpper(name)
+		mu.Lock()
+		t, ok := cache[key]
+		if !ok {
+			t = lastDDL(name)
+			cache[key] = t
+		}
+		mu.Unlock()
+		return t.IsZero() || !t.Before(since)
+	}
+}
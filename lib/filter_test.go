@@ -0,0 +1,109 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter(strings.NewReader(`
+# everything from DB_WEB, but its internal functions
++DB_WEB.*
+-DB_WEB.INT_*
+  # except this one
++db_web.int_keep
+-DB_OTHER.*
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"DB_WEB.GET_X":    true,
+		"DB_WEB.INT_X":    false,
+		"DB_WEB.INT_KEEP": true,
+		"db_web.int_keep": true,
+		"DB_OTHER.GET_X":  false,
+		"DB_THIRD.GET_X":  false, // there are include lines, so not included by default
+	} {
+		if got := filter(name); got != want {
+			t.Errorf("%s: got %t, wanted %t", name, got, want)
+		}
+	}
+
+	if filter, err = ParseFilter(strings.NewReader("-DB_WEB.INT_*\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !filter("DB_THIRD.GET_X") {
+		t.Error("exclude-only filter should include unmatched names")
+	}
+
+	if _, err = ParseFilter(strings.NewReader("DB_WEB.*\n")); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("missing prefix: got %+v, wanted ErrInvalidArgument", err)
+	}
+	if _, err = ParseFilter(strings.NewReader("+DB_WEB.[\n")); err == nil {
+		t.Error("bad pattern: wanted error")
+	}
+}
+
+func TestExceptFilter(t *testing.T) {
+	filter := ExceptFilter("get_x", "DB_WEB.SET_Y", ".STANDALONE")
+	for name, want := range map[string]bool{
+		"DB_WEB.GET_X":   false, // -except OBJ excludes the packaged function, too
+		"DB_OTHER.GET_X": false,
+		"GET_X":          false,
+		"DB_WEB.SET_Y":   false,
+		"DB_OTHER.SET_Y": true,
+		"SET_Y":          true,
+		"STANDALONE":     false,
+		"DB_WEB.GET_Z":   true,
+	} {
+		if got := filter(name); got != want {
+			t.Errorf("%s: got %t, wanted %t", name, got, want)
+		}
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	since := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	lastDDLs := map[string]time.Time{
+		"DB_WEB.OLD":     since.Add(-time.Hour),
+		"DB_WEB.NEW":     since.Add(time.Hour),
+		"DB_WEB.EXACT":   since,
+		"DB_OTHER.NEW":   since.Add(time.Hour),
+		"STANDALONE_OLD": since.AddDate(-1, 0, 0),
+	}
+	calls := make(map[string]int)
+	filter := ChangedSince(
+		func(name string) bool { return !strings.HasPrefix(name, "DB_OTHER.") },
+		since,
+		func(name string) time.Time { calls[name]++; return lastDDLs[name] },
+	)
+	for name, want := range map[string]bool{
+		"DB_WEB.OLD":     false,
+		"DB_WEB.NEW":     true,
+		"DB_WEB.EXACT":   true,
+		"DB_OTHER.NEW":   false, // excluded by the wrapped filter
+		"STANDALONE_OLD": false,
+		"DB_WEB.UNKNOWN": true, // no LastDDL, as from a csv
+	} {
+		for i := 0; i < 2; i++ {
+			if got := filter(name); got != want {
+				t.Errorf("%s: got %t, wanted %t", name, got, want)
+			}
+		}
+	}
+	for name, n := range calls {
+		if n != 1 {
+			t.Errorf("%s: lastDDL is called %d times", name, n)
+		}
+	}
+	if calls["DB_OTHER.NEW"] != 0 {
+		t.Error("lastDDL is called for the filtered out name")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gogoFileOptions are the file-level options of gogoproto (see gogo.proto), all of them bool.
+var gogoFileOptions = map[string]struct{}{
+	"benchgen_all": {}, "compare_all": {}, "description_all": {},
+	"enum_stringer_all": {}, "enumdecl_all": {}, "equal_all": {},
+	"face_all": {}, "gogoproto_import": {}, "goproto_enum_prefix_all": {},
+	"goproto_enum_stringer_all": {}, "goproto_extensions_map_all": {}, "goproto_getters_all": {},
+	"goproto_registration": {}, "goproto_sizecache_all": {}, "goproto_stringer_all": {},
+	"goproto_unkeyed_all": {}, "goproto_unrecognized_all": {}, "gostring_all": {},
+	"marshaler_all": {}, "messagename_all": {}, "onlyone_all": {},
+	"populate_all": {}, "protosizer_all": {}, "sizer_all": {},
+	"stable_marshaler_all": {}, "stringer_all": {}, "testgen_all": {},
+	"typedecl_all": {}, "unmarshaler_all": {}, "unsafe_marshaler_all": {},
+	"unsafe_unmarshaler_all": {}, "verbose_equal_all": {},
+}
+
+// ParseGogoOption parses a "name=true" (or "name=false") file-level gogoproto option,
+// such as "goproto_getters_all=false". The name may have the "gogoproto." prefix.
+func ParseGogoOption(s string) (string, bool, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", false, fmt.Errorf("%q: want name=true or name=false", s)
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return "", false, fmt.Errorf("%q: %w", s, err)
+	}
+	name = strings.TrimPrefix(strings.TrimSpace(name), "gogoproto.")
+	if err = checkGogoOption(name); err != nil {
+		return "", false, err
+	}
+	return name, b, nil
+}
+
+// checkGogoOption returns an error wrapping ErrInvalidArgument if name is not a file-level gogoproto option.
+func checkGogoOption(name string) error {
+	if _, ok := gogoFileOptions[name]; ok {
+		return nil
+	}
+	if _, ok := gogoFileOptions[name+"_all"]; ok {
+		return fmt.Errorf("%q is not a file-level gogoproto option, %q is: %w", name, name+"_all", ErrInvalidArgument)
+	}
+	return fmt.Errorf("unknown gogoproto file-level option %q: %w", name, ErrInvalidArgument)
+}
+
+// checkGogoOptions checks the names of the GogoOptions.
+func (opts ProtoOptions) checkGogoOptions() error {
+	for name := range opts.GogoOptions {
+		if err := checkGogoOption(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGogoOptions writes the GogoOptions as file-level options, sorted by their names.
+func (opts ProtoOptions) writeGogoOptions(w io.Writer) {
+	names := make([]string, 0, len(opts.GogoOptions))
+	for name := range opts.GogoOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "option (gogoproto.%s) = %t;\n", name, opts.GogoOptions[name])
+	}
+}
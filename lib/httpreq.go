@@ -0,0 +1,163 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// SaveHTTPRequests writes a .http file (as used by the VS Code REST Client and the JetBrains HTTP client)
+// with an example JSON-over-HTTP request for each function, POSTing to the {{baseUrl}}/<pkg>.<Service>/<Rpc>
+// path - as the gRPC full method name, served by grpc-gateway or any JSON transcoding proxy.
+//
+// The request bodies are built from the input messages, with the value set by an example annotation,
+// or a zero value (an example timestamp, one-element arrays) for each field, in protojson form.
+func SaveHTTPRequests(dst io.Writer, functions []Function, pkg string, naming NamingStrategy) error {
+	if naming == nil {
+		naming = DefaultNaming{}
+	}
+	var err error
+	w := errWriter{Writer: dst, err: &err}
+	io.WriteString(w, "# Generated by oracall - example requests.\n@baseUrl = http://localhost:8080\n")
+	service := naming.ServiceName(pkg)
+	if pkg != "" {
+		service = pkg + "." + service
+	}
+	for _, fun := range functions {
+		var body strings.Builder
+		if fbErr := fun.writeExampleInput(&body, naming); fbErr != nil {
+			if SkipMissingTableOf && (errors.Is(fbErr, ErrMissingTableOf) || errors.Is(fbErr, ErrUnknownSimpleType)) {
+				continue
+			}
+			return fmt.Errorf("%s: %w", fun.Name(), fbErr)
+		}
+		rpc := naming.RPCName(fun)
+		fmt.Fprintf(w, "\n### %s\n", rpc)
+		if doc, _, _ := strings.Cut(strings.TrimSpace(fun.Documentation), "\n"); doc != "" {
+			fmt.Fprintf(w, "# %s\n", doc)
+		}
+		fmt.Fprintf(w, "POST {{baseUrl}}/%s/%s\nContent-Type: application/json\n\n%s\n", service, rpc, body.String())
+	}
+	return err
+}
+
+// writeExampleInput writes the example JSON of the input message of the function.
+func (f Function) writeExampleInput(w *strings.Builder, naming NamingStrategy) error {
+	var args []Argument
+	for _, arg := range f.Args {
+		if arg.IsInput() {
+			args = append(args, arg)
+		}
+	}
+	if len(args) == 0 {
+		w.WriteString("{}")
+		return nil
+	}
+	w.WriteString("{")
+	for i, arg := range args {
+		if i != 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, "\n  %q: ", fieldJSONName(naming, arg))
+		if err := exampleValue(w, naming, arg, "  ", false); err != nil {
+			return fmt.Errorf("%s: %w", arg.Name, err)
+		}
+	}
+	w.WriteString("\n}")
+	return nil
+}
+
+// exampleValue writes the example JSON value of the argument.
+func exampleValue(w *strings.Builder, naming NamingStrategy, arg Argument, indent string, parentIsTable bool) error {
+	switch arg.Flavor {
+	case FLAVOR_SIMPLE:
+		got, err := arg.goType(parentIsTable)
+		if err != nil {
+			return err
+		}
+		typ, _ := arg.mappedProtoType(strings.TrimPrefix(got, "*"))
+		if arg.example != "" {
+			w.WriteString(arg.exampleJSON(typ))
+			return nil
+		}
+		switch typ {
+		case "string":
+			if got == "godror.Number" {
+				w.WriteString(`"0"`)
+			} else {
+				w.WriteString(`""`)
+			}
+		case "bool":
+			w.WriteString("false")
+		case "bytes":
+			w.WriteString(`""`)
+		case "google.protobuf.Timestamp":
+			w.WriteString(`"2006-01-02T15:04:05Z"`)
+		default:
+			w.WriteString("0")
+		}
+		return nil
+
+	case FLAVOR_TABLE:
+		if arg.TableOf == nil {
+			return ErrMissingTableOf
+		}
+		if arg.IsStringIndexed() {
+			fmt.Fprintf(w, "{\n%s  \"key\": ", indent)
+			if err := exampleValue(w, naming, *arg.TableOf, indent+"  ", true); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "\n%s}", indent)
+			return nil
+		}
+		fmt.Fprintf(w, "[\n%s  ", indent)
+		if err := exampleValue(w, naming, *arg.TableOf, indent+"  ", true); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\n%s]", indent)
+		return nil
+
+	default: // FLAVOR_RECORD
+		if len(arg.RecordOf) == 0 {
+			w.WriteString("{}")
+			return nil
+		}
+		w.WriteString("{")
+		for i, sub := range arg.RecordOf {
+			if i != 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "\n%s  %q: ", indent, fieldJSONName(naming, *sub.Argument))
+			if err := exampleValue(w, naming, *sub.Argument, indent+"  ", parentIsTable); err != nil {
+				return fmt.Errorf("%s: %w", sub.Name, err)
+			}
+		}
+		fmt.Fprintf(w, "\n%s}", indent)
+		return nil
+	}
+}
+
+// protoJSONName returns the protojson name of the field, as protoc does:
+// the underscores are removed, and the letters following them are uppercased.
+func protoJSONName(name string) string {
+	name = replHidden(name)
+	var buf strings.Builder
+	var upper bool
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r, upper = unicode.ToUpper(r), false
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
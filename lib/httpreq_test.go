@@ -0,0 +1,149 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSaveHTTPRequests(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_NAME,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,P_SINCE,IN,DATE,,,,,DATE,0,,,,",
+		"1,1,4,DB_WEB,GET_X,0,4,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,2,1,DB_WEB,REFRESH,0,1,,,,,,,,,,,,,",
+	)
+	var buf strings.Builder
+	if err := SaveHTTPRequests(&buf, functions, "db_web", nil); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+
+	requests := strings.Split(s, "\n### ")[1:]
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, wanted 2", len(requests))
+	}
+	for _, tC := range []struct {
+		Head string
+		Body map[string]interface{}
+	}{
+		{
+			Head: "GetX\nPOST {{baseUrl}}/db_web.DbWeb/GetX\nContent-Type: application/json\n\n",
+			Body: map[string]interface{}{"pId": 0.0, "pName": "", "pSince": "2006-01-02T15:04:05Z"},
+		},
+		{
+			Head: "Refresh\nPOST {{baseUrl}}/db_web.DbWeb/Refresh\nContent-Type: application/json\n\n",
+			Body: map[string]interface{}{},
+		},
+	} {
+		var req string
+		for _, r := range requests {
+			if strings.HasPrefix(r, tC.Head) {
+				req = strings.TrimPrefix(r, tC.Head)
+			}
+		}
+		if req == "" {
+			t.Errorf("no request starting with %q", tC.Head)
+			continue
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal([]byte(req), &body); err != nil {
+			t.Errorf("%s: %+v", req, err)
+			continue
+		}
+		if len(body) != len(tC.Body) {
+			t.Errorf("got %v, wanted %v", body, tC.Body)
+		}
+		for k, v := range tC.Body {
+			if body[k] != v {
+				t.Errorf("%s: got %v, wanted %v", k, body[k], v)
+			}
+		}
+	}
+}
+
+func TestExampleAnnotation(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_NAME,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,P_SINCE,IN,DATE,,,,,DATE,0,,,,",
+		"1,1,4,DB_WEB,GET_X,0,4,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	var annotations []Annotation
+	for _, s := range []string{
+		"example DB_web.get_x.p_id => 42",
+		"example DB_web.get_x.p_name => John Doe",
+		`example DB_web.get_x.p_x1 => "x-1"`,
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	functions, err := ApplyAnnotationsStrict(functions, annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the example payloads
+	var buf strings.Builder
+	if err := SaveHTTPRequests(&buf, functions, "db_web", nil); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+	_, req, _ := strings.Cut(s, "Content-Type: application/json\n\n")
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(req), &body); err != nil {
+		t.Fatalf("%s: %+v", req, err)
+	}
+	// the unannotated field gets the default of its type
+	for k, v := range map[string]interface{}{"pId": 42.0, "pName": "John Doe", "pSince": "2006-01-02T15:04:05Z"} {
+		if body[k] != v {
+			t.Errorf("%s: got %v, wanted %v", k, body[k], v)
+		}
+	}
+
+	// the comments of the fields
+	buf.Reset()
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	s = buf.String()
+	t.Log(s)
+	wantContains(t, s,
+		"\t// example: 42\n\t// NUMBER(9)\n\tsint32 p_id = 1;",
+		"\t// example: John Doe\n\t// VARCHAR2(10)\n\tstring p_name = 2;",
+		"\t// example: \"x-1\"\n\t// VARCHAR2(10)\n\tstring p_x1 = 1;",
+	)
+	if strings.Contains(s, "// example: \n") {
+		t.Errorf("empty example in\n%s", s)
+	}
+
+	// the model of the external generators
+	if got := functions[0].Model().Args[1].Example; got != "John Doe" {
+		t.Errorf("got %q for the example of the model, wanted %q", got, "John Doe")
+	}
+
+	for _, s := range []string{
+		"example DB_web.get_x.p_id => forty-two",
+		`example DB_web.get_x.p_id => "42"`,
+		"example DB_web.get_x.p_nope => 1",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ApplyAnnotationsStrict(functions, []Annotation{a}); !errors.Is(err, ErrUnmatchedAnnotation) {
+			t.Errorf("%q: got %+v, wanted ErrUnmatchedAnnotation", s, err)
+		}
+	}
+}
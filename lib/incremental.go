@@ -0,0 +1,154 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	sectionBegin = "// oracall:begin "
+	sectionEnd   = "// oracall:end "
+)
+
+// PreviousSections makes SaveFunctions reuse the previously generated code of the functions
+// whose Fingerprint has not changed, instead of regenerating it - see ReadSections.
+var PreviousSections map[string]Section
+
+// Section is the code generated by SaveFunctions for one function.
+type Section struct {
+	// Fingerprint of the function the code was generated from.
+	Fingerprint string
+	// Text is the code, including the begin and end marker lines.
+	Text string
+}
+
+// ReadSections returns the function sections of a file generated by SaveFunctions,
+// keyed by the function's Name.
+func ReadSections(r io.Reader) (map[string]Section, error) {
+	sections := make(map[string]Section)
+	var name string
+	var sec Section
+	var buf strings.Builder
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name == "" {
+			if rest, ok := strings.CutPrefix(line, sectionBegin); ok {
+				if name, sec.Fingerprint, ok = strings.Cut(rest, " "); !ok || name == "" {
+					return sections, fmt.Errorf("bad section begin %q", line)
+				}
+				buf.Reset()
+				buf.WriteString(line)
+				buf.WriteByte('\n')
+			}
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if line == sectionEnd+name {
+			sec.Text = buf.String()
+			sections[name] = sec
+			name = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return sections, err
+	}
+	if name != "" {
+		return sections, fmt.Errorf("section %q is not closed", name)
+	}
+	return sections, nil
+}
+
+// Fingerprint returns a stable hash of the parsed signature of the function,
+// including everything that changes the generated code (annotations, documentation).
+func (f Function) Fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %q %q %q %q %d %d %t %t %q %q\n",
+		f.Name(), f.RealName(), f.alias, f.Tag, f.handle, f.Documentation,
+		f.maxTableSize, f.fetchSize, f.ReplacementIsJSON, f.deprecated, f.sqlCode, f.sqlErrm)
+	for _, g := range f.argGroups {
+		fmt.Fprintf(h, "group %s\n", g)
+	}
+	if f.idempotent {
+		io.WriteString(h, "idempotent\n")
+	}
+	if f.pageLifetime > 0 {
+		fmt.Fprintf(h, "paginate %s\n", f.pageLifetime)
+	}
+	// the generator settings
+	fmt.Fprintf(h, "%t %t %t %t %t %t %d\n", Gogo, NumberAsString, NumberAsDecimal, NullableWrappers, GenConverters, Envelope, MaxTableSize)
+	fmt.Fprintf(h, "%t %d %q %q %t %t\n", InputChecks, CursorClobLimit, HiddenPrefix, HiddenSuffix, GenAccessors, NamedBinds)
+	if f.Replacement != nil {
+		fmt.Fprintf(h, "replacement %s\n", f.Replacement.Fingerprint())
+	}
+	if f.xmlFetch != nil {
+		fmt.Fprintf(h, "xml-fetch %s\n", f.xmlFetch.Fingerprint())
+	}
+	for _, arg := range f.Args {
+		arg.fingerprint(h, 0)
+	}
+	if f.Returns != nil {
+		io.WriteString(h, "returns\n")
+		f.Returns.fingerprint(h, 0)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (arg Argument) fingerprint(w io.Writer, level int) {
+	fmt.Fprintf(w, "%d %q %q %q %q %q %q %q %q %q %q %d %d %d %d %d %t %t %q %t\n",
+		level, arg.Name, arg.RealName(), arg.Type, arg.TypeName, arg.AbsType,
+		arg.Charset, arg.IndexBy, arg.PlsType.ora, arg.CharUsed, arg.Description,
+		arg.Charlength, arg.Flavor, arg.Direction, arg.Precision, arg.Scale, arg.Defaulted, arg.deprecated,
+		arg.emptyAs, arg.rejectEmpty)
+	if arg.retypedFrom != nil {
+		fmt.Fprintf(w, "retyped from %q\n", arg.retypedFrom.diffType())
+	}
+	if tm, ok := arg.typeMapping(); ok {
+		fmt.Fprintf(w, "mapped %+v\n", tm)
+	}
+	if arg.TableOf != nil {
+		arg.TableOf.fingerprint(w, level+1)
+	}
+	for _, sub := range arg.RecordOf {
+		fmt.Fprintf(w, "%q:", sub.Name)
+		sub.Argument.fingerprint(w, level+1)
+	}
+}
+
+// errSkipSection is returned by the generator of writeSection to skip the function.
+var errSkipSection = errors.New("skip section")
+
+// writeSection writes the code generated by gen for the function, between the section markers,
+// or the previous code from PreviousSections, if the function has not changed.
+//
+// Nothing is written if gen returns an error.
+func writeSection(w io.Writer, fun Function, gen func(io.Writer) error) error {
+	name, fp := fun.Name(), fun.Fingerprint()
+	if prev, ok := PreviousSections[name]; ok && prev.Fingerprint == fp {
+		_, err := io.WriteString(w, prev.Text)
+		return err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s%s %s\n", sectionBegin, name, fp)
+	if err := gen(&buf); err != nil {
+		return err
+	}
+	if b := buf.Bytes(); b[len(b)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	fmt.Fprintf(&buf, "%s%s\n", sectionEnd, name)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
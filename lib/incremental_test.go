@@ -0,0 +1,63 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIncremental(t *testing.T) {
+	old := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"2,1,1,DB_WEB,GET_Y,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	// GET_Y changed
+	act := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"2,1,1,DB_WEB,GET_Y,0,1,P_ID,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	if old[0].Fingerprint() != act[0].Fingerprint() {
+		t.Error("the fingerprint of the unchanged GET_X changed")
+	}
+	if old[1].Fingerprint() == act[1].Fingerprint() {
+		t.Error("the fingerprint of the changed GET_Y is the same")
+	}
+
+	var buf strings.Builder
+	if err := SaveFunctions(&buf, old, "main", "example.com/db_web", false); err != nil {
+		t.Fatal(err)
+	}
+	sections, err := ReadSections(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("got %d sections, wanted 2", len(sections))
+	}
+	// mark the previous code, to see whether it is kept
+	const mark = "// kept from the previous run\n"
+	for k, sec := range sections {
+		sec.Text = strings.Replace(sec.Text, "\n", "\n"+mark, 1)
+		sections[k] = sec
+	}
+
+	defer func() { PreviousSections = nil }()
+	PreviousSections = sections
+	buf.Reset()
+	if err := SaveFunctions(&buf, act, "main", "example.com/db_web", false); err != nil {
+		t.Fatal(err)
+	}
+	next, err := ReadSections(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := next[act[0].Name()].Text; !strings.Contains(s, mark) {
+		t.Errorf("unchanged %s is regenerated:\n%s", act[0].Name(), s)
+	}
+	if s := next[act[1].Name()].Text; strings.Contains(s, mark) {
+		t.Errorf("changed %s is not regenerated:\n%s", act[1].Name(), s)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDialect is the $schema of the document written by SaveJSONSchema.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// SaveJSONSchema writes a JSON Schema document of the request messages of the functions (in protojson form,
+// as the grpc-gateway receives them), for validating the requests at the edge: each request message is
+// a definition in $defs (named as the proto message), as are the records, referred by $ref.
+//
+// The strings get their maxLength (CHAR_LENGTH), the integer NUMBERs their bounds by the precision,
+// the floats their multipleOf by the scale. The decimals (and the 64-bit integers) are strings in protojson:
+// those are constrained by a pattern of the allowed digits. The input arguments without a DEFAULT are required.
+func SaveJSONSchema(dst io.Writer, functions []Function) error {
+	naming := DefaultNaming{}
+	doc := jsonSchema{Schema: JSONSchemaDialect, Defs: make(map[string]*jsonSchema, len(functions))}
+	for _, fun := range functions {
+		msg := &jsonSchema{Type: "object", Title: naming.MessageName(fun, false), Description: fun.Documentation,
+			Properties: make(map[string]*jsonSchema, len(fun.Args))}
+		if fun.deprecated {
+			msg.Deprecated = true
+		}
+		err := func() error {
+			for _, arg := range fun.Args {
+				if !arg.IsInput() {
+					continue
+				}
+				name := fieldJSONName(naming, arg)
+				prop, err := jsonSchemaOf(doc.Defs, naming, arg, false)
+				if err != nil {
+					return fmt.Errorf("%s: %w", arg.Name, err)
+				}
+				msg.Properties[name] = prop
+				// the hidden arguments are filled by the server
+				if !arg.Defaulted && !strings.HasSuffix(arg.Name, "#") {
+					msg.Required = append(msg.Required, name)
+				}
+				if arg.IsNestedTable() {
+					msg.Properties[protoJSONName(nullFieldName(naming.FieldName(arg)))] = &jsonSchema{Type: "boolean"}
+				}
+			}
+			return nil
+		}()
+		if err != nil {
+			if SkipMissingTableOf && (errors.Is(err, ErrMissingTableOf) || errors.Is(err, ErrUnknownSimpleType)) {
+				logger.Info("SKIP function, missing TableOf info", "function", fun.Name())
+				continue
+			}
+			return fmt.Errorf("%s: %w", fun.Name(), err)
+		}
+		doc.Defs[msg.Title] = msg
+	}
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// jsonSchema is the subset of JSON Schema written by SaveJSONSchema.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinLength            int                    `json:"minLength,omitempty"`
+	MaxLength            int                    `json:"maxLength,omitempty"`
+	Minimum              json.Number            `json:"minimum,omitempty"`
+	Maximum              json.Number            `json:"maximum,omitempty"`
+	MultipleOf           json.Number            `json:"multipleOf,omitempty"`
+	Deprecated           bool                   `json:"deprecated,omitempty"`
+	Examples             []json.RawMessage      `json:"examples,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+// jsonSchemaOf returns the schema of the argument - a $ref to the definition of a record, added to defs if new.
+func jsonSchemaOf(defs map[string]*jsonSchema, naming NamingStrategy, arg Argument, parentIsTable bool) (*jsonSchema, error) {
+	var s *jsonSchema
+	switch arg.Flavor {
+	case FLAVOR_SIMPLE:
+		var err error
+		if s, err = jsonSchemaSimple(arg, parentIsTable); err != nil {
+			return nil, err
+		}
+
+	case FLAVOR_TABLE:
+		if arg.TableOf == nil {
+			return nil, ErrMissingTableOf
+		}
+		items, err := jsonSchemaOf(defs, naming, *arg.TableOf, true)
+		if err != nil {
+			return nil, err
+		}
+		if arg.IsStringIndexed() {
+			s = &jsonSchema{Type: "object", AdditionalProperties: items}
+		} else {
+			s = &jsonSchema{Type: "array", Items: items}
+		}
+
+	default: // FLAVOR_RECORD
+		name := CamelCase(strings.Replace(strings.ToUpper(arg.TypeName), "%ROWTYPE", "_rt", 1))
+		if name == "" {
+			name = mkRecTypName(arg.Name)
+		}
+		name = strings.ReplaceAll(name, ".", "__")
+		if _, ok := defs[name]; !ok {
+			rec := &jsonSchema{Type: "object", Title: name, Properties: make(map[string]*jsonSchema, len(arg.RecordOf))}
+			defs[name] = rec // before the fields, for the recursive types
+			for _, sub := range arg.RecordOf {
+				field := *sub.Argument
+				field.Name = sub.Name
+				prop, err := jsonSchemaOf(defs, naming, field, parentIsTable)
+				if err != nil {
+					delete(defs, name)
+					return nil, fmt.Errorf("%s: %w", sub.Name, err)
+				}
+				rec.Properties[fieldJSONName(naming, field)] = prop
+			}
+		}
+		s = &jsonSchema{Ref: "#/$defs/" + name}
+	}
+	if s.Ref == "" {
+		s.Description = arg.Description
+	}
+	if arg.deprecated {
+		s.Deprecated = true
+	}
+	return s, nil
+}
+
+// jsonSchemaSimple returns the schema of the simple argument, by its protojson representation.
+func jsonSchemaSimple(arg Argument, parentIsTable bool) (*jsonSchema, error) {
+	got, err := arg.goType(parentIsTable)
+	if err != nil {
+		return nil, err
+	}
+	typ, _ := arg.mappedProtoType(strings.TrimPrefix(got, "*"))
+	var s jsonSchema
+	prec, scale := int(arg.Precision), int(arg.Scale)
+	isNumber := arg.Type == "NUMBER" && prec > 0
+	switch typ {
+	case "bool":
+		s.Type = "boolean"
+	case "sint32":
+		s.Type = "integer"
+		if isNumber && scale == 0 {
+			s.Minimum, s.Maximum = json.Number("-"+strings.Repeat("9", prec)), json.Number(strings.Repeat("9", prec))
+		}
+	case "sint64":
+		// protojson writes the 64-bit integers as strings, but accepts both
+		s.Type = []string{"integer", "string"}
+		s.Pattern = `^-?[0-9]+$`
+		if isNumber && scale == 0 {
+			s.Minimum, s.Maximum = json.Number("-"+strings.Repeat("9", prec)), json.Number(strings.Repeat("9", prec))
+			s.Pattern = `^-?[0-9]{1,` + strconv.Itoa(prec) + `}$`
+		}
+	case "float", "double":
+		s.Type = "number"
+		if isNumber && scale > 0 {
+			s.MultipleOf = json.Number("0." + strings.Repeat("0", scale-1) + "1")
+			if prec > scale {
+				bound := strings.Repeat("9", prec-scale) + "." + strings.Repeat("9", scale)
+				s.Minimum, s.Maximum = json.Number("-"+bound), json.Number(bound)
+			}
+		}
+	case "bytes":
+		s.Type, s.ContentEncoding = "string", "base64"
+		if arg.Type == "RAW" && arg.Charlength > 0 {
+			s.MaxLength = 4 * ((int(arg.Charlength) + 2) / 3)
+		}
+	case "google.protobuf.Timestamp":
+		s.Type, s.Format = "string", "date-time"
+	case "string":
+		s.Type = "string"
+		switch {
+		case strings.TrimPrefix(got, "*") == "godror.Number":
+			s.Pattern = `^-?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`
+			if isNumber {
+				s.Pattern = `^-?[0-9]{0,` + strconv.Itoa(max(prec-scale, 0)) + `}`
+				if scale > 0 {
+					s.Pattern += `(\.[0-9]{1,` + strconv.Itoa(scale) + `})?`
+				}
+				s.Pattern += `$`
+			}
+		case arg.Charlength > 0 && arg.Type != "CLOB" && arg.Type != "NCLOB" && arg.Type != "XMLTYPE":
+			s.MaxLength = int(arg.Charlength)
+		}
+		if arg.rejectEmpty {
+			s.MinLength = 1
+		}
+	}
+	if arg.example != "" {
+		if ex := arg.exampleJSON(typ); json.Valid([]byte(ex)) {
+			s.Examples = []json.RawMessage{json.RawMessage(ex)}
+		}
+	}
+	return &s, nil
+}
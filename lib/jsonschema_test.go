@@ -0,0 +1,98 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestSaveJSONSchema(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	fh, err := os.Open("testdata/jsonschema.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err = SaveJSONSchema(&buf, functions); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+
+	type schema struct {
+		Ref        string `json:"$ref"`
+		Type       interface{}
+		Format     string
+		Pattern    string
+		MaxLength  int
+		Minimum    json.Number
+		Maximum    json.Number
+		Properties map[string]*schema
+		Required   []string
+		Items      *schema
+	}
+	var doc struct {
+		Schema string             `json:"$schema"`
+		Defs   map[string]*schema `json:"$defs"`
+	}
+	if err = json.Unmarshal([]byte(buf.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Schema != JSONSchemaDialect {
+		t.Errorf("$schema=%q", doc.Schema)
+	}
+	msg := doc.Defs["SaveItem_Input"]
+	if msg == nil {
+		t.Fatalf("no SaveItem_Input in %v", doc.Defs)
+	}
+	// P_PRICE, P_SERIAL, P_VALID_FROM and P_TAGS have DEFAULTs
+	if got := strings.Join(msg.Required, ","); got != "pId,pName" {
+		t.Errorf("required: got %q, wanted pId,pName", got)
+	}
+	if _, ok := msg.Properties["pResult"]; ok {
+		t.Error("the OUT argument is in the request")
+	}
+	props := msg.Properties
+	for _, tC := range []struct {
+		Name, Field, Got, Want string
+	}{
+		{"pId", "type", props["pId"].Type.(string), "integer"},
+		{"pId", "maximum", props["pId"].Maximum.String(), "999999999"},
+		{"pId", "minimum", props["pId"].Minimum.String(), "-999999999"},
+		{"pName", "type", props["pName"].Type.(string), "string"},
+		{"pPrice", "pattern", props["pPrice"].Pattern, `^-?[0-9]{0,8}(\.[0-9]{1,2})?$`},
+		{"pSerial", "pattern", props["pSerial"].Pattern, `^-?[0-9]{1,15}$`},
+		{"pValidFrom", "format", props["pValidFrom"].Format, "date-time"},
+		{"pTags", "type", props["pTags"].Type.(string), "array"},
+	} {
+		if tC.Got != tC.Want {
+			t.Errorf("%s.%s: got %q, wanted %q", tC.Name, tC.Field, tC.Got, tC.Want)
+		}
+	}
+	if got := props["pName"].MaxLength; got != 30 {
+		t.Errorf("pName.maxLength: got %d, wanted 30", got)
+	}
+
+	ref := props["pTags"].Items.Ref
+	rec := doc.Defs[strings.TrimPrefix(ref, "#/$defs/")]
+	if rec == nil {
+		t.Fatalf("no definition of %q", ref)
+	}
+	if got := rec.Properties["code"].MaxLength; got != 5 {
+		t.Errorf("code.maxLength: got %d, wanted 5", got)
+	}
+	if got := rec.Properties["qty"].Maximum.String(); got != "9999" {
+		t.Errorf("qty.maximum: got %q, wanted 9999", got)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import "strings"
+
+// multiAnnotations are the annotation types a target can have more of (tags, groups of arguments),
+// so they do not conflict, only their duplicates are dropped.
+var multiAnnotations = map[string]struct{}{
+	"tag": {}, "handle": {}, "requires": {}, "excludes": {},
+}
+
+// MergeAnnotations merges the annotations of the sources (such as the package sources' comments,
+// an annotations file and the command-line flags) into one list for ApplyAnnotations.
+//
+// The annotations of the same type and target (Owner and FullName, case-insensitively) conflict
+// if they differ (two renames of the same function to different names): the one of the later source
+// (or the later one in the same source) wins, in the place of the first one, and the conflict is warned about.
+// replace and replace_json are of the same type here, as a function has one replacement.
+// The duplicates are dropped, the tag, handle, requires and excludes annotations do not conflict.
+//
+// The result is in the order of the sources (and the annotations in them).
+func MergeAnnotations(sources ...[]Annotation) []Annotation {
+	var n int
+	for _, src := range sources {
+		n += len(src)
+	}
+	merged := make([]Annotation, 0, n)
+	index := make(map[string]int, n)
+	for _, src := range sources {
+		for _, a := range src {
+			k := annotationKey(a)
+			i, ok := index[k]
+			if !ok {
+				index[k] = len(merged)
+				merged = append(merged, a)
+				continue
+			}
+			if prev := merged[i]; prev != a {
+				logger.Warn("conflicting annotations", "previous", prev.String(), "winner", a.String())
+				merged[i] = a
+			}
+		}
+	}
+	return merged
+}
+
+// annotationKey returns the key of the annotation in MergeAnnotations:
+// the same for the conflicting annotations (of the same type and target).
+func annotationKey(a Annotation) string {
+	typ := a.Type
+	if typ == "replace_json" {
+		typ = "replace"
+	}
+	k := typ + " " + strings.ToLower(a.Owner+":"+a.FullName())
+	if _, ok := multiAnnotations[typ]; ok {
+		// only the same one
+		k += "=>" + strings.ToLower(a.Other)
+	}
+	return k
+}
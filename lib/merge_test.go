@@ -0,0 +1,59 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestMergeAnnotations(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	comments := []Annotation{
+		{Package: "DB_WEB", Type: "rename", Name: "get_x", Other: "get_y"},
+		{Package: "DB_WEB", Type: "tag", Name: "get_x", Other: "a"},
+		{Package: "DB_WEB", Type: "private", Name: "set_x"},
+		{Package: "DB_WEB", Type: "replace", Name: "get_z", Other: "get_z_xml"},
+	}
+	flags := []Annotation{
+		{Package: "db_web", Type: "rename", Name: "GET_X", Other: "get_w"},
+		{Package: "DB_WEB", Type: "tag", Name: "get_x", Other: "b"},
+		{Package: "DB_WEB", Type: "private", Name: "set_x"},
+		{Package: "DB_WEB", Type: "replace_json", Name: "get_z", Other: "get_z_json"},
+		// the same name, but another schema's
+		{Owner: "SCOTT", Package: "DB_WEB", Type: "rename", Name: "get_x", Other: "get_v"},
+	}
+	merged := MergeAnnotations(comments, flags)
+	want := []Annotation{
+		// the later source wins, in the place of the first
+		{Package: "db_web", Type: "rename", Name: "GET_X", Other: "get_w"},
+		{Package: "DB_WEB", Type: "tag", Name: "get_x", Other: "a"},
+		{Package: "DB_WEB", Type: "private", Name: "set_x"},
+		{Package: "DB_WEB", Type: "replace_json", Name: "get_z", Other: "get_z_json"},
+		{Package: "DB_WEB", Type: "tag", Name: "get_x", Other: "b"},
+		{Owner: "SCOTT", Package: "DB_WEB", Type: "rename", Name: "get_x", Other: "get_v"},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("got %v, wanted %v", merged, want)
+	}
+	for i, a := range want {
+		if merged[i] != a {
+			t.Errorf("%d. got %v, wanted %v", i, merged[i], a)
+		}
+	}
+	// the order of the sources decides, not their contents
+	if got := MergeAnnotations(flags, comments)[0]; got != comments[0] {
+		t.Errorf("reversed: got %v, wanted %v", got, comments[0])
+	}
+
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	functions = ApplyAnnotations(functions, MergeAnnotations(comments[:1], flags[:1]))
+	if len(functions) != 1 || functions[0].alias != "get_w" {
+		t.Errorf("got %v, wanted the function renamed to get_w", functions)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ModelFunction is the serializable view of a Function, with its unexported fields, for SaveModelJSON.
+type ModelFunction struct {
+	LastDDL time.Time `json:",omitempty"`
+	// Name is the lowercase name of the function, Alias the name set by a rename annotation.
+	Package, Name, Alias string
+	// Group is the service group of the function, if set by a group annotation.
+	Group string `json:",omitempty"`
+	// FullName is Name qualified by the Package, RealName the same for the function called in the database
+	// (the Replacement or the XMLFetch variant, if any).
+	FullName, RealName string
+	Owner              string          `json:",omitempty"`
+	Documentation      string          `json:",omitempty"`
+	Args               []ModelArgument `json:",omitempty"`
+	Returns            *ModelArgument  `json:",omitempty"`
+	Tag                []string        `json:",omitempty"`
+	Handle             []string        `json:",omitempty"`
+	MaxTableSize       int             `json:",omitempty"`
+	Replacement        *ModelFunction  `json:",omitempty"`
+	ReplacementIsJSON  bool            `json:",omitempty"`
+	XMLFetch           *ModelFunction  `json:",omitempty"`
+	HasCursorOut       bool            `json:",omitempty"`
+	Deprecated         bool            `json:",omitempty"` // set by a deprecated annotation
+	Idempotent         bool            `json:",omitempty"` // set by an idempotent annotation
+	FetchSize          int             `json:",omitempty"` // set by a fetch-size annotation
+	// SQLCode and SQLErrm are the OUT arguments capturing SQLCODE and SQLERRM, set by a sqlcode annotation.
+	SQLCode, SQLErrm string `json:",omitempty"`
+	// ArgGroups are the cross-field constraints, set by the requires and excludes annotations.
+	ArgGroups []ModelArgGroup `json:",omitempty"`
+	// PageLifetime is the lifetime of the page tokens of the paginated rpc, set by a paginate annotation.
+	PageLifetime string `json:",omitempty"`
+}
+
+// ModelArgGroup is a cross-field constraint of the input arguments:
+// Kind is "requires" (all or none of Args set) or "excludes" (at most one of them set).
+type ModelArgGroup struct {
+	Kind string
+	Args []string
+}
+
+// ModelArgument is the serializable view of an Argument, with its record fields and table element.
+type ModelArgument struct {
+	// Name is the (maybe renamed) name of the argument, RealName the name in the database.
+	Name, RealName    string
+	Flavor, Direction string
+	Type              string
+	TypeName          string `json:",omitempty"`
+	AbsType           string `json:",omitempty"`
+	PlsType           string `json:",omitempty"`
+	Charset           string `json:",omitempty"`
+	IndexBy           string `json:",omitempty"`
+	CharUsed          string `json:",omitempty"`
+	Description       string `json:",omitempty"`
+	Example           string `json:",omitempty"` // set by an example annotation
+	Charlength        uint   `json:",omitempty"`
+	Precision         uint8  `json:",omitempty"`
+	Scale             uint8  `json:",omitempty"`
+	Defaulted         bool   `json:",omitempty"`
+	Deprecated        bool   `json:",omitempty"` // set by a deprecated annotation
+	JSONName          string `json:",omitempty"` // set by a json-name annotation
+	// EmptyAs is the sentinel bound in place of the empty string, RejectEmpty rejects the empty string,
+	// set by an empty-string annotation.
+	EmptyAs     string `json:",omitempty"`
+	RejectEmpty bool   `json:",omitempty"`
+	// CursorRow is the name of the row of the REF CURSOR, if set by a cursor-row annotation.
+	CursorRow string `json:",omitempty"`
+	// RetypedFrom is the type of the previous version of the argument, if it has changed (see MarkRetypedArgs).
+	RetypedFrom string `json:",omitempty"`
+	// RecordOf are the fields of a RECORD, TableOf is the element of a TABLE.
+	RecordOf []ModelArgument `json:",omitempty"`
+	TableOf  *ModelArgument  `json:",omitempty"`
+}
+
+// SaveModelJSON writes the parsed functions (with the annotations applied) as a JSON array of ModelFunction,
+// for generators outside of oracall.
+func SaveModelJSON(w io.Writer, functions []Function) error {
+	model := make([]ModelFunction, len(functions))
+	for i, f := range functions {
+		model[i] = f.Model()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(model)
+}
+
+// Model returns the serializable view of the function.
+func (f Function) Model() ModelFunction {
+	m := ModelFunction{
+		LastDDL: f.LastDDL,
+		Package: f.Package, Name: f.name, Alias: f.alias, Group: f.group,
+		FullName: f.Name(), RealName: f.RealName(),
+		Owner: f.Owner, Documentation: f.Documentation,
+		Tag: f.Tag, Handle: f.handle,
+		MaxTableSize:      f.maxTableSize,
+		ReplacementIsJSON: f.ReplacementIsJSON,
+		HasCursorOut:      f.HasCursorOut(),
+		Deprecated:        f.deprecated,
+		Idempotent:        f.idempotent,
+		FetchSize:         f.fetchSize,
+		SQLCode:           f.sqlCode, SQLErrm: f.sqlErrm,
+	}
+	for _, g := range f.argGroups {
+		m.ArgGroups = append(m.ArgGroups, ModelArgGroup{Kind: g.Kind, Args: g.Args})
+	}
+	if f.paginated() {
+		m.PageLifetime = f.pageLifetime.String()
+	}
+	if len(f.Args) != 0 {
+		m.Args = make([]ModelArgument, len(f.Args))
+		for i, arg := range f.Args {
+			m.Args[i] = arg.Model()
+		}
+	}
+	if f.Returns != nil {
+		ret := f.Returns.Model()
+		m.Returns = &ret
+	}
+	if f.Replacement != nil {
+		repl := f.Replacement.Model()
+		m.Replacement = &repl
+	}
+	if f.xmlFetch != nil {
+		variant := f.xmlFetch.Model()
+		m.XMLFetch = &variant
+	}
+	return m
+}
+
+// Model returns the serializable view of the argument.
+func (arg Argument) Model() ModelArgument {
+	m := ModelArgument{
+		Name: arg.Name, RealName: arg.RealName(),
+		Flavor: arg.Flavor.String(), Direction: arg.Direction.String(),
+		Type: arg.Type, TypeName: arg.TypeName, AbsType: arg.AbsType, PlsType: arg.PlsType.String(),
+		Charset: arg.Charset, IndexBy: arg.IndexBy, CharUsed: arg.CharUsed, Description: arg.Description,
+		Example:    arg.example,
+		Charlength: arg.Charlength, Precision: arg.Precision, Scale: arg.Scale, Defaulted: arg.Defaulted,
+		Deprecated: arg.deprecated, JSONName: arg.jsonName,
+		EmptyAs: arg.emptyAs, RejectEmpty: arg.rejectEmpty,
+	}
+	if arg.namedRow && arg.TableOf != nil {
+		m.CursorRow = arg.TableOf.TypeName
+	}
+	if arg.retypedFrom != nil {
+		m.RetypedFrom = arg.retypedFrom.diffType()
+	}
+	if len(arg.RecordOf) != 0 {
+		m.RecordOf = make([]ModelArgument, len(arg.RecordOf))
+		for i, sub := range arg.RecordOf {
+			m.RecordOf[i] = sub.Argument.Model()
+			m.RecordOf[i].Name = sub.Name
+		}
+	}
+	if arg.TableOf != nil {
+		elt := arg.TableOf.Model()
+		m.TableOf = &elt
+	}
+	return m
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSaveModelJSON(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_IDS,IN,PL/SQL TABLE,,,,BINARY_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,NUM_TAB_TYP,",
+		"1,1,3,DB_WEB,GET_X,1,1,,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,4,DB_WEB,GET_X,0,3,P_REC,OUT,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,X_REC_TYP,",
+		"1,1,5,DB_WEB,GET_X,1,1,NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,6,DB_WEB,GET_X,1,2,BIRTH,OUT,DATE,,,,,DATE,0,,,,",
+	)
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "DB_WEB", Type: "rename", Name: "get_x.p_id", Other: "customer_id"},
+		{Package: "DB_WEB", Type: "tag", Name: "get_x", Other: "customer"},
+	})
+
+	var buf strings.Builder
+	if err := SaveModelJSON(&buf, functions); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	var got []ModelFunction
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []ModelFunction{functions[0].Model()}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+
+	f := got[0]
+	if f.FullName != "DB_web.get_x" || len(f.Args) != 3 || len(f.Tag) != 1 || f.Tag[0] != "customer" {
+		t.Fatalf("got %+v", f)
+	}
+	if a := f.Args[0]; a.Name != "customer_id" || a.RealName != "p_id" || a.Direction != "IN" || a.Flavor != "SIMPLE" {
+		t.Errorf("renamed arg: got %+v", a)
+	}
+	if a := f.Args[1]; a.Flavor != "TABLE" || a.TableOf == nil || a.TableOf.Type != "NUMBER" {
+		t.Errorf("table arg: got %+v", a)
+	}
+	if a := f.Args[2]; a.Flavor != "RECORD" || a.Direction != "OUT" ||
+		len(a.RecordOf) != 2 || a.RecordOf[0].Name != "name" || a.RecordOf[1].Type != "DATE" {
+		t.Errorf("record arg: got %+v", a)
+	}
+}
+
+func TestSaveModelJSONAnnotations(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	fh, err := os.Open("testdata/model.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var annotations []Annotation
+	for _, s := range []string{
+		"deprecated db_web.set_x",
+		"deprecated db_web.set_x.p_code",
+		"idempotent db_web.list_x",
+		"json-name db_web.set_x.p_id => ID",
+		"sqlcode db_web.set_x => p_err_code, p_err_msg",
+		"requires db_web.set_x => p_from, p_to",
+		"excludes db_web.set_x => p_id, p_code",
+		"empty-string db_web.set_x.p_name => reject",
+		"empty-string db_web.set_x.p_code => ' '",
+		"cursor db_web.list_x.p_cur => id NUMBER(9), name VARCHAR2(100)",
+		"cursor-row db_web.list_x.p_cur => customer_row",
+		"paginate db_web.list_x => 10m",
+		"fetch-size db_web.list_x=100",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatalf("%q: %+v", s, err)
+		}
+		annotations = append(annotations, a)
+	}
+	if functions, err = ApplyAnnotationsStrict(functions, annotations); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name() < functions[j].Name() })
+
+	var buf strings.Builder
+	if err = SaveModelJSON(&buf, functions); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	var got []ModelFunction
+	if err = json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d functions, wanted 2", len(got))
+	}
+
+	list := got[0]
+	if !list.Idempotent || list.FetchSize != 100 || list.PageLifetime != "10m0s" || list.Deprecated {
+		t.Errorf("list_x: got %+v", list)
+	}
+	if a := list.Args[1]; a.CursorRow != "CUSTOMER_ROW" {
+		t.Errorf("p_cur: got cursor row %q", a.CursorRow)
+	}
+
+	set := got[1]
+	if !set.Deprecated || set.Idempotent || set.SQLCode != "p_err_code" || set.SQLErrm != "p_err_msg" || set.PageLifetime != "" {
+		t.Errorf("set_x: got %+v", set)
+	}
+	if d := cmp.Diff([]ModelArgGroup{
+		{Kind: "requires", Args: []string{"p_from", "p_to"}},
+		{Kind: "excludes", Args: []string{"p_id", "p_code"}},
+	}, set.ArgGroups); d != "" {
+		t.Errorf("arg groups: %s", d)
+	}
+	args := make(map[string]ModelArgument, len(set.Args))
+	for _, a := range set.Args {
+		args[a.Name] = a
+	}
+	if a := args["p_id"]; a.JSONName != "ID" || a.Deprecated {
+		t.Errorf("p_id: got %+v", a)
+	}
+	if a := args["p_code"]; !a.Deprecated || a.EmptyAs != " " || a.RejectEmpty {
+		t.Errorf("p_code: got %+v", a)
+	}
+	if a := args["p_name"]; !a.RejectEmpty || a.EmptyAs != "" {
+		t.Errorf("p_name: got %+v", a)
+	}
+}
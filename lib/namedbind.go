@@ -0,0 +1,94 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"database/sql"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/godror/godror"
+)
+
+// NamedBinds makes the generated code bind the parameters of the PL/SQL blocks by their names
+// (:p_id, the names of the arguments), with sql.Named (see NamedParams), instead of by their positions (:1, :2...).
+//
+// The called function gets its arguments by name (p_id=>:p_id) either way, but the names carry over
+// to the driver's binds, too, so the binds (and the logs of them) do not depend on the order of the placeholders.
+//
+// The functions with a replacement (see the replace annotation) bind the CLOBs by position still.
+var NamedBinds bool
+
+// namedBinds reports whether the generated code of the function binds by names (see NamedBinds).
+func (f Function) namedBinds() bool { return NamedBinds && f.Replacement == nil }
+
+// NamedParams binds the first len(names) params by the names (see NamedBinds),
+// keeping the rest (the options, such as godror.PlSQLArrays) as they are.
+func NamedParams(names []string, params ...interface{}) []interface{} {
+	for i, name := range names {
+		params[i] = sql.Named(name, params[i])
+	}
+	return params
+}
+
+// demapNamed is demap for NamedBinds: it keeps the named placeholders of the plsql block,
+// binding each name once, as the repeated placeholders of the same name are bound together by Oracle.
+func demapNamed(plsql, callFun string) (string, string) {
+	idx := make(map[string]int, 16)
+	var names []string
+	godror.MapToSlice(plsql, func(key string) interface{} {
+		if _, ok := idx[key]; !ok {
+			idx[key] = len(names)
+			names = append(names, key)
+		}
+		return key
+	})
+	quoted := make([]string, len(names))
+	for i, nm := range names {
+		quoted[i] = strconv.Quote(nm)
+	}
+
+	type repl struct {
+		ParamsArrLen int
+		ParamNames   string
+	}
+	opts := repl{
+		ParamsArrLen: len(names),
+		ParamNames:   "[]string{" + strings.Join(quoted, ", ") + "}",
+	}
+	tpl, err := template.New("callFun").
+		Funcs(
+			map[string]interface{}{
+				"paramsIdx": func(key string) int {
+					if _, ok := idx[key]; !ok {
+						if k, ok := unreplHidden(key); ok {
+							key = k
+						}
+					}
+					i, ok := idx[key]
+					if !ok {
+						logger.Info("paramsIdx", "key", key, "names", names)
+					}
+					return i
+				},
+			}).
+		Parse(callFun)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", callFun, err))
+	}
+	callBuf := Buffers.Get()
+	defer Buffers.Put(callBuf)
+	if err = tpl.Execute(callBuf, opts); err != nil {
+		panic(err)
+	}
+	b, err := format.Source(callBuf.Bytes())
+	if err != nil {
+		panic(err)
+	}
+	return plsql, string(b)
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestNamedBinds(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, err := ParseCsvFile("testdata/named_binds.csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fun := functions[0]
+
+	defer func(old bool) { NamedBinds = old }(NamedBinds)
+	NamedBinds = false
+	plsql, callFun := fun.PlsqlBlock("")
+	if !strings.Contains(plsql, "p_id=>:3") || strings.Contains(callFun, "NamedParams") {
+		t.Errorf("not positional by default:\n%s\n%s", plsql, callFun)
+	}
+
+	NamedBinds = true
+	plsql, callFun = fun.PlsqlBlock("")
+	t.Log(plsql)
+	t.Log(callFun)
+	if rePositional := regexp.MustCompile(`:[0-9]`); rePositional.MatchString(plsql) {
+		t.Errorf("positional placeholder in\n%s", plsql)
+	}
+	wantContains(t, plsql,
+		"p_id=>:p_id,", "p_name=>:p_name,", "p_count=>:p_count", "p_items=>v001",
+		":p002#id := p002#id;",
+	)
+	// each name is bound once, at its place in the names
+	wantContains(t, callFun,
+		`oracall.NamedParams([]string{"p002#id", "p002#name", "p_id", "p_name", "p_count"}, append(params, godror.PlSQLArrays`,
+		"params := make([]interface{}, 5, 5+2)",
+		"params[0] = sql.Out{Dest: &x__PItems__Id, In: true}",
+		"params[1] = sql.Out{Dest: &x__PItems__Name, In: true}",
+		"params[2] = int32(",
+		"params[3] = sql.Out{Dest: &output.PName, In: true}",
+		"params[4] = sql.Out{Dest: &output.PCount}",
+	)
+	if strings.Contains(callFun, "params[5]") {
+		t.Errorf("a name is bound twice:\n%s", callFun)
+	}
+
+	params := NamedParams([]string{"p_id", "p_name"}, 1, "a", "opt")
+	if len(params) != 3 || params[0] != sql.Named("p_id", 1) || params[1] != sql.Named("p_name", "a") || params[2] != "opt" {
+		t.Errorf("got %#v", params)
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "namedbinds-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for SET_ITEMS
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type SetItems_Input struct {
+	PId    int32
+	PItems []*DbWeb_ItemRecTyp_Scott
+	PName  string
+}
+type SetItems_Output struct {
+	PItems []*DbWeb_ItemRecTyp_Scott
+	PName  string
+	PCount int32
+}
+type DbWeb_ItemRecTyp_Scott struct {
+	Id   int32
+	Name string
+}
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = SaveFunctions(&buf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\nfunc main() {}\n")
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go build: %+v\n%s", err, out.String())
+	}
+}
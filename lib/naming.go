@@ -0,0 +1,133 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamingStrategy names the service, the rpcs, the messages and the fields of the .proto
+// written by SaveProtobuf.
+//
+// The Go code written by SaveFunctions refers to the DefaultNaming names,
+// so a custom strategy is for the consumers of the .proto only.
+type NamingStrategy interface {
+	// ServiceName returns the name of the service of the package pkg.
+	ServiceName(pkg string) string
+	// RPCName returns the name of the rpc calling the function.
+	RPCName(f Function) string
+	// MessageName returns the name of the request (or, if out, the response) message of the function.
+	MessageName(f Function, out bool) string
+	// FieldName returns the name of the field of the argument.
+	FieldName(arg Argument) string
+}
+
+// DefaultNaming is the NamingStrategy used when ProtoOptions has none:
+// CamelCase service and rpc names, and <Function>_Input, <Function>_Output messages
+// with the fields named as the arguments.
+type DefaultNaming struct{}
+
+var _ NamingStrategy = DefaultNaming{}
+
+func (DefaultNaming) ServiceName(pkg string) string { return CamelCase(pkg) }
+func (DefaultNaming) RPCName(f Function) string {
+	return CamelCase(f.protoName())
+}
+func (DefaultNaming) MessageName(f Function, out bool) string {
+	dirname := "input"
+	if out {
+		dirname = "output"
+	}
+	return CamelCase(f.protoName() + "__" + dirname)
+}
+func (DefaultNaming) FieldName(arg Argument) string { return arg.Name }
+
+// CamelCaseFields is a NamingStrategy naming the fields in lowerCamelCase (p_customer_id => pCustomerId),
+// and everything else (and the fields before the conversion) as its NamingStrategy - DefaultNaming if nil.
+//
+// The json_name of the fields is kept as the original name's, so the JSON representation does not change.
+// The generated Go names (PCustomerId) are the same, as protoc-gen-go capitalizes the field names.
+type CamelCaseFields struct{ NamingStrategy }
+
+var _ JSONNamer = CamelCaseFields{}
+
+func (n CamelCaseFields) naming() NamingStrategy {
+	if n.NamingStrategy == nil {
+		return DefaultNaming{}
+	}
+	return n.NamingStrategy
+}
+func (n CamelCaseFields) ServiceName(pkg string) string { return n.naming().ServiceName(pkg) }
+func (n CamelCaseFields) RPCName(f Function) string     { return n.naming().RPCName(f) }
+func (n CamelCaseFields) MessageName(f Function, out bool) string {
+	return n.naming().MessageName(f, out)
+}
+func (n CamelCaseFields) FieldName(arg Argument) string {
+	return lowerCamelCase(n.naming().FieldName(arg))
+}
+func (n CamelCaseFields) JSONName(arg Argument) string {
+	if nm := explicitJSONName(n.naming(), arg); nm != "" {
+		return nm
+	}
+	return protoJSONName(n.naming().FieldName(arg))
+}
+
+// lowerCamelCase converts p_customer_id to pCustomerId.
+func lowerCamelCase(text string) string {
+	if text = CamelCase(text); text == "" {
+		return text
+	}
+	r, size := utf8.DecodeRuneInString(text)
+	return string(unicode.ToLower(r)) + text[size:]
+}
+
+// JSONNamer is an optional interface of a NamingStrategy, for matching a legacy JSON contract:
+// the non-empty name it returns is set as the json_name option of the field,
+// instead of the lowerCamelCase default of protojson.
+// A json-name annotation of the argument takes precedence.
+type JSONNamer interface {
+	// JSONName returns the JSON name of the field of the argument, or "" for the default.
+	JSONName(arg Argument) string
+}
+
+// explicitJSONName returns the JSON name of the field of the argument
+// set by a json-name annotation or the JSONNamer naming, or "" if none is set.
+func explicitJSONName(naming NamingStrategy, arg Argument) string {
+	if arg.jsonName != "" {
+		return arg.jsonName
+	}
+	if jn, ok := naming.(JSONNamer); ok {
+		return jn.JSONName(arg)
+	}
+	return ""
+}
+
+// fieldJSONName returns the name of the field of the argument in the JSON representation.
+func fieldJSONName(naming NamingStrategy, arg Argument) string {
+	if nm := explicitJSONName(naming, arg); nm != "" {
+		return nm
+	}
+	return protoJSONName(naming.FieldName(arg))
+}
+
+// aliasOrName returns the alias of the function set by a rename annotation, or its name.
+func (f Function) aliasOrName() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// protoName returns the lowercased aliasOrName with its dots replaced by "__", the base of the rpc and message names.
+//
+// SaveProtobuf caches it in normName, as it is needed for each name of the function.
+func (f Function) protoName() string {
+	if f.normName != "" {
+		return f.normName
+	}
+	return dot2D.Replace(strings.ToLower(f.aliasOrName()))
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// styleGuideNaming names the service <Pkg>Service, the messages <Rpc>Request and <Rpc>Response,
+// and the fields without the p_ prefix.
+type styleGuideNaming struct{ DefaultNaming }
+
+func (styleGuideNaming) ServiceName(pkg string) string { return CamelCase(pkg) + "Service" }
+func (n styleGuideNaming) MessageName(f Function, out bool) string {
+	if out {
+		return n.RPCName(f) + "Response"
+	}
+	return n.RPCName(f) + "Request"
+}
+func (styleGuideNaming) FieldName(arg Argument) string { return strings.TrimPrefix(arg.Name, "p_") }
+
+func TestSaveProtobufNaming(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+
+	for _, tC := range []struct {
+		Naming   NamingStrategy
+		Messages map[string][]string
+		Want     []string
+	}{
+		{
+			Messages: map[string][]string{"GetX_Input": {"sint32 p_id = 1"}, "GetX_Output": {"string p_x1 = 1"}},
+			Want:     []string{"service DbWeb {", "rpc GetX (GetX_Input) returns (GetX_Output) {}"},
+		},
+		{
+			Naming:   styleGuideNaming{},
+			Messages: map[string][]string{"GetXRequest": {"sint32 id = 1"}, "GetXResponse": {"string x1 = 1"}},
+			Want:     []string{"service DbWebService {", "rpc GetX (GetXRequest) returns (GetXResponse) {}"},
+		},
+	} {
+		var buf strings.Builder
+		if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{Naming: tC.Naming}); err != nil {
+			t.Fatal(err)
+		}
+		s := buf.String()
+		for msg, want := range tC.Messages {
+			if d := cmp.Diff(want, protoFields(t, s, msg)); d != "" {
+				t.Errorf("%T: %s: %s", tC.Naming, msg, d)
+			}
+		}
+		for _, want := range tC.Want {
+			if !strings.Contains(s, want) {
+				t.Errorf("%T: %q not found in\n%s", tC.Naming, want, s)
+			}
+		}
+	}
+}
+
+// legacyJSONNaming names the JSON fields as the REST clients expect.
+type legacyJSONNaming struct {
+	DefaultNaming
+	names map[string]string
+}
+
+func (n legacyJSONNaming) JSONName(arg Argument) string { return n.names[arg.Name] }
+
+func TestSaveProtobufJSONName(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	a, err := ParseAnnotation("json-name DB_web.get_x.p_x1 => X1_VALUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if functions, err = ApplyAnnotationsStrict(functions, []Annotation{a}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	naming := legacyJSONNaming{names: map[string]string{"p_id": "ID", "p_x1": "x1"}}
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{Naming: naming}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+	wantContains(t, s,
+		`sint32 p_id = 1 [json_name="ID"];`,
+		// the annotation takes precedence
+		`string p_x1 = 1 [json_name="X1_VALUE"];`,
+	)
+
+	buf.Reset()
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if s = buf.String(); strings.Contains(s, `sint32 p_id = 1 [json_name=`) {
+		t.Errorf("json_name without mapping in\n%s", s)
+	}
+}
+
+func TestCamelCaseFields(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_CUSTOMER_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_ORDER_LINE_NO,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,ADDRESS_1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+
+	for nm, want := range map[string]string{
+		"p_customer_id":   "pCustomerId",
+		"p_order_line_no": "pOrderLineNo",
+		"id":              "id",
+		"address_1":       "address_1", // protoc-gen-go names both Address_1
+	} {
+		if got := (CamelCaseFields{}).FieldName(Argument{Name: nm}); got != want {
+			t.Errorf("%q: got %q, wanted %q", nm, got, want)
+		}
+	}
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{Naming: CamelCaseFields{}}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+	wantContains(t, s,
+		`sint32 pCustomerId = 1 [json_name="pCustomerId"];`,
+		`sint32 pOrderLineNo = 2 [json_name="pOrderLineNo"];`,
+		`string address_1 = 1 [json_name="address1"];`,
+	)
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NLS are the NLS session parameters (such as NLS_DATE_FORMAT or NLS_NUMERIC_CHARACTERS)
+// and their values, set on the session for a call.
+type NLS map[string]string
+
+type ctxNLS struct{}
+
+// ContextWithNLS returns a context carrying the NLS settings.
+//
+// The generated functions called with such a context set them on the session
+// (with ALTER SESSION) before calling the database, and restore the previous values after it.
+func ContextWithNLS(ctx context.Context, nls NLS) context.Context {
+	return context.WithValue(ctx, ctxNLS{}, nls)
+}
+
+// NLSFromContext returns the NLS settings set by ContextWithNLS, or nil.
+func NLSFromContext(ctx context.Context) NLS {
+	nls, _ := ctx.Value(ctxNLS{}).(NLS)
+	return nls
+}
+
+var rNLSName = regexp.MustCompile(`^NLS_[A-Z_]+$`)
+
+// ParseNLS parses a "name=value" NLS setting, such as "NLS_DATE_FORMAT=YYYY-MM-DD".
+// The name is uppercased, and must be of an NLS session parameter.
+func ParseNLS(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("%q: want name=value: %w", s, ErrInvalidArgument)
+	}
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !rNLSName.MatchString(name) {
+		return "", "", fmt.Errorf("%q is not an NLS parameter: %w", name, ErrInvalidArgument)
+	}
+	return name, value, nil
+}
+
+// sessionExecer is the part of *sql.Conn and *sql.Tx used by AlterSessionNLS.
+type sessionExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// AlterSessionNLS sets the NLS settings on the session of db, returning the function restoring the previous values.
+//
+// An invalid setting is returned as an error wrapping ErrInvalidArgument.
+func AlterSessionNLS(ctx context.Context, db sessionExecer, nls NLS) (restore func() error, err error) {
+	if len(nls) == 0 {
+		return func() error { return nil }, nil
+	}
+	for name := range nls {
+		if !rNLSName.MatchString(name) {
+			return nil, fmt.Errorf("%q is not an NLS parameter: %w", name, ErrInvalidArgument)
+		}
+	}
+	const qry = "SELECT parameter, value FROM nls_session_parameters"
+	rows, err := db.QueryContext(ctx, qry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", qry, err)
+	}
+	defer rows.Close()
+	prev := make(NLS, len(nls))
+	for rows.Next() {
+		var name, value sql.NullString
+		if err = rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("%s: %w", qry, err)
+		}
+		if _, ok := nls[name.String]; ok {
+			prev[name.String] = value.String
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return nil, fmt.Errorf("%s: %w", qry, err)
+	}
+	if len(prev) != len(nls) {
+		for name := range nls {
+			if _, ok := prev[name]; !ok {
+				return nil, fmt.Errorf("%q is not an NLS session parameter: %w", name, ErrInvalidArgument)
+			}
+		}
+	}
+
+	if alter := nls.alterSession(); alter != "" {
+		if _, err = db.ExecContext(ctx, alter); err != nil {
+			return nil, fmt.Errorf("%s: %v: %w", alter, err, ErrInvalidArgument)
+		}
+	}
+	return func() error {
+		alter := prev.alterSession()
+		if alter == "" {
+			return nil
+		}
+		// restore even if the call is canceled
+		if _, err := db.ExecContext(context.WithoutCancel(ctx), alter); err != nil {
+			return fmt.Errorf("%s: %w", alter, err)
+		}
+		return nil
+	}, nil
+}
+
+// alterSession returns the ALTER SESSION statement setting the parameters, sorted by their names.
+func (nls NLS) alterSession() string {
+	if len(nls) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(nls))
+	for name := range nls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf strings.Builder
+	buf.WriteString("ALTER SESSION SET")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %s='%s'", name, strings.ReplaceAll(nls[name], "'", "''"))
+	}
+	return buf.String()
+}
+
+// BeginTxNLS begins a transaction on a session of db pinned for the call, with the NLS settings set on it.
+//
+// The returned end function restores the settings (discarding the session if that fails),
+// and releases the session - call it after the transaction is committed or rolled back.
+// Without NLS settings it is db.BeginTx.
+func BeginTxNLS(ctx context.Context, db *sql.DB, nls NLS) (tx *sql.Tx, end func() error, err error) {
+	if len(nls) == 0 {
+		tx, err = db.BeginTx(ctx, nil)
+		return tx, func() error { return nil }, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	restore, err := AlterSessionNLS(ctx, conn, nls)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	end = func() error {
+		err := restore()
+		if err != nil {
+			// do not return the session with the altered settings into the pool
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		return errors.Join(err, conn.Close())
+	}
+	if tx, err = conn.BeginTx(ctx, nil); err != nil {
+		end()
+		return nil, nil, err
+	}
+	return tx, end, nil
+}
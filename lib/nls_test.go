@@ -0,0 +1,134 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// nlsDriver is a database/sql driver recording the statements, with the NLS parameters of its sessions.
+type nlsDriver struct {
+	mu    sync.Mutex
+	stmts []string
+}
+
+func (d *nlsDriver) Open(string) (driver.Conn, error) { return nlsConn{d}, nil }
+func (d *nlsDriver) record(s string) {
+	d.mu.Lock()
+	d.stmts = append(d.stmts, s)
+	d.mu.Unlock()
+}
+
+type nlsConn struct{ d *nlsDriver }
+
+func (c nlsConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (c nlsConn) Close() error                        { return nil }
+func (c nlsConn) Begin() (driver.Tx, error)           { c.d.record("BEGIN"); return nlsTx(c), nil }
+func (c nlsConn) ExecContext(_ context.Context, qry string, _ []driver.NamedValue) (driver.Result, error) {
+	c.d.record(qry)
+	return driver.RowsAffected(0), nil
+}
+func (c nlsConn) QueryContext(_ context.Context, qry string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(qry)
+	return &nlsRows{rows: [][2]string{
+		{"NLS_DATE_FORMAT", "DD-MON-RR"},
+		{"NLS_NUMERIC_CHARACTERS", ".,"},
+		{"NLS_LANGUAGE", "AMERICAN"},
+	}}, nil
+}
+
+type nlsTx struct{ d *nlsDriver }
+
+func (t nlsTx) Commit() error   { t.d.record("COMMIT"); return nil }
+func (t nlsTx) Rollback() error { t.d.record("ROLLBACK"); return nil }
+
+type nlsRows struct{ rows [][2]string }
+
+func (r *nlsRows) Columns() []string { return []string{"PARAMETER", "VALUE"} }
+func (r *nlsRows) Close() error      { return nil }
+func (r *nlsRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestBeginTxNLS(t *testing.T) {
+	d := new(nlsDriver)
+	sql.Register("oracall-nls-test", d)
+	db, err := sql.Open("oracall-nls-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if nls := NLSFromContext(ctx); nls != nil {
+		t.Errorf("got %v without ContextWithNLS", nls)
+	}
+	// no overrides by default
+	tx, end, err := BeginTxNLS(ctx, db, NLSFromContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = end(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"BEGIN", "COMMIT"}; !reflect.DeepEqual(d.stmts, want) {
+		t.Errorf("without NLS: got %q, wanted %q", d.stmts, want)
+	}
+
+	d.stmts = nil
+	name, value, err := ParseNLS("nls_numeric_characters=,.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = ContextWithNLS(ctx, NLS{name: value, "NLS_DATE_FORMAT": "YYYY-MM-DD'T'HH24:MI"})
+	if tx, end, err = BeginTxNLS(ctx, db, NLSFromContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = end(); err != nil {
+		t.Fatal(err)
+	}
+	// set before, restored after the transaction, on the same session
+	if want := []string{
+		"SELECT parameter, value FROM nls_session_parameters",
+		"ALTER SESSION SET NLS_DATE_FORMAT='YYYY-MM-DD''T''HH24:MI' NLS_NUMERIC_CHARACTERS=',.'",
+		"BEGIN",
+		"COMMIT",
+		"ALTER SESSION SET NLS_DATE_FORMAT='DD-MON-RR' NLS_NUMERIC_CHARACTERS='.,'",
+	}; !reflect.DeepEqual(d.stmts, want) {
+		t.Errorf("with NLS: got %q, wanted %q", d.stmts, want)
+	}
+
+	for _, bad := range []string{"NLS_DATE_FORMAT", "TIME_ZONE=UTC", "NLS_X; DROP TABLE x=1"} {
+		if _, _, err = ParseNLS(bad); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("ParseNLS(%q): got %v, wanted ErrInvalidArgument", bad, err)
+		}
+	}
+	// not a session parameter
+	d.stmts = nil
+	if _, _, err = BeginTxNLS(ctx, db, NLS{"NLS_NOPE": "x"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, wanted ErrInvalidArgument", err)
+	}
+	if len(d.stmts) != 1 {
+		t.Errorf("got %q, wanted only the query of the parameters", d.stmts)
+	}
+}
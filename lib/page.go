@@ -0,0 +1,76 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPageFull is returned by AppendPage when the page is full, and there are more rows:
+// the generated paginated method stops the cursor function with it.
+var ErrPageFull = errors.New("page is full")
+
+// Page is a page of the rows of a returned cursor, for the paginated rpcs (see the paginate annotation).
+type Page struct {
+	// Offset is the number of rows of the previous pages, skipped, and Size the maximal number of rows of the page.
+	Offset, Size int
+	// More is set by AppendPage if there are more rows after the page.
+	More bool
+
+	skipped int
+}
+
+// AppendPage appends the rows belonging to the page p to page, skipping the ones of the previous pages.
+//
+// It returns ErrPageFull when a row is seen after the page is full.
+func AppendPage[T any](p *Page, page, rows []T) ([]T, error) {
+	for _, row := range rows {
+		if p.skipped < p.Offset {
+			p.skipped++
+			continue
+		}
+		if len(page) >= p.Size {
+			p.More = true
+			return page, ErrPageFull
+		}
+		page = append(page, row)
+	}
+	return page, nil
+}
+
+// NewPageToken returns the opaque token of the page starting at the offset, valid for the lifetime.
+func NewPageToken(offset int, lifetime time.Duration) string {
+	b := binary.AppendUvarint(make([]byte, 0, 2*binary.MaxVarintLen64), uint64(offset))
+	b = binary.AppendVarint(b, time.Now().Add(lifetime).Unix())
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParsePageToken returns the offset of the page of the token returned by NewPageToken (0 for the empty token),
+// or an error wrapping ErrInvalidArgument if the token is malformed or expired.
+func ParsePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("page token %q: %v: %w", token, err, ErrInvalidArgument)
+	}
+	offset, n := binary.Uvarint(b)
+	if n <= 0 || offset > 1<<31 {
+		return 0, fmt.Errorf("page token %q: bad offset: %w", token, ErrInvalidArgument)
+	}
+	expires, m := binary.Varint(b[n:])
+	if m <= 0 || n+m != len(b) {
+		return 0, fmt.Errorf("page token %q: bad expiry: %w", token, ErrInvalidArgument)
+	}
+	if time.Now().Unix() > expires {
+		return 0, fmt.Errorf("page token expired at %s: %w", time.Unix(expires, 0).UTC().Format(time.RFC3339), ErrInvalidArgument)
+	}
+	return int(offset), nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPageToken(t *testing.T) {
+	if offset, err := ParsePageToken(""); err != nil || offset != 0 {
+		t.Errorf("empty: got %d, %v", offset, err)
+	}
+	token := NewPageToken(2048, time.Minute)
+	if offset, err := ParsePageToken(token); err != nil || offset != 2048 {
+		t.Errorf("%q: got %d, %v", token, offset, err)
+	}
+	for _, bad := range []string{"!", "AA", token + "AA", NewPageToken(1, -time.Minute)} {
+		if _, err := ParsePageToken(bad); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%q: got %v, wanted ErrInvalidArgument", bad, err)
+		}
+	}
+}
+
+func TestAppendPage(t *testing.T) {
+	for _, tc := range []struct {
+		Batches [][]int
+		Page    Page
+		Want    []int
+		More    bool
+	}{
+		{Batches: [][]int{{0, 1}, {2, 3}, {4}}, Page: Page{Size: 5}, Want: []int{0, 1, 2, 3, 4}},
+		{Batches: [][]int{{0, 1}, {2, 3}, {4}}, Page: Page{Size: 4}, Want: []int{0, 1, 2, 3}, More: true},
+		{Batches: [][]int{{0, 1}, {2, 3}, {4}}, Page: Page{Offset: 3, Size: 4}, Want: []int{3, 4}},
+		{Batches: [][]int{{0, 1}, {2, 3}, {4}}, Page: Page{Offset: 1, Size: 2}, Want: []int{1, 2}, More: true},
+		{Batches: [][]int{{0, 1}}, Page: Page{Offset: 2, Size: 2}},
+	} {
+		var page []int
+		var err error
+		for _, rows := range tc.Batches {
+			if page, err = AppendPage(&tc.Page, page, rows); err != nil {
+				break
+			}
+		}
+		if tc.More != errors.Is(err, ErrPageFull) || tc.More != tc.Page.More {
+			t.Errorf("%v %+v: got %v (more=%t), wanted more=%t", tc.Batches, tc.Page, err, tc.Page.More, tc.More)
+		}
+		if !reflect.DeepEqual(page, tc.Want) {
+			t.Errorf("%v %+v: got %v, wanted %v", tc.Batches, tc.Page, page, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultPageLifetime is the lifetime of the page tokens of the paginated rpcs,
+// if the paginate annotation does not set it.
+const DefaultPageLifetime = 15 * time.Minute
+
+// setPaginate makes the function (returning one cursor) have a paginated unary rpc besides the streaming one,
+// with the lifetime (a time.Duration, such as "10m", DefaultPageLifetime if empty) of its page tokens.
+func (f *Function) setPaginate(lifetime string) error {
+	d := DefaultPageLifetime
+	if lifetime != "" {
+		var err error
+		if d, err = time.ParseDuration(lifetime); err != nil {
+			return fmt.Errorf("page token lifetime %q: %v: %w", lifetime, err, ErrInvalidArgument)
+		}
+		if d <= 0 {
+			return fmt.Errorf("page token lifetime %q is not positive: %w", lifetime, ErrInvalidArgument)
+		}
+	}
+	var n int
+	args := f.Args
+	if f.Returns != nil {
+		args = append(args[:len(args):len(args)], *f.Returns)
+	}
+	for _, arg := range args {
+		if arg.IsOutput() && arg.Type == "REF CURSOR" {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("%s returns %d cursors, not one: %w", f.Name(), n, ErrInvalidArgument)
+	}
+	f.pageLifetime = d
+	return nil
+}
+
+// paginated reports whether the function has a paginated rpc (see setPaginate).
+func (f Function) paginated() bool { return f.pageLifetime > 0 }
+
+// pageCursor returns the (only) cursor returned by the paginated function.
+func (f Function) pageCursor() Argument {
+	if f.Returns != nil && f.Returns.IsOutput() && f.Returns.Type == "REF CURSOR" {
+		return *f.Returns
+	}
+	for _, arg := range f.Args {
+		if arg.IsOutput() && arg.Type == "REF CURSOR" {
+			return arg
+		}
+	}
+	panic(fmt.Errorf("%s returns no cursor", f.Name()))
+}
+
+// pageRPC returns the paginated rpc of the function, besides the streaming rpc.
+func (f Function) pageRPC(rpc protoRPC, naming NamingStrategy) protoRPC {
+	rpc.Name += "Page"
+	rpc.In, rpc.Out = naming.MessageName(f, false)+"Page", naming.MessageName(f, true)+"Page"
+	rpc.Stream = false
+	return rpc
+}
+
+// writePageMessages writes the request and the response messages of the paginated rpc.
+func (f Function) writePageMessages(w io.Writer, naming NamingStrategy) error {
+	rpc := f.pageRPC(protoRPC{Name: naming.RPCName(f)}, naming)
+	_, err := fmt.Fprintf(w, `// %s requests a page of the rows of %s.
+message %s {
+	%s input = 1;
+	// page_size is the maximal number of rows of the page (at most %d).
+	int32 page_size = 2;
+	// page_token is the next_page_token of the previous page, empty for the first page.
+	string page_token = 3;
+}
+// %s is a page of the rows of %s.
+message %s {
+	%s output = 1;
+	// next_page_token requests the next page (for %s), empty after the last one.
+	string next_page_token = 2;
+}
+`,
+		rpc.In, naming.RPCName(f), rpc.In, naming.MessageName(f, false), f.getFetchSize(),
+		rpc.Out, naming.RPCName(f), rpc.Out, naming.MessageName(f, true), f.pageLifetime)
+	return err
+}
+
+// goPageName returns the name of the generated Go method of the paginated rpc.
+func (f Function) goPageName() string { return f.goName() + "Page" }
+
+// goPageSignature returns the signature of the generated Go method of the paginated rpc (without the receiver).
+func (f Function) goPageSignature() string {
+	return fmt.Sprintf("%s(ctx context.Context, input *pb.%sPage) (*pb.%sPage, error)",
+		f.goPageName(), CamelCase(f.getStructName(false, false)), CamelCase(f.getStructName(true, false)))
+}
+
+// pageMethod returns the method of the paginated rpc, calling the streaming method (with a stream collecting the page)
+// again for each page, skipping the rows of the previous pages - so it works behind a load balancer, too,
+// as the server keeps no state between the pages, but the function should not change the database.
+func (f Function) pageMethod() string {
+	cur := f.pageCursor()
+	nm := f.goName()
+	streamName := strings.ToLower(nm[:1]) + nm[1:] + "PageStream"
+	fetchSize := f.getFetchSize()
+	var deprecated string
+	if d := f.goDeprecated(); d != "" {
+		deprecated = "//\n" + d
+	}
+	return fmt.Sprintf(`
+// %[1]s returns a page of the rows of %[2]s, calling it again for each page
+// (skipping the rows of the previous pages).
+%[3]sfunc (s *oracallServer) %[4]s {
+	offset, err := oracall.ParsePageToken(input.PageToken)
+	if err != nil {
+		return nil, err
+	}
+	size := int(input.PageSize)
+	if size <= 0 || size > %[5]d {
+		size = %[5]d
+	}
+	in := input.Input
+	if in == nil {
+		in = new(%[6]s)
+	}
+	stream := &%[7]s{ctx: ctx, page: oracall.Page{Offset: offset, Size: size}, rows: new(%[8]s)}
+	if err = s.%[2]s(in, stream); err != nil && !errors.Is(err, oracall.ErrPageFull) {
+		return nil, err
+	}
+	output := stream.output
+	if output == nil {
+		output = new(%[8]s)
+	}
+	output.%[9]s = stream.rows.%[9]s
+	page := &%[10]s{Output: output}
+	if stream.page.More {
+		page.NextPageToken = oracall.NewPageToken(offset+size, %[11]d) // %[12]s
+	}
+	return page, nil
+}
+
+// %[7]s collects the page of the rows sent by %[2]s.
+type %[7]s struct {
+	pb.%[13]s_%[2]sServer
+	ctx  context.Context
+	page oracall.Page
+	// output is the last one sent, rows collects the rows of the page
+	output, rows *%[8]s
+}
+
+func (ps *%[7]s) Context() context.Context { return ps.ctx }
+func (ps *%[7]s) Send(output *%[8]s) (err error) {
+	ps.output = output
+	ps.rows.%[9]s, err = oracall.AppendPage(&ps.page, ps.rows.%[9]s, output.%[9]s)
+	return err
+}
+`,
+		f.goPageName(), nm,
+		deprecated, f.goPageSignature(),
+		fetchSize,
+		f.pbTypeName(false),
+		streamName,
+		f.pbTypeName(true),
+		CamelCase(cur.Name),
+		"pb."+CamelCase(f.getStructName(true, false))+"Page",
+		int64(f.pageLifetime), f.pageLifetime,
+		f.goService(),
+	)
+}
@@ -0,0 +1,189 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestPaginate(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	fh, err := os.Open("testdata/paginate.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var annotations []Annotation
+	for _, s := range []string{
+		"cursor db_web.list_x.p_cur => id NUMBER(9), name VARCHAR2(100)",
+		"paginate db_web.list_x => 10m",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	if functions, err = ApplyAnnotationsStrict(functions, annotations); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{
+		"paginate db_web.list_y",
+		"paginate db_web.get_z",
+		"paginate db_web.list_x => soon",
+		"paginate db_web.list_x => -1m",
+	} {
+		a, err := ParseAnnotation(bad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ApplyAnnotationsStrict(functions, []Annotation{a}); !errors.Is(err, ErrUnmatchedAnnotation) {
+			t.Errorf("%q: got %v, wanted ErrUnmatchedAnnotation", bad, err)
+		}
+	}
+	for _, f := range functions {
+		if f.name == "LIST_X" {
+			functions = []Function{f}
+			break
+		}
+	}
+	if len(functions) != 1 || functions[0].pageLifetime != 10*time.Minute {
+		t.Fatalf("got %+v", functions)
+	}
+
+	var buf bytes.Buffer
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web/pb", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	wantContains(t, proto,
+		"rpc ListX (ListX_Input) returns (stream ListX_Output) {}",
+		"rpc ListXPage (ListX_InputPage) returns (ListX_OutputPage) {}",
+		"message ListX_InputPage {\n\tListX_Input input = 1;\n",
+		"\tint32 page_size = 2;\n",
+		"\tstring page_token = 3;\n",
+		"message ListX_OutputPage {\n\tListX_Output output = 1;\n",
+		"\tstring next_page_token = 2;\n",
+	)
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "paginate-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for LIST_X
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+import "google.golang.org/grpc"
+
+type ListX_Input struct{ PFilter string }
+type ListX_Output struct {
+	PCount int32
+	PCur   []*ListXPCurRow_DbWeb
+}
+type ListXPCurRow_DbWeb struct {
+	Id   int32
+	Name string
+}
+type ListX_InputPage struct {
+	Input     *ListX_Input
+	PageSize  int32
+	PageToken string
+}
+type ListX_OutputPage struct {
+	Output        *ListX_Output
+	NextPageToken string
+}
+type DbWeb_ListXServer interface {
+	Send(*ListX_Output) error
+	grpc.ServerStream
+}
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = SaveFunctions(&buf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "main.go"), []byte(`package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	pb "`+pbImport+`"
+)
+
+func main() {
+	ctx := context.Background()
+	s := NewServer(nil, nil, nil)
+	if _, err := s.ListXPage(ctx, &pb.ListX_InputPage{PageToken: "!"}); !errors.Is(err, oracall.ErrInvalidArgument) {
+		fmt.Printf("bad token: got %+v, wanted ErrInvalidArgument\n", err)
+		os.Exit(1)
+	}
+
+	// the second page of 3 rows, sent in batches of 2
+	stream := &listXPageStream{ctx: ctx, page: oracall.Page{Offset: 3, Size: 3}, rows: new(pb.ListX_Output)}
+	output := &pb.ListX_Output{PCount: 9}
+	var err error
+	for i := int32(0); i < 5 && err == nil; i++ {
+		output.PCur = []*pb.ListXPCurRow_DbWeb{{Id: 2 * i}, {Id: 2*i + 1}}
+		err = stream.Send(output)
+	}
+	if !errors.Is(err, oracall.ErrPageFull) || !stream.page.More {
+		fmt.Printf("got %+v (more=%t), wanted ErrPageFull\n", err, stream.page.More)
+		os.Exit(1)
+	}
+	var ids []int32
+	for _, row := range stream.rows.PCur {
+		ids = append(ids, row.Id)
+	}
+	if got := fmt.Sprintf("%v", ids); got != "[3 4 5]" {
+		fmt.Printf("got %s, wanted rows 3, 4 and 5\n", got)
+		os.Exit(1)
+	}
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "run", "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go run: %+v\n%s", err, out.String())
+	}
+}
@@ -26,8 +26,24 @@ import (
 // MaxTableSize is the default size of the array elements
 var MaxTableSize = 128
 
+// batchSize is the default number of rows fetched at once from the returned cursors
+// - see the fetch-size annotation.
 const batchSize = 1024
 
+// CursorClobLimit is the size of the CLOB text in the rows buffered for one response of a returned cursor:
+// the fetched rows are sent as soon as their CLOB columns reach it, without waiting for fetchSize rows,
+// so a batch of big documents does not have to fit in memory at once.
+// Zero or negative means no limit.
+var CursorClobLimit = 1 << 20
+
+// getFetchSize returns the number of rows to fetch at once from the returned cursors.
+func (fun Function) getFetchSize() int {
+	if fun.fetchSize > 0 {
+		return fun.fetchSize
+	}
+	return batchSize
+}
+
 // SavePlsqlBlock saves the plsql block definition into writer
 func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 	decls, pre, call, post, convIn, convOut, err := fun.prepareCall()
@@ -55,15 +71,23 @@ func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 	for _, line := range pre {
 		fmt.Fprintf(plsBuf, "  %s\n", line)
 	}
-	if len(fun.handle) == 0 {
+	// the function's errors are captured into its arguments (sqlcode annotation), not raised
+	sqlCodeHandler := fun.sqlCodeHandler()
+	if len(fun.handle) == 0 && sqlCodeHandler == "" {
 		plsBuf.WriteString("\n")
 	} else {
 		plsBuf.WriteString("  BEGIN\n  ")
 	}
 	fmt.Fprintf(plsBuf, "  %s;\n", call)
-	if len(fun.handle) != 0 {
-		fmt.Fprintf(plsBuf, "  EXCEPTION WHEN %s THEN NULL;\n  END;\n",
-			strings.Join(fun.handle, " OR "))
+	if len(fun.handle) != 0 || sqlCodeHandler != "" {
+		plsBuf.WriteString("  EXCEPTION")
+		if len(fun.handle) != 0 {
+			fmt.Fprintf(plsBuf, " WHEN %s THEN NULL;", strings.Join(fun.handle, " OR "))
+		}
+		if sqlCodeHandler != "" {
+			plsBuf.WriteString(" " + sqlCodeHandler)
+		}
+		plsBuf.WriteString("\n  END;\n")
 	}
 	plsBuf.WriteByte('\n')
 	for _, line := range post {
@@ -86,29 +110,40 @@ func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 
 	hasCursorOut := fun.HasCursorOut()
 	if hasCursorOut {
-		fmt.Fprintf(callBuf, `func (s *oracallServer) %s(input *pb.%s, stream pb.%s_%sServer) (err error) {
+		fmt.Fprintf(callBuf, `%sfunc (s *oracallServer) %s {
 			ctx := stream.Context()
 			%s
-			output := new(pb.%s)
+			output := new(%s)
 			iterators := make([]iterator, 0, 1)
+			// the rows fetched at once from the cursors
+			const fetchSize = %d
 		`,
-			CamelCase(fn), CamelCase(fun.getStructName(false, false)), CamelCase(fun.Package), CamelCase(fn),
+			fun.goDeprecated(),
+			fun.goSignature(),
 			check,
-			CamelCase(fun.getStructName(true, false)),
+			fun.pbTypeName(true),
+			fun.getFetchSize(),
 		)
 	} else {
-		fmt.Fprintf(callBuf, `func (s *oracallServer) %s(ctx context.Context, input *pb.%s) (output *pb.%s, err error) {
+		callName, deprecated := fun.goName(), fun.goDeprecated()
+		if fun.useEnvelope() {
+			// the envelope method is the deprecated one
+			callName, deprecated = fun.goEnvelopeCallName(), ""
+		}
+		fmt.Fprintf(callBuf, `%sfunc (s *oracallServer) %s {
 		%s
-		output = new(pb.%s)
+		output = new(%s)
 		iterators := make([]iterator, 0, 1) // just temporary
 		_ = iterators
     `,
-			CamelCase(fn), CamelCase(fun.getStructName(false, false)), CamelCase(fun.getStructName(true, false)),
+			deprecated,
+			fun.goCallSignature(callName),
 			check,
-			CamelCase(fun.getStructName(true, false)),
+			fun.pbTypeName(true),
 		)
 	}
 	fmt.Fprintf(callBuf, `
+	// the per-request logger (with the request ID) - see orasrv.WithContext
 	logger := s.Logger
 	if lgr := oracall.FromContext(ctx); lgr != nil {
 		logger = lgr
@@ -141,15 +176,33 @@ func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 		logger.Info("not found", "name", fun.RealName(), "in", call)
 	}
 	j := i + strings.Index(call[i:], ")") + 1
+	var omitCall string
+	if fun.hasOmittable() {
+		omitCall = "qry = oracall.OmitCallArgs(qry, omitted)\n"
+	}
 	fmt.Fprintf(callBuf, `
 	const funName = "%s"
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	var tx *sql.Tx
-	if tx, err = s.db.BeginTx(ctx, nil); err != nil {
-		return 
+	// use the caller's transaction (see oracall.ContextWithTx), or begin and commit our own,
+	// with the NLS settings of the caller (see oracall.ContextWithNLS) set on its session
+	tx := oracall.TxFromContext(ctx)
+	ownTx := tx == nil
+	nls := oracall.NLSFromContext(ctx)
+	if ownTx {
+		var endTx func() error
+		if tx, endTx, err = oracall.BeginTxNLS(ctx, s.db, nls); err != nil {
+			return
+		}
+		defer endTx()
+		defer tx.Rollback()
+	} else if len(nls) != 0 {
+		var restoreNLS func() error
+		if restoreNLS, err = oracall.AlterSessionNLS(ctx, tx, nls); err != nil {
+			return
+		}
+		defer restoreNLS()
 	}
-	defer tx.Rollback()
 	ctx = godror.ContextWithTraceTag(ctx, godror.TraceTag{Module: %q, Action: %q})
 if s.DBLog != nil {
 	var err error
@@ -162,11 +215,12 @@ if DebugLevel > 0 {
 	logger.Debug("calling", "qry", callText, "stmt", `+"`%s`"+`)
 }
 	qry := %s
-`,
+%s`,
 		fun.Name(),
 		fun.Package, fun.name,
 		call[i:j], rIdentifier.ReplaceAllString(pls, "'%#v'"),
 		fun.getPlsqlConstName(),
+		omitCall,
 	)
 	aS := "1024"
 	if fun.maxTableSize > 0 {
@@ -177,6 +231,11 @@ if DebugLevel > 0 {
 		}
 	}
 
+	execOpts := "godror.PlSQLArrays, godror.ArraySize(" + aS + ")"
+	if hasCursorOut {
+		execOpts += ", godror.FetchArraySize(fetchSize), godror.PrefetchCount(fetchSize+1)"
+	}
+
 	callBuf.WriteString(`
 	stmt, stmtErr := tx.PrepareContext(ctx, qry)
 	if stmtErr != nil {
@@ -187,30 +246,25 @@ if DebugLevel > 0 {
 	stmtP := fmt.Sprintf("%p", stmt)
 	dl, _ := ctx.Deadline()
 	logger.Debug( "calling", "fun", funName, "input", input, "stmt", stmtP, "deadline", dl.UTC().Format(time.RFC3339))
-	_, err = stmt.ExecContext(ctx, append(params, godror.PlSQLArrays, godror.ArraySize(` + aS + `))...)
-	logger.Info( "finished", "fun", funName, "stmt", stmtP, "error", err)
+	start := time.Now()
+	` + fun.goExec(execOpts) + `
+	logger.Info( "finished", "fun", funName, "stmt", stmtP, "binds", len(params), "dur", time.Since(start).String(), "error", err)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return
 		}
-		if c, ok := err.(interface{ Code() int }); ok && c.Code() == 4068 {
-			// "existing state of packages has been discarded"
-			_, err = stmt.ExecContext(ctx, append(params, godror.PlSQLArrays, godror.ArraySize(` + aS + `))...)
-		}
-		if err != nil {
-			qe := oracall.NewQueryError(qry, fmt.Errorf("%v: %w", params, err))
-			err = qe
-			if s.DBLog != nil {
-				var logErr error
-				if _, logErr = s.DBLog(ctx, tx, funName, err); logErr != nil {
-					logger.Error("dbLog", "fun", funName, "error", logErr)
-				}
-			}
-			if qe.Code() == 6502 {  // Numeric or Value Error
-				err = fmt.Errorf("%+v: %w", qe, oracall.ErrInvalidArgument)
+		qe := oracall.NewQueryError(qry, fmt.Errorf("%v: %w", params, err))
+		err = qe
+		if s.DBLog != nil {
+			var logErr error
+			if _, logErr = s.DBLog(ctx, tx, funName, err); logErr != nil {
+				logger.Error("dbLog", "fun", funName, "error", logErr)
 			}
-			return
 		}
+		if qe.Code() == 6502 {  // Numeric or Value Error
+			err = fmt.Errorf("%+v: %w", qe, oracall.ErrInvalidArgument)
+		}
+		return
 	}
     `)
 
@@ -219,11 +273,16 @@ if DebugLevel > 0 {
 		io.WriteString(callBuf, line+"\n")
 	}
 	if !hasCursorOut {
-		fmt.Fprintf(callBuf, "\nerr = tx.Commit()\nreturn\n")
+		fmt.Fprintf(callBuf, "\nif ownTx {\n\terr = tx.Commit()\n}\nreturn\n")
 	} else {
+		iterateErr := `logger.Error("iterate", "error", err)`
+		if fun.paginated() {
+			// the page of the paginated method is full
+			iterateErr = "if !errors.Is(err, oracall.ErrPageFull) {\n" + iterateErr + "\n}"
+		}
 		fmt.Fprintf(callBuf, `
 		if len(iterators) == 0 {
-			if err = stream.Send(output); err == nil {
+			if err = stream.Send(output); err == nil && ownTx {
 				err = tx.Commit()
 			}
 			return
@@ -242,29 +301,85 @@ if DebugLevel > 0 {
 					continue
 				}
 				if !errors.Is(err, io.EOF) {
-					logger.Error("iterate", "error", err)
+					%s
 					return
 				}
 			}
 			if len(iterators) != len(iterators2) {
 				if len(iterators2) == 0 {
-					err = tx.Commit()
+					if ownTx {
+						err = tx.Commit()
+					}
 					return
 				}
 				iterators = append(iterators[:0], iterators2...)
 			}
 			iterators2 = iterators2[:0]
 		}
-		`)
+		`, iterateErr)
 	}
 	callBuf.WriteString("\n}\n")
+	if fun.useEnvelope() {
+		callBuf.WriteString(fun.envelopeMethod(fun.goEnvelopeCallName()))
+	}
+	if fun.paginated() {
+		callBuf.WriteString(fun.pageMethod())
+	}
 	callFun = callBuf.String()
 	plsql = plsBuf.String()
 
-	plsql, callFun = demap(plsql, callFun)
+	if fun.namedBinds() {
+		plsql, callFun = demapNamed(plsql, callFun)
+	} else {
+		plsql, callFun = demap(plsql, callFun)
+	}
 	return
 }
 
+// goName returns the name of the generated Go method.
+func (fun Function) goName() string {
+	fn := fun.name
+	if fun.alias != "" {
+		fn = fun.alias
+	}
+	return CamelCase(strings.Replace(fn, ".", "__", -1))
+}
+
+// goDeprecated returns the "Deprecated:" doc comment of the Go method, if the function is deprecated.
+func (fun Function) goDeprecated() string {
+	if !fun.deprecated {
+		return ""
+	}
+	return fmt.Sprintf("// Deprecated: %s is deprecated.\n", fun.Name())
+}
+
+// goSignature returns the signature of the generated Go method (without the receiver).
+func (fun Function) goSignature() string {
+	if fun.useEnvelope() {
+		return fmt.Sprintf("%s(ctx context.Context, input *%s) (*pb.%sEnvelope, error)",
+			fun.goName(), fun.pbTypeName(false), CamelCase(fun.getStructName(true, false)))
+	}
+	return fun.goCallSignature(fun.goName())
+}
+
+// goCallSignature returns the signature of the Go method named name calling the function.
+func (fun Function) goCallSignature(name string) string {
+	if fun.HasCursorOut() {
+		return fmt.Sprintf("%s(input *pb.%s, stream pb.%s_%sServer) (err error)",
+			name, CamelCase(fun.getStructName(false, false)), fun.goService(), fun.goName())
+	}
+	return fmt.Sprintf("%s(ctx context.Context, input *%s) (output *%s, err error)",
+		name, fun.pbTypeName(false), fun.pbTypeName(true))
+}
+
+// pbTypeName returns the Go type of the input (or output) message of the function.
+func (fun Function) pbTypeName(out bool) string {
+	if fun.usesEmpty() {
+		return "emptypb.Empty"
+	}
+	return "pb." + CamelCase(fun.getStructName(out, false))
+}
+
 func demap(plsql, callFun string) (string, string) {
 	var i int
 	paramsMap := make(map[string][]int, 16)
@@ -293,8 +408,10 @@ func demap(plsql, callFun string) (string, string) {
 		Funcs(
 			map[string]interface{}{
 				"paramsIdx": func(key string) int {
-					if strings.HasSuffix(key, MarkHidden) {
-						key = key[:len(key)-len(MarkHidden)] + "#"
+					if _, ok := paramsMap[key]; !ok {
+						if k, ok := unreplHidden(key); ok {
+							key = k
+						}
 					}
 					arr := paramsMap[key]
 					if len(arr) == 0 {
@@ -462,11 +579,16 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 	var (
 		vn, tmp, typ string
 		ok           bool
+		hasKeyVar    bool
 	)
 	decls = append(decls, "i1 PLS_INTEGER;", "i2 PLS_INTEGER;")
 	convIn = append(convIn,
 		"params := make([]interface{}, {{.ParamsArrLen}}, {{.ParamsArrLen}}+2)",
 	)
+	if fun.hasOmittable() {
+		// the unset defaulted arguments, left out of the call (see OmitCallArgs)
+		convIn = append(convIn, "omitted := make(map[string]int)")
+	}
 
 	addParam := func(paramName string) string {
 		if paramName == "" {
@@ -483,8 +605,54 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 		case FLAVOR_SIMPLE:
 			name := (CamelCase(arg.Name))
 			//name := capitalize(replHidden(arg.Name))
+			if arg.Type == "XMLTYPE" { // bound as CLOB, converted in the block
+				vn = getInnerVarName(fun.Name(), arg.Name)
+				callArgs[arg.Name] = vn
+				decls = append(decls, vn+" SYS.XMLTYPE; --X="+arg.Name)
+				if arg.IsInput() {
+					pre = append(pre, "IF DBMS_LOB.GETLENGTH(:"+arg.Name+") > 0 THEN "+vn+" := XMLTYPE(:"+arg.Name+"); END IF;")
+					convIn = append(convIn, fmt.Sprintf(
+						`if xErr := custom.XML(input.%s).Validate(); xErr != nil { err = fmt.Errorf("%s: %%v: %%w", xErr, oracall.ErrInvalidArgument); return }`,
+						name, arg.Name))
+				}
+				if arg.IsOutput() {
+					post = append(post, "IF "+vn+" IS NOT NULL THEN :"+arg.Name+" := "+vn+".getClobVal(); END IF;")
+				}
+			}
+			if arg.isBoolean() { // bound as 0/1, converted in the block
+				vn = getInnerVarName(fun.Name(), arg.Name)
+				callArgs[arg.Name] = vn
+				decls = append(decls, vn+" BOOLEAN; --L="+arg.Name)
+				if arg.IsInput() {
+					pre = append(pre, vn+" := :"+arg.Name+" = 1;")
+				}
+				if arg.IsOutput() {
+					post = append(post, ":"+arg.Name+" := CASE "+vn+" WHEN TRUE THEN 1 WHEN FALSE THEN 0 END;")
+				}
+				convIn, convOut = arg.getConvBool(convIn, convOut,
+					name, addParam(arg.Name))
+				continue
+			}
+			if fun.isSQLCodeArg(arg.Name) {
+				vn = getInnerVarName(fun.Name(), arg.Name)
+				callArgs[arg.Name] = vn
+				decls = append(decls, vn+" "+arg.sqlCodeVarType()+"; --S="+arg.Name)
+				post = append(post, ":"+arg.Name+" := "+vn+";")
+			}
 			convIn, convOut = arg.getConvSimple(convIn, convOut,
 				name, addParam(arg.Name))
+			if arg.omittable() {
+				if tmp, err = arg.goOmit(arg.Name); err != nil {
+					return
+				}
+				convIn = append(convIn, tmp)
+			}
+			// the XML fetched instead of the REF CURSOR (see XMLFetch)
+			if arg.Type == "XMLTYPE" && arg.IsOutput() && fun.xmlFetch != nil {
+				convOut = append(convOut, fmt.Sprintf(
+					`if xErr := custom.XML(output.%s).Validate(); xErr != nil { err = fmt.Errorf("%s: %%w", xErr); return }`,
+					name, arg.Name))
+			}
 
 		case FLAVOR_RECORD:
 			vn = getInnerVarName(fun.Name(), arg.Name)
@@ -556,8 +724,42 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 				convIn, convOut = arg.getConvSimpleTable(convIn, convOut,
 					name, addParam(arg.Name), maxTableSize)
 			} else {
-				switch arg.TableOf.Flavor {
-				case FLAVOR_SIMPLE: // like simple, but for the arg.TableOf
+				switch {
+				case arg.IsStringIndexed(): // as two arrays, of the keys and of the values
+					vn = getInnerVarName(fun.Name(), arg.Name)
+					callArgs[arg.Name] = vn
+					kp, vp := getParamName(fun.Name(), vn+".keys"), getParamName(fun.Name(), vn+".values")
+					if !hasKeyVar {
+						decls = append(decls, "k1 "+mapKeyType+";")
+						hasKeyVar = true
+					}
+					decls = append(decls,
+						kp+" "+getTableType(mapKeyType)+"; --M="+arg.Name,
+						vp+" "+getTableType(arg.TableOf.AbsType)+"; --M="+arg.Name,
+						vn+" "+arg.TypeName+"; --M="+arg.Name)
+					if arg.IsInput() {
+						pre = append(pre,
+							kp+" := :"+kp+"; "+vp+" := :"+vp+";",
+							"i1 := "+kp+".FIRST;",
+							"WHILE i1 IS NOT NULL LOOP",
+							"  "+vn+"("+kp+"(i1)) := "+vp+"(i1);",
+							"  i1 := "+kp+".NEXT(i1);",
+							"END LOOP;")
+					}
+					if arg.IsOutput() {
+						post = append(post,
+							kp+".DELETE; "+vp+".DELETE;",
+							"k1 := "+vn+".FIRST; i2 := 1;",
+							"WHILE k1 IS NOT NULL LOOP",
+							"  "+kp+"(i2) := k1; "+vp+"(i2) := "+vn+"(k1);",
+							"  k1 := "+vn+".NEXT(k1); i2 := i2 + 1;",
+							"END LOOP;",
+							":"+kp+" := "+kp+"; :"+vp+" := "+vp+";")
+					}
+					convIn, convOut = arg.getConvStringMap(convIn, convOut,
+						CamelCase(arg.Name), addParam(kp), addParam(vp), maxTableSize)
+
+				case arg.TableOf.Flavor == FLAVOR_SIMPLE: // like simple, but for the arg.TableOf
 					typ = getTableType(arg.TableOf.AbsType)
 					if strings.IndexByte(typ, '/') >= 0 {
 						err = fmt.Errorf("nonsense table type of %s", arg)
@@ -572,7 +774,11 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 
 					vn = getInnerVarName(fun.Name(), arg.Name)
 					callArgs[arg.Name] = vn
-					decls = append(decls, vn+" "+arg.TypeName+ " := " + arg.TypeName + "()" + "; --B="+arg.Name)
+					if arg.IsNestedTable() {
+						decls = append(decls, vn+" "+arg.TypeName+" := "+arg.TypeName+"(); --B="+arg.Name)
+					} else { // associative arrays (INDEX BY) have no constructor
+						decls = append(decls, vn+" "+arg.TypeName+"; --B="+arg.Name)
+					}
 					if arg.IsInput() {
 						pre = append(pre,
 							vn+".DELETE;",
@@ -582,25 +788,48 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 							"  i1 := "+arg.Name+".NEXT(i1);",
 							"END LOOP;")
 					}
+					var np string
+					if arg.IsNestedTable() {
+						np = getParamName(fun.Name(), vn+".null")
+						if arg.IsInput() {
+							pre = append(pre, "IF :"+np+" = 1 THEN "+vn+" := NULL; END IF;")
+						}
+					}
 					if arg.IsOutput() {
+						post = append(post, arg.Name+".DELETE;")
+						if np != "" {
+							post = append(post,
+								":"+np+" := CASE WHEN "+vn+" IS NULL THEN 1 ELSE 0 END;",
+								"IF "+vn+" IS NOT NULL THEN")
+						}
 						post = append(post,
-							arg.Name+".DELETE;",
 							"i1 := "+vn+".FIRST;",
 							"WHILE i1 IS NOT NULL LOOP",
 							"  "+arg.Name+"(i1) := "+vn+"(i1);",
 							"  i1 := "+vn+".NEXT(i1);",
-							"END LOOP;",
-							":"+arg.Name+" := "+arg.Name+";")
+							"END LOOP;")
+						if np != "" {
+							post = append(post, "END IF;")
+						}
+						post = append(post, ":"+arg.Name+" := "+arg.Name+";")
 					}
 					name := (CamelCase(arg.Name))
 					//name := capitalize(replHidden(arg.Name))
 					convIn, convOut = arg.getConvSimpleTable(convIn, convOut,
 						name, addParam(arg.Name), maxTableSize)
+					if np != "" {
+						convIn, convOut = arg.getConvNull(convIn, convOut,
+							CamelCase(nullFieldName(arg.Name)), addParam(np))
+					}
 
-				case FLAVOR_RECORD:
+				case arg.TableOf.Flavor == FLAVOR_RECORD:
 					vn = getInnerVarName(fun.Name(), arg.Name+"."+arg.TableOf.Name)
 					callArgs[arg.Name] = vn
-					decls = append(decls, vn+" "+arg.TypeName+ " := " + arg.TypeName + "()" + "; --C="+arg.Name)
+					if arg.IsNestedTable() {
+						decls = append(decls, vn+" "+arg.TypeName+" := "+arg.TypeName+"(); --C="+arg.Name)
+					} else { // associative arrays (INDEX BY) have no constructor
+						decls = append(decls, vn+" "+arg.TypeName+"; --C="+arg.Name)
+					}
 
 					aname := (CamelCase(arg.Name))
 					//aname := capitalize(replHidden(arg.Name))
@@ -633,7 +862,7 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 							err = fmt.Errorf("nonsense table type of %s", arg)
 							return
 						}
-						decls = append(decls, getParamName(fun.Name(), vn+"."+k)+" "+typ+ " := " + typ + "()" + "; --D="+arg.Name)
+						decls = append(decls, getParamName(fun.Name(), vn+"."+k)+" "+typ+"; --D="+arg.Name) // INDEX BY, no constructor
 
 						tmp = getParamName(fun.Name(), vn+"."+k)
 						if arg.IsInput() {
@@ -644,7 +873,12 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 					}
 
 					// here comes the loops
-					var idxvar string
+					var idxvar, np string
+					if arg.IsNestedTable() {
+						np = getParamName(fun.Name(), vn+".null")
+						convIn, convOut = arg.getConvNull(convIn, convOut,
+							CamelCase(nullFieldName(arg.Name)), addParam(np))
+					}
 					for _, a := range arg.TableOf.RecordOf {
 						a := a
 						k, v := a.Name, a.Argument
@@ -659,7 +893,13 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 									"WHILE i1 IS NOT NULL LOOP")
 							}
 							if arg.IsOutput() {
-								post = append(post, "",
+								post = append(post, "")
+								if np != "" {
+									post = append(post,
+										":"+np+" := CASE WHEN "+vn+" IS NULL THEN 1 ELSE 0 END;",
+										"IF "+vn+" IS NOT NULL THEN")
+								}
+								post = append(post,
 									"i1 := "+vn+".FIRST; i2 := 1;",
 									"WHILE i1 IS NOT NULL LOOP")
 							}
@@ -693,10 +933,16 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 							"  i1 := "+idxvar+".NEXT(i1);",
 							"END LOOP;")
 					}
+					if np != "" && arg.IsInput() {
+						pre = append(pre, "IF :"+np+" = 1 THEN "+vn+" := NULL; END IF;")
+					}
 					if arg.IsOutput() {
 						post = append(post,
 							"  i1 := "+vn+".NEXT(i1); i2 := i2 + 1;",
 							"END LOOP;")
+						if np != "" {
+							post = append(post, "END IF;")
+						}
 						for _, a := range arg.TableOf.RecordOf {
 							a := a
 							k := a.Name
@@ -718,7 +964,12 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 	callb := Buffers.Get()
 	defer Buffers.Put(callb)
 	if fun.Returns != nil {
-		callb.WriteString(":ret := ")
+		// the XMLTYPE and the BOOLEAN are returned into their inner variables, converted in the block
+		if vn, ok = callArgs[fun.Returns.Name]; ok {
+			callb.WriteString(vn + " := ")
+		} else {
+			callb.WriteString(":ret := ")
+		}
 	}
 	callb.WriteString(fun.RealName() + "(")
 	for i, arg := range fun.Args {
@@ -728,7 +979,7 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 		if vn, ok = callArgs[arg.Name]; !ok {
 			vn = ":" + arg.Name
 		}
-		fmt.Fprintf(callb, "%s=>%s", arg.Name, vn)
+		fmt.Fprintf(callb, "%s=>%s", arg.RealName(), vn)
 	}
 	callb.WriteString(")")
 	call = callb.String()
@@ -739,9 +990,18 @@ func (arg Argument) getConvSimple(
 	convIn, convOut []string,
 	name, paramName string,
 ) ([]string, []string) {
+	if tm, ok := arg.typeMapping(); ok {
+		return arg.getConvMapped(tm, convIn, convOut, name, paramName)
+	}
+	if pw, ok := arg.protoWrapper(); ok {
+		return arg.getConvWrapper(pw, convIn, convOut, name, paramName)
+	}
 	if !arg.IsOutput() {
 		in, _ := arg.ToOra(paramName, "input."+name, arg.Direction)
 		convIn = append(convIn, in+"  // gcs4i")
+		if conv := arg.emptyConv(paramName, "input."+name); conv != "" {
+			convIn = append(convIn, conv)
+		}
 	} else {
 		got, err := arg.goType(false)
 		if err != nil {
@@ -754,6 +1014,9 @@ func (arg Argument) getConvSimple(
 			}
 		} else if arg.IsInput() {
 			convIn = append(convIn, fmt.Sprintf(`output.%s = input.%s  // gcs3`, name, name))
+			if conv := arg.emptyConv("output."+name, "output."+name); conv != "" {
+				convIn = append(convIn, conv)
+			}
 		}
 		if got == "time.Time" {
 			convOut = append(convOut, fmt.Sprintf("if output.%s != nil && !output.%s.IsValid() { output.%s = nil }", name, name, name))
@@ -770,6 +1033,26 @@ func (arg Argument) getConvSimple(
 	return convIn, convOut
 }
 
+// getConvNull binds the NULL flag (see nullFieldName) of the nested table argument as a number,
+// 1 meaning the collection is NULL.
+func (arg Argument) getConvNull(
+	convIn, convOut []string,
+	name, paramName string,
+) ([]string, []string) {
+	varName := "is" + name
+	convIn = append(convIn, fmt.Sprintf("var %s int32", varName))
+	if arg.IsInput() {
+		convIn = append(convIn, fmt.Sprintf("if input.%s { %s = 1 }", name, varName))
+	}
+	if !arg.IsOutput() {
+		convIn = append(convIn, fmt.Sprintf("%s = %s  // gcn1", paramName, varName))
+		return convIn, convOut
+	}
+	convIn = append(convIn, fmt.Sprintf("%s = sql.Out{Dest: &%s, In: %t}  // gcn2", paramName, varName, arg.IsInput()))
+	convOut = append(convOut, fmt.Sprintf("output.%s = %s != 0", name, varName))
+	return convIn, convOut
+}
+
 func (arg Argument) getConvSimpleTable(
 	convIn, convOut []string,
 	name, paramName string,
@@ -863,6 +1146,34 @@ func (arg Argument) getConvRefCursor(
 		panic(err)
 	}
 	GoT := withPb(CamelCase(got))
+	// the sizes of the CLOB columns, limiting the rows buffered
+	var clobSizes []string
+	if CursorClobLimit > 0 {
+		for _, col := range arg.TableOf.RecordOf {
+			if col.Argument.Type == "CLOB" || col.Argument.Type == "XMLTYPE" {
+				clobSizes = append(clobSizes, "len(row."+CamelCase(col.Name)+")")
+			}
+		}
+	}
+	appendRow := "a = append(a, " + arg.getFromRset("I") + ")"
+	// the number of the columns, unknown for the dynamic row (see setDynamicRows)
+	numCols := strconv.Itoa(len(arg.TableOf.RecordOf))
+	var colsDecl string
+	if arg.TableOf.dynamicRow {
+		numCols, colsDecl = "len(rset.Columns())", "cols := rset.Columns()"
+		appendRow = fmt.Sprintf("a = append(a, &%s{Columns: oracall.RowColumns(cols, I)})", strings.TrimPrefix(GoT, "*"))
+	}
+	var clobSize string
+	if len(clobSizes) != 0 {
+		clobSize = "var clobSize int"
+		appendRow = fmt.Sprintf(`row := %s
+				a = append(a, row)
+				// send the rows once their CLOB text reaches the limit
+				if clobSize += %s; clobSize >= %d {
+					break
+				}`,
+			arg.getFromRset("I"), strings.Join(clobSizes, " + "), CursorClobLimit)
+	}
 	convIn = append(convIn, fmt.Sprintf(`output.%s = make([]%s, 0, %d)  // gcrf1
 		%s = sql.Out{Dest:new(driver.Rows)} // gcrf1 %q`,
 		name, GoT, tableSize,
@@ -877,13 +1188,15 @@ func (arg Argument) getConvRefCursor(
 				Reset: func() { output.%s = output.%s[:0] },
 				Iterate: func() error {
 			a := output.%s[:0]
-			I := make([]driver.Value, %d)
+			I := make([]driver.Value, %s)
 			var err error
-			for i := 0; i < %d; i++ {
+			%s
+			%s
+			for i := 0; i < fetchSize; i++ {
 				if err = rset.Next(I); err != nil {
 					break
 				}
-				a = append(a, %s)
+				%s
 			}
 			output.%s = a
 			return err
@@ -894,9 +1207,10 @@ func (arg Argument) getConvRefCursor(
 		paramName,
 		name, name,
 		name,
-		len(arg.TableOf.RecordOf),
-		batchSize,
-		arg.getFromRset("I"),
+		numCols,
+		colsDecl,
+		clobSize,
+		appendRow,
 		name,
 	))
 	return convIn, convOut
@@ -5,9 +5,13 @@
 package oracall
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/google/go-cmp/cmp"
 	"github.com/kylelemons/godebug/diff"
 )
 
@@ -24,3 +28,236 @@ func TestOne(t *testing.T) {
 		}
 	}
 }
+
+func TestPlsqlBlockContextTx(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,SET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	_, callFun := functions[0].PlsqlBlock("")
+	wantContains(t, callFun,
+		"tx := oracall.TxFromContext(ctx)",
+		"if ownTx {\n\t\tvar endTx func() error\n\t\tif tx, endTx, err = oracall.BeginTxNLS(ctx, s.db, nls)",
+		"} else if len(nls) != 0 {\n\t\tvar restoreNLS func() error\n\t\tif restoreNLS, err = oracall.AlterSessionNLS(ctx, tx, nls)",
+		"if ownTx {\n\t\terr = tx.Commit()\n\t}",
+	)
+}
+
+func TestPlsqlBlockNullableTable(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,UPSERT_X,0,1,P_IDS,IN/OUT,TABLE,,,,,TABLE,0,SCOTT,DB_WEB,NUM_NT_TYP,",
+		"1,1,2,DB_WEB,UPSERT_X,1,1,,IN/OUT,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,3,DB_WEB,UPSERT_X,0,2,P_TAGS,IN,PL/SQL TABLE,,,,BINARY_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,NUM_TAB_TYP,",
+		"1,1,4,DB_WEB,UPSERT_X,1,1,,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	t.Log(proto)
+	// both in the request and the response, but only for the nested table
+	for msg, want := range map[string][]string{
+		"UpsertX_Input":  {"repeated sint32 p_ids = 1", "repeated sint32 p_tags = 2", "bool null_p_ids = 3"},
+		"UpsertX_Output": {"repeated sint32 p_ids = 1", "bool null_p_ids = 2"},
+	} {
+		if d := cmp.Diff(want, protoFields(t, proto, msg)); d != "" {
+			t.Errorf("%s: %s", msg, d)
+		}
+	}
+
+	plsql, callFun := functions[0].PlsqlBlock("")
+	t.Log(plsql)
+	wantContains(t, plsql,
+		" = 1 THEN ",
+		" := NULL; END IF;",
+		" IS NULL THEN 1 ELSE 0 END;",
+	)
+	wantContains(t, callFun,
+		"if input.NullPIds {\n\t\tisNullPIds = 1\n\t}",
+		"sql.Out{Dest: &isNullPIds, In: true}",
+		"output.NullPIds = isNullPIds != 0",
+	)
+	if strings.Contains(callFun, "NullPTags") {
+		t.Errorf("index-by table got a NULL flag:\n%s", callFun)
+	}
+}
+
+func TestPlsqlBlockAssocArrayOfRecords(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,SET_ITEMS,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,SET_ITEMS,0,2,P_ITEMS,IN/OUT,PL/SQL TABLE,,,,PLS_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,ITEM_TAB_TYP,",
+		"1,1,3,DB_WEB,SET_ITEMS,1,1,,IN/OUT,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,ITEM_REC_TYP,",
+		"1,1,4,DB_WEB,SET_ITEMS,2,1,ID,IN/OUT,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,5,DB_WEB,SET_ITEMS,2,2,NAME,IN/OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,",
+		"1,1,6,DB_WEB,SET_ITEMS,2,3,AMOUNT,IN/OUT,NUMBER,12,2,,,NUMBER,0,,,,",
+		"1,1,7,DB_WEB,SET_ITEMS,0,3,P_COUNT,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+
+	items := functions[0].Args[1]
+	if items.Flavor != FLAVOR_TABLE || items.IndexBy != "PLS_INTEGER" || items.TableOf == nil {
+		t.Fatalf("p_items is not an associative array: %+v", items)
+	}
+	if items.TableOf.Flavor != FLAVOR_RECORD {
+		t.Fatalf("p_items is not a table of records: %+v", items.TableOf)
+	}
+	var fields []string
+	for _, a := range items.TableOf.RecordOf {
+		fields = append(fields, a.Name)
+	}
+	if got, want := strings.Join(fields, ","), "id,name,amount"; got != want {
+		t.Errorf("got fields %q, wanted %q", got, want)
+	}
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	t.Log(proto)
+	for msg, want := range map[string][]string{
+		"SetItems_Input":         {"sint32 p_id = 1", "repeated DbWeb_ItemRecTyp_Scott p_items = 2"},
+		"DbWeb_ItemRecTyp_Scott": {"sint32 id = 1", "string name = 2", "string amount = 3"},
+	} {
+		if d := cmp.Diff(want, protoFields(t, proto, msg)); d != "" {
+			t.Errorf("%s: %s", msg, d)
+		}
+	}
+
+	plsql, callFun := functions[0].PlsqlBlock("")
+	t.Log(plsql)
+	wantContains(t, plsql,
+		"v001(i1).id := p002#id(i1);",
+		"p_items=>v001",
+	)
+	// associative arrays cannot be initialized with a constructor
+	for _, notWant := range []string{"ITEM_TAB_TYP()", "NUMBER_9_tab_typ()"} {
+		if strings.Contains(plsql, notWant) {
+			t.Errorf("constructor %q in\n%s", notWant, plsql)
+		}
+	}
+	if want := "output.PItems[i].Id = int32(v)"; !strings.Contains(callFun, want) {
+		t.Errorf("no %q in\n%s", want, callFun)
+	}
+}
+
+func TestPlsqlBlockSQLCode(t *testing.T) {
+	rows := []string{
+		"1,1,1,DB_WEB,DO_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,DO_X,0,2,P_ERR_CODE,OUT,NUMBER,,,,,NUMBER,0,,,,",
+		"1,1,3,DB_WEB,DO_X,0,3,P_ERR_MSG,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,200,,,,",
+	}
+	parse := func(t *testing.T, annotation string) (Function, error) {
+		t.Helper()
+		functions := parseTestCsv(t, rows...)
+		a, err := ParseAnnotation(annotation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		functions, err = ApplyAnnotationsStrict(functions, []Annotation{a})
+		if len(functions) != 1 {
+			t.Fatalf("got %d functions, wanted 1", len(functions))
+		}
+		return functions[0], err
+	}
+
+	fun, err := parse(t, "sqlcode db_web.do_x => p_err_code, p_err_msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plsql, callFun := fun.PlsqlBlock("")
+	t.Log(plsql)
+	// the error is captured into the OUT arguments, not raised
+	code, msg := getInnerVarName(fun.Name(), "p_err_code"), getInnerVarName(fun.Name(), "p_err_msg")
+	wantContains(t, plsql,
+		code+" NUMBER;",
+		msg+" VARCHAR2(200);",
+		"  BEGIN\n    DB_web.do_x(",
+		"p_err_code=>"+code+",",
+		"EXCEPTION WHEN OTHERS THEN "+code+" := SQLCODE; "+msg+" := SUBSTRB(SQLERRM, 1, 200);\n  END;",
+	)
+	// bound once, after the call
+	if strings.Contains(plsql, ":4") {
+		t.Errorf("more than 3 binds in\n%s", plsql)
+	}
+	for _, want := range []string{"output.PErrCode", "output.PErrMsg"} {
+		if !strings.Contains(callFun, want) {
+			t.Errorf("%s is not set from the response in\n%s", want, callFun)
+		}
+	}
+	if doc := fun.errorDoc(map[int]string{20404: "NotFound"}); strings.Contains(doc, "NotFound") ||
+		!strings.Contains(doc, "Captured, not errors: any other database error, into p_err_code and p_err_msg.") {
+		t.Errorf("errorDoc: %s", doc)
+	}
+
+	// without the annotation, the errors are raised
+	if fun, err = parse(t, "handle db_web.no_data_found"); err != nil {
+		t.Fatal(err)
+	}
+	if plsql, _ = fun.PlsqlBlock(""); strings.Contains(plsql, "SQLCODE") {
+		t.Errorf("SQLCODE without annotation in\n%s", plsql)
+	} else if !strings.Contains(plsql, "EXCEPTION WHEN NO_DATA_FOUND THEN NULL;\n") {
+		t.Errorf("no handler in\n%s", plsql)
+	}
+
+	for _, bad := range []string{
+		"sqlcode db_web.do_x => p_id",
+		"sqlcode db_web.do_x => p_err_msg",
+		"sqlcode db_web.do_x => p_err_code, p_id",
+		"sqlcode db_web.do_x => p_nope",
+	} {
+		if _, err = parse(t, bad); !errors.Is(err, ErrUnmatchedAnnotation) {
+			t.Errorf("%q: got %v, wanted ErrUnmatchedAnnotation", bad, err)
+		}
+	}
+}
+
+func TestPlsqlBlockDefaulted(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, err := ParseCsv(strings.NewReader(`OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK,DEFAULTED
+1,1,1,DB_WEB,SET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,,N
+1,1,2,DB_WEB,SET_X,0,2,P_NAME,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,,Y
+1,1,3,DB_WEB,SET_X,0,3,P_CODE,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,,Y
+1,1,4,DB_WEB,SET_X,0,4,P_RES,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,,N
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plsql, callFun := functions[0].PlsqlBlock("")
+	wantContains(t, callFun,
+		"if !(input.PName != \"\") {\n\t\t// unset, left out for the DEFAULT to be used\n\t\tomitted[\"p_name\"] = 1\n\t}",
+		"omitted[\"p_code\"] = 2",
+		"qry = oracall.OmitCallArgs(qry, omitted)",
+		"stmt.ExecContext(ctx, oracall.OmitParams(omitted, append(params, ",
+	)
+	if strings.Contains(callFun, `omitted["p_id"]`) || strings.Contains(callFun, `omitted["p_res"]`) {
+		t.Errorf("not defaulted IN argument omitted in\n%s", callFun)
+	}
+
+	for name, tc := range map[string]struct {
+		Omitted map[string]int
+		Call    string
+		Params  []interface{}
+	}{
+		"none":   {Call: "set_x(p_id=>:1,\n\t\tp_name=>:2,\n\t\tp_code=>:3,\n\t\tp_res=>:4);", Params: []interface{}{0, 1, 2, 3}},
+		"middle": {Omitted: map[string]int{"p_name": 1}, Call: "set_x(p_id=>:1,\n\t\tp_code=>:3,\n\t\tp_res=>:4);", Params: []interface{}{0, 2, 3}},
+		"both":   {Omitted: map[string]int{"p_name": 1, "p_code": 2}, Call: "set_x(p_id=>:1,\n\t\tp_res=>:4);", Params: []interface{}{0, 3}},
+		"first":  {Omitted: map[string]int{"p_id": 0}, Call: "set_x(p_name=>:2,\n\t\tp_code=>:3,\n\t\tp_res=>:4);", Params: []interface{}{1, 2, 3}},
+		"last":   {Omitted: map[string]int{"p_res": 3}, Call: "set_x(p_id=>:1,\n\t\tp_name=>:2,\n\t\tp_code=>:3);", Params: []interface{}{0, 1, 2}},
+	} {
+		if got := OmitCallArgs(plsql, tc.Omitted); !strings.Contains(got, tc.Call) {
+			t.Errorf("%s: no %q in\n%s", name, tc.Call, got)
+		}
+		if got := OmitParams(tc.Omitted, 0, 1, 2, 3); fmt.Sprint(got) != fmt.Sprint(tc.Params) {
+			t.Errorf("%s: got params %v, wanted %v", name, got, tc.Params)
+		}
+	}
+
+	var buf strings.Builder
+	if _, err = functions[0].GenChecks(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "if s.PName != \"\" {\n\t\tif len(s.PName) > 100 {"; !strings.Contains(buf.String(), want) {
+		t.Errorf("the check of the defaulted argument is not guarded by %q:\n%s", want, buf.String())
+	}
+}
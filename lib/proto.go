@@ -9,8 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	fstructs "github.com/fatih/structs"
 )
@@ -28,88 +30,312 @@ var NumberAsString bool
 
 // build: protoc --go_out=. --go-grpc_out=. my.proto
 
-func SaveProtobuf(dst io.Writer, functions []Function, pkg, path string) error {
+// ProtoOptions configures SaveProtobuf.
+type ProtoOptions struct {
+	// Schema is the source schema's name.
+	Schema string
+	// Version of oracall.
+	Version string
+	// Query used to extract the arguments (or the source of them), UserArgumentsQuery if empty.
+	Query string
+	// Timestamp of the extraction - left out if zero, for reproducible builds.
+	Timestamp time.Time
+	// MessagesOnly leaves out the service (and its rpcs), emitting only the messages.
+	MessagesOnly bool
+	// ORACodes maps the ORA- error codes to the names of the gRPC codes (see ParseORACode)
+	// the server registers with orasrv.RegisterORACode, to document the errors of each rpc.
+	ORACodes map[int]string
+	// NoErrorDocs leaves out the documentation of the errors of the rpcs.
+	NoErrorDocs bool
+	// Naming names the service, rpcs, messages and fields - DefaultNaming if nil.
+	Naming NamingStrategy
+	// HashFieldNumbers derives the field numbers from the hashes of the field names, instead of their positions,
+	// so reordering, adding or removing arguments does not renumber the other fields (see fieldNumbers).
+	//
+	// Switching an existing .proto to (or from) this renumbers all its fields once,
+	// breaking the wire compatibility with the clients generated from the previous one.
+	HashFieldNumbers bool
+	// ServicePerPackage writes a service for each group of functions (see Function.Group),
+	// instead of one service named after the proto package.
+	// The Go code written by SaveFunctions still implements all the rpcs on one server.
+	ServicePerPackage bool
+	// GogoOptions are the file-level gogoproto options (such as "goproto_getters_all": false),
+	// written after the package declaration, importing gogo.proto.
+	// SaveProtobuf returns an error (wrapping ErrInvalidArgument) for an unknown option.
+	GogoOptions map[string]bool
+	// Edition is the protobuf edition (such as "2023") declared instead of the default syntax = "proto3",
+	// with the features keeping the proto3 semantics (the generated Go code does not change).
+	// SaveProtobuf returns an error (wrapping ErrInvalidArgument) for an unknown edition,
+	// or for one with gogoproto (Gogo or GogoOptions).
+	Edition string
+	// ReserveRenamedFields reserves the previous names of the fields renamed by rename annotations
+	// (reserved "p_old";), so they cannot be reused with another meaning, breaking the JSON clients.
+	ReserveRenamedFields bool
+	// Previous are the field numbers of the previously generated .proto (see ReadProtoFieldNumbers):
+	// the messages with reserved numbers (the retyped fields renumbered, see MarkRetypedArgs)
+	// keep their field numbers and reserved numbers.
+	Previous ProtoFieldNumbers
+}
+
+// writeHeader writes the file-level comment documenting the provenance of the generated file.
+func (opts ProtoOptions) writeHeader(w io.Writer) {
+	version := opts.Version
+	if version == "" {
+		version = "(devel)"
+	}
+	fmt.Fprintf(w, "// Generated by oracall %s.\n", version)
+	if opts.Schema != "" {
+		fmt.Fprintf(w, "// Schema: %s\n", opts.Schema)
+	}
+	if !opts.Timestamp.IsZero() {
+		fmt.Fprintf(w, "// Extracted at: %s\n", opts.Timestamp.Format(time.RFC3339))
+	}
+	qry := opts.Query
+	if qry == "" {
+		qry = UserArgumentsQuery
+	}
+	io.WriteString(w, "//\n// Extraction query:")
+	io.WriteString(w, strings.ReplaceAll("\n"+strings.TrimSpace(qry), "\n", "\n//   "))
+	io.WriteString(w, "\n\n")
+}
+
+// imports returns the files to be imported by the .proto of the functions, deduplicated and sorted,
+// to keep the diffs of the generated file stable (and satisfy the import ordering lint of Buf).
+func (opts ProtoOptions) imports(functions []Function) []string {
+	imports := map[string]struct{}{"google/protobuf/timestamp.proto": {}}
+	if NullableWrappers && !Gogo {
+		imports["google/protobuf/wrappers.proto"] = struct{}{}
+	}
+	if Envelope {
+		imports["google/protobuf/duration.proto"] = struct{}{}
+	}
+	if !opts.MessagesOnly {
+		for _, fun := range functions {
+			if fun.usesEmpty() {
+				imports["google/protobuf/empty.proto"] = struct{}{}
+				break
+			}
+		}
+	}
+	if Gogo || len(opts.GogoOptions) != 0 {
+		imports["github.com/gogo/protobuf/gogoproto/gogo.proto"] = struct{}{}
+	}
+	for _, imp := range mappedProtoImports(functions) {
+		imports[imp] = struct{}{}
+	}
+	names := make([]string, 0, len(imports))
+	for imp := range imports {
+		names = append(names, imp)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func SaveProtobuf(dst io.Writer, functions []Function, pkg, path string, opts ProtoOptions) error {
 	var err error
 	w := errWriter{Writer: dst, err: &err}
+	naming := opts.Naming
+	if naming == nil {
+		naming = DefaultNaming{}
+	}
+	if err = opts.checkGogoOptions(); err != nil {
+		return err
+	}
+	if err = opts.checkEdition(); err != nil {
+		return err
+	}
 
-	io.WriteString(w, `syntax = "proto3";`+"\n\n")
+	opts.writeHeader(w)
+	opts.writeSyntax(w)
 
 	if pkg != "" {
-		fmt.Fprintf(w, `package %s;
-option go_package = %q;`, pkg, path)
+		fmt.Fprintf(w, "package %s;\n\n", pkg)
 	}
-	io.WriteString(w, "\nimport \"google/protobuf/timestamp.proto\";\n")
-
-	if Gogo {
-		io.WriteString(w, "\nimport \"github.com/gogo/protobuf/gogoproto/gogo.proto\";\n")
+	for _, imp := range opts.imports(functions) {
+		fmt.Fprintf(w, "import %q;\n", imp)
+	}
+	if pkg != "" {
+		fmt.Fprintf(w, "\noption go_package = %q;\n", path)
+	}
+	if opts.Edition != "" {
+		opts.writeFeatures(w)
+	}
+	if len(opts.GogoOptions) != 0 {
+		opts.writeGogoOptions(w)
 	}
 	seen := make(map[string]struct{}, 16)
+	if Envelope {
+		io.WriteString(w, envelopeMetaProto)
+	}
 
-	services := make([]string, 0, len(functions))
+	services := make(map[string][]string)
 
 FunLoop:
 	for _, fun := range functions {
 		//b, _ := json.Marshal(struct{Name, Documentation string}{Name:fun.Name(), Documentation:fun.Documentation})
 		//fmt.Println(string(b))
-		fName := fun.name
-		if fun.alias != "" {
-			fName = fun.alias
+		// the rpc and message names all derive from it
+		fun.normName = fun.protoName()
+		if err := fun.checkDepth(); err != nil {
+			return err
 		}
-		fName = strings.ToLower(fName)
-		if err := fun.SaveProtobuf(w, seen); err != nil {
+		if err := fun.saveProtobuf(w, seen, naming, opts); err != nil {
 			if SkipMissingTableOf && (errors.Is(err, ErrMissingTableOf) ||
 				errors.Is(err, ErrUnknownSimpleType)) {
-				logger.Info("SKIP function, missing TableOf info", "function", fName)
+				logger.Info("SKIP function, missing TableOf info", "function", strings.ToLower(fun.aliasOrName()))
 				continue FunLoop
 			}
 			return fmt.Errorf("%s: %w", fun.name, err)
 		}
+		if fun.useEnvelope() {
+			if err := fun.writeEnvelope(w, naming); err != nil {
+				return err
+			}
+		}
+		if fun.paginated() {
+			if err := fun.writePageMessages(w, naming); err != nil {
+				return err
+			}
+		}
+		if opts.MessagesOnly {
+			continue
+		}
+		rpc := fun.protoRPC(naming, pkg, opts)
 		var streamQual string
-		if fun.HasCursorOut() {
+		if rpc.Stream {
 			streamQual = "stream "
 		}
-		name := CamelCase(dot2D.Replace(fName))
 		var comment string
 		if fun.Documentation != "" {
 			comment = asComment(fun.Documentation, "")
 		}
-		services = append(services,
-			fmt.Sprintf(`%srpc %s (%s) returns (%s%s) {}`,
+		if !opts.NoErrorDocs {
+			if comment == "" {
+				comment = asComment(fun.errorDoc(opts.ORACodes), "")
+			} else {
+				comment = asComment(fun.Documentation+"\n\n"+fun.errorDoc(opts.ORACodes), "")
+			}
+		}
+		body := "{}"
+		if fun.deprecated {
+			body = "{\n\t\toption deprecated = true;\n\t}"
+		}
+		services[rpc.Group] = append(services[rpc.Group],
+			fmt.Sprintf(`%srpc %s (%s) returns (%s%s) %s`,
 				comment,
-				name,
-				CamelCase(fun.getStructName(false, false)),
+				rpc.Name,
+				rpc.In,
 				streamQual,
-				CamelCase(fun.getStructName(true, false)),
+				rpc.Out,
+				body,
 			),
 		)
+		if fun.paginated() {
+			page := fun.pageRPC(rpc, naming)
+			services[rpc.Group] = append(services[rpc.Group],
+				fmt.Sprintf(`// %s returns a page of the rows of %s.
+	rpc %s (%s) returns (%s) %s`,
+					page.Name, rpc.Name,
+					page.Name,
+					page.In,
+					page.Out,
+					body,
+				),
+			)
+		}
 	}
 
-	fmt.Fprintf(w, "\nservice %s {\n", CamelCase(pkg))
-	for _, s := range services {
-		fmt.Fprintf(w, "\t%s\n", s)
+	if opts.MessagesOnly {
+		return err
+	}
+	groups := make([]string, 0, len(services))
+	for group := range services {
+		groups = append(groups, group)
+	}
+	if len(groups) == 0 {
+		groups = append(groups, pkg)
+	}
+	sort.Strings(groups)
+	for i, group := range groups {
+		if i != 0 {
+			io.WriteString(w, "\n")
+		}
+		fmt.Fprintf(w, "\nservice %s {\n", naming.ServiceName(group))
+		for _, s := range services[group] {
+			fmt.Fprintf(w, "\t%s\n", s)
+		}
+		w.Write([]byte("}"))
 	}
-	w.Write([]byte("}"))
 
 	return nil
 }
 
+// protoRPC is an rpc of a service written by SaveProtobuf.
+type protoRPC struct {
+	// Group is the service's (see ServiceName) group.
+	Group string
+	// Name of the rpc, In and Out the names of its request and response messages.
+	Name, In, Out string
+	// Stream is true for the rpc streaming its responses.
+	Stream bool
+}
+
+// protoRPC returns the rpc of the function written by SaveProtobuf into the pkg package.
+func (fun Function) protoRPC(naming NamingStrategy, pkg string, opts ProtoOptions) protoRPC {
+	rpc := protoRPC{
+		Group: pkg, Name: naming.RPCName(fun),
+		In: naming.MessageName(fun, false), Out: naming.MessageName(fun, true),
+		Stream: fun.HasCursorOut(),
+	}
+	if fun.usesEmpty() {
+		rpc.In, rpc.Out = "google.protobuf.Empty", "google.protobuf.Empty"
+	}
+	if fun.useEnvelope() {
+		rpc.Out = fun.envelopeName(naming)
+	}
+	if opts.ServicePerPackage {
+		rpc.Group = strings.ToLower(fun.Group())
+	}
+	return rpc
+}
+
+// usesEmpty reports whether the function has neither arguments nor return value,
+// so google.protobuf.Empty is used for its request and response.
+func (f Function) usesEmpty() bool { return !Gogo && len(f.Args) == 0 && f.Returns == nil }
+
+// SaveProtobuf writes the input and output messages of the function - nothing if it usesEmpty.
 func (f Function) SaveProtobuf(dst io.Writer, seen map[string]struct{}) error {
-	var buf bytes.Buffer
-	if err := f.saveProtobufDir(&buf, seen, false); err != nil {
+	return f.saveProtobuf(dst, seen, DefaultNaming{}, ProtoOptions{})
+}
+func (f Function) saveProtobuf(dst io.Writer, seen map[string]struct{}, naming NamingStrategy, opts ProtoOptions) error {
+	if f.usesEmpty() {
+		return nil
+	}
+	buf := Buffers.Get()
+	defer Buffers.Put(buf)
+	// the arguments of both directions
+	args := make([]Argument, 0, len(f.Args)+1)
+	// the field numbers of the IN OUT arguments in the input, for the output
+	inOut := make(map[string]int)
+	if err := f.saveProtobufDir(buf, args, seen, inOut, naming, opts, false); err != nil {
 		return fmt.Errorf("%s: %w", "input", err)
 	}
-	if err := f.saveProtobufDir(&buf, seen, true); err != nil {
+	if err := f.saveProtobufDir(buf, args, seen, inOut, naming, opts, true); err != nil {
 		return fmt.Errorf("%s: %w", "output", err)
 	}
 	_, err := dst.Write(buf.Bytes())
 	return err
 }
-func (f Function) saveProtobufDir(dst io.Writer, seen map[string]struct{}, out bool) error {
-	dirmap, dirname := DIR_IN, "input"
+
+// saveProtobufDir writes the input (or, if out, the output) message of the function,
+// collecting its arguments into args (reused from its start).
+// The IN OUT arguments get the same field numbers in the output as in the input, recorded in inOut.
+func (f Function) saveProtobufDir(dst io.Writer, args []Argument, seen map[string]struct{}, inOut map[string]int, naming NamingStrategy, opts ProtoOptions, out bool) error {
+	dirmap := DIR_IN
 	if out {
-		dirmap, dirname = DIR_OUT, "output"
+		dirmap = DIR_OUT
 	}
-	args := make([]Argument, 0, len(f.Args)+1)
+	args = args[:0]
 	for _, arg := range f.Args {
 		if arg.Direction&dirmap > 0 {
 			args = append(args, arg)
@@ -120,18 +346,36 @@ func (f Function) saveProtobufDir(dst io.Writer, seen map[string]struct{}, out b
 		args = append(args, *f.Returns)
 	}
 
-	nm := f.name
-	if f.alias != "" {
-		nm = f.alias
+	D := getDirDoc(f.Documentation, dirmap)
+	// the IN OUT arguments may be documented for one direction only
+	other := getDirDoc(f.Documentation, DIR_INOUT^dirmap)
+	for _, arg := range args {
+		if nm := arg.Name; arg.Direction == DIR_INOUT && D.Map[nm] == "" && other.Map[nm] != "" {
+			if D.Map == nil {
+				D.Map = make(map[string]string)
+			}
+			D.Map[nm] = other.Map[nm]
+		}
 	}
-	return protoWriteMessageTyp(dst,
-		CamelCase(dot2D.Replace(strings.ToLower(nm))+"__"+dirname),
-		seen, getDirDoc(f.Documentation, dirmap), args...)
+	return protoWriteMessageTyp(dst, naming, opts, naming.MessageName(f, out),
+		seen, inOut, D, true, f.deprecated, args...)
 }
 
 var dot2D = strings.NewReplacer(".", "__")
 
-func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{}, D argDocs, args ...Argument) error {
+// ErrDuplicateField is returned by SaveProtobuf if two fields of a message get the same name.
+var ErrDuplicateField = errors.New("duplicate field name")
+
+// protoWriteMessageTyp writes the message for the args, and the messages of its record/table args.
+// The simple args are wrapped (see NullableWrappers) only if wrap is true.
+// The fields are numbered by their names' hashes if opts.HashFieldNumbers is true (see fieldNumbers).
+// The previous names of the renamed args are reserved if opts.ReserveRenamedFields is true,
+// the previous numbers of the retyped args (see MarkRetypedArgs) always.
+// The top-level (wrap) nested table args get a bool null_<field> field, too (see nullFieldName).
+// The message (and the fields of the deprecated args) get the deprecated option if deprecated is true.
+// The IN OUT args found in inOut (if not nil) get the field number recorded there, the others are recorded
+// (see pinInOutNumbers).
+func protoWriteMessageTyp(dst io.Writer, naming NamingStrategy, opts ProtoOptions, msgName string, seen map[string]struct{}, inOut map[string]int, D argDocs, wrap, deprecated bool, args ...Argument) error {
 	for _, arg := range args {
 		if arg.Flavor == FLAVOR_TABLE && arg.TableOf == nil {
 			return fmt.Errorf("no table of data for %s.%s (%v): %w", msgName, arg, arg, ErrMissingTableOf)
@@ -141,9 +385,73 @@ func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{
 	var err error
 	w := &errWriter{Writer: dst, err: &err}
 	fmt.Fprintf(w, "%smessage %s {\n", asComment(strings.TrimRight(D.Pre+D.Post, " \n\t"), ""), msgName)
+	if deprecated {
+		io.WriteString(w, "\toption deprecated = true;\n")
+	}
+
+	// the mangled names (hidden, renamed) must be unique, as protoc rejects the duplicate field (and JSON) names
+	names := make([]string, len(args))
+	// the explicit json_names (see JSONNamer)
+	jsonNames := make([]string, len(args))
+	byJSONName := make(map[string]int, len(args))
+	for i, arg := range args {
+		if strings.HasSuffix(arg.Name, "#") {
+			arg.Name = replHidden(arg.Name)
+		}
+		names[i] = naming.FieldName(arg)
+		jsonNames[i] = explicitJSONName(naming, arg)
+		jsonName := jsonNames[i]
+		if jsonName == "" {
+			jsonName = protoJSONName(names[i])
+		}
+		if j, ok := byJSONName[jsonName]; ok {
+			return fmt.Errorf("%s: the fields of %s and %s (%s, %s) are both %s in JSON - rename one of them with a rename annotation: %w",
+				msgName, args[j].Name, args[i].Name, names[j], names[i], jsonName, ErrDuplicateField)
+		}
+		byJSONName[jsonName] = i
+	}
+	// a nullable (nested table) collection has a null_<field> flag, too, after the arguments,
+	// as a repeated field cannot tell the NULL collection from the empty one
+	nullIdx := make(map[int]int)
+	if wrap {
+		for i, arg := range args {
+			if !arg.IsNestedTable() {
+				continue
+			}
+			nullIdx[i] = len(names)
+			names = append(names, nullFieldName(names[i]))
+			jsonName := protoJSONName(names[nullIdx[i]])
+			if j, ok := byJSONName[jsonName]; ok {
+				return fmt.Errorf("%s: the fields of %s and the NULL flag of %s (%s, %s) are both %s in JSON - rename one of them with a rename annotation: %w",
+					msgName, args[j].Name, arg.Name, names[j], names[nullIdx[i]], jsonName, ErrDuplicateField)
+			}
+			byJSONName[jsonName] = i
+		}
+	}
+	nums := fieldNumbers(names, opts.HashFieldNumbers)
+	prev := opts.Previous[msgName]
+	var done []bool
+	if len(prev.Reserved) != 0 {
+		types := make([]string, len(names))
+		for i, arg := range args {
+			types[i] = simpleProtoType(arg, wrap)
+		}
+		done = keepPreviousNumbers(names, types, nums, prev)
+	}
+	reservedNums := renumberRetyped(args, nums, prev, done)
+	if len(reservedNums) != 0 {
+		fmt.Fprintf(w, "\treserved %s;\n", strings.Join(reservedNums, ", "))
+	}
+	pinInOutNumbers(args, names, nums, reservedNums, inOut)
+	if opts.ReserveRenamedFields {
+		if reserved := reservedNames(naming, names, args); len(reserved) != 0 {
+			fmt.Fprintf(w, "\treserved %s;\n", strings.Join(reserved, ", "))
+		}
+	}
 
 	buf := Buffers.Get()
 	defer Buffers.Put(buf)
+	var line []byte
 	for i, arg := range args {
 		var rule string
 		if strings.HasSuffix(arg.Name, "#") {
@@ -161,21 +469,49 @@ func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{
 			return fmt.Errorf("%s: %w", msgName, err)
 		}
 		got = strings.TrimPrefix(got, "*")
-		if strings.HasPrefix(got, "[]") {
+		var isMap bool
+		if strings.HasPrefix(got, "[]") && got != "[]byte" {
 			rule = "repeated "
 			got = got[2:]
+		} else if got, isMap = strings.CutPrefix(got, "map[string]"); isMap {
+			rule = ""
 		}
 		got = strings.TrimPrefix(got, "*")
 		if got == "" {
 			got = mkRecTypName(arg.Name)
 		}
-		typ, pOpts := protoType(got, arg.Name, arg.AbsType)
+		typ, pOpts := arg.mappedProtoType(got)
+		if isMap {
+			typ = "map<string, " + typ + ">"
+		}
+		if wrap && rule == "" {
+			if pw, ok := arg.protoWrapper(); ok {
+				typ, pOpts = pw.Message, nil
+			}
+		}
+		if arg.deprecated {
+			pOpts = pOpts.with("deprecated", true)
+		}
+		if jsonNames[i] != "" {
+			pOpts = pOpts.with("json_name", jsonNames[i])
+		}
 		var optS string
 		if s := pOpts.String(); s != "" {
 			optS = " " + s
 		}
+		doc := D.Map[aName]
+		if doc == "" {
+			doc = arg.Description
+		}
+		// an IN OUT argument is the same field (name and type) in the request and the response
+		var inOut string
+		if wrap && arg.Direction == DIR_INOUT {
+			inOut = "IN OUT: sent in the request, and returned (maybe changed) in the response."
+		}
 		if arg.Flavor == FLAVOR_SIMPLE || arg.Flavor == FLAVOR_TABLE && arg.TableOf.Flavor == FLAVOR_SIMPLE {
-			fmt.Fprintf(w, "%s\t// %s\n\t%s%s %s = %d%s;\n", asComment(D.Map[aName], "\t"), arg.AbsType, rule, typ, aName, i+1, optS)
+			line = append(append(line[:0], asComment(joinDoc(doc, inOut, arg.exampleDoc()), "\t")...), "\t// "...)
+			line = appendProtoField(append(append(line, arg.AbsType...), '\n'), rule, typ, names[i], nums[i], optS)
+			w.Write(line)
 			continue
 		}
 		typ = CamelCase(strings.Replace(strings.ToUpper(typ), "%ROWTYPE", "_rt", 1))
@@ -196,12 +532,21 @@ func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{
 					}
 				}
 			}
-			if err = protoWriteMessageTyp(buf, typ, seen, argDocs{Pre: D.Map[aName]}, subArgs...); err != nil {
+			if err = protoWriteMessageTyp(buf, naming, opts, typ, seen, nil, argDocs{Pre: D.Map[aName]}, false, false, subArgs...); err != nil {
 				logger.Error("protoWriteMessageTyp", "error", err)
 				return err
 			}
 		}
-		fmt.Fprintf(w, "\t%s%s %s = %d%s;\n", rule, typ, aName, i+1, optS)
+		if desc := joinDoc(arg.Description, inOut); desc != "" {
+			io.WriteString(w, asComment(desc, "\t"))
+		}
+		line = appendProtoField(line[:0], rule, typ, names[i], nums[i], optS)
+		w.Write(line)
+	}
+	for i := range args {
+		if j, ok := nullIdx[i]; ok {
+			fmt.Fprintf(w, "\t// %s is NULL (not just empty).\n\tbool %s = %d;\n", names[i], names[j], nums[j])
+		}
 	}
 	io.WriteString(w, "}\n")
 	w.Write(buf.Bytes())
@@ -209,6 +554,61 @@ func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{
 	return err
 }
 
+// reservedNames returns the quoted previous field names of the renamed args,
+// which are not the names of other fields of the message.
+func reservedNames(naming NamingStrategy, names []string, args []Argument) []string {
+	var reserved []string
+	seen := make(map[string]struct{}, len(names))
+	for _, nm := range names {
+		seen[nm] = struct{}{}
+	}
+	for _, arg := range args {
+		for _, prev := range arg.renamedFrom {
+			old := arg
+			old.Name = replHidden(prev)
+			nm := naming.FieldName(old)
+			if _, ok := seen[nm]; ok {
+				continue
+			}
+			seen[nm] = struct{}{}
+			reserved = append(reserved, strconv.Quote(nm))
+		}
+	}
+	return reserved
+}
+
+// simpleProtoType returns the type of the field of the simple argument, empty for the others.
+func simpleProtoType(arg Argument, wrap bool) string {
+	if arg.Flavor != FLAVOR_SIMPLE {
+		return ""
+	}
+	got, err := arg.goType(false)
+	if err != nil {
+		return ""
+	}
+	if wrap {
+		if pw, ok := arg.protoWrapper(); ok {
+			return pw.Message
+		}
+	}
+	typ, _ := arg.mappedProtoType(strings.TrimPrefix(got, "*"))
+	return typ
+}
+
+// appendProtoField appends the "\t<rule><typ> <name> = <num><opts>;\n" field line to dst,
+// without the allocations of fmt, as it is written for each field of each message.
+func appendProtoField(dst []byte, rule, typ, name string, num int, opts string) []byte {
+	dst = append(append(append(append(append(dst, '\t'), rule...), typ...), ' '), name...)
+	dst = strconv.AppendInt(append(dst, " = "...), int64(num), 10)
+	return append(append(dst, opts...), ";\n"...)
+}
+
+// nullFieldName returns the name of the field telling that the nested table of the field is NULL.
+//
+// A nested table can be NULL (or empty), which a repeated field cannot tell apart,
+// so the request sets this flag to pass NULL, and the response sets it for a NULL collection.
+func nullFieldName(field string) string { return "null_" + field }
+
 func protoType(got, aName, absType string) (string, protoOptions) {
 	switch trimmed := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(got, "[]"), "*")); trimmed {
 	case "bool", "string":
@@ -247,7 +647,7 @@ func protoType(got, aName, absType string) (string, protoOptions) {
 		return "double", nil
 
 	case "godror.number", "n":
-		if i := strings.IndexByte(absType, '('); i >= 0 && absType[len(absType)-1] == ')' {
+		if i := strings.IndexByte(absType, '('); i >= 0 && absType[len(absType)-1] == ')' && !NumberAsDecimal {
 			if strings.HasPrefix(absType, "INTEGER(") || strings.HasPrefix(absType, "NUMBER(") {
 				s := absType[i+1 : len(absType)-1]
 				if !strings.ContainsRune(s, ',') {
@@ -272,15 +672,20 @@ func protoType(got, aName, absType string) (string, protoOptions) {
 
 	case "custom.date", "time.time":
 		if Gogo {
+			customType := "github.com/tgulacsi/oracall/custom.DateTime"
+			if absType == "TIMESTAMP" {
+				// keeps the fractional seconds
+				customType = "github.com/tgulacsi/oracall/custom.Timestamp"
+			}
 			return "google.protobuf.Timestamp", protoOptions{
 				//"gogoproto.stdtime":    true,
-				"gogoproto.customtype": "github.com/tgulacsi/oracall/custom.DateTime",
+				"gogoproto.customtype": customType,
 				"gogoproto.moretags":   `xml:",omitempty"`,
 			}
 		}
 		return "google.protobuf.Timestamp", nil
 
-	case "raw":
+	case "raw", "byte":
 		return "bytes", nil
 
 	case "godror.lob", "ora.lob":
@@ -303,13 +708,24 @@ func (opts protoOptions) String() string {
 	if len(opts) == 0 {
 		return ""
 	}
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	var buf bytes.Buffer
 	buf.WriteByte('[')
-	for k, v := range opts {
+	for _, k := range keys {
+		v := opts[k]
 		if buf.Len() != 1 {
 			buf.WriteString(", ")
 		}
-		fmt.Fprintf(&buf, "(%s)=", k)
+		// the custom options are qualified with their package, and must be parenthesized
+		if strings.IndexByte(k, '.') >= 0 {
+			fmt.Fprintf(&buf, "(%s)=", k)
+		} else {
+			fmt.Fprintf(&buf, "%s=", k)
+		}
 		switch v.(type) {
 		case bool:
 			fmt.Fprintf(&buf, "%t", v)
@@ -321,6 +737,16 @@ func (opts protoOptions) String() string {
 	return buf.String()
 }
 
+// with returns a copy of the options, with the option k set to v.
+func (opts protoOptions) with(k string, v interface{}) protoOptions {
+	m := make(protoOptions, len(opts)+1)
+	for k, v := range opts {
+		m[k] = v
+	}
+	m[k] = v
+	return m
+}
+
 func CopyStruct(dest interface{}, src interface{}) error {
 	ds := fstructs.New(dest)
 	ss := fstructs.New(src)
@@ -341,6 +767,17 @@ func CopyStruct(dest interface{}, src interface{}) error {
 }
 func mkRecTypName(name string) string { return strings.ToLower(name) + "_rek_typ" }
 
+// joinDoc joins the non-empty docs with newlines.
+func joinDoc(docs ...string) string {
+	nonEmpty := docs[:0:0]
+	for _, doc := range docs {
+		if doc != "" {
+			nonEmpty = append(nonEmpty, doc)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
 func asComment(s, prefix string) string {
 	return "\n" + prefix + "// " + strings.Replace(s, "\n", "\n"+prefix+"// ", -1) + "\n"
 }
@@ -7,10 +7,14 @@ package oracall
 import (
 	"bufio"
 	"bytes"
+	"database/sql"
 	"encoding/csv"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +24,8 @@ import (
 
 // UserArgument represents the required info from the user_arguments view
 type UserArgument struct {
+	// Owner is the schema of the package, only in all_arguments.
+	Owner       string `sql:"OWNER"`
 	PackageName string `sql:"PACKAGE_NAME"`
 	ObjectName  string `sql:"OBJECT_NAME"`
 	LastDDL     time.Time
@@ -31,6 +37,8 @@ type UserArgument struct {
 
 	CharacterSetName string `sql:"CHARACTER_SET_NAME"`
 	IndexBy          string `sql:"INDEX_BY"`
+	// CharUsed is "C" if CharLength is in characters, "B" if in bytes, empty if unknown.
+	CharUsed string `sql:"CHAR_USED"`
 
 	PlsType     string `sql:"PLS_TYPE"`
 	TypeLink    string `sql:"TYPE_LINK"`
@@ -42,23 +50,39 @@ type UserArgument struct {
 	SubprogramID uint `sql:"SUBPROGRAM_ID"`
 
 	CharLength uint `sql:"CHAR_LENGTH"`
-	Position   uint `sql:"POSITION"`
+	// Position is the SEQUENCE of the argument, ordering the rows of the subprogram
+	// (see ArgumentPosition for the POSITION).
+	Position uint `sql:"SEQUENCE"`
+	// ArgumentPosition is the POSITION of the argument, 0 for the function's return value.
+	// Not Valid if unknown.
+	ArgumentPosition sql.NullInt32 `sql:"POSITION"`
 
 	DataPrecision uint8 `sql:"DATA_PRECISION"`
 	DataScale     uint8 `sql:"DATA_SCALE"`
 	DataLevel     uint8 `sql:"DATA_LEVEL"`
+
+	// Defaulted is true if the argument has a DEFAULT value, so it can be omitted.
+	Defaulted bool `sql:"DEFAULTED"`
+}
+
+// fullName returns PACKAGE.OBJECT, or just OBJECT for the standalone procedures and functions.
+func (ua UserArgument) fullName() string {
+	if ua.PackageName == "" {
+		return ua.ObjectName
+	}
+	return ua.PackageName + "." + ua.ObjectName
 }
 
-// ParseCsv reads the given csv file as user_arguments
-// The csv should be an export of
-/*
-   SELECT object_id, subprogram_id, package_name, sequence, object_name,
-          data_level, argument_name, in_out,
-          data_type, data_precision, data_scale, character_set_name,
-          pls_type, char_length, type_owner, type_name, type_subname, type_link
-     FROM user_arguments
-     ORDER BY object_id, subprogram_id, SEQUENCE;
-*/
+// UserArgumentsQuery is the canonical query for extracting the function arguments.
+const UserArgumentsQuery = `SELECT object_id, subprogram_id, package_name, sequence, object_name,
+       data_level, position, argument_name, in_out,
+       data_type, data_precision, data_scale, character_set_name,
+       pls_type, char_length, type_owner, type_name, type_subname, type_link, defaulted, char_used
+  FROM user_arguments
+  ORDER BY object_id, subprogram_id, SEQUENCE`
+
+// ParseCsvFile reads the given csv file as user_arguments
+// The csv should be an export of UserArgumentsQuery.
 func ParseCsvFile(filename string, filter func(string) bool) (functions []Function, err error) {
 	fh, err := OpenCsv(filename)
 	if err != nil {
@@ -70,30 +94,69 @@ func ParseCsvFile(filename string, filter func(string) bool) (functions []Functi
 
 // ParseCsv parses the csv
 func ParseCsv(r io.Reader, filter func(string) bool) (functions []Function, err error) {
+	functions, _, err = ParseCsvStats(r, filter)
+	return functions, err
+}
+
+// ParseStats counts what ParseCsvStats (or FilterAndGroup and ParseArguments) processed.
+type ParseStats struct {
+	// Rows is the number of the user_arguments rows read.
+	Rows int
+	// Functions is the number of the functions parsed.
+	Functions int
+	// Hidden is the number of the hidden (# suffixed) functions skipped.
+	Hidden int
+	// Filtered is the number of the functions dropped by the filter.
+	Filtered int
+	// MissingTableOf is the number of the parsed functions with a table (or REF CURSOR) argument
+	// without its TableOf info, which are skipped by the generators if SkipMissingTableOf is set.
+	MissingTableOf int
+	// CursorInput is the number of the functions skipped for their REF CURSOR input argument (see ErrCursorInput).
+	CursorInput int
+	// TooDeep is the number of the functions skipped for their arguments nested deeper than MaxNestingDepth.
+	TooDeep int
+}
+
+// ParseCsvStats parses the csv as ParseCsv, and returns the statistics of the parsing, too.
+func ParseCsvStats(r io.Reader, filter func(string) bool) ([]Function, ParseStats, error) {
+	r = withReadTimeout(r, CsvReadTimeout)
 	userArgs := make(chan UserArgument, 16)
 	var grp errgroup.Group
 	grp.Go(func() error { return ReadCsv(userArgs, r) })
 	filteredArgs := make(chan []UserArgument, 16)
-	grp.Go(func() error { FilterAndGroup(filteredArgs, userArgs, filter); return nil })
-	functions = ParseArguments(filteredArgs, filter)
-	return functions, grp.Wait()
+	var stats ParseStats
+	grp.Go(func() error { FilterAndGroup(filteredArgs, userArgs, filter, &stats); return nil })
+	functions := ParseArguments(filteredArgs, filter, &stats)
+	err := grp.Wait()
+	return functions, stats, err
 }
 
-func FilterAndGroup(filteredArgs chan<- []UserArgument, userArgs <-chan UserArgument, filter func(string) bool) {
+// FilterAndGroup groups the user arguments by functions, dropping the ones not passing the filter.
+//
+// It counts the Rows and the Filtered functions in stats, if it is not nil.
+func FilterAndGroup(filteredArgs chan<- []UserArgument, userArgs <-chan UserArgument, filter func(string) bool, stats *ParseStats) {
 	defer close(filteredArgs)
+	if stats == nil {
+		stats = new(ParseStats)
+	}
 	type program struct {
 		PackageName, ObjectName string
 		ObjectID, SubprogramID  uint
 	}
-	var lastProg, zeroProg program
+	var lastProg, lastFiltered, zeroProg program
 	args := make([]UserArgument, 0, 4)
 	for ua := range userArgs {
-		if filter != nil && !filter(ua.PackageName+"."+ua.ObjectName) {
-			continue
-		}
+		stats.Rows++
 		actProg := program{
 			ObjectID: ua.ObjectID, SubprogramID: ua.SubprogramID,
 			PackageName: ua.PackageName, ObjectName: ua.ObjectName}
+		if filter != nil && !filter(ua.fullName()) {
+			if actProg != lastFiltered {
+				stats.Filtered++
+				lastFiltered = actProg
+			}
+			continue
+		}
 		if lastProg != zeroProg && lastProg != actProg {
 			if len(args) != 0 {
 				filteredArgs <- args
@@ -130,6 +193,80 @@ func MustOpenCsv(filename string) *os.File {
 	return fh
 }
 
+// CsvDelimiter is the field delimiter of the csv read by ReadCsv.
+// If zero, it is detected from the header line: the most frequent of ',', ';' and tab.
+var CsvDelimiter rune
+
+// CsvReadTimeout makes ParseCsv fail with ErrReadTimeout if no data arrives
+// for this long from a pipe or terminal (such as stdin).
+// Regular files are not timed out, and zero means no timeout.
+var CsvReadTimeout time.Duration
+
+// ErrReadTimeout is returned when the csv input stalls for CsvReadTimeout.
+var ErrReadTimeout = errors.New("read timeout")
+
+// withReadTimeout returns r wrapped in a timeoutReader, unless timeout is zero or r is a regular file.
+func withReadTimeout(r io.Reader, timeout time.Duration) io.Reader {
+	if timeout <= 0 {
+		return r
+	}
+	if fh, ok := r.(*os.File); ok {
+		if fi, err := fh.Stat(); err == nil && fi.Mode().IsRegular() {
+			return r
+		}
+	}
+	return &timeoutReader{r: r, timeout: timeout}
+}
+
+// timeoutReader returns ErrReadTimeout if a Read of the underlying reader does not return in time.
+//
+// The stalled Read is left running in the background, so the reader is unusable after the first timeout.
+type timeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+	err     error
+}
+
+func (tr *timeoutReader) Read(p []byte) (int, error) {
+	if tr.err != nil {
+		return 0, tr.err
+	}
+	type result struct {
+		b   []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		// read into a separate buffer, p may be reused after a timeout
+		b := make([]byte, len(p))
+		n, err := tr.r.Read(b)
+		ch <- result{b: b[:n], err: err}
+	}()
+	timer := time.NewTimer(tr.timeout)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		return copy(p, res.b), res.err
+	case <-timer.C:
+		tr.err = fmt.Errorf("no data in %s: %w", tr.timeout, ErrReadTimeout)
+		return 0, tr.err
+	}
+}
+
+// detectDelimiter returns the most frequent candidate delimiter in the first line of b.
+func detectDelimiter(b []byte) rune {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[:i]
+	}
+	delim, max := ',', bytes.Count(b, []byte{','})
+	for _, c := range []rune{';', '\t'} {
+		if n := bytes.Count(b, []byte{byte(c)}); n > max {
+			delim, max = c, n
+		}
+	}
+	return delim
+}
+
 // ReadCsv reads the csv from the Reader, and sends the arguments to the given channel.
 func ReadCsv(userArgs chan<- UserArgument, r io.Reader) error {
 	defer close(userArgs)
@@ -138,29 +275,30 @@ func ReadCsv(userArgs chan<- UserArgument, r io.Reader) error {
 
 	br := bufio.NewReader(r)
 	csvr := csv.NewReader(br)
-	b, err := br.Peek(100)
-	if err != nil {
-		return fmt.Errorf("error peeking into file: %s", err)
-	}
-	if bytes.IndexByte(b, ';') >= 0 {
-		csvr.Comma = ';'
+	if csvr.Comma = CsvDelimiter; csvr.Comma == 0 {
+		b, err := br.Peek(br.Size())
+		if len(b) == 0 {
+			return fmt.Errorf("error peeking into file: %w", err)
+		}
+		csvr.Comma = detectDelimiter(b)
 	}
-	csvr.LazyQuotes, csvr.TrimLeadingSpace = true, true
+	// leading white space trimming would eat the empty fields of a TSV
+	csvr.LazyQuotes, csvr.TrimLeadingSpace = true, csvr.Comma != '\t'
 	csvr.ReuseRecord = true
 	var (
 		rec       []string
 		csvFields = make(map[string]int, 20)
 	)
-	for _, h := range []string{"OBJECT_ID", "SUBPROGRAM_ID", "PACKAGE_NAME",
+	for _, h := range []string{"OBJECT_ID", "SUBPROGRAM_ID", "OWNER", "PACKAGE_NAME",
 		"OBJECT_NAME", "DATA_LEVEL", "SEQUENCE", "ARGUMENT_NAME", "IN_OUT",
 		"DATA_TYPE", "DATA_PRECISION", "DATA_SCALE", "CHARACTER_SET_NAME",
-		"INDEX_BY", "PLS_TYPE", "CHAR_LENGTH",
+		"INDEX_BY", "PLS_TYPE", "CHAR_LENGTH", "POSITION", "DEFAULTED", "CHAR_USED",
 		"TYPE_LINK", "TYPE_OWNER", "TYPE_NAME", "TYPE_SUBNAME"} {
 		csvFields[h] = -1
 	}
 	// get head
 	if rec, err = csvr.Read(); err != nil {
-		return fmt.Errorf("cannot read head: %s", err)
+		return fmt.Errorf("cannot read head: %w", err)
 	}
 	csvr.FieldsPerRecord = len(rec)
 	for i, h := range rec {
@@ -170,6 +308,29 @@ func ReadCsv(userArgs chan<- UserArgument, r io.Reader) error {
 		}
 	}
 	logger.Info("field order", "fields", csvFields)
+	// get returns the named field of the record, or "" if the column is missing (as in older exports).
+	get := func(name string) string {
+		if i := csvFields[name]; i >= 0 {
+			return rec[i]
+		}
+		return ""
+	}
+
+	// the first malformed numeric field of the record
+	var fieldErr error
+	// getUint returns the named numeric field of the record, or 0 if it is empty.
+	getUint := func(name string, bitSize int) uint64 {
+		text := get(name)
+		if text == "" || fieldErr != nil {
+			return 0
+		}
+		u, err := strconv.ParseUint(text, 10, bitSize)
+		if err != nil {
+			line, _ := csvr.FieldPos(csvFields[name])
+			fieldErr = fmt.Errorf("line %d: %s: %w", line, name, err)
+		}
+		return u
+	}
 
 	for {
 		rec, err = csvr.Read()
@@ -180,30 +341,40 @@ func ReadCsv(userArgs chan<- UserArgument, r io.Reader) error {
 			break
 		}
 		arg := UserArgument{
-			ObjectID:     mustBeUint(rec[csvFields["OBJECT_ID"]]),
-			SubprogramID: mustBeUint(rec[csvFields["SUBPROGRAM_ID"]]),
+			ObjectID:     uint(getUint("OBJECT_ID", uintWidthBits)),
+			SubprogramID: uint(getUint("SUBPROGRAM_ID", uintWidthBits)),
+
+			Owner:       get("OWNER"),
+			PackageName: get("PACKAGE_NAME"),
+			ObjectName:  get("OBJECT_NAME"),
 
-			PackageName: rec[csvFields["PACKAGE_NAME"]],
-			ObjectName:  rec[csvFields["OBJECT_NAME"]],
+			DataLevel:    uint8(getUint("DATA_LEVEL", 8)),
+			Position:     uint(getUint("SEQUENCE", uintWidthBits)),
+			ArgumentName: get("ARGUMENT_NAME"),
+			InOut:        get("IN_OUT"),
 
-			DataLevel:    mustBeUint8(rec[csvFields["DATA_LEVEL"]]),
-			Position:     mustBeUint(rec[csvFields["SEQUENCE"]]),
-			ArgumentName: rec[csvFields["ARGUMENT_NAME"]],
-			InOut:        rec[csvFields["IN_OUT"]],
+			DataType:      get("DATA_TYPE"),
+			DataPrecision: uint8(getUint("DATA_PRECISION", 8)),
+			DataScale:     uint8(getUint("DATA_SCALE", 8)),
 
-			DataType:      rec[csvFields["DATA_TYPE"]],
-			DataPrecision: mustBeUint8(rec[csvFields["DATA_PRECISION"]]),
-			DataScale:     mustBeUint8(rec[csvFields["DATA_SCALE"]]),
+			CharacterSetName: get("CHARACTER_SET_NAME"),
+			IndexBy:          get("INDEX_BY"),
+			CharLength:       uint(getUint("CHAR_LENGTH", uintWidthBits)),
+			CharUsed:         get("CHAR_USED"),
 
-			CharacterSetName: rec[csvFields["CHARACTER_SET_NAME"]],
-			IndexBy:          rec[csvFields["INDEX_BY"]],
-			CharLength:       mustBeUint(rec[csvFields["CHAR_LENGTH"]]),
+			PlsType:     get("PLS_TYPE"),
+			TypeLink:    get("TYPE_LINK"),
+			TypeOwner:   get("TYPE_OWNER"),
+			TypeName:    get("TYPE_NAME"),
+			TypeSubname: get("TYPE_SUBNAME"),
 
-			PlsType:     rec[csvFields["PLS_TYPE"]],
-			TypeLink:    rec[csvFields["TYPE_LINK"]],
-			TypeOwner:   rec[csvFields["TYPE_OWNER"]],
-			TypeName:    rec[csvFields["TYPE_NAME"]],
-			TypeSubname: rec[csvFields["TYPE_SUBNAME"]],
+			Defaulted: get("DEFAULTED") == "Y",
+		}
+		if get("POSITION") != "" {
+			arg.ArgumentPosition = sql.NullInt32{Int32: int32(getUint("POSITION", 31)), Valid: true}
+		}
+		if fieldErr != nil {
+			return fieldErr
 		}
 
 		userArgs <- arg
@@ -211,28 +382,85 @@ func ReadCsv(userArgs chan<- UserArgument, r io.Reader) error {
 	return err
 }
 
-func ParseArguments(userArgs <-chan []UserArgument, filter func(string) bool) []Function {
+// DumpXML makes ParseArguments dump each parsed function, with its argument tree, as indented XML to it -
+// for debugging the grouping of the records and tables.
+var DumpXML io.Writer
+
+// ParseArguments parses the grouped (see FilterAndGroup) user arguments into functions,
+// skipping the hidden ones and the ones not passing the filter.
+//
+// The functions with a REF CURSOR input argument are skipped, too, with an error logged (see ErrCursorInput),
+// just as the ones with arguments nested deeper than MaxNestingDepth (see ErrTooDeep).
+//
+// It counts the Functions, the Hidden, Filtered, MissingTableOf, CursorInput and TooDeep ones in stats, if it is not nil.
+func ParseArguments(userArgs <-chan []UserArgument, filter func(string) bool, stats *ParseStats) []Function {
+	if stats == nil {
+		stats = new(ParseStats)
+	}
 	// Split args by functions
 	names := make([]string, 0, len(userArgs)/4)
 	functions := make([]Function, cap(names))
+	dumpXML := func(Function) {}
+	if DumpXML != nil {
+		enc := xml.NewEncoder(DumpXML)
+		enc.Indent("", "  ")
+		dumpXML = func(fun Function) {
+			if err := enc.Encode(fun); err != nil {
+				logger.Warn("dump XML", "function", fun.Name(), "error", err)
+				return
+			}
+			io.WriteString(DumpXML, "\n")
+		}
+	}
 	var row int
 	for uas := range userArgs {
-		if ua := uas[0]; ua.ObjectName[len(ua.ObjectName)-1] == '#' || //hidden
-			filter != nil && !filter(ua.ObjectName) {
+		if ua := uas[0]; strings.HasSuffix(ua.ObjectName, "#") { //hidden
+			stats.Hidden++
+			continue
+		} else if filter != nil && !filter(ua.fullName()) {
+			stats.Filtered++
 			continue
 		}
 
 		var fun Function
-		lastArgs := make(map[int8]*Argument, 8)
+		lastArgs := make(map[int]*Argument, 8)
 		lastArgs[-1] = &Argument{Flavor: FLAVOR_RECORD}
-		var level int8
+		var level int
+		var tooDeep error
 		for i, ua := range uas {
 			row++
 			if i == 0 {
-				fun = Function{Package: ua.PackageName, name: ua.ObjectName, LastDDL: ua.LastDDL}
+				fun = Function{Owner: ua.Owner, Package: ua.PackageName, name: ua.ObjectName, LastDDL: ua.LastDDL}
+			}
+			if ua.ArgumentName == "" && ua.DataType == "" {
+				// the only row of a procedure without arguments
+				continue
 			}
 
-			level = int8(ua.DataLevel)
+			level = int(ua.DataLevel)
+			if level > MaxNestingDepth {
+				// the path of the names of the ancestors, [] for the (unnamed) elements of the tables
+				var path strings.Builder
+				for l := 0; l <= level; l++ {
+					nm := strings.ToLower(ua.ArgumentName)
+					if l < level {
+						if lastArgs[l] == nil {
+							continue
+						}
+						nm = lastArgs[l].Name
+					}
+					if nm == "" {
+						path.WriteString("[]")
+						continue
+					}
+					if path.Len() != 0 {
+						path.WriteByte('.')
+					}
+					path.WriteString(nm)
+				}
+				tooDeep = fun.tooDeep(path.String(), ua.TypeOwner+"."+ua.TypeName+"."+ua.TypeSubname)
+				break
+			}
 			typeName := ua.TypeOwner + "." + ua.TypeName + "." + ua.TypeSubname + "@" + ua.TypeLink
 			if ua.TypeSubname == "" && ua.PlsType+"@" == typeName {
 				typeName = ua.TypeOwner + "." + ua.TypeName + "%ROWTYPE"
@@ -249,6 +477,11 @@ func ParseArguments(userArgs <-chan []UserArgument, filter func(string) bool) []
 				ua.DataScale,
 				ua.CharLength,
 			)
+			if ua.DataType == "LONG" || ua.DataType == "LONG RAW" {
+				logger.Warn("LONG and LONG RAW are deprecated, use CLOB or BLOB", "function", ua.fullName(), "argument", ua.ArgumentName, "type", ua.DataType)
+			}
+			arg.Defaulted = ua.Defaulted
+			arg.CharUsed = ua.CharUsed
 			logger.Debug("ParseArgument", "level", level, "fun", fun.name, "arg", arg.Name, "type", ua.DataType, "last", lastArgs, "flavor", arg.Flavor, "typeName", typeName, "ua", ua, "arg", arg, "typeSub", ua.TypeSubname, "pls", ua.PlsType)
 			// Possibilities:
 			// 1. SIMPLE
@@ -258,7 +491,12 @@ func ParseArguments(userArgs <-chan []UserArgument, filter func(string) bool) []
 			if arg.Flavor != FLAVOR_SIMPLE {
 				lastArgs[level] = &arg
 			}
-			if level == 0 && fun.Returns == nil && arg.Name == "" {
+			// The return value is at POSITION 0 - fall back to the missing name if POSITION is unknown.
+			isReturn := arg.Name == ""
+			if ua.ArgumentPosition.Valid {
+				isReturn = ua.ArgumentPosition.Int32 == 0
+			}
+			if level == 0 && fun.Returns == nil && isReturn {
 				arg.Name = "ret"
 				fun.Returns = &arg
 				continue
@@ -274,40 +512,146 @@ func ParseArguments(userArgs <-chan []UserArgument, filter func(string) bool) []
 				parent.RecordOf = append(parent.RecordOf, NamedArgument{Name: arg.Name, Argument: &arg})
 			}
 		}
+		if tooDeep != nil {
+			logger.Error("SKIP function", "function", fun.Name(), "error", tooDeep)
+			stats.TooDeep++
+			continue
+		}
 		fun.Args = make([]Argument, len(lastArgs[-1].RecordOf))
 		for i, na := range lastArgs[-1].RecordOf {
 			fun.Args[i] = *na.Argument
 		}
+		if err := fun.checkCursorInput(); err != nil {
+			logger.Error("SKIP function", "function", fun.Name(), "error", err)
+			stats.CursorInput++
+			continue
+		}
+		fun.setDynamicRows()
+		dumpXML(fun)
 		functions = append(functions, fun)
 		names = append(names, fun.Name())
+		stats.Functions++
+		if fun.missingTableOf() {
+			stats.MissingTableOf++
+		}
 	}
 	logger.Info("found", "functions", names)
 	return functions
 }
 
-func mustBeUint(text string) uint {
-	if text == "" {
-		return 0
+// ErrCursorInput is logged for the functions skipped by ParseArguments for their REF CURSOR input argument.
+var ErrCursorInput = errors.New("REF CURSOR input arguments are not supported")
+
+// checkCursorInput returns an error wrapping ErrCursorInput if an argument of the function is an IN (or IN OUT) REF CURSOR:
+// the generated code could neither build nor bind such a cursor from the request.
+func (f Function) checkCursorInput() error {
+	for _, arg := range f.Args {
+		if arg.Type == "REF CURSOR" && arg.IsInput() {
+			return fmt.Errorf("%s: %s is an input REF CURSOR - pass the rows in a PL/SQL table (or a CLOB) instead: %w",
+				f.Name(), arg.Name, ErrCursorInput)
+		}
 	}
-	u, e := strconv.ParseUint(text, 10, uintWidthBits)
-	if e != nil {
-		panic(e)
+	return nil
+}
+
+// missingTableOf reports whether an argument (or the return value) of the function
+// is a table (or REF CURSOR) without its TableOf info.
+func (f Function) missingTableOf() bool {
+	var missing func(Argument) bool
+	missing = func(arg Argument) bool {
+		switch arg.Flavor {
+		case FLAVOR_TABLE:
+			return arg.TableOf == nil || missing(*arg.TableOf)
+		case FLAVOR_RECORD:
+			for _, sub := range arg.RecordOf {
+				if missing(*sub.Argument) {
+					return true
+				}
+			}
+		}
+		return false
 	}
-	return uint(u)
+	for _, arg := range f.Args {
+		if missing(arg) {
+			return true
+		}
+	}
+	return f.Returns != nil && missing(*f.Returns)
 }
 
-func mustBeUint8(text string) uint8 {
-	if text == "" {
-		return 0
+// renameArg renames the argument (or the return value, named "ret") in the messages,
+// but keeps the name used for calling the function in the database.
+func (f *Function) renameArg(name, newName string) bool {
+	rename := func(arg *Argument) bool {
+		if !strings.EqualFold(arg.Name, name) {
+			return false
+		}
+		if arg.oraName == "" {
+			arg.oraName = arg.Name
+		}
+		// a new slice, not to modify the caller's
+		arg.renamedFrom = append(arg.renamedFrom[:len(arg.renamedFrom):len(arg.renamedFrom)], arg.Name)
+		arg.Name = newName
+		return true
 	}
-	u, err := strconv.ParseUint(text, 10, 8)
-	if err != nil {
-		panic(err)
+	for i := range f.Args {
+		if strings.EqualFold(f.Args[i].Name, name) {
+			// do not modify the caller's Args
+			f.Args = append([]Argument(nil), f.Args...)
+			return rename(&f.Args[i])
+		}
+	}
+	if f.Returns != nil {
+		ret := *f.Returns
+		if rename(&ret) {
+			f.Returns = &ret
+			return true
+		}
+	}
+	return false
+}
+
+// setArgJSONName sets the JSON name of the field of the argument (or the return value, named "ret").
+func (f *Function) setArgJSONName(name, jsonName string) bool {
+	for i := range f.Args {
+		if strings.EqualFold(f.Args[i].Name, name) {
+			// do not modify the caller's Args
+			f.Args = append([]Argument(nil), f.Args...)
+			f.Args[i].jsonName = jsonName
+			return true
+		}
+	}
+	if f.Returns != nil && strings.EqualFold(f.Returns.Name, name) {
+		ret := *f.Returns
+		ret.jsonName = jsonName
+		f.Returns = &ret
+		return true
+	}
+	return false
+}
+
+// deprecateArg marks the argument (or the return value, named "ret") deprecated.
+func (f *Function) deprecateArg(name string) bool {
+	for i := range f.Args {
+		if strings.EqualFold(f.Args[i].Name, name) {
+			// do not modify the caller's Args
+			f.Args = append([]Argument(nil), f.Args...)
+			f.Args[i].deprecated = true
+			return true
+		}
+	}
+	if f.Returns != nil && strings.EqualFold(f.Returns.Name, name) {
+		ret := *f.Returns
+		ret.deprecated = true
+		f.Returns = &ret
+		return true
 	}
-	return uint8(u)
+	return false
 }
 
 type Annotation struct {
+	// Owner restricts the annotation to the package in this schema - see ApplyAnnotations.
+	Owner                      string
 	Package, Type, Name, Other string
 	Size                       int
 }
@@ -328,86 +672,476 @@ func (a Annotation) String() string {
 	if a.Type == "" || a.Name == "" {
 		return ""
 	}
+	name := a.FullName()
+	if a.Owner != "" {
+		name = a.Owner + ":" + name
+	}
 	switch a.Type {
-	case "private":
-		return a.Type + " " + a.FullName()
+	case "private", "deprecated", "idempotent":
+		return a.Type + " " + name
 	case "max-table-size":
-		return fmt.Sprintf("%s.MaxTableSize=%d", a.FullName(), a.Size)
+		return fmt.Sprintf("%s.MaxTableSize=%d", name, a.Size)
+	case "fetch-size":
+		return fmt.Sprintf("%s %s=%d", a.Type, name, a.Size)
+	case "paginate":
+		if a.Other == "" {
+			return a.Type + " " + name
+		}
 	}
-	return a.Type + " " + a.FullName() + "=>" + a.FullOther()
+	return a.Type + " " + name + "=>" + a.FullOther()
 }
 
+// ParseAnnotation parses the textual form of an Annotation, as Annotation.String returns it:
+// "type pkg.name", "type pkg.name=>other", "type pkg.name=N" or "pkg.name.MaxTableSize=N".
+//
+// The package is the part of the name before the first dot, and is stripped from other.
+// The name may be qualified with the owner, as "owner:pkg.name".
+func ParseAnnotation(s string) (Annotation, error) {
+	var a Annotation
+	orig := s
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		a.Type, s = s[:i], strings.TrimSpace(s[i+1:])
+	}
+	if i := strings.Index(s, "=>"); i >= 0 {
+		a.Name, a.Other = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+2:])
+	} else if i = strings.IndexByte(s, '='); i >= 0 {
+		size, err := strconv.Atoi(strings.TrimSpace(s[i+1:]))
+		if err != nil {
+			return a, fmt.Errorf("parse size of annotation %q: %w", orig, err)
+		}
+		a.Name, a.Size = strings.TrimSpace(s[:i]), size
+		if a.Type == "" {
+			if nm, ok := strings.CutSuffix(a.Name, ".MaxTableSize"); ok {
+				a.Type, a.Name = "max-table-size", nm
+			}
+		}
+	} else {
+		a.Name = s
+	}
+	switch a.Type {
+	case "private", "deprecated", "rename", "replace", "replace_json", "handle", "max-table-size", "fetch-size", "tag", "cursor", "cursor-row", "group", "json-name", "example", "empty-string", "sqlcode", "paginate", "idempotent", "requires", "excludes", "xml-fetch":
+	default:
+		return a, fmt.Errorf("unknown annotation type %q in %q", a.Type, orig)
+	}
+	if a.Name == "" {
+		return a, fmt.Errorf("no name in annotation %q", orig)
+	}
+	if owner, nm, ok := strings.Cut(a.Name, ":"); ok {
+		a.Owner, a.Name = owner, nm
+	}
+	if i := strings.IndexByte(a.Name, '.'); i >= 0 {
+		a.Package, a.Name = a.Name[:i], a.Name[i+1:]
+		a.Other = strings.TrimPrefix(a.Other, a.Package+".")
+	}
+	return a, nil
+}
+
+// ErrUnmatchedAnnotation is returned by ApplyAnnotationsStrict for the annotations
+// whose target function (or argument) does not exist.
+var ErrUnmatchedAnnotation = errors.New("annotation matches no function")
+
+// ApplyAnnotations applies the annotations to the functions.
+//
+// An annotation with an Owner applies only to the functions of that schema
+// (or to those whose Owner is unknown), an annotation without one to the
+// same-named functions of every schema.
+//
+// The annotations matching no function are logged and ignored - see ApplyAnnotationsStrict.
+// The annotations of more sources should be merged by MergeAnnotations first.
 func ApplyAnnotations(functions []Function, annotations []Annotation) []Function {
+	functions, unmatched := applyAnnotations(functions, annotations)
+	for _, a := range unmatched {
+		logger.Warn("directive", a.Type, a.FullName(), "owner", a.Owner, "error", ErrUnmatchedAnnotation)
+	}
+	return functions
+}
+
+// ApplyAnnotationsStrict is ApplyAnnotations, but returns an error
+// listing the annotations matching no function (each wrapping ErrUnmatchedAnnotation),
+// for catching the misspelled targets.
+func ApplyAnnotationsStrict(functions []Function, annotations []Annotation) ([]Function, error) {
+	functions, unmatched := applyAnnotations(functions, annotations)
+	errs := make([]error, 0, len(unmatched))
+	for _, a := range unmatched {
+		errs = append(errs, fmt.Errorf("%s: %w", a, ErrUnmatchedAnnotation))
+	}
+	return functions, errors.Join(errs...)
+}
+
+// applyAnnotations applies the annotations to the functions, returning the annotations matching none.
+func applyAnnotations(functions []Function, annotations []Annotation) ([]Function, []Annotation) {
 	if len(annotations) == 0 {
-		return functions
+		return functions, nil
 	}
 	L := strings.ToLower
+	// key of the function in funcs: the name, qualified with the owner if it is known
+	key := func(owner, nm string) string {
+		if owner == "" {
+			return L(nm)
+		}
+		return L(owner + "." + nm)
+	}
 	funcs := make(map[string]*Function, len(functions))
 	for i := range functions {
 		f := functions[i]
-		funcs[L(f.RealName())] = &f
+		funcs[key(f.Owner, f.RealName())] = &f
 	}
-	for _, a := range annotations {
-		if a.Name == "" || a.Type == "" {
-			continue
+	// lookup returns the keys of the functions named nm the annotation applies to.
+	lookup := func(a Annotation, nm string) []string {
+		if k := key(a.Owner, nm); funcs[k] != nil {
+			return []string{k}
 		}
-		if a.Other == "" && !(a.Type == "private" || a.Type == "handle" || a.Type == "max-table-size") {
-			continue
+		if a.Owner != "" {
+			if k := L(nm); funcs[k] != nil && funcs[k].Owner == "" {
+				return []string{k}
+			}
+			return nil
 		}
-		if a.Size <= 0 && a.Type == "max-table-size" {
-			continue
+		var keys []string
+		for k, f := range funcs {
+			if f.Owner != "" && k == key(f.Owner, nm) {
+				keys = append(keys, k)
+			}
 		}
+		sort.Strings(keys)
+		return keys
+	}
+	// the cursor-row annotations are applied after the cursor annotations setting the rows
+	var cursorRows []Annotation
+	// apply the annotation, reporting whether it matched a function (and argument)
+	apply := func(a Annotation) bool {
 		switch a.Type {
 		case "private":
 			nm := L(a.FullName())
-			logger.Info("directive", "private", nm)
-			delete(funcs, nm)
+			logger.Info("directive", "private", nm, "owner", a.Owner)
+			keys := lookup(a, nm)
+			for _, k := range keys {
+				delete(funcs, k)
+			}
+			return len(keys) != 0
+		// deprecated pkg.func marks the function, deprecated pkg.func.arg the argument deprecated
+		case "deprecated":
+			nm := L(a.FullName())
+			if keys := lookup(a, nm); len(keys) != 0 {
+				for _, k := range keys {
+					logger.Info("directive", "deprecated", nm, "owner", funcs[k].Owner)
+					funcs[k].deprecated = true
+				}
+				return true
+			}
+			i := strings.LastIndexByte(nm, '.')
+			if i < 0 {
+				return false
+			}
+			var matched bool
+			for _, k := range lookup(a, nm[:i]) {
+				if !funcs[k].deprecateArg(nm[i+1:]) {
+					logger.Warn("directive", "deprecated", nm, "owner", funcs[k].Owner, "error", "no such argument")
+					continue
+				}
+				matched = true
+				logger.Info("directive", "deprecated", nm, "owner", funcs[k].Owner)
+			}
+			return matched
 		case "rename":
 			nm := L(a.FullName())
-			if f := funcs[nm]; f != nil {
-				delete(funcs, nm)
-				funcs[L(a.FullOther())] = f
-				logger.Info("directive", "rename", nm, "to", a.Other)
-				f.alias = a.Other
+			if keys := lookup(a, nm); len(keys) != 0 {
+				for _, k := range keys {
+					f := funcs[k]
+					delete(funcs, k)
+					funcs[key(f.Owner, a.FullOther())] = f
+					logger.Info("directive", "rename", nm, "owner", f.Owner, "to", a.Other)
+					f.alias = a.Other
+				}
+				return true
 			}
+			// rename pkg.func.arg => new_name renames only that argument
+			i := strings.LastIndexByte(nm, '.')
+			if i < 0 {
+				return false
+			}
+			var matched bool
+			for _, k := range lookup(a, nm[:i]) {
+				if !funcs[k].renameArg(nm[i+1:], L(a.Other)) {
+					logger.Warn("directive", "rename", nm, "owner", funcs[k].Owner, "error", "no such argument")
+					continue
+				}
+				matched = true
+				logger.Info("directive", "rename", nm, "owner", funcs[k].Owner, "to", a.Other)
+			}
+			return matched
 		case "replace", "replace_json":
 			k, v := L(a.FullName()), L(a.FullOther())
-			if f := funcs[k]; f != nil {
-				logger.Info("directive", "replace", k, "with", v)
-				f.Replacement = funcs[v]
+			var matched bool
+			for _, fk := range lookup(a, k) {
+				f := funcs[fk]
+				vk := key(f.Owner, v)
+				logger.Info("directive", "replace", k, "owner", f.Owner, "with", v)
+				f.Replacement = funcs[vk]
+				matched = matched || f.Replacement != nil
 				f.ReplacementIsJSON = a.Type == "replace_json"
-				delete(funcs, v)
+				delete(funcs, vk)
 				logger.Info("directive", "delete", v, "add", f.Name())
-				funcs[L(f.Name())] = f
+				funcs[key(f.Owner, f.Name())] = f
+			}
+			return matched
+
+		// xml-fetch pkg.func => pkg.func_xml fetches the REF CURSOR of func as the XMLTYPE func_xml returns
+		case "xml-fetch":
+			k, v := L(a.FullName()), L(a.FullOther())
+			var matched bool
+			for _, fk := range lookup(a, k) {
+				f := funcs[fk]
+				vk := key(f.Owner, v)
+				if err := f.setXMLFetch(funcs[vk]); err != nil {
+					logger.Warn("directive", "xml-fetch", k, "owner", f.Owner, "with", v, "error", err)
+					continue
+				}
+				matched = true
+				logger.Info("directive", "xml-fetch", k, "owner", f.Owner, "with", v)
+				delete(funcs, vk)
 			}
+			return matched
+
+		// json-name pkg.func.arg => name sets the json_name of the argument's field, for a legacy JSON contract
+		case "json-name":
+			nm := L(a.FullName())
+			i := strings.LastIndexByte(nm, '.')
+			if i < 0 {
+				return false
+			}
+			var matched bool
+			for _, k := range lookup(a, nm[:i]) {
+				if !funcs[k].setArgJSONName(nm[i+1:], a.Other) {
+					logger.Warn("directive", "json-name", nm, "owner", funcs[k].Owner, "error", "no such argument")
+					continue
+				}
+				matched = true
+				logger.Info("directive", "json-name", nm, "owner", funcs[k].Owner, "to", a.Other)
+			}
+			return matched
+
+		// example pkg.func.arg => value sets the example value of the argument's field, for the documentation
+		case "example":
+			nm := L(a.FullName())
+			i := strings.LastIndexByte(nm, '.')
+			if i < 0 {
+				return false
+			}
+			var matched bool
+			for _, k := range lookup(a, nm[:i]) {
+				if err := funcs[k].setArgExample(nm[i+1:], a.Other); err != nil {
+					logger.Warn("directive", "example", nm, "owner", funcs[k].Owner, "error", err)
+					continue
+				}
+				matched = true
+				logger.Info("directive", "example", nm, "owner", funcs[k].Owner, "value", a.Other)
+			}
+			return matched
+
+		// empty-string pkg.func.arg => reject|null|'sentinel' sets how the empty string of the input argument is bound
+		case "empty-string":
+			nm := L(a.FullName())
+			i := strings.LastIndexByte(nm, '.')
+			if i < 0 {
+				return false
+			}
+			var matched bool
+			for _, k := range lookup(a, nm[:i]) {
+				if err := funcs[k].setArgEmptyString(nm[i+1:], a.Other); err != nil {
+					logger.Warn("directive", "empty-string", nm, "owner", funcs[k].Owner, "error", err)
+					continue
+				}
+				matched = true
+				logger.Info("directive", "empty-string", nm, "owner", funcs[k].Owner, "how", a.Other)
+			}
+			return matched
+
+		// cursor pkg.func.arg => col1 TYPE1, col2 TYPE2 sets the row of a SYS_REFCURSOR
+		case "cursor":
+			nm := L(a.FullName())
+			i := strings.LastIndexByte(nm, '.')
+			if i < 0 {
+				return false
+			}
+			var matched bool
+			for _, k := range lookup(a, nm[:i]) {
+				if err := funcs[k].setCursorShape(nm[i+1:], a.Other); err != nil {
+					logger.Warn("directive", "cursor", nm, "owner", funcs[k].Owner, "error", err)
+					continue
+				}
+				matched = true
+				logger.Info("directive", "cursor", nm, "owner", funcs[k].Owner, "shape", a.Other)
+			}
+			return matched
+
+		// sqlcode pkg.func => code_arg[, msg_arg] captures the errors into the OUT arguments, instead of returning them
+		case "sqlcode":
+			nm := L(a.FullName())
+			var matched bool
+			for _, k := range lookup(a, nm) {
+				if err := funcs[k].setSQLCodeArgs(L(a.Other)); err != nil {
+					logger.Warn("directive", "sqlcode", nm, "owner", funcs[k].Owner, "error", err)
+					continue
+				}
+				matched = true
+				logger.Info("directive", "sqlcode", nm, "owner", funcs[k].Owner, "args", a.Other)
+			}
+			return matched
+
+		// requires pkg.func => arg1, arg2 makes the arguments required together,
+		// excludes pkg.func => arg1, arg2 mutually exclusive (see GenChecks)
+		case "requires", "excludes":
+			nm := L(a.FullName())
+			var matched bool
+			for _, k := range lookup(a, nm) {
+				if err := funcs[k].addArgGroup(a.Type, L(a.Other)); err != nil {
+					logger.Warn("directive", a.Type, nm, "owner", funcs[k].Owner, "error", err)
+					continue
+				}
+				matched = true
+				logger.Info("directive", a.Type, nm, "owner", funcs[k].Owner, "args", a.Other)
+			}
+			return matched
+
+		// cursor-row pkg.func[.arg] => name names the row message of the REF CURSOR
+		case "cursor-row":
+			// matched when applied, below
+			cursorRows = append(cursorRows, a)
+			return true
 
 		// add handler to ALL functions in the same package
 		case "handle":
 			exc := strings.ToUpper(a.Name)
+			var matched bool
 			for _, f := range funcs {
-				if strings.EqualFold(f.Package, a.Package) {
+				if strings.EqualFold(f.Package, a.Package) &&
+					(a.Owner == "" || f.Owner == "" || strings.EqualFold(f.Owner, a.Owner)) {
 					f.handle = append(f.handle, exc)
+					matched = true
 				}
 			}
+			return matched
 
 		case "max-table-size":
 			nm := L(a.FullName())
-			logger.Info("directive", "max-table-size", nm, "size", a.Size)
-			if f := funcs[nm]; f != nil && a.Size >= f.maxTableSize {
-				f.maxTableSize = a.Size
+			logger.Info("directive", "max-table-size", nm, "owner", a.Owner, "size", a.Size)
+			keys := lookup(a, nm)
+			for _, k := range keys {
+				if f := funcs[k]; a.Size >= f.maxTableSize {
+					f.maxTableSize = a.Size
+				}
+			}
+			return len(keys) != 0
+
+		// idempotent pkg.func marks the function safe to call again, so it is retried on the transient errors
+		case "idempotent":
+			nm := L(a.FullName())
+			logger.Info("directive", "idempotent", nm, "owner", a.Owner)
+			keys := lookup(a, nm)
+			for _, k := range keys {
+				funcs[k].idempotent = true
+			}
+			return len(keys) != 0
+
+		// paginate pkg.func [=> lifetime] adds a paginated unary rpc of the function returning a cursor
+		case "paginate":
+			nm := L(a.FullName())
+			var matched bool
+			for _, k := range lookup(a, nm) {
+				if err := funcs[k].setPaginate(a.Other); err != nil {
+					logger.Warn("directive", "paginate", nm, "owner", funcs[k].Owner, "error", err)
+					continue
+				}
+				matched = true
+				logger.Info("directive", "paginate", nm, "owner", funcs[k].Owner, "lifetime", funcs[k].pageLifetime.String())
+			}
+			return matched
+
+		// fetch-size pkg.func=N sets the number of rows fetched at once from the returned cursors
+		case "fetch-size":
+			nm := L(a.FullName())
+			if a.Size <= 0 {
+				logger.Warn("directive", "fetch-size", nm, "owner", a.Owner, "size", a.Size, "error", "size must be positive")
+				return false
 			}
+			logger.Info("directive", "fetch-size", nm, "owner", a.Owner, "size", a.Size)
+			keys := lookup(a, nm)
+			for _, k := range keys {
+				funcs[k].fetchSize = a.Size
+			}
+			return len(keys) != 0
 
 		case "tag":
 			nm := L(a.FullName())
-			logger.Info("directive", "f", nm, "tag", a.Other)
-			if f := funcs[nm]; f != nil {
-				f.Tag = append(f.Tag, a.Other)
+			logger.Info("directive", "f", nm, "owner", a.Owner, "tag", a.Other)
+			keys := lookup(a, nm)
+			for _, k := range keys {
+				funcs[k].Tag = append(funcs[k].Tag, a.Other)
+			}
+			return len(keys) != 0
+
+		// group pkg.func => other puts the function into the service of other (see ProtoOptions.ServicePerPackage)
+		case "group":
+			nm := L(a.FullName())
+			logger.Info("directive", "f", nm, "owner", a.Owner, "group", a.Other)
+			keys := lookup(a, nm)
+			for _, k := range keys {
+				funcs[k].group = a.Other
+			}
+			return len(keys) != 0
+		}
+		return false
+	}
+	var unmatched []Annotation
+	for _, a := range annotations {
+		if a.Name == "" || a.Type == "" {
+			continue
+		}
+		if a.Other == "" && !(a.Type == "private" || a.Type == "deprecated" || a.Type == "handle" || a.Type == "max-table-size" || a.Type == "fetch-size" || a.Type == "paginate" || a.Type == "idempotent") {
+			continue
+		}
+		if a.Size <= 0 && a.Type == "max-table-size" {
+			continue
+		}
+		if !apply(a) {
+			unmatched = append(unmatched, a)
+		}
+	}
+	// the row messages of the same name must have the same shape, as they are written once
+	shapes := make(map[string]string, len(cursorRows))
+	for _, a := range cursorRows {
+		var matched bool
+		nm := L(a.FullName())
+		keys, argName := lookup(a, nm), ""
+		if len(keys) == 0 {
+			if i := strings.LastIndexByte(nm, '.'); i >= 0 {
+				keys, argName = lookup(a, nm[:i]), nm[i+1:]
 			}
 		}
+		for _, k := range keys {
+			f := funcs[k]
+			if err := f.setCursorRowName(argName, a.Other); err != nil {
+				logger.Warn("directive", "cursor-row", nm, "owner", f.Owner, "error", err)
+				continue
+			}
+			matched = true
+			logger.Info("directive", "cursor-row", nm, "owner", f.Owner, "name", a.Other)
+			shape := f.cursorRowShape(a.Other)
+			if prev, ok := shapes[L(a.Other)]; ok && prev != shape {
+				logger.Warn("directive", "cursor-row", nm, "owner", f.Owner, "name", a.Other,
+					"error", "the rows of the same name have different columns", "shape", shape, "other", prev)
+			}
+			shapes[L(a.Other)] = shape
+		}
+		if !matched {
+			unmatched = append(unmatched, a)
+		}
 	}
 	functions = functions[:0]
 	for _, f := range funcs {
 		functions = append(functions, *f)
 	}
-	return functions
+	return functions, unmatched
 }
@@ -5,10 +5,46 @@
 package oracall
 
 import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/UNO-SOFT/zlog/v2/slog"
 )
 
+// testCsvHeader is the header of the csv read by ParseCsv, without the optional columns
+// (DEFAULTED, CHAR_USED, OWNER).
+const testCsvHeader = "OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK"
+
+// parseTestCsv logs to the test, and returns the functions of the csv rows (under testCsvHeader).
+func parseTestCsv(t testing.TB, rows ...string) []Function {
+	t.Helper()
+	logger = zlog.NewT(t).SLog()
+	functions, err := ParseCsv(strings.NewReader(testCsvHeader+"\n"+strings.Join(rows, "\n")+"\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return functions
+}
+
+// wantContains reports each of the wants not found in the generated s.
+func wantContains(t testing.TB, s string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(s, want) {
+			t.Errorf("%q not found in\n%s", want, s)
+		}
+	}
+}
+
 //var flagConnect = flag.String("connect", "", "database DSN to connect to")
 
 func TestParseCsv(t *testing.T) {
@@ -23,3 +59,629 @@ func TestParseCsv(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCsvReturn(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	const head = testCsvHeader + "\n"
+	for name, csv := range map[string]string{
+		"named": head + `1,1,1,DB_WEB,GET_NAME,0,0,RESULT,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,0,,,,
+1,1,2,DB_WEB,GET_NAME,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+`,
+		"unnamed": head + `1,1,1,DB_WEB,GET_NAME,0,0,,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,0,,,,
+1,1,2,DB_WEB,GET_NAME,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+`,
+		"no position": strings.Replace(head, ",POSITION", "", 1) + `1,1,1,DB_WEB,GET_NAME,0,,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,0,,,,
+1,1,2,DB_WEB,GET_NAME,0,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+`,
+	} {
+		functions, err := ParseCsv(strings.NewReader(csv), nil)
+		if err != nil {
+			t.Fatalf("%s: %+v", name, err)
+		}
+		if len(functions) != 1 {
+			t.Fatalf("%s: got %d functions, wanted 1", name, len(functions))
+		}
+		fun := functions[0]
+		if fun.Returns == nil {
+			t.Errorf("%s: no return", name)
+		} else if fun.Returns.Type != "VARCHAR2" {
+			t.Errorf("%s: return is %q, wanted VARCHAR2", name, fun.Returns.Type)
+		}
+		if len(fun.Args) != 1 || fun.Args[0].Name != "p_id" {
+			t.Errorf("%s: got args %v, wanted only p_id", name, fun.Args)
+		}
+	}
+}
+
+func TestApplyAnnotationsReplace(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions := ApplyAnnotations([]Function{
+		{Package: "DB_WEB", name: "get_x", Args: []Argument{{Name: "p_id"}}},
+		{Package: "DB_WEB", name: "get_x_json", Args: []Argument{{Name: "p_in"}, {Name: "p_out"}}},
+	}, []Annotation{{Package: "DB_WEB", Type: "replace_json", Name: "get_x", Other: "get_x_json"}})
+	if len(functions) != 1 {
+		t.Fatalf("got %d functions, wanted 1: %v", len(functions), functions)
+	}
+	f := functions[0]
+	if !f.ReplacementUsesJSON() {
+		t.Error("wanted ReplacementUsesJSON")
+	}
+	repl := f.ReplacementFunction()
+	if repl == nil {
+		t.Fatal("no replacement")
+	}
+	if len(f.Args) != 1 || f.Args[0].Name != "p_id" {
+		t.Errorf("replaced function's args: %v", f.Args)
+	}
+	if len(repl.Args) != 2 || repl.Args[0].Name != "p_in" {
+		t.Errorf("replacement's args: %v", repl.Args)
+	}
+	if got, want := f.RealName(), "DB_web.get_x_json"; got != want {
+		t.Errorf("RealName: got %q, wanted %q", got, want)
+	}
+}
+
+func TestParseCsvDefaulted(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	for name, tC := range map[string]struct {
+		Csv  string
+		Want []bool
+	}{
+		"defaulted": {Csv: `OBJECT_ID;SUBPROGRAM_ID;SEQUENCE;PACKAGE_NAME;OBJECT_NAME;DATA_LEVEL;POSITION;ARGUMENT_NAME;IN_OUT;DATA_TYPE;DATA_PRECISION;DATA_SCALE;CHARACTER_SET_NAME;PLS_TYPE;CHAR_LENGTH;TYPE_OWNER;TYPE_NAME;TYPE_SUBNAME;TYPE_LINK;DEFAULTED
+1;1;1;DB_WEB;SET_X;0;1;P_ID;IN;NUMBER;9;;;NUMBER;0;;;;;N
+1;1;2;DB_WEB;SET_X;0;2;P_NOTE;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;0;;;;;Y
+`, Want: []bool{false, true}},
+		// older exports, without DEFAULTED, SEQUENCE and INDEX_BY
+		"old": {Csv: `OBJECT_ID;SUBPROGRAM_ID;PACKAGE_NAME;OBJECT_NAME;DATA_LEVEL;POSITION;ARGUMENT_NAME;IN_OUT;DATA_TYPE;DATA_PRECISION;DATA_SCALE;CHARACTER_SET_NAME;PLS_TYPE;CHAR_LENGTH;TYPE_LINK;TYPE_OWNER;TYPE_NAME;TYPE_SUBNAME
+1;1;DB_WEB;SET_X;0;1;P_ID;IN;NUMBER;9;;;NUMBER;0;;;;
+1;1;DB_WEB;SET_X;0;2;P_NOTE;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;0;;;;
+`, Want: []bool{false, false}},
+	} {
+		functions, err := ParseCsv(strings.NewReader(tC.Csv), nil)
+		if err != nil {
+			t.Fatalf("%s: %+v", name, err)
+		}
+		if len(functions) != 1 || len(functions[0].Args) != len(tC.Want) {
+			t.Fatalf("%s: got %v", name, functions)
+		}
+		for i, want := range tC.Want {
+			if got := functions[0].Args[i].Defaulted; got != want {
+				t.Errorf("%s: %s.Defaulted=%t, wanted %t", name, functions[0].Args[i].Name, got, want)
+			}
+		}
+	}
+}
+
+func TestApplyAnnotationsRenameArg(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "DB_WEB", Type: "rename", Name: "get_x.p_x1", Other: "customer_name"},
+	})
+	if len(functions) != 1 {
+		t.Fatalf("got %d functions, wanted 1", len(functions))
+	}
+	fun := functions[0]
+	if got := fun.Args[1].Name; got != "customer_name" {
+		t.Errorf("got %q, wanted customer_name", got)
+	}
+	if got := fun.Args[1].RealName(); got != "p_x1" {
+		t.Errorf("got RealName %q, wanted p_x1", got)
+	}
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, " customer_name = 1;") || strings.Contains(s, "p_x1") {
+		t.Errorf("proto field is not renamed:\n%s", s)
+	}
+
+	plsql, callFun := fun.PlsqlBlock("")
+	if !strings.Contains(plsql, "p_x1=>:") {
+		t.Errorf("the database call should use the original name:\n%s", plsql)
+	}
+	if !strings.Contains(callFun, "output.CustomerName") || strings.Contains(callFun, "P_x1") {
+		t.Errorf("the Go field is not renamed:\n%s", callFun)
+	}
+}
+
+func TestApplyAnnotationsOwner(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	const csv = `OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,OWNER,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK
+1,1,1,SCOTT,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+2,1,1,TIGER,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+`
+	owners := func(functions []Function) map[string]Function {
+		m := make(map[string]Function, len(functions))
+		for _, f := range functions {
+			m[f.Owner] = f
+		}
+		return m
+	}
+
+	functions, err := ParseCsv(strings.NewReader(csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 2 {
+		t.Fatalf("got %d functions, wanted 2", len(functions))
+	}
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Owner: "SCOTT", Package: "DB_WEB", Type: "tag", Name: "get_x", Other: "scott"},
+		{Package: "DB_WEB", Type: "tag", Name: "get_x", Other: "both"},
+		{Owner: "tiger", Package: "DB_WEB", Type: "max-table-size", Name: "get_x", Size: 1 << 20},
+	})
+	m := owners(functions)
+	if len(m) != 2 {
+		t.Fatalf("got %d functions, wanted 2", len(functions))
+	}
+	if got := strings.Join(m["SCOTT"].Tag, ","); got != "scott,both" {
+		t.Errorf("SCOTT got tags %q, wanted scott,both", got)
+	}
+	if got := strings.Join(m["TIGER"].Tag, ","); got != "both" {
+		t.Errorf("TIGER got tags %q, wanted both", got)
+	}
+	if m["SCOTT"].maxTableSize != 0 || m["TIGER"].maxTableSize != 1<<20 {
+		t.Errorf("max-table-size applied to SCOTT=%d TIGER=%d", m["SCOTT"].maxTableSize, m["TIGER"].maxTableSize)
+	}
+
+	if functions, err = ParseCsv(strings.NewReader(csv), nil); err != nil {
+		t.Fatal(err)
+	}
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Owner: "SCOTT", Package: "DB_WEB", Type: "private", Name: "get_x"},
+	})
+	if m = owners(functions); len(m) != 1 || m["TIGER"].name == "" {
+		t.Errorf("private SCOTT: got %v, wanted only TIGER", functions)
+	}
+}
+
+func TestParseAnnotation(t *testing.T) {
+	for _, a := range []Annotation{
+		{Package: "DB_WEB", Type: "private", Name: "get_x"},
+		{Package: "DB_WEB", Type: "rename", Name: "get_x", Other: "get_y"},
+		{Package: "DB_WEB", Type: "rename", Name: "get_x.p_x1", Other: "p_first"},
+		{Package: "DB_WEB", Type: "replace", Name: "get_x", Other: "get_x_xml"},
+		{Package: "DB_WEB", Type: "replace_json", Name: "get_x", Other: "get_x_json"},
+		{Package: "DB_WEB", Type: "handle", Name: "get_x"},
+		{Package: "DB_WEB", Type: "max-table-size", Name: "get_x", Size: 1024},
+		{Package: "DB_WEB", Type: "deprecated", Name: "get_x"},
+		{Package: "DB_WEB", Type: "deprecated", Name: "get_x.p_x1"},
+		{Package: "DB_WEB", Type: "cursor-row", Name: "list_x.p_cur", Other: "customer_row"},
+		{Type: "private", Name: "get_x"},
+		{Owner: "SCOTT", Package: "DB_WEB", Type: "rename", Name: "get_x", Other: "get_y"},
+		{Owner: "SCOTT", Package: "DB_WEB", Type: "max-table-size", Name: "get_x", Size: 1024},
+	} {
+		s := a.String()
+		got, err := ParseAnnotation(s)
+		if err != nil {
+			t.Errorf("%q: %+v", s, err)
+			continue
+		}
+		if got != a {
+			t.Errorf("%q: got %#v, wanted %#v", s, got, a)
+		}
+		if gotS := got.String(); gotS != s {
+			t.Errorf("round-trip: got %q, wanted %q", gotS, s)
+		}
+	}
+
+	for s, want := range map[string]Annotation{
+		"rename  DB_WEB.get_x => get_y":     {Package: "DB_WEB", Type: "rename", Name: "get_x", Other: "get_y"},
+		"max-table-size DB_WEB.get_x = 100": {Package: "DB_WEB", Type: "max-table-size", Name: "get_x", Size: 100},
+	} {
+		if got, err := ParseAnnotation(s); err != nil {
+			t.Errorf("%q: %+v", s, err)
+		} else if got != want {
+			t.Errorf("%q: got %#v, wanted %#v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "unknown DB_WEB.get_x", "private", "max-table-size DB_WEB.get_x=many"} {
+		if a, err := ParseAnnotation(s); err == nil {
+			t.Errorf("%q: wanted error, got %#v", s, a)
+		}
+	}
+}
+
+func TestParseCsvDelimiter(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	const header = testCsvHeader + `
+1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,
+`
+	for name, csv := range map[string]string{
+		"comma":     header,
+		"semicolon": strings.ReplaceAll(header, ",", ";"),
+		"tab":       strings.ReplaceAll(header, ",", "\t"),
+		// as SQL Developer exports it
+		"tsv": "testdata/user_arguments.tsv",
+	} {
+		var functions []Function
+		var err error
+		if strings.HasPrefix(csv, "testdata/") {
+			functions, err = ParseCsvFile(csv, nil)
+		} else {
+			functions, err = ParseCsv(strings.NewReader(csv), nil)
+		}
+		if err != nil {
+			t.Fatalf("%s: %+v", name, err)
+		}
+		if len(functions) != 1 || len(functions[0].Args) != 2 {
+			t.Fatalf("%s: got %v", name, functions)
+		}
+		args := functions[0].Args
+		if args[0].Name != "p_id" || args[0].Precision != 9 || args[0].IsOutput() {
+			t.Errorf("%s: got %#v", name, args[0])
+		}
+		if args[1].Name != "p_x1" || args[1].Charlength != 10 || !args[1].IsOutput() {
+			t.Errorf("%s: got %#v", name, args[1])
+		}
+	}
+}
+
+// stallingReader returns its data, then blocks till unblock is closed.
+type stallingReader struct {
+	data    io.Reader
+	unblock chan struct{}
+}
+
+func (sr stallingReader) Read(p []byte) (int, error) {
+	if n, err := sr.data.Read(p); n != 0 || err != io.EOF {
+		return n, err
+	}
+	<-sr.unblock
+	return 0, io.EOF
+}
+
+func TestParseCsvReadTimeout(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	defer func(old time.Duration) { CsvReadTimeout = old }(CsvReadTimeout)
+	CsvReadTimeout = 100 * time.Millisecond
+
+	sr := stallingReader{
+		data:    strings.NewReader(testCsvHeader + "\n1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,\n"),
+		unblock: make(chan struct{}),
+	}
+	defer close(sr.unblock)
+	done := make(chan error, 1)
+	go func() {
+		_, err := ParseCsv(sr, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrReadTimeout) {
+			t.Errorf("got %+v, wanted ErrReadTimeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ParseCsv hangs on a stalled reader")
+	}
+
+	// a reader that does not stall is not affected
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	if len(functions) != 1 {
+		t.Errorf("got %d functions, wanted 1", len(functions))
+	}
+}
+
+func TestParseCsvStandalone(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	var filtered []string
+	functions, err := ParseCsv(strings.NewReader(`OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK
+1,1,1,,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+1,1,2,,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,
+2,1,1,,SET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+3,1,1,,HIDE_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+`), func(s string) bool { filtered = append(filtered, s); return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nm := range filtered {
+		if strings.HasPrefix(nm, ".") {
+			t.Errorf("filter got %q", nm)
+		}
+	}
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Type: "private", Name: "hide_x"},
+		{Type: "rename", Name: "set_x", Other: "put_x"},
+	})
+	var names []string
+	for _, f := range functions {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, " "), "get_x set_x"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	var buf strings.Builder
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	buf.Reset()
+	if err = SaveFunctions(&buf, functions, "main", "example.com/db_web", true); err != nil {
+		t.Fatal(err)
+	}
+	code := buf.String()
+	wantContains(t, proto,
+		"message GetX_Input {", "message PutX_Output {",
+		"rpc GetX (GetX_Input) returns (GetX_Output) {}",
+		"rpc PutX (PutX_Input) returns (PutX_Output) {}",
+	)
+	wantContains(t, code,
+		"type GetX_Input struct {", "const Get_x__plsql = `",
+		"func (s *oracallServer) GetX(ctx context.Context, input *pb.GetX_Input) (output *pb.GetX_Output, err error) {",
+		"func (s *oracallServer) PutX(ctx context.Context, input *pb.PutX_Input) (output *pb.PutX_Output, err error) {",
+		"  set_x(p_id=>",
+	)
+	for _, stray := range []string{"_GetX", "__get_x", "__plsql = `", ".get_x"} {
+		if strings.Contains(code, " "+stray) || strings.Contains(proto, " "+stray) {
+			t.Errorf("stray %q found", stray)
+		}
+	}
+}
+
+func TestParseCsvDumpXML(t *testing.T) {
+	var buf strings.Builder
+	DumpXML = &buf
+	defer func() { DumpXML = nil }()
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_REC,OUT,PL/SQL RECORD,,,,,PL/SQL RECORD,0,SCOTT,DB_WEB,X_REC_TYP,",
+		"1,1,3,DB_WEB,GET_X,1,1,NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	s := buf.String()
+	t.Log(s)
+	if len(functions) != 1 || strings.Count(s, "<Function>") != 1 {
+		t.Fatalf("got %d functions, and XML\n%s", len(functions), s)
+	}
+	var got struct {
+		Args []struct {
+			Name     string
+			Flavor   string
+			RecordOf []struct {
+				Name string
+			}
+		}
+	}
+	if err := xml.Unmarshal([]byte(s), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Args) != 2 || got.Args[1].Flavor != "RECORD" ||
+		len(got.Args[1].RecordOf) != 1 || got.Args[1].RecordOf[0].Name != "name" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestApplyAnnotationsGroup(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_CUSTOMER,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"2,1,1,DB_ORDER,GET_ORDER,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"2,2,1,DB_ORDER,PURGE_ORDERS,0,1,P_BEFORE,IN,DATE,,,,,DATE,0,,,,",
+	)
+	var annotations []Annotation
+	for _, s := range []string{"group db_web.get_customer=>api", "group db_order.get_order=>api"} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	functions = ApplyAnnotations(functions, annotations)
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name() < functions[j].Name() })
+	for _, f := range functions {
+		want := "api"
+		if f.name == "PURGE_ORDERS" {
+			want = "DB_ORDER"
+		}
+		if got := f.Group(); got != want {
+			t.Errorf("%s: got group %q, wanted %q", f.Name(), got, want)
+		}
+	}
+	// the function is still called in its package
+	if plsql, _ := functions[0].PlsqlBlock(""); !strings.Contains(plsql, "DB_order.get_order(") {
+		t.Errorf("call target changed:\n%s", plsql)
+	}
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{ServicePerPackage: true}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+	services := make(map[string][]string)
+	for _, part := range strings.Split(s, "\nservice ")[1:] {
+		name, body, _ := strings.Cut(part, " {\n")
+		body, _, _ = strings.Cut(body, "\n}")
+		for _, line := range strings.Split(body, "\n") {
+			if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "rpc "); ok {
+				rpc, _, _ := strings.Cut(rest, " ")
+				services[name] = append(services[name], rpc)
+			}
+		}
+	}
+	want := map[string][]string{"Api": {"GetOrder", "GetCustomer"}, "DbOrder": {"PurgeOrders"}}
+	if len(services) != len(want) {
+		t.Fatalf("got services %v, wanted %v", services, want)
+	}
+	for name, rpcs := range want {
+		got := services[name]
+		sort.Strings(got)
+		sort.Strings(rpcs)
+		if strings.Join(got, ",") != strings.Join(rpcs, ",") {
+			t.Errorf("service %s: got %v, wanted %v", name, got, rpcs)
+		}
+	}
+}
+
+func TestApplyAnnotationsDeprecated(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,2,1,DB_WEB,GET_Y,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,2,2,DB_WEB,GET_Y,0,2,P_OLD,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	var annotations []Annotation
+	for _, s := range []string{"deprecated DB_WEB.get_x", "deprecated DB_WEB.get_y.p_old"} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	functions = ApplyAnnotations(functions, annotations)
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name() < functions[j].Name() })
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+	wantContains(t, s,
+		"message GetX_Input {\n\toption deprecated = true;\n",
+		"message GetX_Output {\n\toption deprecated = true;\n",
+		"rpc GetX (GetX_Input) returns (GetX_Output) {\n\t\toption deprecated = true;\n\t}",
+		"rpc GetY (GetY_Input) returns (GetY_Output) {}",
+		" p_old = 2 [deprecated=true];",
+		" p_id = 1;",
+	)
+	if strings.Contains(s, "message GetY_Input {\n\toption deprecated") {
+		t.Error("GetY_Input is deprecated, too")
+	}
+
+	_, callFun := functions[0].PlsqlBlock("")
+	if !strings.Contains(callFun, "// Deprecated: DB_web.get_x is deprecated.\nfunc (s *oracallServer) GetX(") {
+		t.Errorf("no Deprecated comment on GetX:\n%s", callFun)
+	}
+	if _, callFun = functions[1].PlsqlBlock(""); strings.Contains(callFun, "Deprecated") {
+		t.Errorf("GetY is deprecated:\n%s", callFun)
+	}
+}
+
+func TestApplyAnnotationsStrict(t *testing.T) {
+	rows := []string{
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,2,1,DB_WEB,GET_Y,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	}
+	var annotations []Annotation
+	for _, s := range []string{
+		"rename DB_WEB.get_x=>get_z",
+		"rename DB_WEB.get_xx=>get_w",   // misspelled function
+		"deprecated DB_WEB.get_y.p_idd", // misspelled argument
+		"tag DB_WEB.get_y=>public",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+
+	functions := parseTestCsv(t, rows...)
+	if functions = ApplyAnnotations(functions, annotations); len(functions) != 2 {
+		t.Errorf("lenient: got %d functions, wanted 2", len(functions))
+	}
+
+	functions, err := ApplyAnnotationsStrict(parseTestCsv(t, rows...), annotations)
+	if !errors.Is(err, ErrUnmatchedAnnotation) {
+		t.Fatalf("strict: got %v, wanted %v", err, ErrUnmatchedAnnotation)
+	}
+	t.Log(err)
+	for _, want := range []string{"get_xx", "p_idd"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q is not reported in %q", want, err)
+		}
+	}
+	for _, notWant := range []string{"get_z", "public"} {
+		if strings.Contains(err.Error(), notWant) {
+			t.Errorf("%q is reported in %q", notWant, err)
+		}
+	}
+	if len(functions) != 2 {
+		t.Errorf("strict: got %d functions, wanted 2", len(functions))
+	}
+
+	if _, err = ApplyAnnotationsStrict(functions, annotations[:1]); err != nil {
+		t.Errorf("strict with matching annotations: %+v", err)
+	}
+}
+
+func TestParseCsvStats(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, stats, err := ParseCsvStats(strings.NewReader(`OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK
+1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+1,1,2,DB_WEB,GET_X,0,2,P_NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,
+1,2,1,DB_WEB,GET_TAB,0,1,P_TAB,OUT,PL/SQL TABLE,,,,BINARY_INTEGER,PL/SQL TABLE,0,SCOTT,DB_WEB,NUM_TAB_TYP,
+1,3,1,DB_WEB,HIDDEN#,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+1,4,1,DB_WEB,INTERNAL_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,
+1,4,2,DB_WEB,INTERNAL_X,0,2,P_NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,
+1,5,1,DB_WEB,PING,0,0,,,,,,,,,,,,,
+`), func(name string) bool { return !strings.HasPrefix(strings.ToLower(name), "db_web.internal_") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ParseStats{Rows: 7, Functions: 3, Hidden: 1, Filtered: 1, MissingTableOf: 1}
+	if stats != want {
+		t.Errorf("got %+v, wanted %+v", stats, want)
+	}
+	if len(functions) != stats.Functions {
+		t.Errorf("got %d functions, stats say %d", len(functions), stats.Functions)
+	}
+}
+
+func FuzzReadCsv(f *testing.F) {
+	for _, tc := range testCases {
+		f.Add([]byte(tc.Csv))
+	}
+	fns, err := filepath.Glob("testdata/*.[ct]sv")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, fn := range fns {
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Add([]byte{})
+
+	// the logs of the millions of inputs are just noise
+	defer func(old *slog.Logger) { logger = old }(logger)
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		done := make(chan error, 1)
+		go func() {
+			userArgs := make(chan UserArgument)
+			go func() {
+				for range userArgs {
+				}
+			}()
+			// any malformed input is an error, not a panic
+			_ = ReadCsv(userArgs, bytes.NewReader(data))
+
+			// only the malformed argument trees panic (in ParseArguments), returned as errors
+			_, _, err := SafeParse(bytes.NewReader(data), nil)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if errors.Is(err, ErrParsePanic) {
+				if msg := err.Error(); strings.Contains(msg, "read csv: ") || strings.Contains(msg, "group arguments: ") {
+					t.Errorf("panic outside of ParseArguments: %+v", err)
+				}
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("did not terminate on %q", data)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+)
+
+// SaveRegistry writes a Go file of the pkg package - the one generated by protoc from the .proto
+// SaveProtobuf writes with the same functions, pkg and opts - with the RequestFactories and ResponseFactories maps,
+// returning a fresh request (response) message for the full method name (/pkg.Service/Rpc) of each rpc,
+// for dynamic dispatch (such as a generic CLI or proxy).
+//
+// The messages are of google.golang.org/protobuf, so it returns an error (wrapping ErrInvalidArgument) for Gogo.
+func SaveRegistry(dst io.Writer, functions []Function, pkg string, opts ProtoOptions) error {
+	if pkg == "" {
+		return errors.New("SaveRegistry needs a package name")
+	}
+	if Gogo {
+		return fmt.Errorf("the registry needs google.golang.org/protobuf messages, not gogoproto: %w", ErrInvalidArgument)
+	}
+	naming := opts.Naming
+	if naming == nil {
+		naming = DefaultNaming{}
+	}
+	var requests, responses strings.Builder
+	var usesEmpty bool
+	for _, fun := range functions {
+		// skip the functions SaveProtobuf skips
+		if err := fun.saveProtobuf(io.Discard, make(map[string]struct{}), naming, ProtoOptions{}); err != nil {
+			if SkipMissingTableOf && (errors.Is(err, ErrMissingTableOf) || errors.Is(err, ErrUnknownSimpleType)) {
+				continue
+			}
+			return fmt.Errorf("%s: %w", fun.name, err)
+		}
+		rpc := fun.protoRPC(naming, pkg, opts)
+		method := "/" + pkg + "." + naming.ServiceName(rpc.Group) + "/" + rpc.Name
+		usesEmpty = usesEmpty || fun.usesEmpty()
+		fmt.Fprintf(&requests, "\t%q: func() proto.Message { return new(%s) },\n", method, goMessageType(rpc.In))
+		fmt.Fprintf(&responses, "\t%q: func() proto.Message { return new(%s) },\n", method, goMessageType(rpc.Out))
+		if fun.paginated() {
+			page := fun.pageRPC(rpc, naming)
+			method = "/" + pkg + "." + naming.ServiceName(page.Group) + "/" + page.Name
+			fmt.Fprintf(&requests, "\t%q: func() proto.Message { return new(%s) },\n", method, goMessageType(page.In))
+			fmt.Fprintf(&responses, "\t%q: func() proto.Message { return new(%s) },\n", method, goMessageType(page.Out))
+		}
+	}
+	var emptyImport string
+	if usesEmpty {
+		emptyImport = "\t\"google.golang.org/protobuf/types/known/emptypb\"\n"
+	}
+	b, err := format.Source([]byte(`// Code generated by oracall, DO NOT EDIT.
+
+package ` + pkg + `
+
+import (
+	"google.golang.org/protobuf/proto"
+` + emptyImport + `)
+
+// RequestFactories returns a fresh request message of the rpc, by its full method name.
+var RequestFactories = map[string]func() proto.Message{
+` + requests.String() + `}
+
+// ResponseFactories returns a fresh response message of the rpc, by its full method name.
+var ResponseFactories = map[string]func() proto.Message{
+` + responses.String() + `}
+`))
+	if err != nil {
+		return fmt.Errorf("format the registry: %w", err)
+	}
+	_, err = dst.Write(b)
+	return err
+}
+
+// goMessageType returns the Go type generated by protoc-gen-go for the message of SaveProtobuf.
+func goMessageType(message string) string {
+	if message == "google.protobuf.Empty" {
+		return "emptypb.Empty"
+	}
+	return goCamelCase(message)
+}
+
+// goCamelCase converts the name of a message to its Go name, as protoc-gen-go does:
+// the lowercase letter starting a word (after a digit, an underscore or a dot) is uppercased,
+// and the underscore (or dot) before a lowercase letter is dropped.
+func goCamelCase(s string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isLower(s[i+1]):
+			// skip the '.' of ".{{lowercase}}"
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			// start with a capital letter
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+			// skip the '_' of "_{{lowercase}}"
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isLower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
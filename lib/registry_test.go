@@ -0,0 +1,128 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveRegistry(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"2,1,1,DB_ADM,SET_Y,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"3,1,1,DB_WEB,REFRESH,0,1,,,,,,,,,,,,,",
+	)
+
+	var buf bytes.Buffer
+	if err := SaveRegistry(&buf, functions, "pb", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	registry := buf.String()
+	t.Log(registry)
+	// the names of the .proto
+	buf.Reset()
+	if err := SaveProtobuf(&buf, functions, "pb", "example.com/pb", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	proto := buf.String()
+	wantContains(t, proto,
+		"rpc GetX (GetX_Input) returns (GetX_Output)",
+		"rpc SetY (SetY_Input) returns (SetY_Output)",
+		"rpc Refresh (google.protobuf.Empty) returns (google.protobuf.Empty)",
+	)
+
+	buf.Reset()
+	if err := SaveRegistry(&buf, functions, "pb", ProtoOptions{ServicePerPackage: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"/pb.DbWeb/GetX": func() proto.Message { return new(GetX_Input) },`,
+		`"/pb.DbAdm/SetY": func() proto.Message { return new(SetY_Output) },`,
+	} {
+		// as gofmt aligns them
+		if s := strings.Join(strings.Fields(buf.String()), " "); !strings.Contains(s, want) {
+			t.Errorf("%q not found in\n%s", want, s)
+		}
+	}
+
+	Gogo = true
+	err := SaveRegistry(&buf, functions, "pb", ProtoOptions{})
+	Gogo = false
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %+v for Gogo, wanted ErrInvalidArgument", err)
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "registry-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"cmd", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the messages protoc would generate
+	var stub strings.Builder
+	stub.WriteString("package pb\n\nimport \"google.golang.org/protobuf/types/known/emptypb\"\n\n")
+	for _, nm := range []string{"GetX_Input", "GetX_Output", "SetY_Input", "SetY_Output"} {
+		stub.WriteString("type " + nm + " struct{ emptypb.Empty }\n")
+	}
+	for nm, b := range map[string]string{
+		filepath.Join("pb", "pb.go"):               stub.String(),
+		filepath.Join("pb", "registry_oracall.go"): registry,
+		filepath.Join("cmd", "main.go"): `package main
+
+import (
+	"fmt"
+	"sort"
+
+	pb "` + pbImport + `"
+)
+
+func main() {
+	methods := make([]string, 0, len(pb.RequestFactories))
+	for m := range pb.RequestFactories {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	for _, m := range methods {
+		fmt.Printf("%s %T %T\n", m, pb.RequestFactories[m](), pb.ResponseFactories[m]())
+	}
+}
+`,
+	} {
+		if err = os.WriteFile(filepath.Join(dn, nm), []byte(b), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goBin, "run", "./"+filepath.ToSlash(filepath.Join(dn, "cmd")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("go run: %+v\n%s", err, out.String())
+	}
+	if got, want := out.String(), `/pb.Pb/GetX *pb.GetX_Input *pb.GetX_Output
+/pb.Pb/Refresh *emptypb.Empty *emptypb.Empty
+/pb.Pb/SetY *pb.SetY_Input *pb.SetY_Output
+`; got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import "errors"
+
+// oraPackageStateDiscarded is ORA-04068: "existing state of packages has been discarded".
+//
+// The first call of a session after a package (body) it has used is recompiled fails with this,
+// but the error also discards the package state of the session, so the repeated call
+// initializes the package anew, and succeeds.
+const oraPackageStateDiscarded = 4068
+
+// transientORACodes are the Oracle error codes after which the same call may succeed when repeated.
+var transientORACodes = map[int]struct{}{
+	oraPackageStateDiscarded: {},
+}
+
+// IsTransient reports whether err is an Oracle error after which the call may be repeated
+// (once), such as ORA-04068.
+func IsTransient(err error) bool {
+	var ec interface{ Code() int }
+	if err == nil || !errors.As(err, &ec) {
+		return false
+	}
+	_, ok := transientORACodes[ec.Code()]
+	return ok
+}
+
+// IsPackageStateDiscarded reports whether err is ORA-04068.
+func IsPackageStateDiscarded(err error) bool {
+	var ec interface{ Code() int }
+	return err != nil && errors.As(err, &ec) && ec.Code() == oraPackageStateDiscarded
+}
+
+// RetryTransient calls f, and calls it once again if it returns a transient error (see IsTransient).
+func RetryTransient(f func() error) error { return retryOnce(f, IsTransient) }
+
+// RetryPackageStateDiscarded calls f, and calls it once again if it returns ORA-04068 only.
+//
+// The call failing so has not run the procedure, so this is safe for any function,
+// even for the ones not idempotent.
+func RetryPackageStateDiscarded(f func() error) error { return retryOnce(f, IsPackageStateDiscarded) }
+
+func retryOnce(f func() error, retry func(error) bool) error {
+	err := f()
+	if retry(err) {
+		logger.Warn("retry", "error", err)
+		err = f()
+	}
+	return err
+}
+
+// Idempotent reports whether the function is safe to call again, set by an idempotent annotation:
+// the generated code retries only the idempotent functions on all the transient errors (see RetryTransient),
+// the others only on ORA-04068 (see RetryPackageStateDiscarded).
+func (f Function) Idempotent() bool { return f.idempotent }
+
+// goExec returns the Go code executing the stmt of the function with the params and the execOpts,
+// retried once on the transient errors if the function is idempotent, on ORA-04068 only if not.
+//
+// With NamedBinds, the params are bound by the names of the placeholders (see NamedParams),
+// the binds of the unset defaulted arguments are left out (see OmitParams).
+func (f Function) goExec(execOpts string) string {
+	args := "append(params, " + execOpts + ")..."
+	if f.namedBinds() {
+		args = "oracall.NamedParams({{.ParamNames}}, " + args + ")..."
+	}
+	if f.hasOmittable() {
+		args = "oracall.OmitParams(omitted, " + args + ")..."
+	}
+	if !f.idempotent {
+		return `// retried once on ORA-04068 only, as it is not idempotent (see the idempotent annotation)
+	err = oracall.RetryPackageStateDiscarded(func() error {
+		_, err := stmt.ExecContext(ctx, ` + args + `)
+		return err
+	})`
+	}
+	return `// retried once on the transient errors, such as ORA-04068 after the package is recompiled
+	err = oracall.RetryTransient(func() error {
+		_, err := stmt.ExecContext(ctx, ` + args + `)
+		return err
+	})`
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+type oraError int
+
+func (e oraError) Code() int     { return int(e) }
+func (e oraError) Error() string { return fmt.Sprintf("ORA-%05d", int(e)) }
+
+func TestRetryTransient(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	for _, tc := range []struct {
+		Name  string
+		Errs  []error
+		Calls int
+		Err   error
+	}{
+		{Name: "ok", Errs: []error{nil}, Calls: 1},
+		{Name: "4068", Errs: []error{oraError(4068), nil}, Calls: 2},
+		{Name: "wrapped", Errs: []error{fmt.Errorf("exec: %w", oraError(4068)), nil}, Calls: 2},
+		{Name: "once", Errs: []error{oraError(4068), oraError(4068), nil}, Calls: 2, Err: oraError(4068)},
+		{Name: "other", Errs: []error{oraError(1), nil}, Calls: 1, Err: oraError(1)},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var calls int
+			err := RetryTransient(func() error {
+				calls++
+				return tc.Errs[calls-1]
+			})
+			if calls != tc.Calls {
+				t.Errorf("got %d calls, wanted %d", calls, tc.Calls)
+			}
+			if !errors.Is(err, tc.Err) {
+				t.Errorf("got %v, wanted %v", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestRetryPackageStateDiscarded(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	const oraOther = 3113
+	transientORACodes[oraOther] = struct{}{}
+	defer delete(transientORACodes, oraOther)
+	for _, tc := range []struct {
+		Name                 string
+		Err                  error
+		Discarded, Transient int
+	}{
+		{Name: "4068", Err: oraError(oraPackageStateDiscarded), Discarded: 2, Transient: 2},
+		{Name: "other transient", Err: oraError(oraOther), Discarded: 1, Transient: 2},
+		{Name: "other", Err: oraError(1), Discarded: 1, Transient: 1},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			for _, rc := range []struct {
+				Retry func(func() error) error
+				Calls int
+			}{
+				{Retry: RetryPackageStateDiscarded, Calls: tc.Discarded},
+				{Retry: RetryTransient, Calls: tc.Transient},
+			} {
+				var calls int
+				rc.Retry(func() error {
+					calls++
+					if calls == 1 {
+						return tc.Err
+					}
+					return nil
+				})
+				if calls != rc.Calls {
+					t.Errorf("got %d calls, wanted %d", calls, rc.Calls)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_NAME,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,",
+		"1,2,1,DB_WEB,SET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,2,2,DB_WEB,SET_X,0,2,P_NAME,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,100,,,,",
+	)
+	a, err := ParseAnnotation("idempotent db_web.get_x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := a.String(); s != "idempotent db_web.get_x" {
+		t.Errorf("round-trip: got %q", s)
+	}
+	if functions, err = ApplyAnnotationsStrict(functions, []Annotation{a}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range functions {
+		_, callFun := f.PlsqlBlock("")
+		retried := strings.Contains(callFun, "oracall.RetryTransient(")
+		discarded := strings.Contains(callFun, "oracall.RetryPackageStateDiscarded(")
+		switch f.name {
+		case "GET_X":
+			if !f.Idempotent() || !retried || discarded {
+				t.Errorf("%s: idempotent=%t retried=%t, wanted both\n%s", f.Name(), f.Idempotent(), retried, callFun)
+			}
+		case "SET_X":
+			// still retried on ORA-04068, as the failed call has not run the procedure
+			if f.Idempotent() || retried || !discarded {
+				t.Errorf("%s: idempotent=%t retried=%t on ORA-04068=%t, wanted only on ORA-04068\n%s", f.Name(), f.Idempotent(), retried, discarded, callFun)
+			}
+		default:
+			t.Errorf("unknown function %s", f.Name())
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bufio"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarkRetypedArgs marks the arguments of the new functions whose type has changed since the old functions
+// (ArgumentRetyped changes of DiffFunctions, such as VARCHAR2 to NUMBER), to ease the migration of the clients:
+//
+//   - SaveProtobuf moves the retyped fields to new field numbers, reserving the old ones,
+//     so the old clients' values are not misread as the new type,
+//   - SaveFunctions generates Old<Message>_<Field> funcs returning the field coerced to its old type
+//     (see SaveCompatShims).
+//
+// Only the simple (top-level) arguments retyped to another simple type are marked.
+// The new functions are returned, the Args of the marked ones copied.
+//
+// The new field numbers are kept by the later generations (without the old functions) only if
+// ProtoOptions.Previous has the numbers of the .proto generated with them - see ReadProtoFieldNumbers.
+func MarkRetypedArgs(old, new []Function) []Function {
+	retyped := make(map[string]map[string]struct{})
+	for _, c := range DiffFunctions(old, new) {
+		if c.Kind != ArgumentRetyped {
+			continue
+		}
+		if retyped[c.Function] == nil {
+			retyped[c.Function] = make(map[string]struct{})
+		}
+		retyped[c.Function][c.Argument] = struct{}{}
+	}
+	if len(retyped) == 0 {
+		return new
+	}
+	oldFuncs := make(map[string]Function, len(old))
+	for _, f := range old {
+		oldFuncs[f.Name()] = f
+	}
+
+	functions := make([]Function, len(new))
+	copy(functions, new)
+	for i, f := range functions {
+		names := retyped[f.Name()]
+		if len(names) == 0 {
+			continue
+		}
+		oldArgs := make(map[string]Argument)
+		for _, arg := range oldFuncs[f.Name()].diffArgs() {
+			oldArgs[arg.RealName()] = arg
+		}
+		mark := func(arg *Argument) bool {
+			if _, ok := names[arg.RealName()]; !ok || arg.Flavor != FLAVOR_SIMPLE {
+				return false
+			}
+			o := oldArgs[arg.RealName()]
+			if o.Flavor != FLAVOR_SIMPLE {
+				return false
+			}
+			arg.retypedFrom = &o
+			return true
+		}
+		f.Args = append([]Argument(nil), f.Args...)
+		for j := range f.Args {
+			mark(&f.Args[j])
+		}
+		if f.Returns != nil {
+			ret := *f.Returns
+			if mark(&ret) {
+				f.Returns = &ret
+			}
+		}
+		functions[i] = f
+	}
+	return functions
+}
+
+// renumberRetyped gives new numbers (after the greatest of nums and the previously reserved ones)
+// to the fields of the retyped args - except the ones already renumbered (done) -,
+// returning the numbers to be reserved: the old numbers of these, and the previously reserved ones.
+func renumberRetyped(args []Argument, nums []int, prev ProtoMessageNumbers, done []bool) []string {
+	var next int
+	for _, n := range nums {
+		next = max(next, n)
+	}
+	reserved := make(map[int]struct{}, len(prev.Reserved))
+	for _, n := range prev.Reserved {
+		next = max(next, n)
+		reserved[n] = struct{}{}
+	}
+	for i, arg := range args {
+		if arg.retypedFrom == nil || done != nil && done[i] {
+			continue
+		}
+		reserved[nums[i]] = struct{}{}
+		if next++; next == reservedFieldNumberFirst {
+			next = reservedFieldNumberLast + 1
+		}
+		nums[i] = next
+	}
+	if len(reserved) == 0 {
+		return nil
+	}
+	ns := make([]int, 0, len(reserved))
+	for n := range reserved {
+		ns = append(ns, n)
+	}
+	sort.Ints(ns)
+	ss := make([]string, len(ns))
+	for i, n := range ns {
+		ss[i] = strconv.Itoa(n)
+	}
+	return ss
+}
+
+// ProtoFieldNumbers are the field numbers of the messages of a .proto, by the message name,
+// as read by ReadProtoFieldNumbers.
+type ProtoFieldNumbers map[string]ProtoMessageNumbers
+
+// ProtoMessageNumbers are the fields (by their names) and the reserved field numbers of a message.
+type ProtoMessageNumbers struct {
+	Fields   map[string]ProtoField
+	Reserved []int
+}
+
+// ProtoField is the type and the number of a field.
+type ProtoField struct {
+	Type   string
+	Number int
+}
+
+var (
+	rProtoMessage  = regexp.MustCompile(`^message\s+(\w+)\s*\{`)
+	rProtoReserved = regexp.MustCompile(`^\s*reserved\s+([^;]+);`)
+	rProtoField    = regexp.MustCompile(`^\s*(?:repeated\s+|optional\s+)?(map<[^>]+>|\S+)\s+(\w+)\s*=\s*([0-9]+)\b`)
+)
+
+// ReadProtoFieldNumbers reads the field numbers (and the reserved numbers) of the messages of a .proto
+// generated by SaveProtobuf, for ProtoOptions.Previous.
+func ReadProtoFieldNumbers(r io.Reader) (ProtoFieldNumbers, error) {
+	pfn := make(ProtoFieldNumbers)
+	var msgName string
+	var msg ProtoMessageNumbers
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if msgName == "" {
+			if m := rProtoMessage.FindStringSubmatch(line); m != nil {
+				msgName, msg = m[1], ProtoMessageNumbers{Fields: make(map[string]ProtoField)}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "}") {
+			pfn[msgName], msgName = msg, ""
+			continue
+		}
+		if m := rProtoReserved.FindStringSubmatch(line); m != nil {
+			// the reserved names are quoted
+			for _, s := range strings.Split(m[1], ",") {
+				if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+					msg.Reserved = append(msg.Reserved, n)
+				}
+			}
+			continue
+		}
+		if m := rProtoField.FindStringSubmatch(line); m != nil {
+			n, err := strconv.Atoi(m[3])
+			if err != nil {
+				return pfn, fmt.Errorf("%q: %w", line, err)
+			}
+			msg.Fields[m[2]] = ProtoField{Type: m[1], Number: n}
+		}
+	}
+	return pfn, scanner.Err()
+}
+
+// keepPreviousNumbers gives the fields of a message their previous numbers, if the message has reserved
+// numbers (so a field has been renumbered, see MarkRetypedArgs): the renumbering is kept by the later generations.
+// The other fields are moved off the previous numbers, and
+// the fields of the same type as before are reported as done (renumbered already, if retyped).
+func keepPreviousNumbers(names, types []string, nums []int, prev ProtoMessageNumbers) []bool {
+	if len(prev.Reserved) == 0 {
+		return nil
+	}
+	done := make([]bool, len(names))
+	kept := make([]bool, len(names))
+	for i, nm := range names {
+		f, ok := prev.Fields[nm]
+		if !ok {
+			continue
+		}
+		kept[i], done[i] = true, f.Type == types[i]
+		if nums[i] == f.Number {
+			continue
+		}
+		for j := range nums {
+			if nums[j] == f.Number {
+				nums[j] = nums[i]
+				break
+			}
+		}
+		nums[i] = f.Number
+	}
+	// the new fields must not get a reserved (or kept) number
+	used := make(map[int]struct{}, len(nums)+len(prev.Reserved))
+	next := 0
+	for _, n := range prev.Reserved {
+		used[n] = struct{}{}
+		next = max(next, n)
+	}
+	for i, n := range nums {
+		if kept[i] {
+			used[n] = struct{}{}
+		}
+		next = max(next, n)
+	}
+	for i, n := range nums {
+		if kept[i] {
+			continue
+		}
+		if _, ok := used[n]; ok {
+			if next++; next == reservedFieldNumberFirst {
+				next = reservedFieldNumberLast + 1
+			}
+			nums[i] = next
+		}
+		used[nums[i]] = struct{}{}
+	}
+	return done
+}
+
+// hasRetyped reports whether the function has arguments marked by MarkRetypedArgs.
+func (f Function) hasRetyped() bool {
+	for _, arg := range f.Args {
+		if arg.retypedFrom != nil {
+			return true
+		}
+	}
+	return f.Returns != nil && f.Returns.retypedFrom != nil
+}
+
+// SaveCompatShims writes the Old<Message>_<Field> funcs of the retyped fields of the input (or output) message
+// (see MarkRetypedArgs), returning the value of the field coerced to the Go type of its old version,
+// or an error if it cannot be coerced (such as a non-numeric string to a number).
+//
+// The retypes without a coercion (such as to or from a date) get no shim, just a warning.
+func (f Function) SaveCompatShims(dst io.Writer, out bool) error {
+	args := make([]Argument, 0, len(f.Args)+1)
+	for _, arg := range f.Args {
+		if arg.retypedFrom != nil && (out && arg.IsOutput() || !out && arg.IsInput()) {
+			args = append(args, arg)
+		}
+	}
+	if out && f.Returns != nil && f.Returns.retypedFrom != nil {
+		args = append(args, *f.Returns)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	structName := strings.TrimPrefix(f.pbTypeName(out), "pb.")
+
+	buf := Buffers.Get()
+	defer Buffers.Put(buf)
+	var c converter
+	for _, arg := range args {
+		got, err := arg.goType(false)
+		if err != nil {
+			return fmt.Errorf("%s: %w", arg.Name, err)
+		}
+		o := *arg.retypedFrom
+		oldGot, err := o.goType(false)
+		if err != nil {
+			return fmt.Errorf("%s (old): %w", arg.Name, err)
+		}
+		from, to := c.pbGoType(arg, got), c.pbGoType(o, oldGot)
+		coerce, ok := coerceShim(from, to)
+		if !ok {
+			logger.Warn("no compatibility shim", "function", f.Name(), "argument", arg.Name, "old", o.diffType(), "new", arg.diffType())
+			continue
+		}
+		field := CamelCase(arg.Name)
+		value := "s." + field
+		if _, ok := arg.protoWrapper(); ok {
+			value += ".GetValue()"
+		}
+		fmt.Fprintf(buf, `
+// Old%[1]s_%[2]s returns s.%[2]s as its old type (%[3]s), before it has changed to %[4]s.
+//
+// Deprecated: for the migration of the clients to the new type only.
+func Old%[1]s_%[2]s(s *pb.%[1]s) (%[5]s, error) {
+	var zero %[5]s
+	if s == nil {
+		return zero, nil
+	}
+	v := %[6]s
+	%[7]s
+}
+`, structName, field, o.diffType(), arg.diffType(), to, value, coerce)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("save compatibility shims of %q (%s): %w", structName, buf.String(), err)
+	}
+	_, err = dst.Write(b)
+	return err
+}
+
+// coerceShim returns the statements of a shim returning v (of the from Go type) as the to Go type.
+func coerceShim(from, to string) (string, bool) {
+	bits := func(typ string) int {
+		if strings.HasSuffix(typ, "32") {
+			return 32
+		}
+		return 64
+	}
+	isInt := func(typ string) bool { return typ == "int32" || typ == "int64" }
+	isFloat := func(typ string) bool { return typ == "float32" || typ == "float64" }
+	switch {
+	case from == to:
+		return "return v, nil", true
+	case (isInt(from) || isFloat(from)) && (isInt(to) || isFloat(to)):
+		return "return " + to + "(v), nil", true
+	case to == "string":
+		switch {
+		case isInt(from):
+			return "return strconv.FormatInt(int64(v), 10), nil", true
+		case isFloat(from):
+			return fmt.Sprintf("return strconv.FormatFloat(float64(v), 'f', -1, %d), nil", bits(from)), true
+		case from == "bool":
+			return "return strconv.FormatBool(v), nil", true
+		case from == "[]byte":
+			return "return string(v), nil", true
+		}
+	case from == "string":
+		// the empty string is NULL for Oracle
+		switch {
+		case isInt(to):
+			return fmt.Sprintf("if v == \"\" {\nreturn zero, nil\n}\nn, err := strconv.ParseInt(v, 10, %d)\nreturn %s(n), err", bits(to), to), true
+		case isFloat(to):
+			return fmt.Sprintf("if v == \"\" {\nreturn zero, nil\n}\nn, err := strconv.ParseFloat(v, %d)\nreturn %s(n), err", bits(to), to), true
+		case to == "bool":
+			return "if v == \"\" {\nreturn zero, nil\n}\nreturn strconv.ParseBool(v)", true
+		case to == "[]byte":
+			return "return []byte(v), nil", true
+		}
+	}
+	return "", false
+}
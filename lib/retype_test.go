@@ -0,0 +1,200 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarkRetypedArgs(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	parse := func(fn string) []Function {
+		fh, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+		functions, err := ParseCsv(fh, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return functions
+	}
+	// P_KIND (IN) and P_CODE (OUT) are changed from VARCHAR2(10) to NUMBER(9)
+	old, new := parse("testdata/retype_old.csv"), parse("testdata/retype_new.csv")
+	functions := MarkRetypedArgs(old, new)
+	if new[0].hasRetyped() {
+		t.Error("the new functions are modified")
+	}
+	if !functions[0].hasRetyped() {
+		t.Fatal("no retyped argument is marked")
+	}
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	wantContains(t, buf.String(),
+		"\treserved 2;\n", "sint32 p_kind = 3;",
+		"\treserved 1;\n", "sint32 p_code = 3;", "string p_name = 2;",
+	)
+
+	var goBuf bytes.Buffer
+	if err := SaveFunctions(&goBuf, functions, "main", "example.com/db_web/pb", false); err != nil {
+		t.Fatal(err)
+	}
+	wantContains(t, goBuf.String(),
+		"func OldGetCode_Input_PKind(s *pb.GetCode_Input) (string, error) {",
+		"func OldGetCode_Output_PCode(s *pb.GetCode_Output) (string, error) {",
+		"return strconv.FormatInt(int64(v), 10), nil",
+	)
+	if strings.Contains(goBuf.String(), "OldGetCode_Output_PName") {
+		t.Error("shim of the unchanged P_NAME")
+	}
+
+	// the other way round, a string is parsed
+	var shims strings.Builder
+	if err := MarkRetypedArgs(new, old)[0].SaveCompatShims(&shims, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "n, err := strconv.ParseInt(v, 10, 32)"; !strings.Contains(shims.String(), want) {
+		t.Errorf("no %q in\n%s", want, shims.String())
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "retype-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for the new GET_CODE
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type GetCode_Input struct {
+	PId   int32
+	PKind int32
+}
+type GetCode_Output struct {
+	PCode int32
+	PName string
+}
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goBuf.Reset()
+	if err = SaveFunctions(&goBuf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	goBuf.WriteString("\nfunc main() {}\n")
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), goBuf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go build: %+v\n%s", err, out.String())
+	}
+}
+
+func TestRetypedNumbersKept(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	parse := func(fn string) []Function {
+		fh, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+		functions, err := ParseCsv(fh, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return functions
+	}
+	old, new := parse("testdata/retype_old.csv"), parse("testdata/retype_new.csv")
+	// generate returns the .proto generated after the previous one, and its field numbers
+	generate := func(functions []Function, previous string) (string, ProtoFieldNumbers) {
+		t.Helper()
+		var opts ProtoOptions
+		if previous != "" {
+			var err error
+			if opts.Previous, err = ReadProtoFieldNumbers(strings.NewReader(previous)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf strings.Builder
+		if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", opts); err != nil {
+			t.Fatal(err)
+		}
+		pfn, err := ReadProtoFieldNumbers(strings.NewReader(buf.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String(), pfn
+	}
+	want := ProtoFieldNumbers{
+		"GetCode_Input": {
+			Fields: map[string]ProtoField{
+				"p_id":   {Type: "sint32", Number: 1},
+				"p_kind": {Type: "sint32", Number: 3},
+			},
+			Reserved: []int{2},
+		},
+		"GetCode_Output": {
+			Fields: map[string]ProtoField{
+				"p_code": {Type: "sint32", Number: 3},
+				"p_name": {Type: "string", Number: 2},
+			},
+			Reserved: []int{1},
+		},
+	}
+	check := func(name string, got ProtoFieldNumbers) {
+		t.Helper()
+		for msg, w := range want {
+			if d := cmp.Diff(w, got[msg]); d != "" {
+				t.Errorf("%s: %s: %s", name, msg, d)
+			}
+		}
+	}
+
+	first, got := generate(MarkRetypedArgs(old, new), "")
+	check("first", got)
+	// the next run, without the old functions
+	second, got := generate(new, first)
+	check("second", got)
+	// and with them again: the retyped fields are not renumbered again
+	_, got = generate(MarkRetypedArgs(old, new), second)
+	check("third", got)
+
+	// a new field does not get a reserved number
+	added := append([]Function(nil), new...)
+	added[0].Args = append(append([]Argument(nil), added[0].Args...), added[0].Args[0])
+	added[0].Args[len(added[0].Args)-1].Name = "p_new"
+	_, got = generate(added, second)
+	if n := got["GetCode_Input"].Fields["p_new"].Number; n != 4 {
+		t.Errorf("p_new got number %d, wanted 4", n)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrParsePanic is wrapped by the errors SafeParse returns for the panics of the parsing.
+var ErrParsePanic = errors.New("panic while parsing")
+
+// SafeParse parses the csv as ParseCsvStats, but never panics:
+// a panic while parsing (such as of a malformed argument tree) is returned as an error
+// wrapping ErrParsePanic, naming the function being parsed - for the long-running services
+// reloading the definitions, where a bad input must not crash the host.
+//
+// The functions parsed before the panic are returned, too.
+func SafeParse(r io.Reader, filter func(string) bool) (functions []Function, stats ParseStats, err error) {
+	r = withReadTimeout(r, CsvReadTimeout)
+	userArgs := make(chan UserArgument, 16)
+	filteredArgs := make(chan []UserArgument, 16)
+	var grp errgroup.Group
+	grp.Go(func() (err error) {
+		defer recoverParse(&err, "read csv")
+		return ReadCsv(userArgs, r)
+	})
+	grp.Go(func() (err error) {
+		defer func() {
+			// let ReadCsv finish
+			for range userArgs {
+			}
+		}()
+		defer recoverParse(&err, "group arguments")
+		FilterAndGroup(filteredArgs, userArgs, filter, &stats)
+		return nil
+	})
+	// parse the functions one by one, to know which one panicked
+	for uas := range filteredArgs {
+		if err != nil {
+			continue // let the readers finish
+		}
+		var parsed []Function
+		parsed, err = safeParseArguments(uas, filter, &stats)
+		functions = append(functions, parsed...)
+	}
+	if grpErr := grp.Wait(); grpErr != nil {
+		err = errors.Join(err, grpErr)
+	}
+	return functions, stats, err
+}
+
+// safeParseArguments parses the arguments of one function with ParseArguments, recovering its panic.
+func safeParseArguments(uas []UserArgument, filter func(string) bool, stats *ParseStats) (functions []Function, err error) {
+	defer recoverParse(&err, "parse "+uas[0].fullName())
+	ch := make(chan []UserArgument, 1)
+	ch <- uas
+	close(ch)
+	return ParseArguments(ch, filter, stats), nil
+}
+
+// recoverParse sets err to the recovered panic of the parsing stage, if any.
+func recoverParse(err *error, stage string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	logger.Error("parse panic", "stage", stage, "panic", r, "stack", string(debug.Stack()))
+	if e, ok := r.(error); ok {
+		*err = fmt.Errorf("%s: %w: %w", stage, ErrParsePanic, e)
+	} else {
+		*err = fmt.Errorf("%s: %w: %v", stage, ErrParsePanic, r)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestSafeParse(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	const head = "OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK\n"
+	const getX = "1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,\n"
+
+	functions, stats, err := SafeParse(strings.NewReader(head+getX), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 1 || functions[0].Name() != "DB_web.get_x" || stats.Functions != 1 || stats.Rows != 1 {
+		t.Errorf("got %v (%+v), wanted DB_web.get_x", functions, stats)
+	}
+
+	for _, tc := range []struct {
+		Name, CSV string
+		Panic     bool
+		Want      string
+	}{
+		{Name: "malformed cell", CSV: head + getX + "2,1,1,DB_WEB,SET_X,0,1,P_ID,IN,NUMBER,999,,,,NUMBER,0,,,,\n",
+			Want: "line 3: DATA_PRECISION: "},
+		{Name: "not a number", CSV: head + "x,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,\n",
+			Want: "line 2: OBJECT_ID: "},
+		{Name: "nil parent", CSV: head + getX + "2,1,1,DB_WEB,SET_X,0,1,P_REC,IN,PL/SQL RECORD,,,,,PL/SQL RECORD,0,,,,\n" +
+			"2,1,2,DB_WEB,SET_X,2,1,ID,IN,NUMBER,9,,,,NUMBER,0,,,,\n",
+			Panic: true, Want: "parse DB_WEB.SET_X: "},
+		{Name: "no object name", CSV: head + "2,1,1,DB_WEB,,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,\n" +
+			"2,1,2,DB_WEB,,2,1,ID,IN,NUMBER,9,,,,NUMBER,0,,,,\n",
+			Panic: true, Want: "parse DB_WEB.: "},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			functions, _, err := SafeParse(strings.NewReader(tc.CSV), nil)
+			t.Log(err)
+			if err == nil {
+				t.Fatalf("no error, got %v", functions)
+			}
+			if got := errors.Is(err, ErrParsePanic); got != tc.Panic {
+				t.Errorf("got ErrParsePanic=%t, wanted %t", got, tc.Panic)
+			}
+			var numErr *strconv.NumError
+			if !tc.Panic && !errors.As(err, &numErr) {
+				t.Errorf("%v is not a *strconv.NumError", err)
+			}
+			if !strings.Contains(err.Error(), tc.Want) {
+				t.Errorf("got %q, wanted %q", err, tc.Want)
+			}
+		})
+	}
+}
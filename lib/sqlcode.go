@@ -0,0 +1,93 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"strings"
+)
+
+// setSQLCodeArgs sets the OUT arguments capturing the SQLCODE and SQLERRM of the function,
+// from the "code_arg[, msg_arg]" list of a sqlcode annotation.
+//
+// The code argument must be a numeric, the message argument a character OUT argument,
+// as they are assigned in the EXCEPTION handler of the generated block.
+func (f *Function) setSQLCodeArgs(names string) error {
+	codeName, msgName, _ := strings.Cut(names, ",")
+	codeName, msgName = strings.TrimSpace(codeName), strings.TrimSpace(msgName)
+	if codeName == "" {
+		return fmt.Errorf("%q: no code argument: %w", names, ErrInvalidArgument)
+	}
+	find := func(name string, types ...string) (string, error) {
+		for _, arg := range f.Args {
+			if !strings.EqualFold(arg.Name, name) {
+				continue
+			}
+			if !arg.IsOutput() || arg.Flavor != FLAVOR_SIMPLE {
+				return "", fmt.Errorf("%s is not a simple OUT argument: %w", name, ErrInvalidArgument)
+			}
+			for _, t := range types {
+				if arg.Type == t {
+					return arg.Name, nil
+				}
+			}
+			return "", fmt.Errorf("%s is %s, not one of %v: %w", name, arg.Type, types, ErrInvalidArgument)
+		}
+		return "", fmt.Errorf("%s: no such argument: %w", name, ErrInvalidArgument)
+	}
+	code, err := find(codeName, "NUMBER", "INTEGER", "PLS_INTEGER", "BINARY_INTEGER")
+	if err != nil {
+		return err
+	}
+	var msg string
+	if msgName != "" {
+		if msg, err = find(msgName, "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR"); err != nil {
+			return err
+		}
+	}
+	f.sqlCode, f.sqlErrm = code, msg
+	return nil
+}
+
+// isSQLCodeArg reports whether the argument (named as in prepareCall) captures the SQLCODE or SQLERRM.
+//
+// Such an argument is passed to the function in a variable assigned to its bind only after the call,
+// as the same bind in the EXCEPTION handler would be another (positional) one.
+func (f Function) isSQLCodeArg(name string) bool {
+	return f.sqlCode != "" && (name == replHidden(f.sqlCode) || f.sqlErrm != "" && name == replHidden(f.sqlErrm))
+}
+
+// sqlCodeVarType returns the type of the variable of the argument capturing the SQLCODE or SQLERRM.
+func (arg Argument) sqlCodeVarType() string {
+	if strings.HasSuffix(arg.Type, "CHAR2") && !strings.Contains(arg.AbsType, "(") {
+		return arg.Type + "(32767)"
+	}
+	return arg.AbsType
+}
+
+// sqlCodeHandler returns the EXCEPTION handler assigning SQLCODE and SQLERRM
+// to the variables of the arguments set by a sqlcode annotation, or "" if there are none.
+func (f Function) sqlCodeHandler() string {
+	if f.sqlCode == "" {
+		return ""
+	}
+	s := "WHEN OTHERS THEN " + getInnerVarName(f.Name(), replHidden(f.sqlCode)) + " := SQLCODE;"
+	if f.sqlErrm == "" {
+		return s
+	}
+	errm := "SQLERRM"
+	for _, arg := range f.Args {
+		if arg.Name == f.sqlErrm && arg.Charlength > 0 {
+			// the variable is not longer than the argument
+			substr := "SUBSTRB"
+			if arg.LengthInChars() {
+				substr = "SUBSTR"
+			}
+			errm = fmt.Sprintf("%s(SQLERRM, 1, %d)", substr, arg.Charlength)
+			break
+		}
+	}
+	return s + " " + getInnerVarName(f.Name(), replHidden(f.sqlErrm)) + " := " + errm + ";"
+}
@@ -31,14 +31,24 @@ const (
 
 type Function struct {
 	LastDDL              time.Time
-	Replacement          *Function
+	Replacement          *Function // Deprecated: read it by ReplacementFunction, set it by a replace annotation.
+	xmlFetch             *Function // the variant returning the REF CURSOR as XMLTYPE, set by an xml-fetch annotation
 	Returns              *Argument
 	Package, name, alias string
+	group                string // the service of the function, if set by a group annotation
+	normName             string // the normalized name (see Function.protoName), cached by SaveProtobuf
+	Owner                string // schema of the package - empty if unknown
 	Documentation        string
 	Args                 []Argument
 	Tag, handle          []string
 	maxTableSize         int
-	ReplacementIsJSON    bool
+	fetchSize            int           // the rows fetched at once from the returned cursors, if set by a fetch-size annotation
+	sqlCode, sqlErrm     string        // the OUT arguments capturing SQLCODE and SQLERRM, if set by a sqlcode annotation
+	argGroups            []argGroup    // the cross-field constraints, set by requires and excludes annotations
+	pageLifetime         time.Duration // the lifetime of the page tokens of the paginated rpc, set by a paginate annotation
+	ReplacementIsJSON    bool          // Deprecated: read it by ReplacementUsesJSON, set it by a replace_json annotation.
+	deprecated           bool          // set by a deprecated annotation
+	idempotent           bool          // safe to call again, set by an idempotent annotation
 }
 
 func (f Function) Name() string {
@@ -51,10 +61,34 @@ func (f Function) Name() string {
 	}
 	return UnoCap(f.Package) + "." + nm
 }
+
+// Group returns the service group of the function: the one set by a group annotation, or the Package.
+// The function is still called in its Package.
+func (f Function) Group() string {
+	if f.group != "" {
+		return f.group
+	}
+	return f.Package
+}
+
+// ReplacementFunction returns the function set by a replace or replace_json annotation, or nil.
+//
+// The generated code calls the replacement instead of this function,
+// passing the whole input as one serialized CLOB, and parses the output from the returned CLOB.
+// The replaced function's arguments still define the gRPC messages.
+func (f Function) ReplacementFunction() *Function { return f.Replacement }
+
+// ReplacementUsesJSON reports whether the ReplacementFunction gets its input and returns its output
+// as JSON (replace_json), not XML (replace).
+func (f Function) ReplacementUsesJSON() bool { return f.ReplacementIsJSON }
+
 func (f Function) RealName() string {
 	if f.Replacement != nil {
 		return f.Replacement.RealName()
 	}
+	if f.xmlFetch != nil {
+		return f.xmlFetch.RealName()
+	}
 	nm := strings.ToLower(f.name)
 	if f.Package == "" {
 		return nm
@@ -139,6 +173,15 @@ type Argument struct {
 	TableOf          *Argument // this argument is a table (array) of this type
 	mu               *sync.Mutex
 	goTypeName       string
+	oraName          string   // the name in the database, if renamed
+	renamedFrom      []string // the previous names, set by rename annotations
+	deprecated       bool     // set by a deprecated annotation
+	jsonName         string   // the json_name of the field, if set by a json-name annotation
+	example          string   // the example value of the field, if set by an example annotation
+	emptyAs          string   // the sentinel bound in place of the empty string, set by an empty-string annotation
+	rejectEmpty      bool     // the empty string is rejected, set by an empty-string annotation
+	namedRow         bool     // the REF CURSOR's row is named by a cursor-row annotation
+	dynamicRow       bool     // the row of a weakly typed REF CURSOR without a shape (see setDynamicRows)
 	Name             string
 	Type, TypeName   string
 	AbsType          string
@@ -150,7 +193,30 @@ type Argument struct {
 	Direction  direction
 	Precision  uint8
 	Scale      uint8
+	// Defaulted is true if the argument has a DEFAULT value in the database, so it is optional.
+	Defaulted bool `xml:",omitempty"`
+	// CharUsed is "C" if Charlength is in characters, "B" if in bytes, empty if unknown.
+	CharUsed string `xml:",omitempty"`
+	// Description of the argument, emitted as the field's comment (see ApplyArgumentComments).
+	Description string `xml:",omitempty"`
+	// retypedFrom is the previous version of the argument, if its type has changed (see MarkRetypedArgs).
+	retypedFrom *Argument
+}
+
+// RealName returns the name of the argument in the database - Name may be renamed by an annotation.
+func (a Argument) RealName() string {
+	if a.oraName != "" {
+		return a.oraName
+	}
+	return a.Name
+}
+
+// LengthInChars reports whether Charlength counts characters, not bytes.
+// The national character set (NCHAR, NVARCHAR2) always has character semantics.
+func (a Argument) LengthInChars() bool {
+	return a.CharUsed == "C" || a.Charset == "NCHAR_CS"
 }
+
 type NamedArgument struct {
 	*Argument
 	Name string
@@ -183,10 +249,23 @@ func (a Argument) IsNestedTable() bool {
 	return false
 }
 
+// plsSpecificTypes maps the PL/SQL-only PLS_TYPEs to the Type they are bound as.
+// The subtypes of PLS_INTEGER are bound as PLS_INTEGER.
+var plsSpecificTypes = map[string]string{
+	"PLS_INTEGER": "PLS_INTEGER", "BINARY_INTEGER": "BINARY_INTEGER",
+	"SIMPLE_INTEGER": "PLS_INTEGER", "NATURAL": "PLS_INTEGER", "NATURALN": "PLS_INTEGER",
+	"POSITIVE": "PLS_INTEGER", "POSITIVEN": "PLS_INTEGER", "SIGNTYPE": "PLS_INTEGER",
+	"BOOLEAN": "BOOLEAN",
+}
+
 func NewArgument(name, dataType, plsType, typeName, dirName string, dir direction,
 	charset, indexBy string, precision, scale uint8, charlength uint) Argument {
 
 	name = strings.ToLower(name)
+	// XMLTYPE is an OPAQUE object type (maybe without PLS_TYPE), passed as its CLOB serialization
+	if dataType == "OPAQUE/XMLTYPE" || dataType == "XMLTYPE" || plsType == "XMLTYPE" {
+		dataType, plsType, typeName = "XMLTYPE", "CLOB", ""
+	}
 	if typeName == "..@" {
 		typeName = ""
 	}
@@ -230,6 +309,10 @@ func NewArgument(name, dataType, plsType, typeName, dirName string, dir directio
 	case "TABLE", "PL/SQL TABLE", "REF CURSOR":
 		arg.Flavor = FLAVOR_TABLE
 	}
+	// DATA_TYPE may be generic (NUMBER, BINARY_INTEGER) or empty for the PL/SQL-only types
+	if t, ok := plsSpecificTypes[arg.ora]; ok && arg.Flavor == FLAVOR_SIMPLE {
+		arg.Type = t
+	}
 
 	switch arg.Type {
 	case "CHAR", "NCHAR", "VARCHAR", "NVARCHAR", "VARCHAR2", "NVARCHAR2":
@@ -251,6 +334,13 @@ func NewArgument(name, dataType, plsType, typeName, dirName string, dir directio
 		}
 	case "PLS_INTEGER", "BINARY_INTEGER":
 		arg.AbsType = "INTEGER(10)"
+	case "RAW":
+		// Charlength is in bytes; PL/SQL needs a length for a RAW variable
+		if arg.Charlength != 0 {
+			arg.AbsType = fmt.Sprintf("RAW(%d)", arg.Charlength)
+		} else {
+			arg.AbsType = "RAW(32767)"
+		}
 	default:
 		arg.AbsType = arg.Type
 	}
@@ -0,0 +1,27 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"database/sql"
+)
+
+type ctxTx struct{}
+
+// ContextWithTx returns a context carrying the transaction.
+//
+// The generated functions called with such a context execute in tx,
+// and leave its commit (or rollback) to the caller;
+// without one, each call begins and commits its own transaction.
+func ContextWithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, ctxTx{}, tx)
+}
+
+// TxFromContext returns the transaction set by ContextWithTx, or nil.
+func TxFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(ctxTx{}).(*sql.Tx)
+	return tx
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// TypeMapping is the mapping of a (site-specific) Oracle type to the generated code, see RegisterTypeMapper.
+type TypeMapping struct {
+	// GoType is the Go type of the field, as the protobuf package has it.
+	// It may use only the imports of the generated code (such as custom or godror), or the pb package.
+	GoType string
+	// ProtoType is the type of the field in the .proto, with the field options ProtoOptions
+	// (such as "gogoproto.customtype"), and ProtoImport imports the file defining it, if not empty.
+	ProtoType    string
+	ProtoOptions map[string]interface{}
+	ProtoImport  string
+
+	// BindType is the Go type of the OUT bind variable, GoType if empty.
+	BindType string
+	// ToBind and FromBind convert the field to the bind variable and back:
+	// fmt formats with one %s for the value to convert - the value is used as is if empty.
+	ToBind, FromBind string
+}
+
+var (
+	typeMappingsMu sync.RWMutex
+	typeMappings   map[string]TypeMapping
+)
+
+// RegisterTypeMapper registers the mapping of the simple arguments of the Oracle type
+// - either the data type (such as "OBJECT") or the qualified type name (such as "SCOTT.POINT_T"),
+// the latter takes precedence.
+//
+// The generators consult the mappings before the built-in types, so the types unknown to oracall
+// can be generated without forking it. The mappings must be registered before parsing the functions.
+func RegisterTypeMapper(oracleType string, mapper TypeMapping) {
+	typeMappingsMu.Lock()
+	if typeMappings == nil {
+		typeMappings = make(map[string]TypeMapping)
+	}
+	typeMappings[strings.ToUpper(oracleType)] = mapper
+	typeMappingsMu.Unlock()
+}
+
+// typeMapping returns the mapping registered for the type of the simple argument.
+func (arg Argument) typeMapping() (TypeMapping, bool) {
+	if arg.Flavor != FLAVOR_SIMPLE {
+		return TypeMapping{}, false
+	}
+	typeMappingsMu.RLock()
+	defer typeMappingsMu.RUnlock()
+	if arg.TypeName != "" {
+		// the type name of an object type has an empty subname
+		if tm, ok := typeMappings[strings.TrimSuffix(strings.ToUpper(arg.TypeName), ".")]; ok {
+			return tm, true
+		}
+	}
+	tm, ok := typeMappings[strings.ToUpper(arg.Type)]
+	return tm, ok
+}
+
+// mappedProtoType is protoType for the argument, with its registered mapping, if any.
+func (arg Argument) mappedProtoType(got string) (string, protoOptions) {
+	if tm, ok := arg.typeMapping(); ok {
+		return tm.ProtoType, protoOptions(tm.ProtoOptions)
+	}
+	return protoType(got, arg.Name, arg.AbsType)
+}
+
+// mappedProtoImports returns the ProtoImports of the mappings of the arguments of the functions.
+func mappedProtoImports(functions []Function) []string {
+	typeMappingsMu.RLock()
+	n := len(typeMappings)
+	typeMappingsMu.RUnlock()
+	if n == 0 {
+		return nil
+	}
+	var imports []string
+	seen := make(map[*Argument]struct{}) // the types may refer to each other
+	var walk func(arg Argument)
+	walkPtr := func(arg *Argument) {
+		if _, ok := seen[arg]; !ok {
+			seen[arg] = struct{}{}
+			walk(*arg)
+		}
+	}
+	walk = func(arg Argument) {
+		if tm, ok := arg.typeMapping(); ok && tm.ProtoImport != "" {
+			imports = append(imports, tm.ProtoImport)
+		}
+		if arg.TableOf != nil {
+			walkPtr(arg.TableOf)
+		}
+		for _, sub := range arg.RecordOf {
+			walkPtr(sub.Argument)
+		}
+	}
+	for _, fun := range functions {
+		for _, arg := range fun.Args {
+			walk(arg)
+		}
+		if fun.Returns != nil {
+			walk(*fun.Returns)
+		}
+	}
+	return imports
+}
+
+func (tm TypeMapping) conv(format, src string) string {
+	if format == "" {
+		return src
+	}
+	return fmt.Sprintf(format, src)
+}
+
+// getConvMapped is getConvSimple for the arguments with a registered mapping.
+func (arg Argument) getConvMapped(
+	tm TypeMapping,
+	convIn, convOut []string,
+	name, paramName string,
+) ([]string, []string) {
+	if !arg.IsOutput() {
+		convIn = append(convIn, fmt.Sprintf("%s = %s  // gcm1", paramName, tm.conv(tm.ToBind, "input."+name)))
+		return convIn, convOut
+	}
+	bindType := tm.BindType
+	if bindType == "" {
+		bindType = tm.GoType
+	}
+	vn := mkVarName(paramName)
+	convIn = append(convIn, fmt.Sprintf("var %s %s", vn, bindType))
+	if arg.IsInput() {
+		convIn = append(convIn, fmt.Sprintf("%s = %s  // gcm2", vn, tm.conv(tm.ToBind, "input."+name)))
+	}
+	convIn = append(convIn, fmt.Sprintf("%s = sql.Out{Dest:&%s, In:%t}  // gcm3", paramName, vn, arg.IsInput()))
+	convOut = append(convOut, fmt.Sprintf("output.%s = %s  // gcm4", name, tm.conv(tm.FromBind, vn)))
+	return convIn, convOut
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestRegisterTypeMapper(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	RegisterTypeMapper("scott.point_t", TypeMapping{
+		GoType:       "string",
+		ProtoType:    "string",
+		ProtoOptions: map[string]interface{}{"json_name": "posWkt"},
+		ProtoImport:  "scott/point.proto",
+		BindType:     "string",
+		ToBind:       "strings.TrimSpace(%s)",
+		FromBind:     "strings.ToUpper(%s)",
+	})
+	defer func() {
+		typeMappingsMu.Lock()
+		delete(typeMappings, "SCOTT.POINT_T")
+		typeMappingsMu.Unlock()
+	}()
+
+	fh, err := os.Open("testdata/typemapping.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	arg := functions[0].Args[1]
+	if tm, ok := arg.typeMapping(); !ok || tm.GoType != "string" {
+		t.Fatalf("%s: no mapping for %q", arg.Name, arg.TypeName)
+	}
+
+	var buf strings.Builder
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`import "scott/point.proto";`, `string p_pos = 2 [json_name="posWkt"];`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("no %q in\n%s", want, buf.String())
+		}
+	}
+
+	_, callFun := functions[0].PlsqlBlock("")
+	for _, want := range []string{"strings.TrimSpace(input.PPos)", "output.PPos = strings.ToUpper("} {
+		if !strings.Contains(callFun, want) {
+			t.Errorf("no %q in\n%s", want, callFun)
+		}
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "typemapping-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for MOVE_POINT
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type MovePoint_Input struct {
+	PId  int32
+	PPos string
+}
+type MovePoint_Output struct {
+	PPos string
+}
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var goBuf bytes.Buffer
+	if err = SaveFunctions(&goBuf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	goBuf.WriteString("\nfunc main() {}\n")
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), goBuf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go build: %+v\n%s", err, out.String())
+	}
+}
@@ -32,7 +32,7 @@ func (arg PlsType) FromOra(dst, src, varName string) string {
 		switch arg.ora {
 		case "DATE", "TIMESTAMP":
 			if Gogo {
-				return fmt.Sprintf("%s = &custom.DateTime{Time:%s}", dst, varName)
+				return fmt.Sprintf("%s = &custom.%s{Time:%s}", dst, arg.customTime(), varName)
 				//return fmt.Sprintf("%s = &custom.DateTime{Time:%s}", dst, varName)
 			} else {
 				return fmt.Sprintf("%s = %s.Timestamp()", dst, varName)
@@ -52,7 +52,7 @@ func (arg PlsType) FromOra(dst, src, varName string) string {
 		return fmt.Sprintf("%s = godror.Lob{IsClob:true, Reader: strings.NewReader(%s)}", dst, src)
 	case "DATE", "TIMESTAMP":
 		if Gogo {
-			return fmt.Sprintf("%s = custom.DateTime{Time:%s}", dst, src)
+			return fmt.Sprintf("%s = custom.%s{Time:%s}", dst, arg.customTime(), src)
 		}
 		return fmt.Sprintf("%s = timestamppb.New(%s)", dst, src)
 	case "PLS_INTEGER", "PL/SQL PLS INTEGER":
@@ -75,15 +75,19 @@ func (arg PlsType) FromOra(dst, src, varName string) string {
 
 func (arg PlsType) GetOra(src, varName string) string {
 	switch arg.ora {
-	case "DATE":
+	// the driver scans the dates into time.Time, without formatting them (and NLS_DATE_FORMAT)
+	case "DATE", "TIMESTAMP":
 		if Gogo {
 			if varName != "" {
-				return fmt.Sprintf("%s.Format(time.RFC3339)", varName)
+				return fmt.Sprintf("&custom.%s{Time:%s}", arg.customTime(), varName)
+			}
+			if arg.ora == "TIMESTAMP" {
+				return fmt.Sprintf("(*custom.Timestamp)(custom.AsDate(%s))", src)
 			}
 			return fmt.Sprintf("custom.AsDate(%s)", src)
 		}
 		if varName != "" {
-			return fmt.Sprintf("%s.Format(time.RFC3339)", varName)
+			return fmt.Sprintf("timestamppb.New(%s)", varName)
 		}
 		return fmt.Sprintf("custom.AsTimestamp(%s)", src)
 
@@ -98,6 +102,15 @@ func (arg PlsType) GetOra(src, varName string) string {
 	return src
 }
 
+// customTime returns the name of the custom type of the DATE or TIMESTAMP in Gogo mode:
+// Timestamp for TIMESTAMP, to keep its fractional seconds, DateTime for the rest.
+func (arg PlsType) customTime() string {
+	if arg.ora == "TIMESTAMP" {
+		return "Timestamp"
+	}
+	return "DateTime"
+}
+
 // ToOra adds the value of the argument with arg type, from src variable to dst variable.
 func (arg PlsType) ToOra(dst, src string, dir direction) (expr string, variable string) {
 	dstVar := mkVarName(dst)
@@ -105,21 +118,23 @@ func (arg PlsType) ToOra(dst, src string, dir direction) (expr string, variable
 	if dir.IsInput() {
 		inTrue = ",In:true"
 	}
-	if arg.ora == "NUMBER" && arg.Precision != 0 && arg.Precision < 10 && arg.Scale == 0 {
+	if arg.ora == "NUMBER" && arg.Precision != 0 && arg.Precision < 10 && arg.Scale == 0 && !NumberAsDecimal {
 		arg.ora = "PLS_INTEGER"
 	}
 	np := strings.TrimPrefix(src, "&")
 	switch arg.ora {
-	case "DATE":
+	// the dates are bound as time.Time (or *timestamppb.Timestamp, which the driver handles as time.Time),
+	// never as strings, so the result does not depend on the NLS_DATE_FORMAT of the session
+	case "DATE", "TIMESTAMP":
 		if Gogo {
 			np := strings.TrimPrefix(src, "&")
 			if dir.IsOutput() {
 				if !strings.HasPrefix(dst, "params[") {
 					return fmt.Sprintf(`%s = %s.Time`, dst, np), ""
 				}
-				return fmt.Sprintf(`if %s == nil { %s = new(custom.DateTime) }
+				return fmt.Sprintf(`if %s == nil { %s = new(custom.%s) }
 					%s = sql.Out{Dest:&%s.Time%s}`,
-						np, np,
+						np, np, arg.customTime(),
 						dst, strings.TrimPrefix(src, "&"), inTrue,
 					),
 					""
@@ -186,10 +201,12 @@ func ParseDigits(s string, precision, scale int) error {
 		s = s[1:]
 	}
 	var dotSeen bool
-	bucket := precision
+	// the integer part has precision-scale digits (without the leading zeros)
+	bucket := precision - scale
 	if precision == 0 && scale == 0 {
 		bucket = 38
 	}
+	s = strings.TrimLeft(s, "0")
 	for _, r := range s {
 		if !dotSeen && r == '.' {
 			dotSeen = true
@@ -210,8 +227,12 @@ func ParseDigits(s string, precision, scale int) error {
 	return nil
 }
 
+// NumberAsDecimal maps every NUMBER to the decimal string godror.Number (string in the proto),
+// regardless of its precision and scale - for domains where no precision can be lost.
+var NumberAsDecimal bool
+
 func goNumType(precision, scale uint8) string {
-	if precision >= 19 || precision == 0 || scale != 0 {
+	if NumberAsDecimal || precision >= 19 || precision == 0 || scale != 0 {
 		return "godror.Number"
 	}
 	if scale != 0 {
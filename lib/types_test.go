@@ -8,7 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestParseDigits(t *testing.T) {
@@ -21,6 +24,10 @@ func TestParseDigits(t *testing.T) {
 		{In: "0", Prec: 32, Scale: 4},
 		{In: "-12.3", Prec: 3, Scale: 1},
 		{In: "12.34", Prec: 3, Scale: 1, WantErr: true},
+		{In: "999.99", Prec: 5, Scale: 2},
+		{In: "0.5", Prec: 2, Scale: 2},
+		{In: "1234.5", Prec: 5, Scale: 2, WantErr: true},
+		{In: "123456", Prec: 5, WantErr: true},
 	} {
 		if err := ParseDigits(tC.In, tC.Prec, tC.Scale); err == nil && tC.WantErr {
 			t.Errorf("%d. wanted error for %q", tN, tC.In)
@@ -70,3 +77,121 @@ func (fe *fakeErr) Error() string {
 	return fmt.Sprintf("%s %s ORA-%05d: %s", fe.query, fe.params, fe.code, fe.errMsg)
 }
 func (fe *fakeErr) Code() int { return fe.code }
+
+func TestPlsTypePreferred(t *testing.T) {
+	for _, tC := range []struct {
+		DataType, PlsType string
+		WantType, WantGo  string
+	}{
+		{DataType: "NUMBER", PlsType: "PLS_INTEGER", WantType: "PLS_INTEGER", WantGo: "int32"},
+		{DataType: "", PlsType: "PLS_INTEGER", WantType: "PLS_INTEGER", WantGo: "int32"},
+		{DataType: "NUMBER", PlsType: "BINARY_INTEGER", WantType: "BINARY_INTEGER", WantGo: "int32"},
+		{DataType: "BINARY_INTEGER", PlsType: "NATURAL", WantType: "PLS_INTEGER", WantGo: "int32"},
+		{DataType: "", PlsType: "BOOLEAN", WantType: "BOOLEAN", WantGo: "bool"},
+		{DataType: "PL/SQL BOOLEAN", PlsType: "BOOLEAN", WantType: "BOOLEAN", WantGo: "bool"},
+		// the generic PLS_TYPEs do not override
+		{DataType: "NUMBER", PlsType: "NUMBER", WantType: "NUMBER", WantGo: "godror.Number"},
+		{DataType: "VARCHAR2", PlsType: "VARCHAR2", WantType: "VARCHAR2", WantGo: "string"},
+	} {
+		arg := NewArgument("p_x", tC.DataType, tC.PlsType, "", "IN", 0, "", "", 0, 0, 0)
+		if arg.Type != tC.WantType {
+			t.Errorf("%s/%s: got type %q, wanted %q", tC.DataType, tC.PlsType, arg.Type, tC.WantType)
+		}
+		if got, err := arg.goType(false); err != nil {
+			t.Errorf("%s/%s: %+v", tC.DataType, tC.PlsType, err)
+		} else if got != tC.WantGo {
+			t.Errorf("%s/%s: got Go type %q, wanted %q", tC.DataType, tC.PlsType, got, tC.WantGo)
+		}
+	}
+}
+
+func TestXMLType(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,TRANSFORM,0,1,P_DOC,IN,OPAQUE/XMLTYPE,,,,,,0,PUBLIC,XMLTYPE,,",
+		"1,1,2,DB_WEB,TRANSFORM,0,2,P_RESULT,OUT,OPAQUE/XMLTYPE,,,,,,0,PUBLIC,XMLTYPE,,",
+	)
+	for _, arg := range functions[0].Args {
+		if arg.Type != "XMLTYPE" || arg.Flavor != FLAVOR_SIMPLE {
+			t.Errorf("%s: got %s (%s)", arg.Name, arg.Type, arg.Flavor)
+		}
+		if got, err := arg.goType(false); err != nil || got != "string" {
+			t.Errorf("%s: got %q, %+v", arg.Name, got, err)
+		}
+	}
+
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for msg, want := range map[string][]string{
+		"Transform_Input":  {"string p_doc = 1"},
+		"Transform_Output": {"string p_result = 1"},
+	} {
+		if d := cmp.Diff(want, protoFields(t, buf.String(), msg)); d != "" {
+			t.Errorf("%s: %s", msg, d)
+		}
+	}
+
+	plsql, callFun := functions[0].PlsqlBlock("")
+	t.Log(plsql)
+	for _, want := range []string{" SYS.XMLTYPE; --X=p_doc", " := XMLTYPE(:", ".getClobVal();"} {
+		if !strings.Contains(plsql, want) {
+			t.Errorf("no %q in\n%s", want, plsql)
+		}
+	}
+	for _, want := range []string{"custom.XML(input.PDoc).Validate()", "godror.Lob{IsClob: true"} {
+		if !strings.Contains(callFun, want) {
+			t.Errorf("no %q in\n%s", want, callFun)
+		}
+	}
+}
+
+func TestDateBinding(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,DATES,0,1,P_D,IN,DATE,,,,,DATE,0,,,,",
+		"1,1,2,DB_WEB,DATES,0,2,P_T,OUT,TIMESTAMP,,6,,,TIMESTAMP,0,,,,",
+		"1,1,3,DB_WEB,DATES,0,3,P_IO,IN/OUT,DATE,,,,,DATE,0,,,,",
+	)
+	plsql, callFun := functions[0].PlsqlBlock("")
+	t.Log(callFun)
+	// typed binds: time.Time in, *timestamppb.Timestamp (scanned as time.Time) out
+	wantContains(t, callFun,
+		"= custom.AsTime(input.PD)",
+		"sql.Out{Dest: output.PT}",
+		"sql.Out{Dest: output.PIo, In: true}",
+	)
+	// no formatting or parsing, which would depend on NLS_DATE_FORMAT
+	for _, bad := range []string{"Format(time.RFC3339)}", "ParseTime", "&output.PT"} {
+		if strings.Contains(callFun, bad) {
+			t.Errorf("%q in\n%s", bad, callFun)
+		}
+	}
+	for _, bad := range []string{"TO_DATE", "TO_CHAR", "TO_TIMESTAMP"} {
+		if strings.Contains(plsql, bad) {
+			t.Errorf("%q in\n%s", bad, plsql)
+		}
+	}
+}
+
+func TestTimestampGogo(t *testing.T) {
+	defer func(old bool) { Gogo = old }(Gogo)
+	Gogo = true
+	for _, tC := range []struct {
+		Ora, Want string
+	}{
+		{Ora: "DATE", Want: "custom.DateTime"},
+		{Ora: "TIMESTAMP", Want: "custom.Timestamp"},
+	} {
+		arg := NewPlsType(tC.Ora, 0, 0)
+		if got := arg.FromOra("dst", "src", "v"); !strings.Contains(got, tC.Want+"{") {
+			t.Errorf("%s: FromOra got %q, wanted %s", tC.Ora, got, tC.Want)
+		}
+		if got := arg.GetOra("src", ""); tC.Ora == "TIMESTAMP" && !strings.Contains(got, tC.Want) {
+			t.Errorf("%s: GetOra got %q, wanted %s", tC.Ora, got, tC.Want)
+		}
+		_, opts := protoType("time.Time", "x", tC.Ora)
+		if got, _ := opts["gogoproto.customtype"].(string); !strings.HasSuffix(got, "/"+tC.Want) {
+			t.Errorf("%s: customtype got %q, wanted %s", tC.Ora, got, tC.Want)
+		}
+	}
+}
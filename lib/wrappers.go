@@ -0,0 +1,108 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NullableWrappers makes SaveProtobuf map the nullable scalar arguments of the
+// functions to the well-known wrapper messages (google.protobuf.StringValue etc.),
+// and the generated Go code box/unbox them, so NULL is distinguishable from the zero value.
+//
+// Only the top-level simple arguments are wrapped, and it has no effect with Gogo.
+var NullableWrappers bool
+
+// protoWrapper describes the wrapper message of a scalar, and how it is bound.
+type protoWrapper struct {
+	// Message is the wrapper message's name in the .proto.
+	Message string
+	// Box is the wrapperspb constructor, the Go type is Box+"Value".
+	Box string
+	// Var is the Go type of the bind variable.
+	Var string
+	// Field of Var holding the value - empty for string-like Var, where NULL is the empty string.
+	Field string
+	// ToVar and FromVar are the conversions between the wrapped value and the Var (or its Field).
+	ToVar, FromVar string
+}
+
+// protoWrappers maps the Go types to the wrapper messages.
+var protoWrappers = map[string]protoWrapper{
+	"string":        {Message: "google.protobuf.StringValue", Box: "wrapperspb.String", Var: "string"},
+	"godror.Number": {Message: "google.protobuf.StringValue", Box: "wrapperspb.String", Var: "godror.Number", ToVar: "godror.Number", FromVar: "string"},
+	"int32":         {Message: "google.protobuf.Int32Value", Box: "wrapperspb.Int32", Var: "sql.NullInt32", Field: "Int32"},
+	"int64":         {Message: "google.protobuf.Int64Value", Box: "wrapperspb.Int64", Var: "sql.NullInt64", Field: "Int64"},
+	"float32":       {Message: "google.protobuf.FloatValue", Box: "wrapperspb.Float", Var: "sql.NullFloat64", Field: "Float64", ToVar: "float64", FromVar: "float32"},
+	"float64":       {Message: "google.protobuf.DoubleValue", Box: "wrapperspb.Double", Var: "sql.NullFloat64", Field: "Float64"},
+}
+
+// protoWrapper returns the wrapper of the argument, if it should be wrapped.
+func (arg Argument) protoWrapper() (protoWrapper, bool) {
+	if !NullableWrappers || Gogo || arg.Flavor != FLAVOR_SIMPLE || arg.Type == "CLOB" || arg.Type == "XMLTYPE" {
+		return protoWrapper{}, false
+	}
+	if _, ok := arg.typeMapping(); ok {
+		return protoWrapper{}, false
+	}
+	got, err := arg.goType(false)
+	if err != nil {
+		return protoWrapper{}, false
+	}
+	pw, ok := protoWrappers[strings.TrimPrefix(got, "*")]
+	return pw, ok
+}
+
+// GoType returns the Go type of the wrapper message.
+func (pw protoWrapper) GoType() string { return pw.Box + "Value" }
+
+func (pw protoWrapper) toVar(src string) string {
+	if pw.ToVar == "" {
+		return src
+	}
+	return pw.ToVar + "(" + src + ")"
+}
+
+func (pw protoWrapper) fromVar(vn string) string {
+	if pw.Field != "" {
+		vn += "." + pw.Field
+	}
+	if pw.FromVar == "" {
+		return vn
+	}
+	return pw.FromVar + "(" + vn + ")"
+}
+
+// getConvWrapper is getConvSimple for the wrapped arguments:
+// a nil input is bound as NULL, and a NULL output is left nil.
+func (arg Argument) getConvWrapper(
+	pw protoWrapper,
+	convIn, convOut []string,
+	name, paramName string,
+) ([]string, []string) {
+	if !arg.IsOutput() {
+		convIn = append(convIn,
+			fmt.Sprintf("if input.%s != nil { %s = %s }  // gcw1", name, paramName, pw.toVar("input."+name+".Value")))
+		return convIn, convOut
+	}
+	vn := mkVarName(paramName)
+	convIn = append(convIn, fmt.Sprintf("var %s %s", vn, pw.Var))
+	valid := vn + ` != ""`
+	if pw.Field != "" {
+		valid = vn + ".Valid"
+	}
+	if arg.IsInput() {
+		set := fmt.Sprintf("%s = %s", vn, pw.toVar("input."+name+".Value"))
+		if pw.Field != "" {
+			set = fmt.Sprintf("%s.%s, %s = %s, true", vn, pw.Field, valid, pw.toVar("input."+name+".Value"))
+		}
+		convIn = append(convIn, fmt.Sprintf("if input.%s != nil { %s }  // gcw2", name, set))
+	}
+	convIn = append(convIn, fmt.Sprintf("%s = sql.Out{Dest:&%s, In:%t}  // gcw3", paramName, vn, arg.IsInput()))
+	convOut = append(convOut,
+		fmt.Sprintf("if %s { output.%s = %s(%s) }  // gcw4", valid, name, pw.Box, pw.fromVar(vn)))
+	return convIn, convOut
+}
@@ -9,10 +9,12 @@ import (
 	"errors"
 	"fmt"
 	"go/format"
+	"go/scanner"
 	"io"
 	"os"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -22,52 +24,193 @@ import (
 var ErrMissingTableOf = errors.New("missing TableOf info")
 var ErrInvalidArgument = errors.New("invalid argument")
 
+// InputChecks makes SaveFunctions generate a Check<Input> func of each function (see GenChecks),
+// validating the lengths and the precisions of the input before binding it,
+// so an overflowing value is rejected with ErrInvalidArgument, naming the field.
+var InputChecks = true
+
+// GenInterface makes SaveFunctions generate a <Service>Service interface of the generated methods,
+// implemented by oracallServer, and a New<Service>Server func adapting it to the gRPC server interface.
+var GenInterface bool
+
+// ServicePerPackage makes SaveFunctions implement the service of each group of the functions (see Function.Group),
+// as ProtoOptions.ServicePerPackage writes them into the .proto, instead of the one service of the pb package:
+// oracallServer embeds the Unimplemented<Service>Server of each, and RegisterServices registers all of them.
+var ServicePerPackage bool
+
+// SaveFunctions writes the Go code calling the functions, formatted by gofmt (see formatGo).
 func SaveFunctions(dst io.Writer, functions []Function, pkg, pbImport string, saveStructs bool) error {
+	var buf bytes.Buffer
 	var err error
-	w := errWriter{Writer: dst, err: &err}
+	w := errWriter{Writer: &buf, err: &err}
 
-	var tagB strings.Builder
+	// the sections first, as the shared code implements the services of the written functions
+	var sections bytes.Buffer
+	signatures, err := saveFunctionSections(errWriter{Writer: &sections, err: &err}, functions, saveStructs)
+	if err != nil {
+		return err
+	}
 	if pkg != "" {
-		pbPkg := CamelCase(path.Base(pbImport))
+		writeGoHeader(w, pkg, pbImport)
+		writeGoShared(w, functions, pbImport, signatures)
+	}
+	w.Write(sections.Bytes())
+	if err = writeGoFooter(w, pkg, pbImport, signatures); err != nil {
+		return err
+	}
+	b, err := formatGo(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(b)
+	return err
+}
 
-		if pbImport != "" {
-			pbImport = `pb "` + pbImport + `"`
+// formatGo formats the generated Go source as gofmt does, so the output is gofmt-clean.
+// If the source does not parse (a bug of the generator), the error shows the offending lines.
+func formatGo(src []byte) ([]byte, error) {
+	b, err := format.Source(src)
+	if err == nil {
+		return b, nil
+	}
+	var errs scanner.ErrorList
+	if !errors.As(err, &errs) || len(errs) == 0 {
+		return nil, fmt.Errorf("format the generated Go source: %w", err)
+	}
+	lines := bytes.Split(src, []byte("\n"))
+	line := errs[0].Pos.Line // 1-based
+	var snippet strings.Builder
+	for i := max(line-4, 0); i < min(line+3, len(lines)); i++ {
+		fmt.Fprintf(&snippet, "%6d\t%s\n", i+1, lines[i])
+	}
+	return nil, fmt.Errorf("format the generated Go source: %w\n%s", err, snippet.String())
+}
+
+// SaveFunctionsSplit writes the code SaveFunctions does, but the functions of each Oracle package
+// into a separate file of the same Go package, returned by their names (<package>_oracall.go).
+// The shared code (the server, the registry of the functions) is written to dst.
+func SaveFunctionsSplit(dst io.Writer, functions []Function, pkg, pbImport string, saveStructs bool) (map[string][]byte, error) {
+	if pkg == "" {
+		return nil, errors.New("SaveFunctionsSplit needs a package name")
+	}
+	return saveFunctionFiles(dst, functions, pkg, pbImport, saveStructs, func(f Function) string {
+		return strings.ToLower(f.Package)
+	})
+}
+
+// SaveFunctionsPerFunction writes the code SaveFunctions does, but each function (with its input and output structs)
+// into a separate file of the same Go package, returned by their names (<package>_<function>_oracall.go),
+// so the change of one function changes one file only.
+// The shared code (the server, the registry of the functions) is written to dst.
+func SaveFunctionsPerFunction(dst io.Writer, functions []Function, pkg, pbImport string, saveStructs bool) (map[string][]byte, error) {
+	if pkg == "" {
+		return nil, errors.New("SaveFunctionsPerFunction needs a package name")
+	}
+	seen := make(map[string]int, len(functions))
+	return saveFunctionFiles(dst, functions, pkg, pbImport, saveStructs, func(f Function) string {
+		nm := f.name
+		if f.alias != "" {
+			nm = f.alias
 		}
-		var lastDDL time.Time
-		for _, f := range functions {
-			if f.LastDDL.After(lastDDL) {
-				lastDDL = f.LastDDL
+		if f.Package != "" {
+			nm = f.Package + "_" + nm
+		}
+		nm = strings.ToLower(nm)
+		// the same name (overloads) would put the functions into the same file
+		if n := seen[nm]; n != 0 {
+			seen[nm] = n + 1
+			return fmt.Sprintf("%s_%d", nm, n+1)
+		}
+		seen[nm] = 1
+		return nm
+	})
+}
+
+// saveFunctionFiles writes the functions into the files named by fileKey (with an _oracall.go suffix,
+// "standalone" for the empty key), and the shared code to dst.
+func saveFunctionFiles(dst io.Writer, functions []Function, pkg, pbImport string, saveStructs bool, fileKey func(Function) string) (map[string][]byte, error) {
+	byKey := make(map[string][]Function)
+	for _, f := range functions {
+		k := fileKey(f)
+		byKey[k] = append(byKey[k], f)
+	}
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	files := make(map[string][]byte, len(byKey))
+	signatures := make(serviceSignatures)
+	for _, k := range keys {
+		var buf bytes.Buffer
+		var err error
+		w := errWriter{Writer: &buf, err: &err}
+		// pb is not used by the functions with google.protobuf.Empty request and response
+		imp := ""
+		for _, f := range byKey[k] {
+			if !f.usesEmpty() || f.useEnvelope() {
+				imp = pbImport
+				break
 			}
 		}
-		if lastDDL.IsZero() {
-			lastDDL = time.Now()
+		writeGoHeader(w, pkg, imp)
+		sigs, err := saveFunctionSections(w, byKey[k], saveStructs)
+		if err != nil {
+			return files, err
 		}
-		var implement string
-		if !Gogo {
-			implement = "pb.Unimplemented" + pbPkg + "Server"
+		for svc, sig := range sigs {
+			signatures[svc] = append(signatures[svc], sig...)
 		}
-		tagB.Reset()
-		for _, fun := range functions {
-			if len(fun.Tag) == 0 {
-				continue
-			}
-			fn := fun.name
-			if fun.alias != "" {
-				fn = fun.alias
-			}
-			fmt.Fprintf(&tagB, "%q: []string{", CamelCase(fn))
-			for i, t := range fun.Tag {
-				if i != 0 {
-					tagB.WriteString(",\n")
-				}
-				fmt.Fprintf(&tagB, "%q", t)
-			}
-			tagB.WriteString("},\n")
+		if k == "" {
+			k = "standalone"
 		}
-		tagMap := "tags: map[string][]string{\n" + tagB.String() + "\n},"
-		io.WriteString(w,
-			// https://github.com/golang/go/issues/13560#issuecomment-288457920
-			`// Code generated by oracall, DO NOT EDIT.
+		// the _oracall suffix keeps the name from looking like a _test.go or a _GOOS.go file
+		nm := k + "_oracall.go"
+		if files[nm], err = formatGo(buf.Bytes()); err != nil {
+			return files, fmt.Errorf("%s: %w", nm, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	w := errWriter{Writer: &buf, err: &err}
+	writeGoHeader(w, pkg, pbImport)
+	writeGoShared(w, functions, pbImport, signatures)
+	if err = writeGoFooter(w, pkg, pbImport, signatures); err != nil {
+		return files, err
+	}
+	b, err := formatGo(buf.Bytes())
+	if err != nil {
+		return files, err
+	}
+	_, err = dst.Write(b)
+	return files, err
+}
+
+// writeGoHeader writes the package clause and the imports of a generated file
+// - pbImport is the import path of the protobuf package, empty if unused.
+func writeGoHeader(w io.Writer, pkg, pbImport string) {
+	if pbImport != "" {
+		pbImport = `pb "` + pbImport + `"`
+	}
+	// the envelope methods fill the meta
+	var envelopeImports, envelopeGuards string
+	if Envelope {
+		envelopeImports = `"github.com/tgulacsi/oracall/orasrv"	// orasrv.ContextGetReqID
+	"google.golang.org/protobuf/types/known/durationpb"
+`
+		envelopeGuards = "var _ = orasrv.ContextGetReqID\nvar _ durationpb.Duration\n"
+	}
+	// RegisterServices (see ServicePerPackage) gets a grpc.ServiceRegistrar
+	if ServicePerPackage && !Gogo {
+		envelopeImports += `"google.golang.org/grpc"	// grpc.ServiceRegistrar
+`
+		envelopeGuards += "var _ grpc.ServiceRegistrar\n"
+	}
+	io.WriteString(w,
+		// https://github.com/golang/go/issues/13560#issuecomment-288457920
+		`// Code generated by oracall, DO NOT EDIT.
 
 package `+pkg+`
 
@@ -85,21 +228,20 @@ import (
 	"os"
 	"strconv"
 	"time"    // for datetimes
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/tgulacsi/oracall/custom"	// custom.AsDate/AsTimestamp
 	"github.com/godror/knownpb/timestamppb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	oracall "github.com/tgulacsi/oracall/lib"	// ErrInvalidArgument
 	"github.com/godror/godror"
 	"github.com/UNO-SOFT/zlog/v2/slog"
-
+	`+envelopeImports+`
 	`+pbImport+`
 )
 
-var DebugLevel = uint(0)
-
-const LastDDL = "`+lastDDL.Format(time.RFC3339)+`"
-
 // against "unused import" error
 var _ json.Marshaler
 var _ = io.EOF
@@ -108,16 +250,78 @@ var _ = custom.AsTimestamp
 var _ strconv.NumError
 var _ time.Time
 var _ timestamppb.Timestamp
+var _ wrapperspb.StringValue
+var _ emptypb.Empty
 var _ strings.Reader
 var _ xml.Name
 var _ = errors.New
 var _ = fmt.Printf
 var _ godror.Lob
 var _ unsafe.Pointer
+var _ = utf8.RuneCountInString
 var _ = os.Stdout
 var _ driver.Rows
 var _ = oracall.ErrInvalidArgument
 var _ = ioutil.ReadAll
+var _ sql.Out
+var _ *slog.Logger
+`+envelopeGuards+``)
+}
+
+// writeGoShared writes the declarations shared by the generated functions: the server and its constructor
+// - and with ServicePerPackage, the registration of the services of the signatures.
+func writeGoShared(w io.Writer, functions []Function, pbImport string, signatures serviceSignatures) {
+	var lastDDL time.Time
+	for _, f := range functions {
+		if f.LastDDL.After(lastDDL) {
+			lastDDL = f.LastDDL
+		}
+	}
+	if lastDDL.IsZero() {
+		lastDDL = time.Now()
+	}
+	var implement, register string
+	if !Gogo {
+		implement = "pb.Unimplemented" + CamelCase(path.Base(pbImport)) + "Server"
+		if ServicePerPackage {
+			var impl, reg strings.Builder
+			reg.WriteString(`
+// RegisterServices registers the server as each service of the .proto (see the group annotation).
+func RegisterServices(s grpc.ServiceRegistrar, srv *oracallServer) {
+`)
+			var checks strings.Builder
+			for _, svc := range signatures.services() {
+				fmt.Fprintf(&impl, "pb.Unimplemented%sServer\n", svc)
+				fmt.Fprintf(&reg, "\tpb.Register%sServer(s, srv)\n", svc)
+				fmt.Fprintf(&checks, "var _ pb.%sServer = (*oracallServer)(nil)\n", svc)
+			}
+			reg.WriteString("}\n\n")
+			implement, register = impl.String(), reg.String()+checks.String()
+		}
+	}
+	var tagB strings.Builder
+	for _, fun := range functions {
+		if len(fun.Tag) == 0 {
+			continue
+		}
+		fn := fun.name
+		if fun.alias != "" {
+			fn = fun.alias
+		}
+		fmt.Fprintf(&tagB, "%q: []string{", CamelCase(fn))
+		for i, t := range fun.Tag {
+			if i != 0 {
+				tagB.WriteString(",\n")
+			}
+			fmt.Fprintf(&tagB, "%q", t)
+		}
+		tagB.WriteString("},\n")
+	}
+	tagMap := "tags: map[string][]string{\n" + tagB.String() + "\n},"
+	io.WriteString(w, `
+var DebugLevel = uint(0)
+
+const LastDDL = "`+lastDDL.Format(time.RFC3339)+`"
 
 type iterator struct {
 	Reset func()
@@ -145,62 +349,221 @@ func NewServer(
 	}
 }
 
+`+register+`
 `)
+}
+
+// serviceSignatures are the signatures of the generated methods by their services (see Function.goService),
+// all under the empty key without ServicePerPackage.
+type serviceSignatures map[string][]string
+
+// services returns the services of the signatures, sorted.
+func (ss serviceSignatures) services() []string {
+	services := make([]string, 0, len(ss))
+	for svc := range ss {
+		services = append(services, svc)
 	}
-	types := make(map[string]string, 16)
-	inits := make([]string, 0, len(functions))
-	var b []byte
+	sort.Strings(services)
+	return services
+}
 
-FunLoop:
+// saveFunctionSections writes the code of the functions, returning the signatures of the written ones.
+func saveFunctionSections(w io.Writer, functions []Function, saveStructs bool) (serviceSignatures, error) {
+	signatures := make(serviceSignatures)
+	if !ServicePerPackage {
+		signatures[""] = make([]string, 0, len(functions))
+	}
 	for _, fun := range functions {
-		structW := io.Writer(w)
-		if !saveStructs {
-			structW = io.Discard
-		}
-		var checkName string
-		for _, dir := range []bool{false, true} {
-			if err = fun.SaveStruct(structW, dir); err != nil {
-				if SkipMissingTableOf && (errors.Is(err, ErrMissingTableOf) || errors.Is(err, ErrUnknownSimpleType)) {
-					logger.Error("SKIP function, missing TableOf info", "function", fun.Name(), "error", err)
-					continue FunLoop
+		if err := fun.checkDepth(); err != nil {
+			return signatures, err
+		}
+		if err := writeSection(w, fun, func(w io.Writer) error {
+			structW := w
+			if !saveStructs {
+				structW = io.Discard
+			}
+			var checkName string
+			if fun.usesEmpty() {
+				structW = io.Discard
+			}
+			for _, dir := range []bool{false, true} {
+				if err := fun.SaveStruct(structW, dir); err != nil {
+					if SkipMissingTableOf && (errors.Is(err, ErrMissingTableOf) || errors.Is(err, ErrUnknownSimpleType)) {
+						logger.Error("SKIP function, missing TableOf info", "function", fun.Name(), "error", err)
+						return errSkipSection
+					}
+					return err
+				}
+			}
+			if GenConverters && !Gogo && structW != io.Discard {
+				for _, dir := range []bool{false, true} {
+					if err := fun.SaveConverters(w, dir); err != nil {
+						return err
+					}
+				}
+			}
+			if GenAccessors && !fun.usesEmpty() {
+				for _, dir := range []bool{false, true} {
+					if err := fun.SaveAccessors(w, dir); err != nil {
+						return err
+					}
+				}
+			}
+			if fun.hasRetyped() && !fun.usesEmpty() {
+				for _, dir := range []bool{false, true} {
+					if err := fun.SaveCompatShims(w, dir); err != nil {
+						return err
+					}
+				}
+			}
+			if InputChecks && !fun.usesEmpty() {
+				var err error
+				if checkName, err = fun.GenChecks(w); err != nil {
+					return err
 				}
-				return err
 			}
+			plsBlock, callFun := fun.PlsqlBlock(checkName)
+			fmt.Fprintf(w, "\nconst %s = `", fun.getPlsqlConstName())
+			io.WriteString(w, plsBlock)
+			io.WriteString(w, "`\n\n")
+			b, err := format.Source([]byte(callFun))
+			if err != nil {
+				logger.Error("saving function", "function", fun.Name(), "error", err)
+				os.Stderr.WriteString("\n\n---------------------8<--------------------\n")
+				os.Stderr.WriteString(callFun)
+				os.Stderr.WriteString("\n--------------------->8--------------------\n\n")
+				return fmt.Errorf("error saving function %s: %s", fun.Name(), err)
+			}
+			_, err = w.Write(b)
+			return err
+		}); err != nil {
+			if errors.Is(err, errSkipSection) {
+				continue
+			}
+			return signatures, err
 		}
-		plsBlock, callFun := fun.PlsqlBlock(checkName)
-		fmt.Fprintf(w, "\nconst %s = `", fun.getPlsqlConstName())
-		io.WriteString(w, plsBlock)
-		io.WriteString(w, "`\n\n")
-		if b, err = format.Source([]byte(callFun)); err != nil {
-			logger.Error("saving function", "function", fun.Name(), "error", err)
-			os.Stderr.WriteString("\n\n---------------------8<--------------------\n")
-			os.Stderr.WriteString(callFun)
-			os.Stderr.WriteString("\n--------------------->8--------------------\n\n")
-			return fmt.Errorf("error saving function %s: %s", fun.Name(), err)
+		var svc string
+		if ServicePerPackage {
+			svc = fun.goService()
+		}
+		signatures[svc] = append(signatures[svc], fun.goSignature())
+		if fun.paginated() {
+			signatures[svc] = append(signatures[svc], fun.goPageSignature())
 		}
-		w.Write(b)
 	}
-	for tn, text := range types {
-		if tn[0] == '+' { // REF CURSOR skip
+	return signatures, nil
+}
+
+// goService returns the name of the service (of the .proto) the generated method of the function implements:
+// the one of its package, or of its group with ServicePerPackage.
+func (f Function) goService() string {
+	if ServicePerPackage {
+		return CamelCase(strings.ToLower(f.Group()))
+	}
+	return CamelCase(f.Package)
+}
+
+// writeGoFooter writes the interface (see GenInterface) and the registry of the generated methods,
+// and the Tags method of the server.
+func writeGoFooter(w io.Writer, pkg, pbImport string, signatures serviceSignatures) error {
+	var b []byte
+	var err error
+	var all []string
+	for _, svc := range signatures.services() {
+		all = append(all, signatures[svc]...)
+		if !GenInterface || pkg == "" {
 			continue
 		}
-		if b, err = format.Source([]byte(text)); err != nil {
-			return fmt.Errorf("error saving type %s: %s\n%s", tn, err, text)
+		name := svc
+		if name == "" {
+			name = CamelCase(path.Base(pbImport))
+		}
+		if b, err = format.Source([]byte(genInterface(name, signatures[svc]))); err != nil {
+			return fmt.Errorf("error saving interface: %w", err)
 		}
 		w.Write(b)
 	}
-
-	io.WriteString(w, "\nfunc init() {\n")
-	for _, text := range inits {
-		io.WriteString(w, text)
-		w.Write([]byte{'\n'})
+	if b, err = format.Source([]byte(genRegistry(all))); err != nil {
+		return fmt.Errorf("error saving the registry of functions: %w", err)
 	}
-	_, err = io.WriteString(w, `}
-
+	w.Write(b)
+	_, err = io.WriteString(w, `
 func (s *oracallServer) Tags(name string) []string { return s.tags[name] }
 `)
 	return err
 }
+
+// genInterface returns the <Service>Service interface with the given method signatures,
+// and the New<Service>Server adapter.
+func genInterface(service string, signatures []string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "\n// %sService is implemented by oracallServer - use it for mocking.\ntype %sService interface {\n", service, service)
+	for _, sig := range signatures {
+		fmt.Fprintf(&buf, "\t%s\n", sig)
+	}
+	fmt.Fprintf(&buf, "}\n\nvar _ %sService = (*oracallServer)(nil)\n", service)
+	if Gogo {
+		fmt.Fprintf(&buf, `
+// New%[1]sServer returns the %[1]sService as a pb.%[1]sServer.
+func New%[1]sServer(svc %[1]sService) pb.%[1]sServer { return svc }
+`, service)
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, `
+// New%[1]sServer returns the %[1]sService as a pb.%[1]sServer.
+func New%[1]sServer(svc %[1]sService) pb.%[1]sServer {
+	return %[2]sServiceServer{%[1]sService: svc}
+}
+
+type %[2]sServiceServer struct {
+	%[1]sService
+	unimplemented%[1]sServer
+}
+
+// unimplemented%[1]sServer is embedded one level deeper, so the %[1]sService methods win.
+type unimplemented%[1]sServer struct{ pb.Unimplemented%[1]sServer }
+`, service, strings.ToLower(service[:1])+service[1:])
+	return buf.String()
+}
+
+// genRegistry returns the generatedFunctions list of the names of the generated methods,
+// and the checkGeneratedFunctions guard for the hand-written extensions of them.
+func genRegistry(signatures []string) string {
+	var buf strings.Builder
+	buf.WriteString(`
+// generatedFunctions lists the methods of oracallServer generated from the PL/SQL functions,
+// so the hand-written extensions can assert that they still have a target.
+var generatedFunctions = []string{
+`)
+	for _, sig := range signatures {
+		if i := strings.IndexByte(sig, '('); i >= 0 {
+			fmt.Fprintf(&buf, "\t%q,\n", sig[:i])
+		}
+	}
+	buf.WriteString(`}
+
+// checkGeneratedFunctions returns an error listing those of the names which are not in generatedFunctions -
+// call it from a test with the names of the functions extended by hand, to detect the removed or renamed ones.
+func checkGeneratedFunctions(names ...string) error {
+	generated := make(map[string]struct{}, len(generatedFunctions))
+	for _, nm := range generatedFunctions {
+		generated[nm] = struct{}{}
+	}
+	var missing []string
+	for _, nm := range names {
+		if _, ok := generated[nm]; !ok {
+			missing = append(missing, nm)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("extended functions are not generated anymore: %q", missing)
+	}
+	return nil
+}
+`)
+	return buf.String()
+}
+
 func SaveFunctionTests(dst io.Writer, functions []Function, pkg, pbImport string, saveStructs bool) error {
 	var err error
 	w := errWriter{Writer: dst, err: &err}
@@ -232,9 +595,12 @@ import (
 	oracall "github.com/tgulacsi/oracall/lib"	// ErrInvalidArgument
 
 	_ "github.com/godror/godror" // Oracle
+	"google.golang.org/protobuf/types/known/emptypb"
 	`+pbImport+`
 )
 
+var _ emptypb.Empty
+
 var (
 	connectOnce sync.Once
 	flagConnect = flag.String("connect", "", "database to connect to")
@@ -299,7 +665,7 @@ func TestCalls(t *testing.T) {
 
 	funNames := make([]string, 0, len(functions))
 	for _, f := range functions {
-		structName := CamelCase(f.getStructName(false, false))
+		structName := f.pbTypeName(false)
 		if f.HasCursorOut() {
 			// No test for streams yet
 			continue
@@ -309,7 +675,7 @@ func TestCalls(t *testing.T) {
 		fmt.Fprintf(w, `
 func test%s(t *testing.T, jsonText []byte) {
 	srv := testSetup(t)
-	var input pb.%s
+	var input %s
 	if err := json.Unmarshal(jsonText, &input); err != nil {
 		t.Fatal(err)
 	}
@@ -348,6 +714,9 @@ func (f Function) getPlsqlConstName() string {
 	if f.alias != "" {
 		nm = f.alias
 	}
+	if f.Package == "" {
+		return capitalize(nm + "__plsql")
+	}
 	return capitalize(f.Package + "__" + nm + "__plsql")
 }
 
@@ -360,7 +729,7 @@ func (f Function) getStructName(out, withPackage bool) string {
 	if f.alias != "" {
 		nm = f.alias
 	}
-	if !withPackage {
+	if !withPackage || f.Package == "" {
 		return nm + "__" + dirname
 	}
 	return capitalize(f.Package + "__" + nm + "__" + dirname)
@@ -412,7 +781,13 @@ func (f Function) SaveStruct(dst io.Writer, out bool) error {
 		if got == "" || got == "*" {
 			got = got + mkRecTypName(arg.Name)
 		}
+		if pw, ok := arg.protoWrapper(); ok {
+			got = "*" + pw.GoType()
+		}
 		lName := strings.ToLower(arg.Name)
+		if arg.Description != "" {
+			io.WriteString(w, asComment(arg.Description, "\t"))
+		}
 		io.WriteString(w, "\t"+aName+" "+got+
 			"\t`json:\""+lName+"\""+
 			" xml:\""+lName+"\"`\n")
@@ -450,24 +825,41 @@ func (f Function) GenChecks(w io.Writer) (string, error) {
 	}
 	checks := make([]string, 0, len(args)+1)
 	for _, arg := range args {
-		checks = genChecks(checks, arg, "s", false)
+		if !arg.omittable() {
+			checks = genChecks(checks, arg, "s", false)
+			continue
+		}
+		// the unset defaulted argument is left out of the call (see OmitCallArgs), so it is checked only if set
+		sub := genChecks(nil, arg, "s", false)
+		if len(sub) == 0 {
+			continue
+		}
+		isSet, err := arg.isSetExpr("s." + CamelCase(arg.Name))
+		if err != nil {
+			return "", err
+		}
+		checks = append(append(append(checks, "if "+isSet+" {"), sub...), "}")
+	}
+	checks, err := f.genGroupChecks(checks, "s")
+	if err != nil {
+		return "", err
 	}
 	if len(checks) == 0 {
 		return "", nil
 	}
-	structName := CamelCase(strings.SplitN(f.getStructName(false, true), "__", 2)[1])
+	structName := strings.TrimPrefix(f.pbTypeName(false), "pb.")
 	buf := Buffers.Get()
 	defer Buffers.Put(buf)
 	nm := "Check" + structName
 	fmt.Fprintf(buf, `
-// %s checks input bounds for pb.%s
+// %s checks input bounds (and the argument groups) for pb.%s
 func %s(s *pb.%s) error {
 	`,
 		nm, structName,
 		nm, structName,
 	)
 	for _, line := range checks {
-		fmt.Fprintf(buf, line+"\n")
+		buf.WriteString(line + "\n")
 	}
 	if _, err := io.WriteString(buf, "\n\treturn nil\n}\n"); err != nil {
 		return "", err
@@ -497,47 +889,35 @@ func genChecks(checks []string, arg Argument, base string, parentIsTable bool) [
 	case FLAVOR_SIMPLE:
 		switch got {
 		case "string":
-			checks = append(checks,
-				fmt.Sprintf(`if len(%s) > %d {
-		return fmt.Errorf("%s is longer than accepted (%d): %%w", oracall.ErrInvalidArgument)
-    }`,
-					name, arg.Charlength, name, arg.Charlength))
+			checks = append(checks, lengthCheck(arg, name, ""))
+			if check := arg.emptyCheck(name); check != "" {
+				checks = append(checks, check)
+			}
 		case "*string":
-			checks = append(checks,
-				fmt.Sprintf(`if %s != nil && len(*%s) > %d {
-		return fmt.Errorf("%s is longer than accepted (%d): %%w", oracall.ErrInvalidArgument)
-    }`,
-					name, name, arg.Charlength,
-					name, arg.Charlength))
-		case "sql.NullString":
-			checks = append(checks,
-				fmt.Sprintf(`if %s.Valid && len(%s.String) > %d {
-		return fmt.Errorf("%s is longer than accepted (%d): %%w", oracall.ErrInvalidArgument)
-    }`,
-					name, name, arg.Charlength,
-					name, arg.Charlength))
-		case "NullString":
-			checks = append(checks,
-				fmt.Sprintf(`if %s.Valid && len(%s.String) > %d {
-		return fmt.Errorf("%s is longer than accepted (%d): %%w", oracall.ErrInvalidArgument)
-    }`,
-					name, name, arg.Charlength,
-					name, arg.Charlength))
+			checks = append(checks, lengthCheck(arg, "*"+name, name+" != nil"))
+		case "sql.NullString", "NullString":
+			checks = append(checks, lengthCheck(arg, name+".String", name+".Valid"))
 		case "godror.Number":
-			checks = append(checks,
-				fmt.Sprintf(
-					`if err := oracall.ParseDigits(%s, %d, %d); err != nil {
-						return fmt.Errorf("%s: %%w", oracall.ErrInvalidArgument)
+			// an unconstrained NUMBER accepts any number
+			if arg.Precision > 0 {
+				checks = append(checks,
+					fmt.Sprintf(
+						`if err := oracall.ParseDigits(%s, %d, %d); err != nil {
+						return fmt.Errorf("%s: %%v: %%w", err, oracall.ErrInvalidArgument)
 					}`,
-					name, arg.Precision, arg.Scale,
-					name))
+						name, arg.Precision, arg.Scale,
+						name))
+			}
 
-		case "int32": // no check is needed
-		case "int64", "float64":
+		case "[]byte":
+			if arg.Type == "RAW" && arg.Charlength != 0 {
+				checks = append(checks, lengthCheck(arg, name, ""))
+			}
+		case "int32", "int64", "float64":
 			if arg.Precision > 0 {
 				cons := strings.Repeat("9", int(arg.Precision))
 				checks = append(checks,
-					fmt.Sprintf(`if (%s <= -%s || %s > %s) {
+					fmt.Sprintf(`if (%s < -%s || %s > %s) {
 		return fmt.Errorf("%s is out of bounds (-%s..%s): %%w", oracall.ErrInvalidArgument)
     }`,
 						name, cons, name, cons,
@@ -548,7 +928,7 @@ func genChecks(checks []string, arg Argument, base string, parentIsTable bool) [
 				vn := got[strings.Index(got, "Null")+4:]
 				cons := strings.Repeat("9", int(arg.Precision))
 				checks = append(checks,
-					fmt.Sprintf(`if %s.Valid && (%s.%s <= -%s || %s.%s > %s) {
+					fmt.Sprintf(`if %s.Valid && (%s.%s < -%s || %s.%s > %s) {
 		return fmt.Errorf("%s is out of bounds (-%s..%s): %%w", oracall.ErrInvalidArgument)
     }`,
 						name, name, vn, cons, name, vn, cons,
@@ -591,6 +971,24 @@ func genChecks(checks []string, arg Argument, base string, parentIsTable bool) [
 	return checks
 }
 
+// lengthCheck returns the check of the string expression against arg.Charlength,
+// counting characters or bytes, as the argument's length semantics.
+// The byte count is the UTF-8 length, which is exact for an AL32UTF8 database.
+func lengthCheck(arg Argument, expr, cond string) string {
+	length, unit := fmt.Sprintf("len(%s)", expr), "bytes"
+	if arg.LengthInChars() {
+		length, unit = fmt.Sprintf("utf8.RuneCountInString(%s)", expr), "characters"
+	}
+	if cond != "" {
+		cond += " && "
+	}
+	return fmt.Sprintf(`if %s%s > %d {
+		return fmt.Errorf("%s is longer than accepted (%d %s): %%w", oracall.ErrInvalidArgument)
+    }`,
+		cond, length, arg.Charlength,
+		strings.TrimPrefix(expr, "*"), arg.Charlength, unit)
+}
+
 func capitalize(text string) string {
 	if text == "" {
 		return text
@@ -613,7 +1011,7 @@ func (arg *Argument) goType(isTable bool) (typName string, err error) {
 	defer arg.mu.Unlock()
 	// cached?
 	if arg.goTypeName != "" {
-		if strings.Index(arg.goTypeName, "__") > 0 {
+		if strings.Index(arg.goTypeName, "__") > 0 || arg.namedRow || arg.TableOf != nil && arg.TableOf.dynamicRow {
 			return "*" + arg.goTypeName, nil
 		}
 		return arg.goTypeName, nil
@@ -623,6 +1021,9 @@ func (arg *Argument) goType(isTable bool) (typName string, err error) {
 		arg.goTypeName = typName
 	}()
 	if arg.Flavor == FLAVOR_SIMPLE {
+		if tm, ok := arg.typeMapping(); ok {
+			return tm.GoType, nil
+		}
 		switch arg.Type {
 		case "CHAR", "VARCHAR2", "ROWID":
 			if !isTable && arg.IsOutput() {
@@ -632,6 +1033,10 @@ func (arg *Argument) goType(isTable bool) (typName string, err error) {
 			return "string", nil // NULL is the same as the empty string for Oracle
 		case "RAW":
 			return "[]byte", nil
+		case "LONG": // PL/SQL LONG is a VARCHAR2(32760), bound as string
+			return "string", nil
+		case "LONG RAW": // PL/SQL LONG RAW is a RAW(32760), bound as []byte
+			return "[]byte", nil
 		case "NUMBER":
 			return goNumType(arg.Precision, arg.Scale), nil
 		case "INTEGER":
@@ -661,7 +1066,7 @@ func (arg *Argument) goType(isTable bool) (typName string, err error) {
 			return "*sql.Rows", nil
 		case "BLOB":
 			return "[]byte", nil
-		case "CLOB":
+		case "CLOB", "XMLTYPE":
 			return "string", nil
 		case "BFILE":
 			return "ora.Bfile", nil
@@ -688,6 +1093,9 @@ func (arg *Argument) goType(isTable bool) (typName string, err error) {
 		if err != nil {
 			return tn, err
 		}
+		if arg.IsStringIndexed() {
+			return "map[string]" + tn, nil
+		}
 		tn = "[]" + tn
 		if arg.Type != "REF CURSOR" {
 			if arg.IsOutput() && arg.TableOf.Flavor == FLAVOR_SIMPLE {
@@ -710,16 +1118,36 @@ func (arg *Argument) goType(isTable bool) (typName string, err error) {
 	return "*" + typName, nil
 }
 
+// HiddenPrefix and HiddenSuffix replace the trailing # of the hidden arguments' names
+// (valid in PL/SQL, but not in protobuf and Go) in the generated .proto and Go code:
+// p_args# is p_args_hidden by default, h_p_args with HiddenPrefix "h_" and an empty HiddenSuffix.
+//
+// orasrv fills the hidden p_args# field with the request JSON, finding it by this naming,
+// so set the same in the server as in the generator.
+var HiddenPrefix, HiddenSuffix = "", MarkHidden
+
 func replHidden(text string) string {
 	if text == "" {
 		return text
 	}
 	if text[len(text)-1] == '#' {
-		return text[:len(text)-1] + MarkHidden
+		return HiddenPrefix + text[:len(text)-1] + HiddenSuffix
 	}
 	return text
 }
 
+// unreplHidden returns the name of the hidden argument replHidden returned text for.
+func unreplHidden(text string) (string, bool) {
+	if HiddenPrefix == "" && HiddenSuffix == "" {
+		return text, false
+	}
+	if !strings.HasPrefix(text, HiddenPrefix) || !strings.HasSuffix(text, HiddenSuffix) ||
+		len(text) <= len(HiddenPrefix)+len(HiddenSuffix) {
+		return text, false
+	}
+	return text[len(HiddenPrefix):len(text)-len(HiddenSuffix)] + "#", true
+}
+
 var digitUnder = strings.NewReplacer(
 	"_0", "__0",
 	"_1", "__1",
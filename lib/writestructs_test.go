@@ -6,16 +6,20 @@ package oracall
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"go/format"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/google/go-cmp/cmp"
 )
 
 var flagKeep = flag.Bool("keep", false, "keep temp files")
@@ -120,3 +124,819 @@ func TestSnakeCase(t *testing.T) {
 		}
 	}
 }
+
+func TestGenInterface(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	defer func(old bool) { GenInterface = old }(GenInterface)
+	for _, gen := range []bool{false, true} {
+		GenInterface = gen
+		var buf strings.Builder
+		if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", false); err != nil {
+			t.Fatal(err)
+		}
+		s := buf.String()
+		if got := strings.Contains(s, "type DbWebService interface"); got != gen {
+			t.Errorf("GenInterface=%t, but interface generated=%t", gen, got)
+			continue
+		}
+		if !gen {
+			continue
+		}
+		wantContains(t, s,
+			"\tGetX(ctx context.Context, input *pb.GetX_Input) (output *pb.GetX_Output, err error)\n",
+			"var _ DbWebService = (*oracallServer)(nil)",
+			"func NewDbWebServer(svc DbWebService) pb.DbWebServer {",
+		)
+	}
+}
+
+func TestGenChecksCharSemantics(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	functions, err := ParseCsv(strings.NewReader(`OBJECT_ID,SUBPROGRAM_ID,SEQUENCE,PACKAGE_NAME,OBJECT_NAME,DATA_LEVEL,POSITION,ARGUMENT_NAME,IN_OUT,DATA_TYPE,DATA_PRECISION,DATA_SCALE,CHARACTER_SET_NAME,INDEX_BY,PLS_TYPE,CHAR_LENGTH,TYPE_OWNER,TYPE_NAME,TYPE_SUBNAME,TYPE_LINK,DEFAULTED,CHAR_USED
+1,1,1,DB_WEB,SET_X,0,1,P_CHARS,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,,N,C
+1,1,2,DB_WEB,SET_X,0,2,P_BYTES,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,,N,B
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 1 {
+		t.Fatalf("got %v", functions)
+	}
+	var buf strings.Builder
+	if _, err = functions[0].GenChecks(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantContains(t, s,
+		"if utf8.RuneCountInString(s.PChars) > 10 {",
+		"if len(s.PBytes) > 10 {",
+	)
+}
+
+func TestLongTypes(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_LONG,0,1,P_TEXT,IN,LONG,,,CHAR_CS,,LONG,0,,,,",
+		"1,1,2,DB_WEB,GET_LONG,0,2,P_DATA,OUT,LONG RAW,,,,,LONG RAW,0,,,,",
+	)
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"\tstring p_text = 1;", "\tbytes p_data = 1;"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q not found in\n%s", want, buf.String())
+		}
+	}
+	buf.Reset()
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", true); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"params[0] = input.PText ", "params[1] = sql.Out{Dest: &output.PData}"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q not found in\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestRawType(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_BY_GUID,0,1,P_GUID,IN,RAW,,,,,RAW,16,,,,",
+		"1,1,2,DB_WEB,GET_BY_GUID,0,2,P_DATA,OUT,RAW,,,,,RAW,0,,,,",
+	)
+	var buf strings.Builder
+	if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"\t// RAW(16)\n\tbytes p_guid = 1;", "\t// RAW(32767)\n\tbytes p_data = 1;"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q not found in\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if _, err := functions[0].GenChecks(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "if len(s.PGuid) > 16 {"; !strings.Contains(buf.String(), want) {
+		t.Errorf("%q not found in\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", false); err != nil {
+		t.Fatal(err)
+	}
+	// the bytes are bound as is, without any conversion
+	for _, want := range []string{"params[0] = input.PGuid ", "params[1] = sql.Out{Dest: &output.PData}"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q not found in\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestGenLogsWithRequestLogger(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_X1,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+	)
+	var buf strings.Builder
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", false); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantContains(t, s,
+		"if lgr := oracall.FromContext(ctx); lgr != nil {",
+		`"fun", funName, "stmt", stmtP, "binds", len(params), "dur", time.Since(start).String()`,
+	)
+}
+
+func TestGenRegistry(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,2,1,DB_WEB,SET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	var buf strings.Builder
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", false); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	_, registry, ok := strings.Cut(s, "var generatedFunctions = []string{\n")
+	if !ok {
+		t.Fatalf("no generatedFunctions in\n%s", s)
+	}
+	registry, _, _ = strings.Cut(registry, "}")
+	if want := "\t\"GetX\",\n\t\"SetX\",\n"; registry != want {
+		t.Errorf("got %q, wanted %q", registry, want)
+	}
+	if !strings.Contains(s, "func checkGeneratedFunctions(names ...string) error {") {
+		t.Error("no checkGeneratedFunctions")
+	}
+}
+
+func TestSaveFunctionsSplit(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_NAME,OUT,VARCHAR2,,,,,VARCHAR2,100,,,,",
+		"2,1,1,DB_ADM,PING,0,0,,,,,,,,,,,,,",
+	)
+
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "split-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for GET_X
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type GetX_Input struct{ PId int32 }
+type GetX_Output struct{ PName string }
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	files, err := SaveFunctionsSplit(&buf, functions, "db", pbImport, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files["db_web_oracall.go"] == nil || files["db_adm_oracall.go"] == nil {
+		t.Errorf("got files %v, wanted db_web_oracall.go and db_adm_oracall.go", files)
+	}
+	files["oracall.go"] = buf.Bytes()
+	for nm, b := range files {
+		if err = os.WriteFile(filepath.Join(dn, "db", nm), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Contains(files["db_web_oracall.go"], []byte("func (s *oracallServer) GetX(")) ||
+		bytes.Contains(files["oracall.go"], []byte("func (s *oracallServer) GetX(")) {
+		t.Error("GetX is not (only) in db_web_oracall.go")
+	}
+
+	cmd := exec.Command(goBin, "build", "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go build: %+v\n%s", err, errBuf.String())
+	}
+}
+
+func TestSaveFunctionsPerFunction(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_NAME,OUT,VARCHAR2,,,,,VARCHAR2,100,,,,",
+		"1,2,1,DB_WEB,PING,0,0,,,,,,,,,,,,,",
+		"2,1,1,DB_ADM,STATUS,0,0,,,,,,,,,,,,,",
+	)
+
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "perfunc-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for GET_X
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type GetX_Input struct{ PId int32 }
+type GetX_Output struct{ PName string }
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	files, err := SaveFunctionsPerFunction(&buf, functions, "db", pbImport, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"db_adm_status_oracall.go", "db_web_get_x_oracall.go", "db_web_ping_oracall.go"}
+	got := make([]string, 0, len(files))
+	for nm := range files {
+		got = append(got, nm)
+	}
+	sort.Strings(got)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got files %q, wanted %q", got, want)
+	}
+	files["oracall.go"] = buf.Bytes()
+	for nm, b := range files {
+		if err = os.WriteFile(filepath.Join(dn, "db", nm), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if getX := files["db_web_get_x_oracall.go"]; !bytes.Contains(getX, []byte("func (s *oracallServer) GetX(")) ||
+		bytes.Contains(getX, []byte("func (s *oracallServer) Ping(")) ||
+		bytes.Contains(files["oracall.go"], []byte("func (s *oracallServer) GetX(")) {
+		t.Error("GetX is not (only) in db_web_get_x_oracall.go")
+	}
+	if !bytes.Contains(files["oracall.go"], []byte("func NewServer(")) {
+		t.Error("the shared code is not in oracall.go")
+	}
+
+	cmd := exec.Command(goBin, "build", "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go build: %+v\n%s", err, errBuf.String())
+	}
+}
+
+func TestSaveFunctionsServicePerPackage(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_CUSTOMER,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"2,1,1,DB_ORDER,LIST_ORDERS,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"2,1,2,DB_ORDER,LIST_ORDERS,0,2,P_CUR,OUT,REF CURSOR,,,,,REF CURSOR,0,,,,",
+		"2,2,1,DB_ORDER,PURGE_ORDERS,0,1,P_BEFORE,IN,DATE,,,,,DATE,0,,,,",
+	)
+	var annotations []Annotation
+	for _, s := range []string{"group db_web.get_customer=>api", "group db_order.list_orders=>api"} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	functions = ApplyAnnotations(functions, annotations)
+
+	defer func(old bool) { GenInterface = old }(GenInterface)
+	defer func(old bool) { ServicePerPackage = old }(ServicePerPackage)
+	GenInterface, ServicePerPackage = true, true
+	var buf bytes.Buffer
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web", false); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantContains(t, s,
+		"\tpb.UnimplementedApiServer\n",
+		"\tpb.UnimplementedDbOrderServer\n",
+		"func RegisterServices(s grpc.ServiceRegistrar, srv *oracallServer) {",
+		"\tpb.RegisterApiServer(s, srv)\n",
+		"\tpb.RegisterDbOrderServer(s, srv)\n",
+		"var _ pb.ApiServer = (*oracallServer)(nil)",
+		"var _ pb.DbOrderServer = (*oracallServer)(nil)",
+		"type ApiService interface",
+		"type DbOrderService interface",
+		"stream pb.Api_ListOrdersServer",
+	)
+	if strings.Contains(s, "UnimplementedDbWebServer") {
+		t.Errorf("the service of the pb package is embedded:\n%s", s)
+	}
+	// each function is in the interface of its group
+	for svc, methods := range map[string][]string{
+		"Api": {"GetCustomer(", "ListOrders("}, "DbOrder": {"PurgeOrders("},
+	} {
+		_, iface, _ := strings.Cut(s, "type "+svc+"Service interface {\n")
+		iface, _, _ = strings.Cut(iface, "\n}")
+		for _, m := range methods {
+			if !strings.Contains(iface, "\t"+m) {
+				t.Errorf("%s not in the %sService interface:\n%s", m, svc, iface)
+			}
+		}
+	}
+	if _, err := format.Source(buf.Bytes()); err != nil {
+		t.Errorf("not gofmt-able: %+v", err)
+	}
+}
+
+func TestSaveFunctionsGofmt(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	var functions []Function
+	for _, fn := range []string{"testdata/named_binds.csv", "testdata/paginate.csv", "testdata/xml_fetch.csv"} {
+		fh, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		funs, err := ParseCsv(fh, nil)
+		fh.Close()
+		if err != nil {
+			t.Fatalf("%s: %+v", fn, err)
+		}
+		functions = append(functions, funs...)
+	}
+
+	stable := func(nm string, b []byte) {
+		t.Helper()
+		again, err := format.Source(b)
+		if err != nil {
+			t.Fatalf("%s: %+v", nm, err)
+		}
+		if !bytes.Equal(again, b) {
+			t.Errorf("%s is not gofmt-clean", nm)
+		}
+	}
+	var buf bytes.Buffer
+	if err := SaveFunctions(&buf, functions, "db", "example.com/pb", false); err != nil {
+		t.Fatal(err)
+	}
+	stable("SaveFunctions", buf.Bytes())
+
+	buf.Reset()
+	files, err := SaveFunctionsSplit(&buf, functions, "db", "example.com/pb", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stable("SaveFunctionsSplit", buf.Bytes())
+	for nm, b := range files {
+		stable(nm, b)
+	}
+
+	// a generation bug shows the offending lines
+	_, err = formatGo([]byte("package db\n\nfunc ok() {}\n\nfunc broken( {\n}\n"))
+	if err == nil || !strings.Contains(err.Error(), "func broken( {") {
+		t.Errorf("got %+v, wanted the offending line", err)
+	}
+}
+
+func TestHiddenNaming(t *testing.T) {
+	defer func(prefix, suffix string) { HiddenPrefix, HiddenSuffix = prefix, suffix }(HiddenPrefix, HiddenSuffix)
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,GET_X,0,1,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,GET_X,0,2,P_ARGS#,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,1000,,,,",
+		"1,1,3,DB_WEB,GET_X,0,3,P_NAME#,OUT,VARCHAR2,,,CHAR_CS,,VARCHAR2,1000,,,,",
+	)
+	for _, tc := range []struct {
+		Prefix, Suffix string
+		Input, Output  []string
+		Go             []string
+	}{
+		{"", MarkHidden,
+			[]string{"sint32 p_id = 1", "string p_args_hidden = 2"}, []string{"string p_name_hidden = 1"},
+			[]string{"params[1] = input.PArgsHidden ", "params[2] = sql.Out{Dest: &output.PNameHidden}"}},
+		{"h_", "",
+			[]string{"sint32 p_id = 1", "string h_p_args = 2"}, []string{"string h_p_name = 1"},
+			[]string{"params[1] = input.HPArgs ", "params[2] = sql.Out{Dest: &output.HPName}"}},
+	} {
+		HiddenPrefix, HiddenSuffix = tc.Prefix, tc.Suffix
+		var buf strings.Builder
+		if err := SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		proto := buf.String()
+		if d := cmp.Diff(tc.Input, protoFields(t, proto, "GetX_Input")); d != "" {
+			t.Errorf("%q: %s", tc.Prefix, d)
+		}
+		if d := cmp.Diff(tc.Output, protoFields(t, proto, "GetX_Output")); d != "" {
+			t.Errorf("%q: %s", tc.Prefix, d)
+		}
+		plsql, callFun := functions[0].PlsqlBlock("")
+		for _, want := range tc.Go {
+			if !strings.Contains(callFun, want) {
+				t.Errorf("%q: no %q in\n%s", tc.Prefix, want, callFun)
+			}
+		}
+		if !strings.Contains(plsql, "p_args#=>:2") {
+			t.Errorf("%q: p_args# is not bound in\n%s", tc.Prefix, plsql)
+		}
+		// orasrv fills the field named so with the request JSON
+		if got, want := CamelCase("p_args#"), strings.TrimPrefix(strings.TrimSuffix(tc.Go[0], " "), "params[1] = input."); got != want {
+			t.Errorf("%q: got %q, wanted %q", tc.Prefix, got, want)
+		}
+	}
+}
+
+func TestGenChecksPrecision(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,SET_X,0,1,P_ID,IN,NUMBER,5,,,,NUMBER,0,,,,",
+		"1,1,2,DB_WEB,SET_X,0,2,P_BIG,IN,NUMBER,12,,,,NUMBER,0,,,,",
+		"1,1,3,DB_WEB,SET_X,0,3,P_AMOUNT,IN,NUMBER,5,2,,,NUMBER,0,,,,",
+		"1,1,4,DB_WEB,SET_X,0,4,P_ANY,IN,NUMBER,,,,,NUMBER,0,,,,",
+		"1,1,5,DB_WEB,SET_X,0,5,P_COUNT,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	var buf bytes.Buffer
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web/pb", false); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantContains(t, s,
+		"func CheckSetX_Input(s *pb.SetX_Input) error {",
+		"if s.PId < -99999 || s.PId > 99999 {",
+		"if s.PBig < -999999999999 || s.PBig > 999999999999 {",
+		"if err := oracall.ParseDigits(s.PAmount, 5, 2); err != nil {",
+		// before binding the input
+		"if err = CheckSetX_Input(input); err != nil {",
+	)
+	// unconstrained
+	if strings.Contains(s, "s.PAny") {
+		t.Errorf("PAny is checked in\n%s", s)
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "checks-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for SET_X
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type SetX_Input struct {
+	PId     int32
+	PBig    int64
+	PAmount string
+	PAny    string
+}
+type SetX_Output struct{ PCount int32 }
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = SaveFunctions(&buf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the over-precision values are rejected early, naming the field
+	if err = os.WriteFile(filepath.Join(dn, "db", "main.go"), []byte(`package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	pb "`+pbImport+`"
+)
+
+func main() {
+	for _, tc := range []struct {
+		In    *pb.SetX_Input
+		Field string
+	}{
+		{In: &pb.SetX_Input{PId: -99999, PBig: 999999999999, PAmount: "999.99", PAny: "123456789.123456789"}},
+		{In: &pb.SetX_Input{PId: 100000}, Field: "s.PId"},
+		{In: &pb.SetX_Input{PBig: -1000000000000}, Field: "s.PBig"},
+		{In: &pb.SetX_Input{PAmount: "1000.5"}, Field: "s.PAmount"},
+		{In: &pb.SetX_Input{PAmount: "9.999"}, Field: "s.PAmount"},
+	} {
+		err := CheckSetX_Input(tc.In)
+		if tc.Field == "" {
+			if err != nil {
+				fmt.Printf("%+v: %+v\n", tc.In, err)
+				os.Exit(1)
+			}
+			continue
+		}
+		if !errors.Is(err, oracall.ErrInvalidArgument) || !strings.Contains(err.Error(), tc.Field) {
+			fmt.Printf("%+v: got %+v, wanted ErrInvalidArgument for %s\n", tc.In, err, tc.Field)
+			os.Exit(1)
+		}
+	}
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "run", "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go run: %+v\n%s", err, out.String())
+	}
+}
+
+func TestGenChecksArgGroups(t *testing.T) {
+	functions := parseTestCsv(t,
+		"1,1,1,DB_WEB,FIND_X,0,1,P_FROM,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,2,DB_WEB,FIND_X,0,2,P_TO,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,3,DB_WEB,FIND_X,0,3,P_ID,IN,NUMBER,9,,,,NUMBER,0,,,,",
+		"1,1,4,DB_WEB,FIND_X,0,4,P_CODE,IN,VARCHAR2,,,CHAR_CS,,VARCHAR2,10,,,,",
+		"1,1,5,DB_WEB,FIND_X,0,5,P_COUNT,OUT,NUMBER,9,,,,NUMBER,0,,,,",
+	)
+	var annotations []Annotation
+	for _, s := range []string{
+		"requires db_web.find_x => p_from, p_to",
+		"excludes db_web.find_x => p_id, p_code",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	functions, err := ApplyAnnotationsStrict(functions, annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{
+		"requires db_web.find_x => p_from",
+		"requires db_web.find_x => p_from, p_count",
+		"excludes db_web.find_x => p_id, p_nope",
+	} {
+		a, err := ParseAnnotation(bad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ApplyAnnotationsStrict(functions, []Annotation{a}); !errors.Is(err, ErrUnmatchedAnnotation) {
+			t.Errorf("%q: got %v, wanted ErrUnmatchedAnnotation", bad, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := SaveFunctions(&buf, functions, "main", "example.com/db_web/pb", false); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantContains(t, s,
+		`if (s.PFrom != "" || s.PTo != "") && !(s.PFrom != "" && s.PTo != "") {`,
+		`"requires p_from, p_to: all or none of them must be set: %w"`,
+		`"excludes p_id, p_code: at most one of them can be set: %w"`,
+	)
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "groups-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for FIND_X
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type FindX_Input struct {
+	PFrom, PTo string
+	PId        int32
+	PCode      string
+}
+type FindX_Output struct{ PCount int32 }
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = SaveFunctions(&buf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "main.go"), []byte(`package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	pb "`+pbImport+`"
+)
+
+func main() {
+	for _, tc := range []struct {
+		In    *pb.FindX_Input
+		Group string
+	}{
+		{In: &pb.FindX_Input{}},
+		{In: &pb.FindX_Input{PFrom: "a", PTo: "b", PId: 1}},
+		{In: &pb.FindX_Input{PCode: "c"}},
+		{In: &pb.FindX_Input{PFrom: "a"}, Group: "requires p_from, p_to"},
+		{In: &pb.FindX_Input{PTo: "b"}, Group: "requires p_from, p_to"},
+		{In: &pb.FindX_Input{PId: 1, PCode: "c"}, Group: "excludes p_id, p_code"},
+	} {
+		err := CheckFindX_Input(tc.In)
+		if tc.Group == "" {
+			if err != nil {
+				fmt.Printf("%+v: %+v\n", tc.In, err)
+				os.Exit(1)
+			}
+			continue
+		}
+		if !errors.Is(err, oracall.ErrInvalidArgument) || !strings.Contains(err.Error(), tc.Group) {
+			fmt.Printf("%+v: got %+v, wanted ErrInvalidArgument for %s\n", tc.In, err, tc.Group)
+			os.Exit(1)
+		}
+	}
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "run", "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go run: %+v\n%s", err, out.String())
+	}
+}
+
+func TestEmptyString(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	fh, err := os.Open("testdata/empty_string.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var annotations []Annotation
+	for _, s := range []string{
+		"empty-string db_web.set_x.p_name => reject",
+		"empty-string db_web.set_x.p_code => ' '",
+		"empty-string db_web.set_x.p_note => '-'",
+		"empty-string db_web.set_x.p_plain => null",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	if functions, err = ApplyAnnotationsStrict(functions, annotations); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{
+		"empty-string db_web.set_x.p_id => reject",
+		"empty-string db_web.set_x.p_code => 'abcd'",
+		"empty-string db_web.set_x.p_code => ''",
+		"empty-string db_web.set_x.p_nope => reject",
+	} {
+		a, err := ParseAnnotation(bad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ApplyAnnotationsStrict(functions, []Annotation{a}); !errors.Is(err, ErrUnmatchedAnnotation) {
+			t.Errorf("%q: got %v, wanted ErrUnmatchedAnnotation", bad, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = SaveFunctions(&buf, functions, "main", "example.com/db_web/pb", false); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantContains(t, s,
+		`"s.PName must not be empty (Oracle would bind it as NULL): %w"`,
+		`if input.PCode == "" {`,
+		`= " "`,
+		`if output.PNote == "" {`,
+		`output.PNote = "-"`,
+	)
+	for _, notWant := range []string{`s.PPlain == ""`, `input.PPlain == ""`} {
+		if strings.Contains(s, notWant) {
+			t.Errorf("%q found in\n%s", notWant, s)
+		}
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "empty-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for SET_X
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type SetX_Input struct {
+	PName, PCode, PNote, PPlain string
+	PId                         int32
+}
+type SetX_Output struct{ PNote string }
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = SaveFunctions(&buf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dn, "db", "main.go"), []byte(`package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	pb "`+pbImport+`"
+)
+
+func main() {
+	if err := CheckSetX_Input(&pb.SetX_Input{PName: "a"}); err != nil {
+		fmt.Printf("empty p_code, p_note and p_plain: %+v\n", err)
+		os.Exit(1)
+	}
+	if err := CheckSetX_Input(&pb.SetX_Input{PCode: "c"}); !errors.Is(err, oracall.ErrInvalidArgument) {
+		fmt.Printf("empty p_name: got %+v, wanted ErrInvalidArgument\n", err)
+		os.Exit(1)
+	}
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "run", "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go run: %+v\n%s", err, out.String())
+	}
+}
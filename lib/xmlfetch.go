@@ -0,0 +1,101 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// XMLFetch returns the variant set by an xml-fetch annotation, or nil.
+//
+// The generated code calls the variant instead of this function, and returns the XMLTYPE it returns
+// (validated as a custom.XML) in the field of the REF CURSOR, instead of streaming the rows of the cursor.
+func (f Function) XMLFetch() *Function { return f.xmlFetch }
+
+// setXMLFetch makes the function fetch the result of its only REF CURSOR as XML, by calling variant instead:
+// it must have the same input arguments, and return the XMLTYPE the same way (as the return value,
+// or as an OUT argument) as the function returns the REF CURSOR.
+//
+// The XMLTYPE argument of the variant replaces the REF CURSOR, keeping its name.
+func (f *Function) setXMLFetch(variant *Function) error {
+	if variant == nil {
+		return fmt.Errorf("no such variant: %w", ErrInvalidArgument)
+	}
+	cursor := -1 // the index of the REF CURSOR in Args, len(Args) for Returns
+	for i, arg := range f.Args {
+		if arg.IsOutput() && arg.Type == "REF CURSOR" {
+			if cursor >= 0 {
+				return fmt.Errorf("%s: more than one REF CURSOR: %w", f.Name(), ErrInvalidArgument)
+			}
+			cursor = i
+		}
+	}
+	if f.Returns != nil && f.Returns.Type == "REF CURSOR" {
+		if cursor >= 0 {
+			return fmt.Errorf("%s: more than one REF CURSOR: %w", f.Name(), ErrInvalidArgument)
+		}
+		cursor = len(f.Args)
+	}
+	if cursor < 0 {
+		return fmt.Errorf("%s: no REF CURSOR: %w", f.Name(), ErrInvalidArgument)
+	}
+
+	xml := -1 // the index of the XMLTYPE in variant.Args, len(variant.Args) for Returns
+	for i, arg := range variant.Args {
+		if arg.IsOutput() && arg.Type == "XMLTYPE" {
+			if xml >= 0 {
+				return fmt.Errorf("%s: more than one XMLTYPE: %w", variant.Name(), ErrInvalidArgument)
+			}
+			xml = i
+		}
+	}
+	if variant.Returns != nil && variant.Returns.Type == "XMLTYPE" {
+		if xml >= 0 {
+			return fmt.Errorf("%s: more than one XMLTYPE: %w", variant.Name(), ErrInvalidArgument)
+		}
+		xml = len(variant.Args)
+	}
+	if xml < 0 {
+		return fmt.Errorf("%s: returns no XMLTYPE: %w", variant.Name(), ErrInvalidArgument)
+	}
+	if (cursor == len(f.Args)) != (xml == len(variant.Args)) {
+		return fmt.Errorf("%s returns the REF CURSOR, but %s the XMLTYPE differently (as the return value or as an argument): %w",
+			f.Name(), variant.Name(), ErrInvalidArgument)
+	}
+
+	// the rest of the arguments are passed as they are
+	others := func(args []Argument, skip int) []string {
+		names := make([]string, 0, len(args))
+		for i, arg := range args {
+			if i != skip {
+				names = append(names, strings.ToLower(arg.RealName()))
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+	if got, want := others(variant.Args, xml), others(f.Args, cursor); strings.Join(got, ",") != strings.Join(want, ",") {
+		return fmt.Errorf("%s has arguments %q, %s %q: %w", variant.Name(), got, f.Name(), want, ErrInvalidArgument)
+	}
+
+	if cursor == len(f.Args) {
+		ret := *variant.Returns
+		ret.Name = f.Returns.Name
+		f.Returns = &ret
+	} else {
+		arg := variant.Args[xml]
+		if arg.oraName == "" {
+			arg.oraName = arg.Name
+		}
+		arg.Name = f.Args[cursor].Name
+		// do not modify the caller's Args
+		f.Args = append([]Argument(nil), f.Args...)
+		f.Args[cursor] = arg
+	}
+	f.xmlFetch = variant
+	return nil
+}
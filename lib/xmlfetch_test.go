@@ -0,0 +1,153 @@
+// Copyright 2023 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+func TestXMLFetch(t *testing.T) {
+	logger = zlog.NewT(t).SLog()
+	fh, err := os.Open("testdata/xml_fetch.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var annotations []Annotation
+	for _, s := range []string{
+		"xml-fetch db_web.list_items => db_web.list_items_xml",
+		"xml-fetch db_web.table_items => db_web.table_items_xml",
+	} {
+		a, err := ParseAnnotation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations = append(annotations, a)
+	}
+	if functions, err = ApplyAnnotationsStrict(functions, annotations); err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 2 {
+		t.Fatalf("got %d functions, wanted the variants to be consumed: %v", len(functions), functions)
+	}
+
+	for _, fun := range functions {
+		if fun.XMLFetch() == nil {
+			t.Fatalf("%s: no XMLFetch", fun.Name())
+		}
+		plsql, callFun := fun.PlsqlBlock("")
+		t.Log(plsql)
+		want := []string{fun.XMLFetch().RealName() + "(", ".getClobVal();"}
+		if fun.Returns == nil {
+			want = append(want, "p_xml=>")
+		} else {
+			// returned into the XMLTYPE variable, not the CLOB
+			want = append(want, "v001 := "+fun.XMLFetch().RealName()+"(")
+		}
+		for _, want := range want {
+			if !strings.Contains(plsql, want) {
+				t.Errorf("%s: no %q in\n%s", fun.Name(), want, plsql)
+			}
+		}
+		if strings.Contains(plsql, "REF CURSOR") || strings.Contains(callFun, "driver.Rows") {
+			t.Errorf("%s: the cursor is fetched:\n%s\n%s", fun.Name(), plsql, callFun)
+		}
+		field := "PRows"
+		if fun.Returns != nil {
+			field = "Ret"
+		}
+		if want := "custom.XML(output." + field + ").Validate()"; !strings.Contains(callFun, want) {
+			t.Errorf("%s: no %q in\n%s", fun.Name(), want, callFun)
+		}
+	}
+
+	// the response field keeps the name of the cursor, but holds the XML
+	var buf strings.Builder
+	if err = SaveProtobuf(&buf, functions, "db_web", "example.com/db_web", ProtoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"string p_rows = ", "string ret = "} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("no %q in\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "stream ") {
+		t.Errorf("streaming rpc in\n%s", buf.String())
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// in the module, to build with its dependencies
+	dn, err := os.MkdirTemp("testdata", "xmlfetch-")
+	if err != nil {
+		t.Skipf("cannot create temp dir: %v", err)
+	}
+	if !*flagKeep {
+		defer os.RemoveAll(dn)
+	}
+	pbImport := "github.com/tgulacsi/oracall/lib/" + filepath.ToSlash(dn) + "/pb"
+	for _, sub := range []string{"db", "pb"} {
+		if err = os.Mkdir(filepath.Join(dn, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// what protoc would generate for LIST_ITEMS and TABLE_ITEMS
+	if err = os.WriteFile(filepath.Join(dn, "pb", "pb.go"), []byte(`package pb
+
+type ListItems_Input struct{ PId int32 }
+type ListItems_Output struct{ PRows string }
+type TableItems_Input struct{ PId int32 }
+type TableItems_Output struct{ Ret string }
+type UnimplementedPbServer struct{}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var goBuf bytes.Buffer
+	if err = SaveFunctions(&goBuf, functions, "main", pbImport, false); err != nil {
+		t.Fatal(err)
+	}
+	goBuf.WriteString("\nfunc main() {}\n")
+	if err = os.WriteFile(filepath.Join(dn, "db", "oracall.go"), goBuf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, "./"+filepath.ToSlash(filepath.Join(dn, "db")))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		t.Errorf("go build: %+v\n%s", err, out.String())
+	}
+
+	// the XMLTYPE must be returned the same way as the cursor
+	fh, err = os.Open("testdata/xml_fetch.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err = ParseCsv(fh, nil)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := ParseAnnotation("xml-fetch db_web.list_items => db_web.table_items_xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ApplyAnnotationsStrict(functions, []Annotation{a}); !errors.Is(err, ErrUnmatchedAnnotation) {
+		t.Errorf("got %+v, wanted ErrUnmatchedAnnotation", err)
+	}
+}
@@ -19,12 +19,14 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/sync/errgroup"
 
@@ -63,17 +65,72 @@ func Main() error {
 	fs := flag.NewFlagSet("call", flag.ContinueOnError)
 	fs.BoolVar(&oracall.SkipMissingTableOf, "skip-missing-table-of", true, "skip functions with missing TableOf info")
 	flagDump := fs.String("dump", "", "dump to this csv")
+	flagDumpXML := fs.String("dump-xml", "", "dump the parsed functions' argument trees as XML to this file (\"-\" for stderr), for debugging")
 	flagBaseDir := fs.String("base-dir", gopSrc, "base dir for the -pb-out, -db-out flags")
 	flagPbOut := fs.String("pb-out", "", "package import path for the Protocol Buffers files, optionally with the package name, like \"my/pb-pkg:main\"")
 	flagDbOut := fs.String("db-out", "-:main", "package name of the generated functions, optionally with the package name, like \"my/db-pkg:main\"")
 	flagGenerator := fs.String("protoc-gen", "go", "use protoc-gen-<generator>")
 	fs.BoolVar(&oracall.NumberAsString, "number-as-string", false, "add ,string to json tags")
+	fs.BoolVar(&oracall.NumberAsDecimal, "number-as-decimal", false, "map every NUMBER to a decimal string, regardless of its precision and scale")
 	fs.BoolVar(&custom.ZeroIsAlmostZero, "zero-is-almost-zero", false, "zero should be just almost zero, to distinguish 0 and non-set field")
 	fs.Var(&verbose, "v", "verbose logging")
 	flagExcept := fs.String("except", "", "except these functions")
 	flagReplace := fs.String("replace", "", "funcA=>funcB")
+	flagStrictAnnotations := fs.Bool("strict-annotations", false, "fail on the annotations matching no function, instead of just warning")
+	flagCsvDelimiter := fs.String("csv-delimiter", "", "field delimiter of the csv read from stdin (\",\", \";\" or \"tab\"); detected from the header if empty")
+	fs.DurationVar(&oracall.CsvReadTimeout, "csv-timeout", 0, "abort if the csv read from stdin stalls for this long (0: wait forever)")
+	flagSplitGo := fs.Bool("split-go", false, "write the functions of each package into a separate <package>_oracall.go file next to the -db-out file")
+	flagSplitGoFunctions := fs.Bool("split-go-functions", false, "write each function into a separate <package>_<function>_oracall.go file next to the -db-out file")
+	flagIncremental := fs.Bool("incremental", false, "keep the previously generated code of the unchanged functions in the -db-out file")
+	flagArgComments := fs.String("arg-comments", "", "csv of PACKAGE_NAME, OBJECT_NAME, ARGUMENT_NAME, COMMENTS to document the arguments with")
+	flagChangedSince := fs.String("changed-since", "", "process only the functions changed (by their last DDL time) since this RFC3339 time - needs -connect")
+	flagFilterFile := fs.String("filter-file", "", "file of +include/-exclude PACKAGE.OBJECT globs, one per line")
+	flagCompatShimsFrom := fs.String("compat-shims-from", "", "csv of the previous version of the functions: the retyped arguments get new field numbers (reserving the old ones) and Old<Message>_<Field> funcs returning them as the old type")
+	fs.BoolVar(&oracall.InputChecks, "input-checks", oracall.InputChecks, "generate the checks of the lengths and precisions of the input, returning InvalidArgument before calling the database")
+	fs.BoolVar(&oracall.NamedBinds, "named-binds", false, "bind the parameters of the generated calls by their names (:p_id) instead of their positions (:1)")
+	fs.BoolVar(&oracall.GenInterface, "gen-interface", false, "generate a Go interface of the service, for mocking")
+	fs.BoolVar(&oracall.GenConverters, "converters", false, "generate plain Go structs, with ToProto and FromProto methods converting them to and from the protobuf messages")
+	fs.BoolVar(&oracall.GenAccessors, "accessors", false, "generate Each<Message>_<Field> and Nth<Message>_<Field> funcs traversing the repeated record fields of the messages")
+	fs.BoolVar(&oracall.NullableWrappers, "wrappers", false, "use the google.protobuf wrapper types (StringValue, Int64Value...) for the nullable scalar arguments")
+	fs.BoolVar(&oracall.Envelope, "envelope", false, "wrap the responses in {data, meta} envelope messages, with the request ID and the duration in meta")
+	fs.StringVar(&oracall.HiddenPrefix, "hidden-prefix", oracall.HiddenPrefix, "prefix of the names of the hidden (# suffixed) arguments in the generated code")
+	fs.StringVar(&oracall.HiddenSuffix, "hidden-suffix", oracall.HiddenSuffix, "suffix of the names of the hidden (# suffixed) arguments in the generated code, replacing the #")
 	fs.IntVar(&oracall.MaxTableSize, "max-table-size", oracall.MaxTableSize, "maximum table size for PL/SQL associative arrays")
+	fs.IntVar(&oracall.MaxNestingDepth, "max-depth", oracall.MaxNestingDepth, "skip the functions with arguments nested (records, tables) deeper than this")
+	fs.IntVar(&oracall.CursorClobLimit, "cursor-clob-limit", oracall.CursorClobLimit, "send the rows fetched from a returned cursor once their CLOB columns reach this many bytes (0: no limit)")
 	fs.StringVar(&dsn, "connect", "", "connect to DB for retrieving function arguments")
+	flagMessagesOnly := fs.Bool("messages-only", false, "generate only the messages into the .proto, without the service")
+	flagCLI := fs.Bool("cli", false, "generate a command calling the rpcs with JSON requests, into the cmd/<pb package>cli directory of the -pb-out package")
+	flagRegistry := fs.Bool("registry", false, "generate the request and response factories of the rpcs by their full method names, into the registry_oracall.go of the -pb-out package")
+	flagHTTPOut := fs.String("http-out", "", "write example JSON requests of the functions into this .http file (for the REST Client of VS Code or the HTTP client of JetBrains)")
+	flagAvroOut := fs.String("avro-out", "", "write the Avro schemas of the responses into this file")
+	flagJSONSchemaOut := fs.String("json-schema-out", "", "write the JSON Schema of the requests (for validating them at the API gateway) into this file")
+	flagModelJSON := fs.String("model-json", "", "write the parsed functions (with the annotations applied) into this JSON file, for external generators")
+	flagNoErrorDocs := fs.Bool("no-error-docs", false, "do not document the possible errors of the rpcs in the .proto")
+	flagServicePerPackage := fs.Bool("service-per-package", false, "write a service for each package (or group, set by the group annotation) into the .proto, instead of one")
+	flagCamelCaseFields := fs.Bool("camel-case-fields", false, "name the fields of the .proto in lowerCamelCase (p_customer_id => pCustomerId), keeping their JSON names")
+	flagReserveRenamed := fs.Bool("reserve-renamed-fields", false, "reserve the previous names of the fields renamed by annotations in the .proto, so they cannot be reused")
+	flagHashFieldNumbers := fs.Bool("hash-field-numbers", false, "number the fields of the .proto by the hashes of their names instead of their positions, so reordering the arguments keeps the numbers (renumbers the existing fields once!)")
+	oraCodes := make(map[int]string)
+	fs.Func("ora-code", "oraCode=CodeName, such as 20404=NotFound: the ORA- error mapped to a gRPC code on the server, for the error documentation of the rpcs (can be repeated)", func(s string) error {
+		oraCode, name, err := oracall.ParseORACode(s)
+		if err != nil {
+			return err
+		}
+		oraCodes[oraCode] = name
+		return nil
+	})
+	gogoOptions := make(map[string]bool)
+	fs.Func("gogo-option", "name=true|false, such as goproto_getters_all=false: a file-level gogoproto option of the .proto (can be repeated)", func(s string) error {
+		name, value, err := oracall.ParseGogoOption(s)
+		if err != nil {
+			return err
+		}
+		gogoOptions[name] = value
+		return nil
+	})
+	flagProtoEdition := fs.String("proto-edition", "", "declare this protobuf edition (such as 2023) in the .proto instead of syntax = \"proto3\" (not with gogoproto)")
+	flagProtoTimestamp := fs.String("proto-timestamp", "", "extraction timestamp (RFC3339, or \"now\") to record in the generated .proto; empty for reproducible builds")
 
 	var db *sql.DB
 
@@ -114,14 +171,57 @@ func Main() error {
 			if *flagExcept != "" {
 				except := strings.FieldsFunc(*flagExcept, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
 				logger.Info("found", "except", except)
-				filters = append(filters, func(s string) bool {
-					for _, e := range except {
-						if strings.EqualFold(e, s) {
-							return false
-						}
-					}
-					return true
-				})
+				filters = append(filters, oracall.ExceptFilter(except...))
+			}
+
+			protoOpts := oracall.ProtoOptions{Version: oracallVersion(), MessagesOnly: *flagMessagesOnly,
+				ORACodes: oraCodes, NoErrorDocs: *flagNoErrorDocs, HashFieldNumbers: *flagHashFieldNumbers,
+				ServicePerPackage: *flagServicePerPackage, GogoOptions: gogoOptions,
+				Edition: *flagProtoEdition, ReserveRenamedFields: *flagReserveRenamed}
+			// the Go code implements the same services as the .proto
+			oracall.ServicePerPackage = *flagServicePerPackage
+			if *flagCamelCaseFields {
+				protoOpts.Naming = oracall.CamelCaseFields{}
+			}
+			switch *flagProtoTimestamp {
+			case "":
+			case "now":
+				protoOpts.Timestamp = time.Now()
+			default:
+				if protoOpts.Timestamp, err = time.Parse(time.RFC3339, *flagProtoTimestamp); err != nil {
+					return fmt.Errorf("parse -proto-timestamp=%q: %w", *flagProtoTimestamp, err)
+				}
+			}
+
+			if *flagFilterFile != "" {
+				fileFilter, err := oracall.LoadFilterFile(*flagFilterFile)
+				if err != nil {
+					return err
+				}
+				filters = append(filters, fileFilter)
+			}
+
+			switch *flagDumpXML {
+			case "":
+			case "-":
+				oracall.DumpXML = os.Stderr
+			default:
+				fh, err := os.Create(*flagDumpXML)
+				if err != nil {
+					return fmt.Errorf("create %s: %w", *flagDumpXML, err)
+				}
+				defer fh.Close()
+				oracall.DumpXML = fh
+			}
+
+			var since time.Time
+			if *flagChangedSince != "" {
+				if db == nil {
+					return errors.New("-changed-since needs the last DDL times from the database (-connect)")
+				}
+				if since, err = time.Parse(time.RFC3339, *flagChangedSince); err != nil {
+					return fmt.Errorf("parse -changed-since=%q: %w", *flagChangedSince, err)
+				}
 			}
 
 			var annotations []oracall.Annotation
@@ -132,9 +232,25 @@ func Main() error {
 						return rPattern.MatchString(s)
 					})
 				}
-				functions, err = oracall.ParseCsvFile("", filter)
+				switch *flagCsvDelimiter {
+				case "":
+				case "tab", `\t`:
+					oracall.CsvDelimiter = '\t'
+				default:
+					oracall.CsvDelimiter, _ = utf8.DecodeRuneInString(*flagCsvDelimiter)
+				}
+				protoOpts.Query = "csv from the standard input"
+				var stats oracall.ParseStats
+				functions, stats, err = oracall.ParseCsvStats(os.Stdin, filter)
+				logger.Info("parsed", "rows", stats.Rows, "functions", stats.Functions, "hidden", stats.Hidden,
+					"filtered", stats.Filtered, "missingTableOf", stats.MissingTableOf, "cursorInput", stats.CursorInput, "tooDeep", stats.TooDeep)
 			} else {
-				functions, annotations, err = parseDB(ctx, db, pattern, *flagDump, filter)
+				if err = db.QueryRowContext(ctx, "SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL").Scan(&protoOpts.Schema); err != nil {
+					return fmt.Errorf("get current schema: %w", err)
+				}
+				tbl, _ := argumentsTables(pattern)
+				protoOpts.Query = argumentsQuery(tbl)
+				functions, annotations, err = parseDB(ctx, db, pattern, *flagDump, since, filter)
 			}
 			if err != nil {
 				return fmt.Errorf("read %s: %w", flag.Arg(0), err)
@@ -150,6 +266,21 @@ func Main() error {
 				}
 				fn = filepath.Join(*flagBaseDir, dbPath, fn)
 				logger.Info("Writing generated functions", "file", fn)
+				if *flagIncremental {
+					if fh, err := os.Open(fn); err == nil {
+						oracall.PreviousSections, err = oracall.ReadSections(fh)
+						fh.Close()
+						if err != nil {
+							return fmt.Errorf("read sections of %s: %w", fn, err)
+						}
+						if *flagSplitGo || *flagSplitGoFunctions {
+							if err = readSplitSections(filepath.Dir(fn)); err != nil {
+								return err
+							}
+						}
+						logger.Info("incremental", "previous", len(oracall.PreviousSections))
+					}
+				}
 				// nosemgrep: go.lang.correctness.permissions.file_permission.incorrect-default-permission
 				_ = os.MkdirAll(filepath.Dir(fn), 0775)
 				outP, err := renameio.NewPendingFile(fn)
@@ -175,6 +306,8 @@ func Main() error {
 				}()
 			}
 
+			// the annotations of the flags win over the ones of the sources
+			var flagAnnotations []oracall.Annotation
 			*flagReplace = strings.TrimSpace(*flagReplace)
 			for _, elt := range strings.FieldsFunc(
 				rReplace.ReplaceAllLiteralString(*flagReplace, "=>"),
@@ -188,10 +321,36 @@ func Main() error {
 					a.Package, a.Name = a.Name[:i], a.Name[i+1:]
 					a.Other = strings.TrimPrefix(a.Other, a.Package)
 				}
-				annotations = append(annotations, a)
+				flagAnnotations = append(flagAnnotations, a)
 			}
+			annotations = oracall.MergeAnnotations(annotations, flagAnnotations)
 			logger.Info("got", "annotations", annotations)
-			functions = oracall.ApplyAnnotations(functions, annotations)
+			if *flagStrictAnnotations {
+				if functions, err = oracall.ApplyAnnotationsStrict(functions, annotations); err != nil {
+					return err
+				}
+			} else {
+				functions = oracall.ApplyAnnotations(functions, annotations)
+			}
+			if *flagArgComments != "" {
+				comments, err := oracall.LoadArgumentComments(*flagArgComments)
+				if err != nil {
+					return err
+				}
+				functions = oracall.ApplyArgumentComments(functions, comments)
+			}
+			if *flagCompatShimsFrom != "" {
+				fh, err := os.Open(*flagCompatShimsFrom)
+				if err != nil {
+					return err
+				}
+				old, err := oracall.ParseCsv(fh, filter)
+				fh.Close()
+				if err != nil {
+					return fmt.Errorf("read %s: %w", *flagCompatShimsFrom, err)
+				}
+				functions = oracall.MarkRetypedArgs(oracall.ApplyAnnotations(old, annotations), functions)
+			}
 			sort.Slice(functions, func(i, j int) bool { return functions[i].Name() < functions[j].Name() })
 
 			var grp errgroup.Group
@@ -200,12 +359,33 @@ func Main() error {
 				if pbPath == dbPath {
 					pbPath = ""
 				}
-				if err := oracall.SaveFunctions(
+				if !(*flagSplitGo || *flagSplitGoFunctions) || dbPath == "" || dbPath == "-" {
+					if err := oracall.SaveFunctions(
+						out, functions,
+						dbPkg, pbPath, oracall.GenConverters,
+					); err != nil {
+						return fmt.Errorf("save functions: %w", err)
+					}
+					return nil
+				}
+				saveSplit := oracall.SaveFunctionsSplit
+				if *flagSplitGoFunctions {
+					saveSplit = oracall.SaveFunctionsPerFunction
+				}
+				files, err := saveSplit(
 					out, functions,
-					dbPkg, pbPath, false,
-				); err != nil {
+					dbPkg, pbPath, oracall.GenConverters,
+				)
+				if err != nil {
 					return fmt.Errorf("save functions: %w", err)
 				}
+				dir := filepath.Dir(out.Name())
+				for nm, b := range files {
+					// nosemgrep: go.lang.correctness.permissions.file_permission.incorrect-default-permission
+					if err := renameio.WriteFile(filepath.Join(dir, nm), b, 0644); err != nil {
+						return fmt.Errorf("write %s: %w", nm, err)
+					}
+				}
 				return nil
 			})
 			if testOut != nil {
@@ -224,6 +404,102 @@ func Main() error {
 				})
 			}
 
+			if *flagAvroOut != "" {
+				grp.Go(func() error {
+					logger.Info("Writing Avro schemas", "file", *flagAvroOut)
+					fh, err := renameio.NewPendingFile(*flagAvroOut)
+					if err != nil {
+						return fmt.Errorf("create %s: %w", *flagAvroOut, err)
+					}
+					defer fh.Cleanup()
+					if err := oracall.SaveAvro(fh, functions); err != nil {
+						return fmt.Errorf("save Avro schemas: %w", err)
+					}
+					return fh.CloseAtomicallyReplace()
+				})
+			}
+
+			if *flagJSONSchemaOut != "" {
+				grp.Go(func() error {
+					logger.Info("Writing JSON Schema", "file", *flagJSONSchemaOut)
+					fh, err := renameio.NewPendingFile(*flagJSONSchemaOut)
+					if err != nil {
+						return fmt.Errorf("create %s: %w", *flagJSONSchemaOut, err)
+					}
+					defer fh.Cleanup()
+					if err := oracall.SaveJSONSchema(fh, functions); err != nil {
+						return fmt.Errorf("save JSON Schema: %w", err)
+					}
+					return fh.CloseAtomicallyReplace()
+				})
+			}
+
+			if *flagModelJSON != "" {
+				grp.Go(func() error {
+					logger.Info("Writing model", "file", *flagModelJSON)
+					fh, err := renameio.NewPendingFile(*flagModelJSON)
+					if err != nil {
+						return fmt.Errorf("create %s: %w", *flagModelJSON, err)
+					}
+					defer fh.Cleanup()
+					if err := oracall.SaveModelJSON(fh, functions); err != nil {
+						return fmt.Errorf("save model: %w", err)
+					}
+					return fh.CloseAtomicallyReplace()
+				})
+			}
+
+			if *flagCLI {
+				grp.Go(func() error {
+					fn := filepath.Join(*flagBaseDir, pbPath, "cmd", pbPkg+"cli", "main.go")
+					logger.Info("Writing CLI", "file", fn)
+					// nosemgrep: go.lang.correctness.permissions.file_permission.incorrect-default-permission
+					_ = os.MkdirAll(filepath.Dir(fn), 0775)
+					fh, err := renameio.NewPendingFile(fn)
+					if err != nil {
+						return fmt.Errorf("create %s: %w", fn, err)
+					}
+					defer fh.Cleanup()
+					if err := oracall.SaveCLI(fh, pbPath); err != nil {
+						return fmt.Errorf("save CLI: %w", err)
+					}
+					return fh.CloseAtomicallyReplace()
+				})
+			}
+
+			if *flagRegistry {
+				grp.Go(func() error {
+					fn := filepath.Join(*flagBaseDir, pbPath, "registry_oracall.go")
+					logger.Info("Writing registry", "file", fn)
+					// nosemgrep: go.lang.correctness.permissions.file_permission.incorrect-default-permission
+					_ = os.MkdirAll(filepath.Dir(fn), 0775)
+					fh, err := renameio.NewPendingFile(fn)
+					if err != nil {
+						return fmt.Errorf("create %s: %w", fn, err)
+					}
+					defer fh.Cleanup()
+					if err := oracall.SaveRegistry(fh, functions, pbPkg, protoOpts); err != nil {
+						return fmt.Errorf("save registry: %w", err)
+					}
+					return fh.CloseAtomicallyReplace()
+				})
+			}
+
+			if *flagHTTPOut != "" {
+				grp.Go(func() error {
+					logger.Info("Writing example requests", "file", *flagHTTPOut)
+					fh, err := renameio.NewPendingFile(*flagHTTPOut)
+					if err != nil {
+						return fmt.Errorf("create %s: %w", *flagHTTPOut, err)
+					}
+					defer fh.Cleanup()
+					if err := oracall.SaveHTTPRequests(fh, functions, pbPkg, protoOpts.Naming); err != nil {
+						return fmt.Errorf("save example requests: %w", err)
+					}
+					return fh.CloseAtomicallyReplace()
+				})
+			}
+
 			grp.Go(func() error {
 				pbFn := "oracall.proto"
 				if pbPkg != "main" {
@@ -232,12 +508,21 @@ func Main() error {
 				pbFn = filepath.Join(*flagBaseDir, pbPath, pbFn)
 				// nosemgrep: go.lang.correctness.permissions.file_permission.incorrect-default-permission
 				_ = os.MkdirAll(filepath.Dir(pbFn), 0775)
+				// the renumbering of the retyped fields (-compat-shims-from) is kept from the previous .proto
+				opts := protoOpts
+				if fh, err := os.Open(pbFn); err == nil {
+					opts.Previous, err = oracall.ReadProtoFieldNumbers(fh)
+					fh.Close()
+					if err != nil {
+						return fmt.Errorf("read the field numbers of %s: %w", pbFn, err)
+					}
+				}
 				logger.Info("Writing Protocol Buffers", "file", pbFn)
 				fh, err := os.Create(pbFn)
 				if err != nil {
 					return fmt.Errorf("create proto: %w", err)
 				}
-				err = oracall.SaveProtobuf(fh, functions, pbPkg, pbPath)
+				err = oracall.SaveProtobuf(fh, functions, pbPkg, pbPath, opts)
 				if closeErr := fh.Close(); closeErr != nil && err == nil {
 					err = closeErr
 				}
@@ -341,10 +626,29 @@ func Main() error {
 	return app.Run(ctx)
 }
 
+// oracallVersion returns the version of the oracall module this binary is built from.
+func oracallVersion() string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if bi.Main.Path == "github.com/tgulacsi/oracall" {
+			return bi.Main.Version
+		}
+		for _, m := range bi.Deps {
+			if m.Path == "github.com/tgulacsi/oracall" {
+				return m.Version
+			}
+		}
+	}
+	return ""
+}
+
 type dbRow struct {
 	Package, Object, InOut sql.NullString
 	dbType
-	SubID    sql.NullInt64
+	SubID, Position sql.NullInt64
+	Defaulted       sql.NullString
+	CharUsed        sql.NullString
+	// Schema is the owner of the package (not of the type)
+	Schema   sql.NullString
 	OID, Seq int
 }
 
@@ -364,34 +668,9 @@ func (t dbType) String() string {
 	return fmt.Sprintf("%s{%s}[%d](%s[%s]/%s.%s.%s@%s)", t.Argument, t.Data, t.Level, t.PLS, t.IndexBy, t.Owner, t.Name, t.Subname, t.Link)
 }
 
-func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter func(string) bool) (functions []oracall.Function, annotations []oracall.Annotation, err error) {
-	tbl, objTbl := "user_arguments", "user_objects"
-	if strings.HasPrefix(pattern, "DBMS_") || strings.HasPrefix(pattern, "UTL_") {
-		tbl, objTbl = "all_arguments", "all_objects"
-	}
-	argumentsQry := `` + //nolint:gas
-		`SELECT A.*
-      FROM
-    (SELECT DISTINCT object_id object_id, subprogram_id, sequence*100 seq,
-           package_name, object_name,
-           data_level, argument_name, in_out,
-           data_type, data_precision, data_scale, character_set_name, NULL AS index_by,
-           pls_type, char_length, type_owner, type_name, type_subname, type_link
-      FROM ` + tbl + `
-      WHERE data_type <> 'OBJECT' AND package_name||'.'||object_name LIKE UPPER(:1)
-     UNION ALL
-     SELECT DISTINCT object_id object_id, subprogram_id, A.sequence*100 + B.attr_no,
-            package_name, object_name,
-            A.data_level, B.attr_name, A.in_out,
-            B.ATTR_TYPE_NAME, B.PRECISION, B.scale, B.character_set_name, NULL AS index_by,
-            NVL2(B.ATTR_TYPE_OWNER, B.attr_type_owner||'.', '')||B.attr_type_name, B.length,
-			NULL, NULL, NULL, NULL
-       FROM all_type_attrs B, ` + tbl + ` A
-       WHERE B.owner = A.type_owner AND B.type_name = A.type_name AND
-             A.data_type = 'OBJECT' AND
-             A.package_name||'.'||A.object_name LIKE UPPER(:2)
-     ) A
-      ORDER BY 1, 2, 3`
+func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, since time.Time, filter func(string) bool) (functions []oracall.Function, annotations []oracall.Annotation, err error) {
+	tbl, objTbl := argumentsTables(pattern)
+	argumentsQry := argumentsQuery(tbl)
 
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
@@ -409,6 +688,17 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 		}
 		return t, nil
 	}
+	if !since.IsZero() {
+		filter = oracall.ChangedSince(filter, since, func(name string) time.Time {
+			// the package, or the standalone object
+			obj, _, _ := strings.Cut(name, ".")
+			t, err := getObjTime(obj)
+			if err != nil {
+				logger.Warn("last DDL time", "object", obj, "error", err)
+			}
+			return t
+		})
+	}
 
 	dbCh := make(chan dbRow)
 	grp, grpCtx := errgroup.WithContext(ctx)
@@ -466,7 +756,7 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
                        owner = :owner AND table_name = :pkg
 				 ORDER BY attr_no`
 				if attrStmt, err = cx.PrepareContext(grpCtx, qry); err != nil {
-					logger.Error("qry", qry, "error", err)
+					logger.Error("prepare", "qry", qry, "error", err)
 				} else {
 					defer attrStmt.Close()
 					if rows, err := attrStmt.QueryContext(grpCtx,
@@ -488,7 +778,7 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 			qry, pattern, pattern, godror.FetchArraySize(1024), godror.PrefetchCount(1025),
 		)
 		if err != nil {
-			logger.Error("qry", qry, "error", err)
+			logger.Error("query", "qry", qry, "error", err)
 			return fmt.Errorf("%s: %w", qry, err)
 		}
 		defer rows.Close()
@@ -497,9 +787,10 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 		for rows.Next() {
 			var row dbRow
 			if err = rows.Scan(&row.OID, &row.SubID, &row.Seq, &row.Package, &row.Object,
-				&row.Level, &row.Argument, &row.InOut,
+				&row.Level, &row.Position, &row.Argument, &row.InOut,
 				&row.Data, &row.Prec, &row.Scale, &row.Charset, &row.IndexBy,
-				&row.PLS, &row.Length, &row.Owner, &row.Name, &row.Subname, &row.Link,
+				&row.PLS, &row.Length, &row.Owner, &row.Name, &row.Subname, &row.Link, &row.Defaulted, &row.CharUsed,
+				&row.Schema,
 			); err != nil {
 				return fmt.Errorf("reading row=%v: %w", rows, err)
 			}
@@ -545,41 +836,7 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 	var cwMu sync.Mutex
 	var cw *csv.Writer
 	if dumpFn != "" {
-		var lastOk bool
-		qry := argumentsQry
-		qry = qry[:strings.Index(qry, "FROM "+tbl)] //nolint:gas
-		qry = strings.TrimPrefix(qry[strings.LastIndex(qry, "SELECT ")+7:], "DISTINCT ")
-		colNames := strings.Split(
-			strings.Map(
-				func(r rune) rune {
-					if 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' {
-						lastOk = true
-						return r
-					}
-					if 'a' <= r && r <= 'z' {
-						lastOk = true
-						return unicode.ToUpper(r)
-					}
-					if r == ',' {
-						return r
-					}
-					if lastOk {
-						lastOk = false
-						return ' '
-					}
-					return -1
-				},
-				qry,
-			),
-			",",
-		)
-		for i, nm := range colNames {
-			nm = strings.TrimSpace(nm)
-			colNames[i] = nm
-			if j := strings.LastIndexByte(nm, ' '); j >= 0 {
-				colNames[i] = nm[j+1:]
-			}
-		}
+		colNames := argumentsColumns(tbl)
 		var fh *os.File
 		if fh, err = os.Create(dumpFn); err != nil {
 			logger.Error("create", "dump", dumpFn, "error", err)
@@ -647,21 +904,27 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 				cwMu.Lock()
 				err := cw.Write([]string{
 					strconv.Itoa(row.OID), N(row.SubID), strconv.Itoa(row.Seq), row.Package.String, row.Object.String,
-					strconv.Itoa(row.Level), row.Argument, ua.InOut,
+					strconv.Itoa(row.Level), N(row.Position), row.Argument, ua.InOut,
 					ua.DataType, N(row.Prec), N(row.Scale), row.Charset, row.IndexBy,
 					row.PLS, N(row.Length),
-					row.Owner, row.Name, row.Subname, row.Link,
+					row.Owner, row.Name, row.Subname, row.Link, row.Defaulted.String, row.CharUsed.String,
+					row.Schema.String,
 				})
 				cwMu.Unlock()
 				if err != nil {
 					return fmt.Errorf("write csv: %w", err)
 				}
 			}
-			if !row.Package.Valid {
-				continue
-			}
 			ua.PackageName = row.Package.String
-			if ua.PackageName != prevPackage {
+			if !row.Package.Valid {
+				// standalone procedure or function: no package source to read annotations and docs from
+				if row.Object.String != prevPackage {
+					if pkgTime, err = getObjTime(row.Object.String); err != nil {
+						return err
+					}
+					prevPackage = row.Object.String
+				}
+			} else if ua.PackageName != prevPackage {
 				if pkgTime, err = getObjTime(ua.PackageName); err != nil {
 					return err
 				}
@@ -679,25 +942,14 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 					replMu.Lock()
 					for _, b := range rAnnotation.FindAll(buf.Bytes(), -1) {
 						b = bytes.TrimSpace(bytes.TrimPrefix(b, []byte("--oracall:")))
-						a := oracall.Annotation{Package: ua.PackageName}
-						if i := bytes.IndexByte(b, ' '); i < 0 {
+						i := bytes.IndexByte(b, ' ')
+						if i < 0 {
 							continue
-						} else {
-							a.Type, b = string(b[:i]), b[i+1:]
 						}
-						if i := bytes.Index(b, []byte("=>")); i < 0 {
-							if i = bytes.IndexByte(b, '='); i < 0 {
-								a.Name = string(bytes.TrimSpace(b))
-							} else {
-								a.Name = string(bytes.TrimSpace(b[:i]))
-								size, err := strconv.Atoi(string(bytes.TrimSpace(b[i+1:])))
-								if err != nil {
-									return err
-								}
-								a.Size = size
-							}
-						} else {
-							a.Name, a.Other = string(bytes.TrimSpace(b[:i])), string(bytes.TrimSpace(b[i+2:]))
+						// the names in the package source are relative to the package
+						a, err := oracall.ParseAnnotation(string(b[:i]) + " " + ua.PackageName + "." + string(bytes.TrimSpace(b[i+1:])))
+						if err != nil {
+							return err
 						}
 						annotations = append(annotations, a)
 					}
@@ -724,6 +976,7 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 				})
 			}
 			ua.LastDDL = pkgTime
+			ua.Owner = row.Schema.String
 			if row.Object.Valid {
 				ua.ObjectName = row.Object.String
 			}
@@ -757,6 +1010,11 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 			}
 			ua.DataLevel = uint8(row.Level)
 			ua.Position = uint(row.Seq)
+			ua.Defaulted = row.Defaulted.String == "Y"
+			ua.CharUsed = row.CharUsed.String
+			if row.Position.Valid {
+				ua.ArgumentPosition = sql.NullInt32{Int32: int32(row.Position.Int64), Valid: true}
+			}
 			if row.Prec.Valid {
 				ua.DataPrecision = uint8(row.Prec.Int64)
 			}
@@ -771,11 +1029,14 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 		return nil
 	})
 	filteredArgs := make(chan []oracall.UserArgument, 16)
-	grp.Go(func() error { oracall.FilterAndGroup(filteredArgs, userArgs, filter); return nil })
-	functions = oracall.ParseArguments(filteredArgs, filter)
+	var stats oracall.ParseStats
+	grp.Go(func() error { oracall.FilterAndGroup(filteredArgs, userArgs, filter, &stats); return nil })
+	functions = oracall.ParseArguments(filteredArgs, filter, &stats)
 	if grpErr := grp.Wait(); grpErr != nil {
 		logger.Error("ParseArguments", "error", grpErr)
 	}
+	logger.Info("parsed", "rows", stats.Rows, "functions", stats.Functions, "hidden", stats.Hidden,
+		"filtered", stats.Filtered, "missingTableOf", stats.MissingTableOf, "cursorInput", stats.CursorInput, "tooDeep", stats.TooDeep)
 	docNames := make([]string, 0, len(docs))
 	for k := range docs {
 		docNames = append(docNames, k)
@@ -797,6 +1058,88 @@ func parseDB(ctx context.Context, cx *sql.DB, pattern, dumpFn string, filter fun
 	return functions, annotations, nil
 }
 
+// argumentsTables returns the views of the arguments and the objects matching the pattern:
+// the all_ ones for the built-in packages, the user_ ones otherwise.
+func argumentsTables(pattern string) (tbl, objTbl string) {
+	if strings.HasPrefix(pattern, "DBMS_") || strings.HasPrefix(pattern, "UTL_") {
+		return "all_arguments", "all_objects"
+	}
+	return "user_arguments", "user_objects"
+}
+
+// argumentsQuery returns the query of the arguments of the functions from tbl (user_arguments or all_arguments).
+func argumentsQuery(tbl string) string {
+	// user_arguments has no owner column
+	owner, aOwner := "USER", "USER"
+	if tbl != "user_arguments" {
+		owner, aOwner = "owner", "A.owner"
+	}
+	return `` + //nolint:gas
+		`SELECT A.*
+      FROM
+    (SELECT DISTINCT object_id object_id, subprogram_id, sequence*100 seq,
+           package_name, object_name,
+           data_level, position, argument_name, in_out,
+           data_type, data_precision, data_scale, character_set_name, NULL AS index_by,
+           pls_type, char_length, type_owner, type_name, type_subname, type_link, defaulted, char_used,
+           ` + owner + ` AS owner
+      FROM ` + tbl + `
+      WHERE data_type <> 'OBJECT' AND package_name||'.'||object_name LIKE UPPER(:1)
+     UNION ALL
+     SELECT DISTINCT object_id object_id, subprogram_id, A.sequence*100 + B.attr_no,
+            package_name, object_name,
+            A.data_level, A.position, B.attr_name, A.in_out,
+            B.ATTR_TYPE_NAME, B.PRECISION, B.scale, B.character_set_name, NULL AS index_by,
+            NVL2(B.ATTR_TYPE_OWNER, B.attr_type_owner||'.', '')||B.attr_type_name, B.length,
+			NULL, NULL, NULL, NULL, A.defaulted, B.char_used, ` + aOwner + `
+       FROM all_type_attrs B, ` + tbl + ` A
+       WHERE B.owner = A.type_owner AND B.type_name = A.type_name AND
+             A.data_type = 'OBJECT' AND
+             A.package_name||'.'||A.object_name LIKE UPPER(:2)
+     ) A
+      ORDER BY 1, 2, 3`
+}
+
+// argumentsColumns returns the (uppercase) column names of the argumentsQuery(tbl), for the header of the dump csv.
+func argumentsColumns(tbl string) []string {
+	var lastOk bool
+	qry := argumentsQuery(tbl)
+	qry = qry[:strings.Index(qry, "FROM "+tbl)] //nolint:gas
+	qry = strings.TrimPrefix(qry[strings.LastIndex(qry, "SELECT ")+7:], "DISTINCT ")
+	colNames := strings.Split(
+		strings.Map(
+			func(r rune) rune {
+				if 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' {
+					lastOk = true
+					return r
+				}
+				if 'a' <= r && r <= 'z' {
+					lastOk = true
+					return unicode.ToUpper(r)
+				}
+				if r == ',' {
+					return r
+				}
+				if lastOk {
+					lastOk = false
+					return ' '
+				}
+				return -1
+			},
+			qry,
+		),
+		",",
+	)
+	for i, nm := range colNames {
+		nm = strings.TrimSpace(nm)
+		colNames[i] = nm
+		if j := strings.LastIndexByte(nm, ' '); j >= 0 {
+			colNames[i] = nm[j+1:]
+		}
+	}
+	return colNames
+}
+
 var bufPool = sync.Pool{New: func() interface{} { return bytes.NewBuffer(make([]byte, 0, 1024)) }}
 
 func getSource(ctx context.Context, w io.Writer, cx *sql.DB, packageName string) error {
@@ -840,7 +1183,7 @@ func parsePkgFlag(s string) (string, string) {
 }
 
 var rReplace = regexp.MustCompile(`\s*=>\s*`)
-var rAnnotation = regexp.MustCompile(`--oracall:(?:(replace(_json)?|rename|tag)\s+[a-zA-Z0-9_#]+\s*=>\s*[a-zA-Z0-9_#]+|(handle|private)\s+[a-zA-Z0-9_#]+|max-table-size\s+[a-zA-Z0-9_$]+\s*=\s*[0-9]+)`)
+var rAnnotation = regexp.MustCompile(`--oracall:(?:(replace(_json)?|tag|group|xml-fetch)\s+[a-zA-Z0-9_#]+\s*=>\s*[a-zA-Z0-9_#]+|rename\s+[a-zA-Z0-9_#]+(\.[a-zA-Z0-9_#]+)?\s*=>\s*[a-zA-Z0-9_#]+|cursor\s+[a-zA-Z0-9_#]+\.[a-zA-Z0-9_#]+\s*=>[^\n]+|cursor-row\s+[a-zA-Z0-9_#]+(\.[a-zA-Z0-9_#]+)?\s*=>\s*[a-zA-Z0-9_#]+|(requires|excludes)\s+[a-zA-Z0-9_#]+\s*=>\s*[a-zA-Z0-9_#]+(\s*,\s*[a-zA-Z0-9_#]+)+|sqlcode\s+[a-zA-Z0-9_#]+\s*=>\s*[a-zA-Z0-9_#]+(\s*,\s*[a-zA-Z0-9_#]+)?|json-name\s+[a-zA-Z0-9_#]+\.[a-zA-Z0-9_#]+\s*=>\s*[a-zA-Z0-9_$-]+|example\s+[a-zA-Z0-9_#]+\.[a-zA-Z0-9_#]+\s*=>[^\n]+|empty-string\s+[a-zA-Z0-9_#]+\.[a-zA-Z0-9_#]+\s*=>\s*(reject|null|'[^'\n]+')|paginate\s+[a-zA-Z0-9_#]+(\s*=>\s*[0-9a-z.]+)?|(handle|private|idempotent)\s+[a-zA-Z0-9_#]+|deprecated\s+[a-zA-Z0-9_#]+(\.[a-zA-Z0-9_#]+)?|(max-table-size|fetch-size)\s+[a-zA-Z0-9_$]+\s*=\s*[0-9]+)`)
 
 func resolveType(ctx context.Context, collStmt, attrStmt *sql.Stmt, typ, owner, pkg, sub string) ([]dbType, error) {
 	plus := make([]dbType, 0, 4)
@@ -997,4 +1340,33 @@ func expandArgs(ctx context.Context, plus []dbType, resolveTypeShort func(ctx co
 	return plus, nil
 }
 
+// readSplitSections adds the sections of the <package>_oracall.go files written by -split-go
+// (or the <package>_<function>_oracall.go files written by -split-go-functions)
+// in dir to oracall.PreviousSections.
+func readSplitSections(dir string) error {
+	fns, err := filepath.Glob(filepath.Join(dir, "*_oracall.go"))
+	if err != nil {
+		return err
+	}
+	for _, fn := range fns {
+		fh, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		sections, err := oracall.ReadSections(fh)
+		fh.Close()
+		if err != nil {
+			return fmt.Errorf("read sections of %s: %w", fn, err)
+		}
+		if oracall.PreviousSections == nil {
+			oracall.PreviousSections = sections
+			continue
+		}
+		for k, v := range sections {
+			oracall.PreviousSections[k] = v
+		}
+	}
+	return nil
+}
+
 // vim: set fileencoding=utf-8 noet:
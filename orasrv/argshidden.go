@@ -0,0 +1,62 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"reflect"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// WithArgsHiddenField sets the name of the request field the unary interceptor of GRPCServer fills
+// with the JSON of the request - by default the field of the hidden p_args# argument
+// (PArgsHidden, see oracall.HiddenPrefix and oracall.HiddenSuffix).
+//
+// The empty name disables it, skipping the reflection on each call,
+// for the services whose messages have no such field.
+func WithArgsHiddenField(name string) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) { so.argsHiddenField = name }}
+}
+
+// argsHidden fills the string field named name of the requests with their JSON,
+// caching the index of the field per request type.
+type argsHidden struct {
+	name  string
+	index sync.Map // reflect.Type -> int, -1 if the type has no such field
+}
+
+func newArgsHidden(name string) *argsHidden {
+	if name == "" {
+		return nil
+	}
+	return &argsHidden{name: name}
+}
+
+// set sets the field of req (a pointer to a struct) to reqJSON, if it has such a field.
+func (ah *argsHidden) set(req interface{}, reqJSON string) {
+	if ah == nil {
+		return
+	}
+	rv := reflect.ValueOf(req)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return
+	}
+	rv = rv.Elem()
+	i, ok := ah.index.Load(rv.Type())
+	if !ok {
+		i = -1
+		if rv.Kind() == reflect.Struct {
+			if f, ok := rv.Type().FieldByName(ah.name); ok && len(f.Index) == 1 &&
+				f.IsExported() && f.Type.Kind() == reflect.String {
+				i = f.Index[0]
+			}
+		}
+		ah.index.Store(rv.Type(), i)
+	}
+	if i := i.(int); i >= 0 {
+		rv.Field(i).SetString(reqJSON)
+	}
+}
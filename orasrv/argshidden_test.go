@@ -0,0 +1,69 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestArgsHidden(t *testing.T) {
+	type withField struct {
+		PId         int32
+		PArgsHidden string
+	}
+	type withArgs struct {
+		Args string
+	}
+	type withoutField struct {
+		PId int32
+	}
+	type notString struct {
+		PArgsHidden int
+	}
+	const reqJSON = `{"p_id":1}`
+
+	so, _ := splitOptions(nil)
+	ah := newArgsHidden(so.argsHiddenField)
+	req := &withField{PId: 1}
+	ah.set(req, reqJSON)
+	if req.PArgsHidden != reqJSON {
+		t.Errorf("by default, got %q, wanted %q", req.PArgsHidden, reqJSON)
+	}
+	// no panic for the requests without such (string) field
+	for _, req := range []interface{}{&withoutField{PId: 1}, &notString{}, withField{}, (*withField)(nil), nil} {
+		ah.set(req, reqJSON)
+	}
+	if n := testing.AllocsPerRun(100, func() { ah.set(&withoutField{}, reqJSON) }); n > 1 {
+		t.Errorf("got %f allocations per call for a cached type", n)
+	}
+
+	so, _ = splitOptions([]grpc.ServerOption{WithArgsHiddenField("Args")})
+	ah = newArgsHidden(so.argsHiddenField)
+	req = &withField{PId: 1}
+	ah.set(req, reqJSON)
+	if req.PArgsHidden != "" {
+		t.Errorf("renamed, but PArgsHidden is set to %q", req.PArgsHidden)
+	}
+	args := &withArgs{}
+	ah.set(args, reqJSON)
+	if args.Args != reqJSON {
+		t.Errorf("renamed, got %q, wanted %q", args.Args, reqJSON)
+	}
+
+	so, _ = splitOptions([]grpc.ServerOption{WithArgsHiddenField("")})
+	if ah = newArgsHidden(so.argsHiddenField); ah != nil {
+		t.Fatalf("disabled, got %+v", ah)
+	}
+	req = &withField{PId: 1}
+	ah.set(req, reqJSON)
+	if req.PArgsHidden != "" {
+		t.Errorf("disabled, but PArgsHidden is set to %q", req.PArgsHidden)
+	}
+	if n := testing.AllocsPerRun(100, func() { ah.set(req, reqJSON) }); n != 0 {
+		t.Errorf("disabled, got %f allocations per call", n)
+	}
+}
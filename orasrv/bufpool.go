@@ -0,0 +1,59 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"bytes"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// DefaultBufferSize is the initial size of the buffers used for logging the requests and responses.
+	DefaultBufferSize = 4096
+	// DefaultMaxBufferSize is the size above which a buffer is not returned to the pool.
+	DefaultMaxBufferSize = 1 << 20
+)
+
+// WithBufferPool sets the initial size of the pooled buffers used for logging the requests and responses,
+// and the size above which a buffer is dropped instead of returning it to the pool.
+//
+// A non-positive maxSize means no limit, which retains the buffers of the largest requests indefinitely.
+func WithBufferPool(size, maxSize int) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) {
+		so.bufferSize, so.maxBufferSize = size, maxSize
+	}}
+}
+
+// bufferPool is a pool of *bytes.Buffers, which drops the buffers grown over maxSize.
+type bufferPool struct {
+	pool    sync.Pool
+	maxSize int
+}
+
+func newBufferPool(size, maxSize int) *bufferPool {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &bufferPool{
+		pool:    sync.Pool{New: func() interface{} { return bytes.NewBuffer(make([]byte, 0, size)) }},
+		maxSize: maxSize,
+	}
+}
+
+func (p *bufferPool) Get() *bytes.Buffer {
+	buf := p.pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func (p *bufferPool) Put(buf *bytes.Buffer) {
+	if buf == nil || p.maxSize > 0 && buf.Cap() > p.maxSize {
+		return
+	}
+	buf.Reset()
+	p.pool.Put(buf)
+}
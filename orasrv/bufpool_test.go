@@ -0,0 +1,57 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"bytes"
+	"testing"
+)
+
+// BenchmarkBufferPool simulates bursty traffic: mostly small requests, with an occasional huge one.
+// The avg-cap-B metric is the average capacity of the buffers handed out for the small requests:
+// without a cap, the huge buffers stay in the pool, and are used (retained) for the small requests, too.
+func BenchmarkBufferPool(b *testing.B) {
+	small, large := bytes.Repeat([]byte("x"), 1<<10), bytes.Repeat([]byte("X"), 4<<20)
+	for _, bm := range []struct {
+		Name    string
+		MaxSize int
+	}{{"uncapped", 0}, {"capped", DefaultMaxBufferSize}} {
+		b.Run(bm.Name, func(b *testing.B) {
+			pool := newBufferPool(DefaultBufferSize, bm.MaxSize)
+			b.ReportAllocs()
+			var smallCaps, smallN int
+			for i := 0; i < b.N; i++ {
+				buf := pool.Get()
+				if i%100 == 0 {
+					buf.Write(large)
+				} else {
+					smallCaps += buf.Cap()
+					smallN++
+					buf.Write(small)
+				}
+				pool.Put(buf)
+			}
+			if smallN != 0 {
+				b.ReportMetric(float64(smallCaps)/float64(smallN), "avg-cap-B")
+			}
+		})
+	}
+}
+
+func TestBufferPoolCap(t *testing.T) {
+	pool := newBufferPool(16, 1024)
+	buf := pool.Get()
+	if buf.Cap() < 16 {
+		t.Errorf("got cap %d, wanted at least 16", buf.Cap())
+	}
+	buf.Write(make([]byte, 4096))
+	pool.Put(buf)
+	// a dropped buffer cannot come back
+	for i := 0; i < 10; i++ {
+		if got := pool.Get(); got == buf {
+			t.Fatal("got back the oversized buffer")
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Methods returns the full names ("pkg.Service/Rpc") of the rpcs of the registered proto files
+// (link the generated pb package to register its services), sorted.
+func Methods() []string {
+	var names []string
+	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				names = append(names, methodName(methods.Get(j)))
+			}
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// CallJSON calls the rpc method with the JSON request, and writes the JSON response to w -
+// one response per line for a streaming rpc.
+//
+// The method is looked up in the registered proto files (see Methods), by its full name
+// ("pkg.Service/Rpc", "/pkg.Service/Rpc", "pkg.Service.Rpc") or by its rpc name alone, if that is unique.
+func CallJSON(ctx context.Context, cc grpc.ClientConnInterface, method string, request []byte, w io.Writer) error {
+	md, err := findMethod(method)
+	if err != nil {
+		return err
+	}
+	if md.IsStreamingClient() {
+		return status.Errorf(codes.Unimplemented, "%s: client streaming is not supported", methodName(md))
+	}
+	in := dynamicpb.NewMessage(md.Input())
+	if len(strings.TrimSpace(string(request))) != 0 {
+		if err = protojson.Unmarshal(request, in); err != nil {
+			return status.Errorf(codes.InvalidArgument, "parse request of %s: %v", methodName(md), err)
+		}
+	}
+	fullMethod := "/" + methodName(md)
+	write := func(out *dynamicpb.Message) error {
+		b, err := protojson.Marshal(out)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+	if !md.IsStreamingServer() {
+		out := dynamicpb.NewMessage(md.Output())
+		if err = cc.Invoke(ctx, fullMethod, in, out); err != nil {
+			return err
+		}
+		return write(out)
+	}
+
+	stream, err := cc.NewStream(ctx, &grpc.StreamDesc{StreamName: string(md.Name()), ServerStreams: true}, fullMethod)
+	if err != nil {
+		return err
+	}
+	if err = stream.SendMsg(in); err != nil {
+		return err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		out := dynamicpb.NewMessage(md.Output())
+		if err = stream.RecvMsg(out); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err = write(out); err != nil {
+			return err
+		}
+	}
+}
+
+// findMethod returns the registered method named method (see CallJSON).
+func findMethod(method string) (protoreflect.MethodDescriptor, error) {
+	method = strings.TrimPrefix(method, "/")
+	if svc, rpc, ok := strings.Cut(method, "/"); ok {
+		method = svc + "." + rpc
+	}
+	if strings.IndexByte(method, '.') >= 0 {
+		if d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(method)); err == nil {
+			if md, ok := d.(protoreflect.MethodDescriptor); ok {
+				return md, nil
+			}
+		}
+		return nil, status.Errorf(codes.NotFound, "unknown method %q", method)
+	}
+	var found []protoreflect.MethodDescriptor
+	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			if md := services.Get(i).Methods().ByName(protoreflect.Name(method)); md != nil {
+				found = append(found, md)
+			}
+		}
+		return true
+	})
+	switch len(found) {
+	case 0:
+		return nil, status.Errorf(codes.NotFound, "unknown method %q", method)
+	case 1:
+		return found[0], nil
+	}
+	names := make([]string, len(found))
+	for i, md := range found {
+		names[i] = methodName(md)
+	}
+	sort.Strings(names)
+	return nil, status.Errorf(codes.InvalidArgument, "ambiguous method %q: one of %s", method, strings.Join(names, ", "))
+}
+
+// methodName returns the "pkg.Service/Rpc" name of the method.
+func methodName(md protoreflect.MethodDescriptor) string {
+	return fmt.Sprintf("%s/%s", md.Parent().FullName(), md.Name())
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestCallJSON(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("db", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	var found bool
+	for _, m := range Methods() {
+		found = found || m == "grpc.health.v1.Health/Check"
+	}
+	if !found {
+		t.Errorf("Check is not registered: %v", Methods())
+	}
+
+	for _, method := range []string{"grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Check", "grpc.health.v1.Health.Check", "Check"} {
+		var buf strings.Builder
+		if err = CallJSON(ctx, cc, method, []byte(`{"service":"db"}`), &buf); err != nil {
+			t.Fatalf("%s: %+v", method, err)
+		}
+		if got, want := strings.Join(strings.Fields(buf.String()), ""), `{"status":"NOT_SERVING"}`; got != want {
+			t.Errorf("%s: got %q, wanted %q", method, got, want)
+		}
+	}
+
+	var buf strings.Builder
+	if err = CallJSON(ctx, cc, "Check", []byte(`{"nope":1}`), &buf); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("bad request: got %v, wanted InvalidArgument", err)
+	}
+	if err = CallJSON(ctx, cc, "NoSuchRpc", nil, &buf); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown method: got %v, wanted NotFound", err)
+	}
+
+	// the stream of Watch sends the current status first
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	defer watchCancel()
+	w := writerFunc(func(p []byte) (int, error) {
+		buf.Write(p)
+		watchCancel()
+		return len(p), nil
+	})
+	buf.Reset()
+	err = CallJSON(watchCtx, cc, "grpc.health.v1.Health/Watch", []byte(`{"service":"db"}`), w)
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("watch: got %v, wanted Canceled", err)
+	}
+	if got := strings.Join(strings.Fields(buf.String()), ""); got != `{"status":"NOT_SERVING"}` {
+		t.Errorf("watch: got %q", got)
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,84 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// WithErrorArgs makes the unary interceptor append the summary of the request
+// (the JSON it records in PArgsHidden) to the error of a failed call,
+// both in the log and in the returned status' message.
+//
+// The values of the fields named in redact (case-insensitively, at any depth)
+// are replaced by "***" in the summary.
+func WithErrorArgs(redact ...string) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) {
+		so.errorArgs = true
+		if so.redact == nil {
+			so.redact = make(map[string]struct{}, len(redact))
+		}
+		for _, k := range redact {
+			so.redact[strings.ToLower(k)] = struct{}{}
+		}
+	}}
+}
+
+// argsError is an error with the summary of the request it was returned for.
+type argsError struct {
+	err  error
+	args string
+}
+
+func (ae *argsError) Error() string { return ae.err.Error() + " (args: " + ae.args + ")" }
+func (ae *argsError) Unwrap() error { return ae.err }
+
+// withArgs returns err with the redacted request summary appended.
+func (so serverOptions) withArgs(err error, reqJSON string) error {
+	if err == nil || !so.errorArgs {
+		return err
+	}
+	return &argsError{err: err, args: redactJSON(reqJSON, so.redact)}
+}
+
+// redactJSON replaces the values of the redact fields of the JSON object.
+//
+// Unparseable JSON is left out entirely when there is anything to redact.
+func redactJSON(s string, redact map[string]struct{}) string {
+	s = strings.TrimSpace(s)
+	if len(redact) == 0 {
+		return s
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return "?"
+	}
+	b, err := json.Marshal(redactValue(v, redact))
+	if err != nil {
+		return "?"
+	}
+	return string(b)
+}
+
+func redactValue(v interface{}, redact map[string]struct{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			if _, ok := redact[strings.ToLower(k)]; ok {
+				x[k] = "***"
+			} else {
+				x[k] = redactValue(e, redact)
+			}
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = redactValue(e, redact)
+		}
+	}
+	return v
+}
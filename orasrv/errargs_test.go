@@ -0,0 +1,51 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithErrorArgs(t *testing.T) {
+	const reqJSON = `{"p_id":1,"p_password":"titok","p_rec":{"Secret":"x","name":"y"}}` + "\n"
+	errBase := fmt.Errorf("bad: %w", oracall.ErrInvalidArgument)
+
+	so, _ := splitOptions(nil)
+	if err := so.withArgs(errBase, reqJSON); err != errBase {
+		t.Errorf("without WithErrorArgs, got %v", err)
+	}
+
+	so, _ = splitOptions([]grpc.ServerOption{WithErrorArgs("P_PASSWORD", "secret")})
+	if err := so.withArgs(nil, reqJSON); err != nil {
+		t.Errorf("nil error became %v", err)
+	}
+	err := so.withArgs(errBase, reqJSON)
+	if !errors.Is(err, oracall.ErrInvalidArgument) {
+		t.Errorf("%v does not wrap ErrInvalidArgument", err)
+	}
+	s := err.Error()
+	t.Log(s)
+	for _, want := range []string{`"p_id":1`, `"p_password":"***"`, `"Secret":"***"`, `"name":"y"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("%q not found in %q", want, s)
+		}
+	}
+	for _, leak := range []string{"titok", `"x"`} {
+		if strings.Contains(s, leak) {
+			t.Errorf("%q leaked in %q", leak, s)
+		}
+	}
+	if st := status.Convert(StatusError(err)); st.Code() != codes.InvalidArgument || !strings.Contains(st.Message(), `"p_id":1`) {
+		t.Errorf("got status %v", st)
+	}
+}
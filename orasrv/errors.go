@@ -0,0 +1,106 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	oraCodesMu sync.RWMutex
+	oraCodes   map[int]codes.Code
+)
+
+// RegisterORACode registers the gRPC code StatusError returns for the ORA-oraCode errors,
+// such as RegisterORACode(20404, codes.NotFound) for raise_application_error(-20404, ...).
+func RegisterORACode(oraCode int, code codes.Code) {
+	if oraCode < 0 {
+		oraCode = -oraCode
+	}
+	oraCodesMu.Lock()
+	if oraCodes == nil {
+		oraCodes = make(map[int]codes.Code)
+	}
+	oraCodes[oraCode] = code
+	oraCodesMu.Unlock()
+}
+
+// oraCodeOf returns the registered gRPC code for the ORA- error in the chain.
+func oraCodeOf(err error) (codes.Code, bool) {
+	// *godror.OraErr
+	var oe interface {
+		Code() int
+		Error() string
+	}
+	if !errors.As(err, &oe) {
+		return 0, false
+	}
+	oraCodesMu.RLock()
+	code, ok := oraCodes[oe.Code()]
+	oraCodesMu.RUnlock()
+	return code, ok
+}
+
+// HTTPStatusFromCode returns the HTTP status for the gRPC code,
+// with the same mapping as the grpc-gateway.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteHTTPError writes the error (converted by StatusError) as a JSON body
+// of the google.rpc.Status (code, message and details), with the matching HTTP status.
+//
+// It can be used as the grpc-gateway's error handler, as
+//
+//	runtime.WithErrorHandler(func(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+//		orasrv.WriteHTTPError(w, err)
+//	})
+func WriteHTTPError(w http.ResponseWriter, err error) {
+	st, ok := status.FromError(StatusError(err))
+	if !ok {
+		st = status.New(codes.Unknown, err.Error())
+	}
+	b, mErr := protojson.Marshal(st.Proto())
+	if mErr != nil {
+		st = status.New(codes.Internal, mErr.Error())
+		b, _ = protojson.Marshal(st.Proto())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatusFromCode(st.Code()))
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type fakeOraErr int
+
+func (oe fakeOraErr) Code() int     { return int(oe) }
+func (oe fakeOraErr) Error() string { return fmt.Sprintf("ORA-%05d: fake", int(oe)) }
+
+func TestWriteHTTPError(t *testing.T) {
+	RegisterORACode(-20404, codes.NotFound)
+
+	withDetails, err := status.New(codes.FailedPrecondition, "precondition").WithDetails(wrapperspb.String("detail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tC := range []struct {
+		Err     error
+		Status  int
+		Code    codes.Code
+		Details string
+	}{
+		{Err: fmt.Errorf("bad: %w", oracall.ErrInvalidArgument), Status: http.StatusBadRequest, Code: codes.InvalidArgument},
+		{Err: fmt.Errorf("wrapped: %w", fakeOraErr(20404)), Status: http.StatusNotFound, Code: codes.NotFound},
+		{Err: fakeOraErr(1), Status: http.StatusInternalServerError, Code: codes.Unknown},
+		{Err: withDetails.Err(), Status: http.StatusBadRequest, Code: codes.FailedPrecondition, Details: `"detail"`},
+	} {
+		rec := httptest.NewRecorder()
+		WriteHTTPError(rec, tC.Err)
+		if rec.Code != tC.Status {
+			t.Errorf("%v: got status %d, wanted %d", tC.Err, rec.Code, tC.Status)
+		}
+		var body struct {
+			Code    codes.Code
+			Message string
+			Details []json.RawMessage
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%v: %q: %+v", tC.Err, rec.Body.String(), err)
+		}
+		if body.Code != tC.Code {
+			t.Errorf("%v: got code %v, wanted %v", tC.Err, body.Code, tC.Code)
+		}
+		if body.Message == "" {
+			t.Errorf("%v: empty message", tC.Err)
+		}
+		if tC.Details != "" && (len(body.Details) != 1 || !strings.Contains(string(body.Details[0]), tC.Details)) {
+			t.Errorf("%v: got details %q, wanted %s", tC.Err, body.Details, tC.Details)
+		}
+	}
+}
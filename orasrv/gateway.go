@@ -0,0 +1,49 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// GatewayHandler returns an http.Handler serving the gRPC calls (HTTP/2 requests of application/grpc content)
+// with grpcServer, and the rest with gateway - such as the *runtime.ServeMux of the grpc-gateway,
+// with the generated REST mapping registered onto it (a 404 handler if nil).
+//
+// It accepts HTTP/2 without TLS (h2c), as the gRPC clients connect with insecure credentials,
+// so gRPC and REST can be served on the same port, with one http.Server.
+func GatewayHandler(grpcServer *grpc.Server, gateway http.Handler) http.Handler {
+	if gateway == nil {
+		gateway = http.NotFoundHandler()
+	}
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		gateway.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
+
+// GatewayServer returns an *http.Server listening on addr, serving GatewayHandler(grpcServer, gateway).
+//
+// Register the generated services onto grpcServer, and their REST mapping onto gateway before serving.
+//
+// Note that grpc.Server.ServeHTTP does not support all the features of grpc.Server.Serve
+// (the grpc.ServerOptions configuring the transport are ignored); with a cmux,
+// the gRPC listener can be served with grpcServer.Serve directly, and the rest with gateway.
+func GatewayServer(addr string, grpcServer *grpc.Server, gateway http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           GatewayHandler(grpcServer, gateway),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
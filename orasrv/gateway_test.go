@@ -0,0 +1,92 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGatewayServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var authPaths []string
+	checkAuth := func(ctx context.Context, path string) error {
+		mu.Lock()
+		authPaths = append(authPaths, path)
+		mu.Unlock()
+		return nil
+	}
+	grpcServer := GRPCServer(ctx, NewT(t), false, checkAuth)
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	defer grpcServer.Stop()
+
+	gateway := http.NewServeMux()
+	gateway.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "gateway "+r.Method)
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := GatewayServer(lis.Addr().String(), grpcServer, gateway)
+	go srv.Serve(lis)
+	defer srv.Close()
+
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.GetStatus(), healthpb.HealthCheckResponse_SERVING; got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+	mu.Lock()
+	if len(authPaths) != 1 || authPaths[0] != "/grpc.health.v1.Health/Check" {
+		t.Errorf("the call did not go through the interceptors: %q", authPaths)
+	}
+	mu.Unlock()
+
+	for path, want := range map[string]struct {
+		Status int
+		Body   string
+	}{
+		"/v1/health": {http.StatusOK, "gateway GET"},
+		"/v1/nope":   {http.StatusNotFound, ""},
+	} {
+		req, err := http.NewRequestWithContext(ctx, "GET", "http://"+lis.Addr().String()+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hResp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(hResp.Body)
+		hResp.Body.Close()
+		if hResp.StatusCode != want.Status {
+			t.Errorf("%s: got status %d, wanted %d", path, hResp.StatusCode, want.Status)
+		} else if want.Body != "" && string(b) != want.Body {
+			t.Errorf("%s: got %q, wanted %q", path, b, want.Body)
+		}
+	}
+}
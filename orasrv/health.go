@@ -0,0 +1,91 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// DefaultHealthQuery is the trivial statement DBHealth executes.
+	DefaultHealthQuery = "SELECT 1 FROM DUAL"
+	// DefaultHealthTimeout is the timeout of one DBHealth check.
+	DefaultHealthTimeout = 5 * time.Second
+)
+
+// DBHealth is a deep health check: it exercises the database connection,
+// distinguishing "server up" from "database reachable".
+type DBHealth struct {
+	DB *sql.DB
+	// Query is a trivial SELECT, or a no-op PL/SQL call (such as "BEGIN NULL; END;").
+	// DefaultHealthQuery if empty.
+	Query string
+	// Timeout of one check, DefaultHealthTimeout if zero.
+	Timeout time.Duration
+}
+
+// Check executes the query, and returns its latency.
+func (h DBHealth) Check(ctx context.Context) (time.Duration, error) {
+	qry, timeout := h.Query, h.Timeout
+	if qry == "" {
+		qry = DefaultHealthQuery
+	}
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	var err error
+	if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(qry)), "SELECT") {
+		var dummy interface{}
+		err = h.DB.QueryRowContext(ctx, qry).Scan(&dummy)
+	} else {
+		_, err = h.DB.ExecContext(ctx, qry)
+	}
+	return time.Since(start), err
+}
+
+// Watch checks the database right away, then at every interval till ctx is done,
+// and sets the serving status of service ("" for the whole server) in hs accordingly.
+func (h DBHealth) Watch(ctx context.Context, hs *health.Server, service string, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last healthpb.HealthCheckResponse_ServingStatus
+	for {
+		st := healthpb.HealthCheckResponse_SERVING
+		dur, err := h.Check(ctx)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			st = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if st != last {
+			if err != nil {
+				logger.Error("health", "service", service, "status", st.String(), "dur", dur.String(), "error", err)
+			} else {
+				logger.Info("health", "service", service, "status", st.String(), "dur", dur.String())
+			}
+			last = st
+		} else {
+			logger.Debug("health", "service", service, "status", st.String(), "dur", dur.String())
+		}
+		hs.SetServingStatus(service, st)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
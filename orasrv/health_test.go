@@ -0,0 +1,100 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthDriver is a database/sql driver whose statements fail iff the query is "fail".
+type healthDriver struct{}
+
+func (healthDriver) Open(string) (driver.Conn, error) { return healthConn{}, nil }
+
+type healthConn struct{}
+
+func (healthConn) Prepare(query string) (driver.Stmt, error) { return healthStmt(query), nil }
+func (healthConn) Close() error                              { return nil }
+func (healthConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no tx") }
+
+type healthStmt string
+
+func (healthStmt) Close() error  { return nil }
+func (healthStmt) NumInput() int { return 0 }
+func (s healthStmt) Exec([]driver.Value) (driver.Result, error) {
+	if s == "fail" {
+		return nil, errors.New("ORA-03113: end-of-file on communication channel")
+	}
+	return driver.RowsAffected(0), nil
+}
+func (s healthStmt) Query([]driver.Value) (driver.Rows, error) {
+	if _, err := s.Exec(nil); err != nil {
+		return nil, err
+	}
+	return &healthRows{}, nil
+}
+
+type healthRows struct{ done bool }
+
+func (*healthRows) Columns() []string { return []string{"1"} }
+func (*healthRows) Close() error      { return nil }
+func (r *healthRows) Next(dest []driver.Value) error {
+	if r.done {
+		return errors.New("EOF")
+	}
+	r.done, dest[0] = true, int64(1)
+	return nil
+}
+
+func init() { sql.Register("orasrv-health", healthDriver{}) }
+
+func TestDBHealth(t *testing.T) {
+	db, err := sql.Open("orasrv-health", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	for _, qry := range []string{"", "BEGIN NULL; END;"} {
+		if _, err := (DBHealth{DB: db, Query: qry}).Check(ctx); err != nil {
+			t.Errorf("%q: %+v", qry, err)
+		}
+	}
+
+	hs := health.NewServer()
+	for qry, want := range map[string]healthpb.HealthCheckResponse_ServingStatus{
+		DefaultHealthQuery: healthpb.HealthCheckResponse_SERVING,
+		"fail":             healthpb.HealthCheckResponse_NOT_SERVING,
+	} {
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			DBHealth{DB: db, Query: qry}.Watch(ctx, hs, "db", time.Millisecond, NewT(t))
+		}()
+		var resp *healthpb.HealthCheckResponse
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if resp, err = hs.Check(ctx, &healthpb.HealthCheckRequest{Service: "db"}); err == nil && resp.Status == want {
+				break
+			}
+		}
+		cancel()
+		<-done
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != want {
+			t.Errorf("%q: got %s, wanted %s", qry, resp.Status, want)
+		}
+	}
+}
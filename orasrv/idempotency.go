@@ -0,0 +1,71 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// IdempotencyKeyMetadata is the gRPC metadata key of the client-supplied idempotency key of a unary call.
+const IdempotencyKeyMetadata = "idempotency-key"
+
+// DefaultIdempotencyWindow is how long the responses are kept for the repeated calls, if WithIdempotencyKeys gets no window.
+const DefaultIdempotencyWindow = 10 * time.Minute
+
+// MaxIdempotencyKeyLength is the maximal length of an idempotency key, longer ones are rejected.
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyCache stores the responses of the unary calls by their idempotency keys (see WithIdempotencyKeys).
+//
+// It must be safe for concurrent use. The keys include the full method name, too.
+type IdempotencyCache interface {
+	// Get returns the response stored for the key, or false if there is none, or it has expired.
+	Get(ctx context.Context, key string) (interface{}, bool)
+	// Set stores the response for the key, for the window.
+	Set(ctx context.Context, key string, response interface{}, window time.Duration)
+}
+
+// WithIdempotencyKeys makes the unary interceptor of GRPCServer return the response stored in the cache
+// for a call repeated with the same IdempotencyKeyMetadata within the window (DefaultIdempotencyWindow if not positive),
+// without calling the handler again - so the clients can retry the calls safely.
+//
+// Only the successful responses are stored, a failed call runs again when repeated.
+// The calls without a key run as usual; the ones with several or too long keys are rejected with InvalidArgument.
+// The repeated calls are authenticated, too, but not counted by the concurrency limits.
+//
+// Concurrent calls with the same key are not deduplicated: both run, if the first has not finished yet.
+func WithIdempotencyKeys(cache IdempotencyCache, window time.Duration) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) {
+		if window <= 0 {
+			window = DefaultIdempotencyWindow
+		}
+		so.idempotencyCache, so.idempotencyWindow = cache, window
+	}}
+}
+
+// idempotencyKey returns the key of the call in the cache, or "" if it has no idempotency key
+// (or WithIdempotencyKeys is not set).
+func (so serverOptions) idempotencyKey(ctx context.Context, fullMethod string) (string, error) {
+	if so.idempotencyCache == nil {
+		return "", nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(IdempotencyKeyMetadata)
+	switch {
+	case len(values) == 0 || len(values) == 1 && values[0] == "":
+		return "", nil
+	case len(values) > 1:
+		return "", fmt.Errorf("%s: %d values: %w", IdempotencyKeyMetadata, len(values), oracall.ErrInvalidArgument)
+	case len(values[0]) > MaxIdempotencyKeyLength:
+		return "", fmt.Errorf("%s is longer than %d: %w", IdempotencyKeyMetadata, MaxIdempotencyKeyLength, oracall.ErrInvalidArgument)
+	}
+	return fullMethod + "\x00" + values[0], nil
+}
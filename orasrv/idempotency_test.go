@@ -0,0 +1,132 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// mapCache is an IdempotencyCache in a map, ignoring the window.
+type mapCache struct {
+	mu sync.Mutex
+	m  map[string]interface{}
+}
+
+func (c *mapCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.m[key]
+	return v, ok
+}
+func (c *mapCache) Set(ctx context.Context, key string, response interface{}, window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = response
+}
+
+// countingHealth is SERVING for the first call only, and fails for the "fail" service.
+type countingHealth struct {
+	healthpb.UnimplementedHealthServer
+	calls atomic.Int32
+}
+
+func (h *countingHealth) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if h.calls.Add(1) == 1 {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+	}
+	if req.GetService() == "fail" {
+		return nil, errors.New("failed")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+}
+
+func TestWithIdempotencyKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cache := &mapCache{m: make(map[string]interface{})}
+	srv := GRPCServer(ctx, NewT(t), false, func(context.Context, string) error { return nil },
+		WithIdempotencyKeys(cache, 0))
+	hs := new(countingHealth)
+	healthpb.RegisterHealthServer(srv, hs)
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	check := func(service string, keys ...string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+		ctx := ctx
+		for _, k := range keys {
+			ctx = metadata.AppendToOutgoingContext(ctx, IdempotencyKeyMetadata, k)
+		}
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		return resp.GetStatus(), err
+	}
+
+	for i := 0; i < 3; i++ {
+		st, err := check("", "key-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("%d. repeat: got %v, wanted the stored SERVING", i, st)
+		}
+	}
+	if n := hs.calls.Load(); n != 1 {
+		t.Errorf("the handler is called %d times for a repeated key, wanted once", n)
+	}
+
+	// another key, or no key calls the handler
+	for _, keys := range [][]string{{"key-2"}, nil, {""}} {
+		if st, err := check("", keys...); err != nil || st != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("keys %q: got %v, %v, wanted NOT_SERVING", keys, st, err)
+		}
+	}
+	if n := hs.calls.Load(); n != 4 {
+		t.Errorf("the handler is called %d times, wanted 4", n)
+	}
+
+	// the failures are not stored
+	for i := 0; i < 2; i++ {
+		if _, err := check("fail", "key-3"); err == nil {
+			t.Errorf("%d. call: no error", i)
+		}
+	}
+	if n := hs.calls.Load(); n != 6 {
+		t.Errorf("the handler is called %d times, wanted the failed call repeated", n)
+	}
+
+	for _, keys := range [][]string{{"a", "b"}, {strings.Repeat("k", MaxIdempotencyKeyLength+1)}} {
+		if _, err := check("", keys...); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("keys %q: got %v, wanted InvalidArgument", keys, err)
+		}
+	}
+	if n := hs.calls.Load(); n != 6 {
+		t.Errorf("the handler is called %d times for rejected keys", n)
+	}
+}
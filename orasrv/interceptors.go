@@ -0,0 +1,31 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"google.golang.org/grpc"
+)
+
+// WithUnaryInterceptors adds interceptors to the unary calls, chained after the built-in one of GRPCServer.
+//
+// The built-in interceptor runs first (outermost): it sets the timeout, the request ID and the logger
+// of the context, checks the authentication, the concurrency limits and the transaction.
+// Then the added interceptors are called in the order given, the last one calling the handler,
+// so they see the context prepared by the built-in one, and their errors are converted by StatusError.
+//
+// Calling it several times appends to the chain.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) {
+		so.unaryInterceptors = append(so.unaryInterceptors, interceptors...)
+	}}
+}
+
+// WithStreamInterceptors adds interceptors to the streaming calls, chained after the built-in one of GRPCServer,
+// in the same order as WithUnaryInterceptors.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) {
+		so.streamInterceptors = append(so.streamInterceptors, interceptors...)
+	}}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestWithInterceptors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var calls []string
+	called := func(s string) {
+		mu.Lock()
+		calls = append(calls, s)
+		mu.Unlock()
+	}
+	checkAuth := func(ctx context.Context, path string) error {
+		called("auth " + path)
+		return nil
+	}
+	srv := GRPCServer(ctx, NewT(t), false, checkAuth,
+		WithUnaryInterceptors(
+			func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if FromContext(ctx) == nil {
+					t.Error("no logger in the context of the user interceptor")
+				}
+				called("unary1 " + info.FullMethod)
+				return handler(ctx, req)
+			},
+			func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				called("unary2 " + info.FullMethod)
+				return handler(ctx, req)
+			},
+		),
+		WithStreamInterceptors(
+			func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				called("stream " + info.FullMethod)
+				return handler(srv, ss)
+			},
+		),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err = client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	const check, watch = "/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Watch"
+	mu.Lock()
+	got := append([]string(nil), calls...)
+	calls = calls[:0]
+	mu.Unlock()
+	if want := []string{"auth " + check, "unary1 " + check, "unary2 " + check}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unary: got %q, wanted %q", got, want)
+	}
+
+	wCtx, wCancel := context.WithCancel(ctx)
+	defer wCancel()
+	stream, err := client.Watch(wCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	got = append([]string(nil), calls...)
+	mu.Unlock()
+	if want := []string{"auth " + watch, "stream " + watch}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stream: got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WithConcurrencyLimits limits the number of concurrently running calls per method,
+// keyed by the full method name (as in grpc.UnaryServerInfo.FullMethod).
+//
+// Calls over the limit are rejected with codes.ResourceExhausted, not queued.
+// Methods without a (positive) limit are unlimited.
+func WithConcurrencyLimits(limits map[string]int) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) {
+		if so.concurrencyLimits == nil {
+			so.concurrencyLimits = make(map[string]int, len(limits))
+		}
+		for k, v := range limits {
+			so.concurrencyLimits[k] = v
+		}
+	}}
+}
+
+// methodLimiter holds a semaphore for each limited method.
+// It is read-only after creation, so needs no locking.
+type methodLimiter map[string]chan struct{}
+
+func newMethodLimiter(limits map[string]int) methodLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	ml := make(methodLimiter, len(limits))
+	for k, n := range limits {
+		if n > 0 {
+			ml[k] = make(chan struct{}, n)
+		}
+	}
+	return ml
+}
+
+// acquire a slot for the method, returning the release function,
+// or a ResourceExhausted error if no slot is available.
+func (ml methodLimiter) acquire(fullMethod string) (func(), error) {
+	sem := ml[fullMethod]
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	default:
+		return nil, status.Errorf(codes.ResourceExhausted, "%s: concurrency limit (%d) reached", fullMethod, cap(sem))
+	}
+}
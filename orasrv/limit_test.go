@@ -0,0 +1,59 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMethodLimiter(t *testing.T) {
+	ml := newMethodLimiter(map[string]int{"/a/b": 2, "/a/zero": 0})
+	if _, err := ml.acquire("/a/zero"); err != nil {
+		t.Errorf("zero limit: %+v", err)
+	}
+	if _, err := ml.acquire("/a/unknown"); err != nil {
+		t.Errorf("unknown: %+v", err)
+	}
+	r1, err := ml.acquire("/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := ml.acquire("/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ml.acquire("/a/b"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("wanted ResourceExhausted, got %+v", err)
+	}
+	r1()
+	r3, err := ml.acquire("/a/b")
+	if err != nil {
+		t.Errorf("after release: %+v", err)
+	}
+	r2()
+	r3()
+
+	var nilML methodLimiter
+	if _, err := nilML.acquire("/a/b"); err != nil {
+		t.Errorf("nil limiter: %+v", err)
+	}
+}
+
+func TestSplitOptions(t *testing.T) {
+	so, rest := splitOptions([]grpc.ServerOption{
+		WithConcurrencyLimits(map[string]int{"/a/b": 1}),
+		grpc.MaxRecvMsgSize(1 << 20),
+	})
+	if len(rest) != 1 {
+		t.Errorf("got %d grpc options, wanted 1", len(rest))
+	}
+	if so.concurrencyLimits["/a/b"] != 1 {
+		t.Errorf("got %v", so.concurrencyLimits)
+	}
+}
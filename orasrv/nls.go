@@ -0,0 +1,46 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NLSMetadataKey is the gRPC metadata key of the NLS settings of the call,
+// each value a "name=value" pair, such as "NLS_NUMERIC_CHARACTERS=,." or "NLS_DATE_FORMAT=YYYY-MM-DD".
+const NLSMetadataKey = "oracall-nls"
+
+// WithNLSMetadata makes the interceptors of GRPCServer pass the NLS settings of the NLSMetadataKey metadata
+// to the generated functions (see oracall.ContextWithNLS), which set them on the session for the call.
+//
+// Without it (by default) the metadata is ignored, and the sessions keep their NLS settings.
+func WithNLSMetadata() grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) { so.nlsMetadata = true }}
+}
+
+// contextWithNLSMetadata returns the context carrying the NLS settings of the NLSMetadataKey metadata
+// of the incoming call, or the context as is if the call has no such metadata.
+func contextWithNLSMetadata(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(NLSMetadataKey)
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	nls := make(oracall.NLS, len(values))
+	for _, v := range values {
+		name, value, err := oracall.ParseNLS(v)
+		if err != nil {
+			return ctx, status.Error(codes.InvalidArgument, err.Error())
+		}
+		nls[name] = value
+	}
+	return oracall.ContextWithNLS(ctx, nls), nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestContextWithNLSMetadata(t *testing.T) {
+	ctx := context.Background()
+	if gotCtx, err := contextWithNLSMetadata(ctx); err != nil {
+		t.Fatal(err)
+	} else if nls := oracall.NLSFromContext(gotCtx); nls != nil {
+		t.Errorf("got %v without metadata", nls)
+	}
+
+	callCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(
+		NLSMetadataKey, "nls_numeric_characters=,.",
+		NLSMetadataKey, "NLS_DATE_FORMAT=YYYY-MM-DD",
+	))
+	gotCtx, err := contextWithNLSMetadata(callCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := oracall.NLS{"NLS_NUMERIC_CHARACTERS": ",.", "NLS_DATE_FORMAT": "YYYY-MM-DD"}
+	if got := oracall.NLSFromContext(gotCtx); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	callCtx = metadata.NewIncomingContext(ctx, metadata.Pairs(NLSMetadataKey, "TIME_ZONE=UTC"))
+	if _, err = contextWithNLSMetadata(callCtx); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("got %v, wanted InvalidArgument", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"time"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc"
+)
+
+// serverOptions holds the orasrv-specific configuration of GRPCServer.
+type serverOptions struct {
+	concurrencyLimits         map[string]int
+	bufferSize, maxBufferSize int
+	// errorArgs and redact are set by WithErrorArgs.
+	errorArgs bool
+	redact    map[string]struct{}
+	txManager *TxManager
+	// nlsMetadata is set by WithNLSMetadata.
+	nlsMetadata bool
+	// payloadWarnSize and observePayload are set by WithPayloadSizes.
+	payloadWarnSize int
+	observePayload  func(fullMethod string, reqSize, respSize int)
+	// idempotencyCache and idempotencyWindow are set by WithIdempotencyKeys.
+	idempotencyCache  IdempotencyCache
+	idempotencyWindow time.Duration
+	// argsHiddenField is set by WithArgsHiddenField, disabled if empty.
+	argsHiddenField string
+	// slowCallThreshold is set by WithSlowCallLogging, every payload is logged if not positive.
+	slowCallThreshold time.Duration
+	// unaryInterceptors and streamInterceptors are chained after the built-in ones.
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+}
+
+// serverOption is a grpc.ServerOption which does not alter the grpc.Server,
+// but configures the interceptors of GRPCServer.
+type serverOption struct {
+	grpc.EmptyServerOption
+	apply func(*serverOptions)
+}
+
+// splitOptions separates the orasrv-specific options from the grpc ones.
+func splitOptions(options []grpc.ServerOption) (serverOptions, []grpc.ServerOption) {
+	so := serverOptions{bufferSize: DefaultBufferSize, maxBufferSize: DefaultMaxBufferSize,
+		payloadWarnSize: DefaultPayloadWarnSize, argsHiddenField: oracall.CamelCase("p_args#")}
+	grpcOptions := make([]grpc.ServerOption, 0, len(options))
+	for _, o := range options {
+		if o, ok := o.(serverOption); ok {
+			o.apply(&so)
+			continue
+		}
+		grpcOptions = append(grpcOptions, o)
+	}
+	return so, grpcOptions
+}
@@ -10,7 +10,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -18,7 +17,6 @@ import (
 	"github.com/UNO-SOFT/zlog/v2"
 	"github.com/UNO-SOFT/zlog/v2/slog"
 
-	bp "github.com/tgulacsi/go/bufpool"
 	oracall "github.com/tgulacsi/oracall/lib"
 
 	"github.com/oklog/ulid"
@@ -33,11 +31,7 @@ import (
 	godror "github.com/godror/godror"
 )
 
-var (
-	Timeout = DefaultTimeout
-
-	bufpool = bp.New(4096)
-)
+var Timeout = DefaultTimeout
 
 const (
 	DefaultTimeout = time.Hour
@@ -51,7 +45,25 @@ func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
 }
 func NewT(t *testing.T) *slog.Logger { return zlog.NewT(t).SLog() }
 
+// GRPCServer returns a new *grpc.Server with logging, authentication and timeout interceptors.
+//
+// Besides the usual grpc.ServerOptions, the options configuring these interceptors
+// (such as WithConcurrencyLimits and WithBufferPool) are accepted, too.
+// WithUnaryInterceptors and WithStreamInterceptors add interceptors, called after the built-in ones.
+// WithArgsHiddenField renames or disables filling the hidden p_args# field of the requests.
+// WithIdempotencyKeys returns the stored responses for the repeated unary calls.
+// WithSlowCallLogging logs the payloads of the slow (or failed) unary calls only.
+//
+// The server does not own a listener: Serve it on the gRPC listener of a cmux,
+// or serve it together with a grpc-gateway on one port with GatewayServer.
 func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, checkAuth func(ctx context.Context, path string) error, options ...grpc.ServerOption) *grpc.Server {
+	so, options := splitOptions(options)
+	limiter := newMethodLimiter(so.concurrencyLimits)
+	bufpool := newBufferPool(so.bufferSize, so.maxBufferSize)
+
+	// the hidden p_args# argument receives the request JSON
+	argsHidden := newArgsHidden(so.argsHiddenField)
+
 	erroredMethods := make(map[string]struct{})
 	var erroredMethodsMu sync.RWMutex
 
@@ -63,7 +75,7 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 		reqID := ContextGetReqID(ctx)
 		ctx = ContextWithReqID(ctx, reqID)
 		lgr := logger.With("reqID", reqID)
-		ctx = zlog.NewSContext(ctx, lgr)
+		ctx = WithContext(zlog.NewSContext(ctx, lgr), lgr)
 		verbose := verbose
 		var wasThere bool
 		if !verbose {
@@ -113,6 +125,24 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 				if err = checkAuth(ctx, info.FullMethod); err != nil {
 					return status.Error(codes.Unauthenticated, err.Error())
 				}
+				release, err := limiter.acquire(info.FullMethod)
+				if err != nil {
+					lgr.Warn("limit", "REQ", info.FullMethod, "error", err)
+					return err
+				}
+				defer release()
+				if so.txManager != nil {
+					var releaseTx func()
+					if ctx, releaseTx, err = so.txManager.acquire(ctx); err != nil {
+						return err
+					}
+					defer releaseTx()
+				}
+				if so.nlsMetadata {
+					if ctx, err = contextWithNLSMetadata(ctx); err != nil {
+						return err
+					}
+				}
 
 				wss := grpc_middleware.WrapServerStream(ss)
 				wss.WrappedContext = ctx
@@ -145,6 +175,34 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 				if err = checkAuth(ctx, info.FullMethod); err != nil {
 					return nil, status.Error(codes.Unauthenticated, err.Error())
 				}
+				idemKey, err := so.idempotencyKey(ctx, info.FullMethod)
+				if err != nil {
+					return nil, StatusError(err)
+				}
+				if idemKey != "" {
+					if res, ok := so.idempotencyCache.Get(ctx, idemKey); ok {
+						logger.Info("idempotent repeat", "REQ", info.FullMethod, "key", idemKey[len(info.FullMethod)+1:])
+						return res, nil
+					}
+				}
+				release, err := limiter.acquire(info.FullMethod)
+				if err != nil {
+					logger.Warn("limit", "REQ", info.FullMethod, "error", err)
+					return nil, err
+				}
+				defer release()
+				if so.txManager != nil {
+					var releaseTx func()
+					if ctx, releaseTx, err = so.txManager.acquire(ctx); err != nil {
+						return nil, err
+					}
+					defer releaseTx()
+				}
+				if so.nlsMetadata {
+					if ctx, err = contextWithNLSMetadata(ctx); err != nil {
+						return nil, err
+					}
+				}
 
 				buf := bufpool.Get()
 				defer bufpool.Put(buf)
@@ -152,37 +210,60 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 				if err = jenc.Encode(req); err != nil {
 					logger.Error("marshal", "req", req, "error", err)
 				}
-				logger.Info("marshaled", "REQ", info.FullMethod, "req", buf.String())
-
-				// Fill PArgsHidden
-				if r := reflect.ValueOf(req).Elem(); r.Kind() != reflect.Struct {
-					logger.Info("not struct", "req", fmt.Sprintf("%T %#v", req, req))
-				} else {
-					if f := r.FieldByName("PArgsHidden"); f.IsValid() {
-						f.Set(reflect.ValueOf(buf.String()))
-					}
+				reqJSON := buf.String()
+				reqSize := len(reqJSON)
+				if so.slowCallThreshold <= 0 {
+					logger.Info("marshaled", "REQ", info.FullMethod, "req", reqJSON, "reqSize", reqSize)
 				}
 
+				// Fill the hidden p_args# (PArgsHidden by default)
+				argsHidden.set(req, reqJSON)
+
 				start := time.Now()
 				res, err := handler(ctx, req)
+				if err == nil && idemKey != "" {
+					so.idempotencyCache.Set(ctx, idemKey, res, so.idempotencyWindow)
+				}
+				err = so.withArgs(err, reqJSON)
 
-				logger.Info("handled", "RESP", info.FullMethod, "dur", time.Since(start).String(), "error", err)
+				dur := time.Since(start)
+				full := so.logPayloads(dur, err)
+				if full {
+					if so.slowCallThreshold > 0 { // not logged before the call
+						logger.Info("marshaled", "REQ", info.FullMethod, "req", reqJSON, "reqSize", reqSize)
+					}
+					logger.Info("handled", "RESP", info.FullMethod, "dur", dur.String(), "error", err)
+				}
 				commit(err)
 
 				buf.Reset()
-				if jErr := jenc.Encode(res); err != nil {
-					logger.Error("marshal", jErr, "res", res, "error", err)
+				if jErr := jenc.Encode(res); jErr != nil {
+					logger.Error("marshal", "res", res, "error", jErr)
+				}
+				if full {
+					logger.Info("encoded", "RESP", res, "respSize", buf.Len(), "error", err)
+				} else {
+					logger.Info("handled", "RESP", info.FullMethod, "dur", dur.String(), "reqSize", reqSize, "respSize", buf.Len())
 				}
-				logger.Info("encoded", "RESP", res, "error", err)
+				so.checkPayload(logger, info.FullMethod, reqSize, buf.Len())
 
 				return res, StatusError(err)
 			}),
 	}
+	if len(so.streamInterceptors) != 0 {
+		opts = append(opts, grpc.ChainStreamInterceptor(so.streamInterceptors...))
+	}
+	if len(so.unaryInterceptors) != 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(so.unaryInterceptors...))
+	}
 	// it should be implemented in checkAuth
 	// nosemgrep: go.grpc.security.grpc-server-insecure-connection.grpc-server-insecure-connection
 	return grpc.NewServer(append(opts, options...)...)
 }
 
+// StatusError converts the error to a gRPC status error:
+// oracall.ErrInvalidArgument to codes.InvalidArgument, the ORA- errors registered with RegisterORACode
+// to their codes, and errors with a Code() codes.Code method to that code.
 func StatusError(err error) error {
 	if err == nil {
 		return nil
@@ -193,6 +274,8 @@ func StatusError(err error) error {
 	}
 	if errors.Is(err, oracall.ErrInvalidArgument) {
 		code = codes.InvalidArgument
+	} else if c, ok := oraCodeOf(err); ok {
+		code = c
 	} else if errors.As(err, &sc) {
 		code = sc.Code()
 	}
@@ -216,6 +299,7 @@ func ContextGetReqID(ctx context.Context) string {
 	}
 	return NewULID()
 }
+
 func NewULID() string {
 	return ulid.MustNew(ulid.Now(), rand.Reader).String()
 }
@@ -0,0 +1,21 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextLogger(t *testing.T) {
+	ctx := context.Background()
+	if lgr := FromContext(ctx); lgr != nil {
+		t.Errorf("got %v from an empty context", lgr)
+	}
+	logger := NewT(t).With("reqID", "abc")
+	if lgr := FromContext(WithContext(ctx, logger)); lgr != logger {
+		t.Errorf("got %p, wanted %p", lgr, logger)
+	}
+}
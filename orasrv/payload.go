@@ -0,0 +1,45 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	"google.golang.org/grpc"
+)
+
+// DefaultPayloadWarnSize is the request or response size above which the unary interceptor warns:
+// 3/4 of the default 4MiB maximal message size of gRPC.
+const DefaultPayloadWarnSize = 3 << 20
+
+// WithPayloadSizes sets the soft limit of the request and response sizes, above which the
+// unary interceptor of GRPCServer logs a warning (DefaultPayloadWarnSize if zero, no warning if negative),
+// and the observe function, called with the sizes of each call - to feed them into metrics.
+//
+// The sizes are those of the JSON encoding the interceptor logs, not of the wire format -
+// good enough for capacity planning, without marshaling the messages again.
+func WithPayloadSizes(warnSize int, observe func(fullMethod string, reqSize, respSize int)) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) {
+		if warnSize != 0 {
+			so.payloadWarnSize = warnSize
+		}
+		so.observePayload = observe
+	}}
+}
+
+// checkPayload warns about the request or response over the soft limit, and observes the sizes.
+func (so serverOptions) checkPayload(logger *slog.Logger, fullMethod string, reqSize, respSize int) {
+	if so.observePayload != nil {
+		so.observePayload(fullMethod, reqSize, respSize)
+	}
+	if so.payloadWarnSize <= 0 {
+		return
+	}
+	if reqSize > so.payloadWarnSize {
+		logger.Warn("request is too big", "REQ", fullMethod, "reqSize", reqSize, "warnSize", so.payloadWarnSize)
+	}
+	if respSize > so.payloadWarnSize {
+		logger.Warn("response is too big", "RESP", fullMethod, "respSize", respSize, "warnSize", so.payloadWarnSize)
+	}
+}
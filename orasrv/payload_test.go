@@ -0,0 +1,53 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	"google.golang.org/grpc"
+)
+
+func TestPayloadSizes(t *testing.T) {
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	so, _ := splitOptions(nil)
+	so.checkPayload(logger, "/pkg.Svc/Small", 10, DefaultPayloadWarnSize)
+	if buf.Len() != 0 {
+		t.Errorf("warned under the default limit: %s", buf.String())
+	}
+	so.checkPayload(logger, "/pkg.Svc/Big", 10, DefaultPayloadWarnSize+1)
+	if s := buf.String(); !strings.Contains(s, "response is too big") || strings.Contains(s, "request is too big") {
+		t.Errorf("got %s", s)
+	}
+
+	type call struct {
+		method            string
+		reqSize, respSize int
+	}
+	var calls []call
+	so, _ = splitOptions([]grpc.ServerOption{WithPayloadSizes(100, func(method string, reqSize, respSize int) {
+		calls = append(calls, call{method, reqSize, respSize})
+	})})
+	buf.Reset()
+	so.checkPayload(logger, "/pkg.Svc/A", 101, 50)
+	so.checkPayload(logger, "/pkg.Svc/B", 100, 100)
+	if s := buf.String(); strings.Count(s, "too big") != 1 || !strings.Contains(s, "request is too big") || !strings.Contains(s, "reqSize=101") {
+		t.Errorf("got %s", s)
+	}
+	if len(calls) != 2 || calls[0] != (call{"/pkg.Svc/A", 101, 50}) || calls[1] != (call{"/pkg.Svc/B", 100, 100}) {
+		t.Errorf("observed %v", calls)
+	}
+
+	so, _ = splitOptions([]grpc.ServerOption{WithPayloadSizes(-1, nil)})
+	buf.Reset()
+	so.checkPayload(logger, "/pkg.Svc/Huge", 1<<30, 1<<30)
+	if buf.Len() != 0 {
+		t.Errorf("warned without limit: %s", buf.String())
+	}
+}
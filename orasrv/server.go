@@ -0,0 +1,105 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	godror "github.com/godror/godror"
+	"google.golang.org/grpc"
+)
+
+// ServerConfig configures NewServer.
+type ServerConfig struct {
+	// DSN is the connection string of the database, as godror.ParseConnString accepts it.
+	DSN string
+	// MinSessions and MaxSessions of the session pool - those of the DSN (or the godror defaults) if zero.
+	MinSessions, MaxSessions int
+	// SkipPing lets the server start with the database unreachable,
+	// instead of failing fast (the pool connects on the first call).
+	SkipPing bool
+	// PingTimeout is the timeout of the ping at startup, DefaultHealthTimeout if zero.
+	PingTimeout time.Duration
+
+	// Logger of the server, slog.Default() if nil.
+	Logger *slog.Logger
+	// Verbose, CheckAuth (required - return nil to allow every call) and Options are passed to GRPCServer.
+	Verbose   bool
+	CheckAuth func(ctx context.Context, path string) error
+	Options   []grpc.ServerOption
+}
+
+// Server is the *grpc.Server returned by NewServer, with the pool of its database.
+//
+// Register the generated services onto it (passing DB to them), then Serve.
+type Server struct {
+	*grpc.Server
+	DB *sql.DB
+}
+
+// NewServer creates the session pool of the database and the *grpc.Server (with GRPCServer).
+//
+// The DSN is validated, and the database is pinged (unless SkipPing),
+// so a misconfigured server fails at startup, not at the first call.
+func NewServer(ctx context.Context, cfg ServerConfig) (*Server, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("no DSN")
+	}
+	if cfg.CheckAuth == nil {
+		return nil, errors.New("no CheckAuth")
+	}
+	P, err := godror.ParseConnString(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("parse DSN: %w", err)
+	}
+	P.StandaloneConnection = false
+	if cfg.MinSessions > 0 {
+		P.MinSessions = cfg.MinSessions
+	}
+	if cfg.MaxSessions > 0 {
+		P.MaxSessions = cfg.MaxSessions
+	}
+	if P.MaxSessions > 0 && P.MinSessions > P.MaxSessions {
+		return nil, fmt.Errorf("min sessions (%d) is more than the max sessions (%d)", P.MinSessions, P.MaxSessions)
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	db := sql.OpenDB(godror.NewConnector(P))
+	// the pool of godror holds the sessions
+	db.SetMaxIdleConns(0)
+	if !cfg.SkipPing {
+		timeout := cfg.PingTimeout
+		if timeout <= 0 {
+			timeout = DefaultHealthTimeout
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("ping %s: %w", P.ConnectString, err)
+		}
+	}
+	logger.Info("NewServer", "connectString", P.ConnectString, "minSessions", P.MinSessions, "maxSessions", P.MaxSessions)
+
+	return &Server{
+		Server: GRPCServer(ctx, logger, cfg.Verbose, cfg.CheckAuth, cfg.Options...),
+		DB:     db,
+	}, nil
+}
+
+// Close stops the server gracefully, then closes the database.
+func (s *Server) Close() error {
+	s.Server.GracefulStop()
+	return s.DB.Close()
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNewServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	logger := NewT(t)
+	allowAll := func(context.Context, string) error { return nil }
+	const dsn = `user=scott password=tiger connectString="127.0.0.1:1/nodb"`
+
+	for nm, cfg := range map[string]ServerConfig{
+		"no DSN":       {CheckAuth: allowAll},
+		"no CheckAuth": {DSN: dsn},
+		"bad DSN":      {DSN: `user=scott connectString="`, CheckAuth: allowAll},
+		"min>max":      {DSN: dsn, MinSessions: 10, MaxSessions: 2, SkipPing: true, CheckAuth: allowAll},
+		"unreachable":  {DSN: dsn, PingTimeout: 5 * time.Second, CheckAuth: allowAll},
+	} {
+		cfg.Logger = logger
+		if srv, err := NewServer(ctx, cfg); err == nil {
+			srv.Close()
+			t.Errorf("%s: no error", nm)
+		} else {
+			t.Logf("%s: %+v", nm, err)
+		}
+	}
+
+	srv, err := NewServer(ctx, ServerConfig{DSN: dsn, MinSessions: 1, MaxSessions: 4, SkipPing: true, Logger: logger, CheckAuth: allowAll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	if _, ok := srv.GetServiceInfo()["grpc.health.v1.Health"]; !ok {
+		t.Errorf("health service is not registered: %v", srv.GetServiceInfo())
+	}
+	if err = srv.Close(); err != nil {
+		t.Error(err)
+	}
+}
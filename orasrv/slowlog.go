@@ -0,0 +1,27 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// WithSlowCallLogging makes the unary interceptor of GRPCServer log the request and response payloads
+// only of the calls which took at least threshold (or failed): the faster calls are logged with
+// their duration and payload sizes only.
+//
+// The payloads are logged after the call, as its duration is known only then.
+// A non-positive threshold logs every payload, as without this option.
+func WithSlowCallLogging(threshold time.Duration) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) { so.slowCallThreshold = threshold }}
+}
+
+// logPayloads reports whether the payloads of the call which took dur and returned err are logged
+// (see WithSlowCallLogging).
+func (so serverOptions) logPayloads(dur time.Duration, err error) bool {
+	return so.slowCallThreshold <= 0 || err != nil || dur >= so.slowCallThreshold
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// syncBuilder is a strings.Builder safe for concurrent writes.
+type syncBuilder struct {
+	mu sync.Mutex
+	strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Builder.Write(p)
+}
+func (b *syncBuilder) Take() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.Builder.String()
+	b.Builder.Reset()
+	return s
+}
+
+// sleepyHealth sleeps for the "slow" service, and fails for the "fail" service.
+type sleepyHealth struct {
+	healthpb.UnimplementedHealthServer
+	sleep time.Duration
+}
+
+func (h sleepyHealth) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	switch req.GetService() {
+	case "slow":
+		time.Sleep(h.sleep)
+	case "fail":
+		return nil, errors.New("failed")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func TestWithSlowCallLogging(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const threshold = 100 * time.Millisecond
+	var buf syncBuilder
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	srv := GRPCServer(ctx, logger, false, func(context.Context, string) error { return nil },
+		WithSlowCallLogging(threshold))
+	healthpb.RegisterHealthServer(srv, sleepyHealth{sleep: 2 * threshold})
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	for _, tC := range []struct {
+		Service string
+		Full    bool
+	}{
+		{"fast", false},
+		{"slow", true},
+		{"fail", true},
+	} {
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: tC.Service})
+		if (err != nil) != (tC.Service == "fail") {
+			t.Errorf("%s: %+v", tC.Service, err)
+		}
+		s := buf.Take()
+		t.Logf("%s: %s", tC.Service, s)
+		if !strings.Contains(s, "dur=") {
+			t.Errorf("%s: no duration logged: %s", tC.Service, s)
+		}
+		if got := strings.Contains(s, tC.Service); got != tC.Full {
+			t.Errorf("%s: request payload logged: %t, wanted %t", tC.Service, got, tC.Full)
+		}
+		if !tC.Full && !strings.Contains(s, "reqSize=") {
+			t.Errorf("%s: no request size logged: %s", tC.Service, s)
+		}
+	}
+
+	// the option is off by default
+	so, _ := splitOptions(nil)
+	if !so.logPayloads(0, nil) {
+		t.Error("payloads are not logged by default")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TxMetadataKey is the gRPC metadata key of the transaction ID (returned by TxManager.Begin)
+// the call should be executed in.
+const TxMetadataKey = "oracall-tx"
+
+// DefaultTxTimeout is the time after which an unfinished transaction is rolled back.
+const DefaultTxTimeout = 5 * time.Minute
+
+// TxManager holds the transactions begun by the clients, so several calls can share one.
+//
+// Expose Begin, Commit and Rollback as RPCs of the application, and pass the TxManager
+// to GRPCServer with WithTxManager: the calls with the TxMetadataKey metadata are then
+// executed in that transaction, and the generated functions leave committing it to Commit.
+type TxManager struct {
+	db      *sql.DB
+	timeout time.Duration
+
+	mu  sync.Mutex
+	txs map[string]*managedTx
+}
+
+// managedTx is a transaction, with its lock serializing the calls using it.
+type managedTx struct {
+	sync.Mutex
+	tx     *sql.Tx
+	cancel context.CancelFunc
+}
+
+// NewTxManager returns a TxManager beginning the transactions on db,
+// rolling them back after timeout (DefaultTxTimeout if not positive).
+func NewTxManager(db *sql.DB, timeout time.Duration) *TxManager {
+	if timeout <= 0 {
+		timeout = DefaultTxTimeout
+	}
+	return &TxManager{db: db, timeout: timeout, txs: make(map[string]*managedTx)}
+}
+
+// Begin a new transaction, returning its ID.
+func (m *TxManager) Begin(ctx context.Context) (string, error) {
+	// the transaction outlives the call beginning it
+	txCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	tx, err := m.db.BeginTx(txCtx, nil)
+	if err != nil {
+		cancel()
+		return "", err
+	}
+	id := NewULID()
+	m.mu.Lock()
+	m.txs[id] = &managedTx{tx: tx, cancel: cancel}
+	m.mu.Unlock()
+	// roll back (and forget) the abandoned transaction
+	context.AfterFunc(txCtx, func() { m.end(id, false) })
+	return id, nil
+}
+
+// Commit the transaction.
+func (m *TxManager) Commit(id string) error { return m.end(id, true) }
+
+// Rollback the transaction.
+func (m *TxManager) Rollback(id string) error { return m.end(id, false) }
+
+func (m *TxManager) end(id string, commit bool) error {
+	m.mu.Lock()
+	mt := m.txs[id]
+	delete(m.txs, id)
+	m.mu.Unlock()
+	if mt == nil {
+		return status.Errorf(codes.NotFound, "no transaction %q", id)
+	}
+	// wait for the running call
+	mt.Lock()
+	defer mt.Unlock()
+	defer mt.cancel()
+	if commit {
+		return mt.tx.Commit()
+	}
+	return mt.tx.Rollback()
+}
+
+// acquire returns the context carrying the transaction named by the TxMetadataKey metadata
+// of the incoming call, and the function releasing it for the other calls.
+// The context is returned as is if the call has no such metadata.
+func (m *TxManager) acquire(ctx context.Context) (context.Context, func(), error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ids := md.Get(TxMetadataKey)
+	if len(ids) == 0 || ids[0] == "" {
+		return ctx, func() {}, nil
+	}
+	m.mu.Lock()
+	mt := m.txs[ids[0]]
+	m.mu.Unlock()
+	if mt == nil {
+		return ctx, nil, status.Errorf(codes.FailedPrecondition, "no transaction %q", ids[0])
+	}
+	mt.Lock()
+	return oracall.ContextWithTx(ctx, mt.tx), mt.Unlock, nil
+}
+
+// WithTxManager makes the interceptors of GRPCServer execute the calls with the TxMetadataKey metadata
+// in the transaction of the TxManager.
+func WithTxManager(m *TxManager) grpc.ServerOption {
+	return serverOption{apply: func(so *serverOptions) { so.txManager = m }}
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 Tamas Gulacsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// txDriver is a database/sql driver only counting the transactions' ends.
+type txDriver struct{ commits, rollbacks atomic.Int32 }
+
+func (d *txDriver) Open(string) (driver.Conn, error) { return txConn{d}, nil }
+
+type txConn struct{ d *txDriver }
+
+func (c txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (c txConn) Close() error                        { return nil }
+func (c txConn) Begin() (driver.Tx, error)           { return txTx(c), nil }
+
+type txTx struct{ d *txDriver }
+
+func (t txTx) Commit() error   { t.d.commits.Add(1); return nil }
+func (t txTx) Rollback() error { t.d.rollbacks.Add(1); return nil }
+
+func TestTxManager(t *testing.T) {
+	d := new(txDriver)
+	sql.Register("orasrv-tx-test", d)
+	db, err := sql.Open("orasrv-tx-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	m := NewTxManager(db, 100*time.Millisecond)
+
+	// without metadata, the context has no transaction
+	if gotCtx, release, err := m.acquire(ctx); err != nil {
+		t.Fatal(err)
+	} else if release(); oracall.TxFromContext(gotCtx) != nil {
+		t.Error("transaction without metadata")
+	}
+
+	id, err := m.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		callCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(TxMetadataKey, id))
+		gotCtx, release, err := m.acquire(callCtx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oracall.TxFromContext(gotCtx) == nil {
+			t.Errorf("call %d: no transaction in the context", i)
+		}
+		release()
+	}
+	if err = m.Commit(id); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.commits.Load(); n != 1 {
+		t.Errorf("got %d commits, wanted 1", n)
+	}
+	callCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(TxMetadataKey, id))
+	if _, _, err = m.acquire(callCtx); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("acquire finished transaction: got %v, wanted FailedPrecondition", err)
+	}
+	if err = m.Rollback(id); status.Code(err) != codes.NotFound {
+		t.Errorf("rollback finished transaction: got %v, wanted NotFound", err)
+	}
+
+	// an abandoned transaction is rolled back
+	if _, err = m.Begin(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for d.rollbacks.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := d.rollbacks.Load(); n != 1 {
+		t.Errorf("got %d rollbacks, wanted 1", n)
+	}
+	m.mu.Lock()
+	n := len(m.txs)
+	m.mu.Unlock()
+	if n != 0 {
+		t.Errorf("%d transactions left", n)
+	}
+}
@@ -387,3 +387,47 @@ END DB_web_dbx;`,
 		}
 	}
 }
+
+func TestAnnotationRegexp(t *testing.T) {
+	const src = `CREATE OR REPLACE PACKAGE db_web AS
+--oracall:replace get_x => get_x_wrapper
+--oracall:xml-fetch get_list => get_list_xml
+--oracall:private get_secret
+--oracall:max-table-size get_list = 1000
+--oracall:deprecated get_old.p_id
+  PROCEDURE get_list(p_cur OUT SYS_REFCURSOR);
+END;`
+	want := []string{
+		"--oracall:replace get_x => get_x_wrapper",
+		"--oracall:xml-fetch get_list => get_list_xml",
+		"--oracall:private get_secret",
+		"--oracall:max-table-size get_list = 1000",
+		"--oracall:deprecated get_old.p_id",
+	}
+	var got []string
+	for _, b := range rAnnotation.FindAll([]byte(src), -1) {
+		got = append(got, string(b))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestArgumentsColumns(t *testing.T) {
+	for _, tbl := range []string{"user_arguments", "all_arguments"} {
+		got := argumentsColumns(tbl)
+		// as parseDB scans them
+		want := []string{"OBJECT_ID", "SUBPROGRAM_ID", "SEQ", "PACKAGE_NAME", "OBJECT_NAME",
+			"DATA_LEVEL", "POSITION", "ARGUMENT_NAME", "IN_OUT",
+			"DATA_TYPE", "DATA_PRECISION", "DATA_SCALE", "CHARACTER_SET_NAME", "INDEX_BY",
+			"PLS_TYPE", "CHAR_LENGTH", "TYPE_OWNER", "TYPE_NAME", "TYPE_SUBNAME", "TYPE_LINK", "DEFAULTED", "CHAR_USED",
+			"OWNER"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %q, wanted %q", tbl, got, want)
+		}
+		qry := argumentsQuery(tbl)
+		if wantOwner := map[string]string{"user_arguments": "USER AS owner", "all_arguments": "owner AS owner"}[tbl]; !strings.Contains(qry, wantOwner) {
+			t.Errorf("%s: %q not found in %s", tbl, wantOwner, qry)
+		}
+	}
+}